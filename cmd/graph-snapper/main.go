@@ -1,25 +1,48 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"github.com/gin-gonic/gin"
 	"github.com/sajeevany/graph-snapper/internal/account"
+	"github.com/sajeevany/graph-snapper/internal/admin"
+	"github.com/sajeevany/graph-snapper/internal/artifact"
+	"github.com/sajeevany/graph-snapper/internal/audit"
 	"github.com/sajeevany/graph-snapper/internal/config"
+	"github.com/sajeevany/graph-snapper/internal/confluence"
 	"github.com/sajeevany/graph-snapper/internal/credentials"
 	"github.com/sajeevany/graph-snapper/internal/db/aerospike"
+	"github.com/sajeevany/graph-snapper/internal/destination"
+	"github.com/sajeevany/graph-snapper/internal/discovery"
+	"github.com/sajeevany/graph-snapper/internal/grafana"
 	"github.com/sajeevany/graph-snapper/internal/health"
 	"github.com/sajeevany/graph-snapper/internal/logging"
 	"github.com/sajeevany/graph-snapper/internal/logging/middleware"
+	"github.com/sajeevany/graph-snapper/internal/prometheus"
+	"github.com/sajeevany/graph-snapper/internal/report"
+	"github.com/sajeevany/graph-snapper/internal/s3"
+	"github.com/sajeevany/graph-snapper/internal/source"
+	"github.com/sajeevany/graph-snapper/internal/tracing"
+	traceMiddleware "github.com/sajeevany/graph-snapper/internal/tracing/middleware"
+	"github.com/sajeevany/graph-snapper/internal/upstream"
 	"github.com/sirupsen/logrus"
 	ginSwagger "github.com/swaggo/gin-swagger"
 	"github.com/swaggo/gin-swagger/swaggerFiles"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
 	_ "github.com/sajeevany/graph-snapper/docs"
 )
 
 const (
 	v1Api = "/api/v1"
+
+	//shutdownTimeout - how long in flight requests are given to finish on shutdown
+	shutdownTimeout = 10 * time.Second
 )
 
 // @title Graph Snapper API
@@ -29,7 +52,7 @@ const (
 // @BasePath /api/v1
 func main() {
 
-	//Create a universal logger. Set default to debug and update later
+	//Create a universal logger. Set default to debug and update once the configuration has been read
 	logger := logging.Init()
 	logger.SetLevel(logrus.DebugLevel)
 
@@ -44,29 +67,101 @@ func main() {
 		}
 	}
 
+	//Apply logging configuration. The log file is closed on shutdown
+	closeLogs, lErr := logging.Configure(logger, conf.Logging)
+	if lErr != nil {
+		logger.WithFields(conf.Logging.GetFields()).Fatalf("Failed to apply logging configuration. Error : <%v>", lErr)
+	}
+	defer func() {
+		if cErr := closeLogs(); cErr != nil {
+			logger.Errorf("An error occurred when closing the log file. <%v>", cErr)
+		}
+	}()
+	logger.WithFields(conf.Logging.GetFields()).Info("Logging configuration applied")
+
+	//Setup tracing. Spans are flushed on shutdown
+	shutdownTracing, tErr := tracing.Init(logger, conf.Tracing)
+	if tErr != nil {
+		logger.WithFields(conf.Tracing.GetFields()).Fatalf("Failed to setup tracing. Error : <%v>", tErr)
+	}
+	defer func() {
+		if sErr := shutdownTracing(context.Background()); sErr != nil {
+			logger.Errorf("An error occurred when flushing traces. <%v>", sErr)
+		}
+	}()
+
 	//Get aerospike client
 	aeroClient, err := aerospike.New(logger, conf.Aerospike)
 	if err != nil {
 		logger.WithFields(conf.Aerospike.GetFields()).Fatalf("Failed to create Aerospike client using client. Error : <%v>", err)
 	}
 
+	//Get audit sink
+	auditor, err := audit.NewSink(logger, conf.Audit, conf.Aerospike, aeroClient)
+	if err != nil {
+		logger.WithFields(conf.Audit.GetFields()).Fatalf("Failed to create audit sink. Error : <%v>", err)
+	}
+
+	//Get artifact store for captured snapshots
+	store, err := artifact.NewStore(conf.Artifacts)
+	if err != nil {
+		logger.WithFields(conf.Artifacts.GetFields()).Fatalf("Failed to create artifact store. Error : <%v>", err)
+	}
+
+	//Get writer of static HTML reports
+	reportWriter, err := report.NewWriter(conf.Reports)
+	if err != nil {
+		logger.WithFields(conf.Reports.GetFields()).Fatalf("Failed to create report writer. Error : <%v>", err)
+	}
+
+	//Shared client for grafana and confluence calls
+	upstreamClient := upstream.New(conf.Upstream)
+
+	//Register sources snapshots can be captured from. Their credentials are stored and checked by type
+	registerSources(upstreamClient)
+
+	//Register destinations snapshots can be published to. Their credentials are stored and checked by type
+	registerDestinations(upstreamClient, store, reportWriter)
+
 	//Initialize router
 	router := setupRouter(logger)
 
 	//Setup routes
-	setupV1Routes(router, logger, aeroClient)
+	setupV1Routes(router, logger, aeroClient, auditor, upstreamClient, store, reportWriter, conf)
 
 	//Add swagger route
 	router.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
 
-	//Use default route of 8080.
-	routerErr := router.Run(":8080")
-	if routerErr != nil {
-		logger.Errorf("An error occurred when starting the router. <%v>", routerErr)
+	//Use default route of 8080. Stops on SIGINT or SIGTERM so that deferred shutdown steps run
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	if routerErr := serve(ctx, logger, router, ":8080"); routerErr != nil {
+		logger.Errorf("An error occurred when running the router. <%v>", routerErr)
 	}
 
 }
 
+//serve - Serves the router until the context is done, then waits for in flight requests to finish
+func serve(ctx context.Context, logger *logrus.Logger, router *gin.Engine, addr string) error {
+
+	server := &http.Server{Addr: addr, Handler: router}
+	errs := make(chan error, 1)
+	go func() {
+		errs <- server.ListenAndServe()
+	}()
+
+	select {
+	case err := <-errs:
+		return err
+	case <-ctx.Done():
+	}
+
+	logger.Info("Shutting down")
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	return server.Shutdown(shutdownCtx)
+}
+
 func readConf(logger *logrus.Logger, filepath string) (*config.Conf, bool, map[string]string) {
 	//Read configuration file. Kill startup if an error was found.
 	conf, err := config.Read(filepath, logger)
@@ -80,12 +175,28 @@ func readConf(logger *logrus.Logger, filepath string) (*config.Conf, bool, map[s
 	return conf, isValid, invalidArgs
 }
 
+//registerSources - Registers every supported source type
+func registerSources(upstreamClient *upstream.Client) {
+	source.Register(grafana.NewSource(upstreamClient))
+	source.Register(prometheus.NewSource(upstreamClient))
+}
+
+//registerDestinations - Registers every supported destination type
+func registerDestinations(upstreamClient *upstream.Client, store *artifact.Store, reportWriter *report.Writer) {
+	destination.Register(confluence.NewServerDestination(upstreamClient))
+	destination.Register(confluence.NewCloudDestination(upstreamClient))
+	destination.Register(s3.NewDestination(upstreamClient))
+	destination.Register(report.NewDestination(reportWriter, store))
+}
+
 //setupRouter - Create the router and set middleware
 func setupRouter(logger *logrus.Logger) *gin.Engine {
 
 	engine := gin.New()
 
 	//Add middleware
+	engine.Use(traceMiddleware.TraceRequest())
+	engine.Use(middleware.SetRequestID())
 	engine.Use(middleware.SetCtxLogger(logger))
 	engine.Use(middleware.LogRequest(logger))
 	engine.Use(gin.Recovery())
@@ -93,9 +204,10 @@ func setupRouter(logger *logrus.Logger) *gin.Engine {
 	return engine
 }
 
-func setupV1Routes(rtr *gin.Engine, logger *logrus.Logger, aeroClient *aerospike.ASClient) {
+func setupV1Routes(rtr *gin.Engine, logger *logrus.Logger, aeroClient *aerospike.ASClient, auditor audit.Sink, upstreamClient *upstream.Client, store *artifact.Store, reportWriter *report.Writer, conf *config.Conf) {
 	addHealthEndpoints(rtr, logger)
-	addAccountEndpoints(rtr, logger, aeroClient)
+	addAdminEndpoints(rtr, logger)
+	addAccountEndpoints(rtr, logger, aeroClient, auditor, upstreamClient, store, reportWriter, conf)
 }
 
 func addHealthEndpoints(rtr *gin.Engine, logger *logrus.Logger) {
@@ -105,14 +217,41 @@ func addHealthEndpoints(rtr *gin.Engine, logger *logrus.Logger) {
 	}
 }
 
-func addAccountEndpoints(rtr *gin.Engine, logger *logrus.Logger, aeroClient *aerospike.ASClient) {
+func addAdminEndpoints(rtr *gin.Engine, logger *logrus.Logger) {
+	v1Api := rtr.Group(fmt.Sprintf("%s%s", v1Api, admin.Group))
+	{
+		v1Api.PUT(admin.LoggingLevelEndpoint, admin.PutLoggingLevelV1(logger))
+	}
+}
+
+func addAccountEndpoints(rtr *gin.Engine, logger *logrus.Logger, aeroClient *aerospike.ASClient, auditor audit.Sink, upstreamClient *upstream.Client, store *artifact.Store, reportWriter *report.Writer, conf *config.Conf) {
 	v1Api := rtr.Group(fmt.Sprintf("%s%s", v1Api, account.Group))
 	{
-		v1Api.PUT(account.PutAccountEndpoint, account.PutAccountV1(logger, aeroClient))
+		v1Api.PUT(account.PutAccountEndpoint, account.PutAccountV1(logger, aeroClient, auditor))
 		v1Api.GET(account.GetAccountEndpoint, account.GetAccountV1(logger, aeroClient))
+		v1Api.GET(audit.GetAuditEndpoint, audit.GetAuditV1(logger, auditor))
 
 		//Credentials sub group
-		v1Api.PUT(credentials.AddCredentialsEndpoint, credentials.PutCredentialsV1(logger, aeroClient))
-		v1Api.POST(credentials.CheckCredentialsEndpoint, credentials.CheckV1(logger))
+		v1Api.PUT(credentials.AddCredentialsEndpoint, credentials.PutCredentialsV1(logger, aeroClient, auditor, upstreamClient, conf.CredentialCheck))
+		v1Api.POST(credentials.CheckCredentialsEndpoint, credentials.CheckV1(logger, upstreamClient, conf.CredentialCheck))
+		v1Api.POST(credentials.CheckAccountCredentialsEndpoint, credentials.CheckAccountV1(logger, aeroClient, upstreamClient, conf.CredentialCheck))
+
+		//Grafana discovery sub group
+		v1Api.GET(discovery.GrafanaSearchEndpoint, discovery.GrafanaSearchV1(logger, aeroClient, upstreamClient))
+		v1Api.GET(discovery.GrafanaFoldersEndpoint, discovery.GrafanaFoldersV1(logger, aeroClient, upstreamClient))
+		v1Api.GET(discovery.GrafanaDashboardPanelsEndpoint, discovery.GrafanaDashboardPanelsV1(logger, aeroClient, upstreamClient))
+		v1Api.POST(discovery.GrafanaCheckTargetEndpoint, discovery.GrafanaCheckTargetV1(logger, aeroClient, upstreamClient))
+		v1Api.POST(discovery.GrafanaCaptureEndpoint, discovery.GrafanaCaptureV1(logger, aeroClient, upstreamClient, store))
+
+		//Source sub group
+		v1Api.GET(discovery.SourceDashboardsEndpoint, discovery.SourceDashboardsV1(logger, aeroClient))
+		v1Api.POST(discovery.SourceCaptureEndpoint, discovery.SourceCaptureV1(logger, aeroClient, store))
+
+		//Snapshot sub group
+		v1Api.GET(artifact.ListSnapshotsEndpoint, artifact.ListSnapshotsV1(logger, store))
+		v1Api.GET(artifact.GetSnapshotImageEndpoint, artifact.GetSnapshotImageV1(logger, store))
+		v1Api.GET(artifact.GetSnapshotExportEndpoint, artifact.GetSnapshotExportV1(logger, store))
+		v1Api.GET(artifact.GetSnapshotDiffEndpoint, artifact.GetSnapshotDiffV1(logger, store))
+		v1Api.POST(report.RegenerateReportEndpoint, report.RegenerateReportV1(logger, aeroClient, store, reportWriter))
 	}
 }
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"github.com/gin-gonic/gin"
 	"github.com/sajeevany/graph-snapper/internal/db/aerospike"
+	"github.com/sajeevany/graph-snapper/internal/logging"
 	"github.com/sirupsen/logrus"
 	"net/http"
 )
@@ -18,9 +19,12 @@ const GetAccountEndpoint = "/:id"
 //@Fail 404 {object} gin.H
 //@Router /account/:id [get]
 //@Tags account
-func GetAccountV1(logger *logrus.Logger, aeroClient *aerospike.ASClient) gin.HandlerFunc {
+func GetAccountV1(baseLogger *logrus.Logger, aeroClient *aerospike.ASClient) gin.HandlerFunc {
 	return func(ctx *gin.Context) {
 
+		//Use request scoped logger
+		logger := logging.FromContext(ctx.Request.Context(), baseLogger)
+
 		//Validate that id parameter has been set
 		accountId := ctx.Param("id")
 		if accountId == "" {
@@ -32,7 +36,7 @@ func GetAccountV1(logger *logrus.Logger, aeroClient *aerospike.ASClient) gin.Han
 
 		//Check if record exists
 		reader := aeroClient.GetReader()
-		recordExists, aKey, kErr := reader.KeyExists(accountId)
+		recordExists, aKey, kErr := reader.KeyExists(ctx.Request.Context(), accountId)
 		if kErr != nil {
 			hrErrMsg := fmt.Sprintf("unable to check db for key <%v>. err <%v>", accountId, kErr)
 			logger.Errorf(hrErrMsg)
@@ -49,7 +53,7 @@ func GetAccountV1(logger *logrus.Logger, aeroClient *aerospike.ASClient) gin.Han
 		}
 
 		//fetch account
-		rec, rErr := reader.ReadRecord(aKey)
+		rec, rErr := reader.ReadRecord(ctx.Request.Context(), aKey)
 		if rErr != nil {
 			hrErrMsg := fmt.Sprintf("unable to read db for key <%v> namespace <%v> set <%v>. err <%v>", accountId, aKey.Namespace(), aKey.SetName(), rErr)
 			logger.Errorf(hrErrMsg)
@@ -62,7 +66,7 @@ func GetAccountV1(logger *logrus.Logger, aeroClient *aerospike.ASClient) gin.Han
 
 		//Return view
 		view := rec.ToRecordViewV1()
-		logger.Infof("Record <%v> as view <%v>", rec, view)
+		logger.WithFields(rec.GetFields()).Debug("Returning record view")
 		ctx.JSON(http.StatusOK, view)
 	}
 }
@@ -1,10 +1,13 @@
 package account
 
 import (
+	"context"
 	"fmt"
 	"github.com/gin-gonic/gin"
+	"github.com/sajeevany/graph-snapper/internal/audit"
 	"github.com/sajeevany/graph-snapper/internal/db/aerospike"
 	"github.com/sajeevany/graph-snapper/internal/db/aerospike/record"
+	"github.com/sajeevany/graph-snapper/internal/logging"
 	"github.com/sirupsen/logrus"
 	"net/http"
 	"time"
@@ -22,9 +25,12 @@ const PutAccountEndpoint = "/:id"
 //@Fail 404 {object} gin.H
 //@Router /account/:id [put]
 //@Tags account
-func PutAccountV1(logger *logrus.Logger, aeroClient *aerospike.ASClient) gin.HandlerFunc {
+func PutAccountV1(baseLogger *logrus.Logger, aeroClient *aerospike.ASClient, auditor audit.Sink) gin.HandlerFunc {
 	return func(ctx *gin.Context) {
 
+		//Use request scoped logger
+		logger := logging.FromContext(ctx.Request.Context(), baseLogger)
+
 		//Validate that id parameter has been set
 		accountId := ctx.Param("id")
 		if accountId == "" {
@@ -51,7 +57,7 @@ func PutAccountV1(logger *logrus.Logger, aeroClient *aerospike.ASClient) gin.Han
 		}
 
 		//Create account
-		record, err := CreateAccount(logger, aeroClient, accountId, account)
+		record, err := CreateAccount(ctx.Request.Context(), logger, aeroClient, accountId, account)
 		if err != nil {
 			hrErrMsg := fmt.Sprintf("internal error when writing record to aerospike. %v", err)
 			logger.WithFields(record.GetFields()).Errorf(hrErrMsg)
@@ -59,8 +65,12 @@ func PutAccountV1(logger *logrus.Logger, aeroClient *aerospike.ASClient) gin.Han
 				"error":              err,
 				"humanReadableError": hrErrMsg,
 			})
+			return
 		}
 
+		//Record account creation
+		audit.Record(ctx, logger, auditor, accountId, audit.AccountCreateAction, nil, record.Account.ToBinMap())
+
 		view := record.ToRecordViewV1()
 
 		ctx.JSON(http.StatusOK, view)
@@ -68,7 +78,7 @@ func PutAccountV1(logger *logrus.Logger, aeroClient *aerospike.ASClient) gin.Han
 }
 
 //assumes valid account
-func CreateAccount(logger *logrus.Logger, aeroClient *aerospike.ASClient, key string, account record.AccountViewV1) (*record.RecordV1, error) {
+func CreateAccount(ctx context.Context, logger *logrus.Entry, aeroClient *aerospike.ASClient, key string, account record.AccountViewV1) (*record.RecordV1, error) {
 
 	logger.Debug("Creating account record")
 
@@ -76,7 +86,7 @@ func CreateAccount(logger *logrus.Logger, aeroClient *aerospike.ASClient, key st
 	rec := newAccountRecordV1(key, account)
 
 	aeroWriter := aeroClient.GetWriter()
-	if wErr := aeroWriter.WriteRecord(key, rec); wErr != nil {
+	if wErr := aeroWriter.WriteRecord(ctx, key, rec); wErr != nil {
 		hErr := fmt.Sprintf("Unable to write record with key <%v>", key)
 		logger.WithFields(rec.GetFields()).Error(hErr)
 		return nil, wErr
@@ -0,0 +1,61 @@
+package admin
+
+import (
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"github.com/sajeevany/graph-snapper/internal/logging"
+	"github.com/sirupsen/logrus"
+	"net/http"
+)
+
+const (
+	Group                = "/admin"
+	LoggingLevelEndpoint = "/logging/level"
+)
+
+//LoggingLevelV1 - Logging level of the service
+type LoggingLevelV1 struct {
+	Level string `json:"level" example:"info"`
+}
+
+//@Summary Set logging level
+//@Description Non-authenticated endpoint that changes the service logging level without a restart. The change is not persisted to the configuration file.
+//@Produce json
+//@Param level body LoggingLevelV1 true "Logging level"
+//@Success 200 {object} LoggingLevelV1
+//@Fail 400 {object} gin.H
+//@Router /admin/logging/level [put]
+//@Tags admin
+func PutLoggingLevelV1(baseLogger *logrus.Logger) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+
+		//Use request scoped logger
+		logger := logging.FromContext(ctx.Request.Context(), baseLogger)
+
+		//Bind level object
+		var req LoggingLevelV1
+		if bErr := ctx.BindJSON(&req); bErr != nil {
+			msg := fmt.Sprintf("Unable to bind request body to logging level object %v", bErr)
+			logger.Errorf(msg)
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": msg})
+			return
+		}
+
+		//Validate level
+		level, pErr := logrus.ParseLevel(req.Level)
+		if pErr != nil {
+			logger.Debugf("Invalid logging level <%v> was requested", req.Level)
+			ctx.JSON(http.StatusBadRequest, gin.H{
+				"humanReadableError": fmt.Sprintf("Logging level <%v> is invalid. Expected one of %v", req.Level, logrus.AllLevels),
+				"error":              pErr.Error(),
+			})
+			return
+		}
+
+		previous := baseLogger.GetLevel()
+		baseLogger.SetLevel(level)
+		logger.Infof("Logging level changed from <%v> to <%v>", previous, level)
+
+		ctx.JSON(http.StatusOK, LoggingLevelV1{Level: level.String()})
+	}
+}
@@ -0,0 +1,66 @@
+package admin
+
+import (
+	"bytes"
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestPutLoggingLevelV1(t *testing.T) {
+	tests := []struct {
+		name      string
+		body      string
+		wantCode  int
+		wantLevel logrus.Level
+	}{
+		{
+			name:      "test0 valid level is applied",
+			body:      `{"level":"warn"}`,
+			wantCode:  http.StatusOK,
+			wantLevel: logrus.WarnLevel,
+		},
+		{
+			name:      "test1 level is case insensitive",
+			body:      `{"level":"ERROR"}`,
+			wantCode:  http.StatusOK,
+			wantLevel: logrus.ErrorLevel,
+		},
+		{
+			name:      "test2 invalid level is rejected and level is unchanged",
+			body:      `{"level":"loud"}`,
+			wantCode:  http.StatusBadRequest,
+			wantLevel: logrus.InfoLevel,
+		},
+		{
+			name:      "test3 malformed body is rejected",
+			body:      `{"level":`,
+			wantCode:  http.StatusBadRequest,
+			wantLevel: logrus.InfoLevel,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			logger := logrus.New()
+			logger.SetLevel(logrus.InfoLevel)
+
+			gin.SetMode(gin.TestMode)
+			w := httptest.NewRecorder()
+			_, r := gin.CreateTestContext(w)
+			r.PUT("/api/v1/admin/logging/level", PutLoggingLevelV1(logger))
+
+			req, _ := http.NewRequest(http.MethodPut, "/api/v1/admin/logging/level", bytes.NewBufferString(tt.body))
+			req.Header.Add("Content-Type", "application/json")
+			r.ServeHTTP(w, req)
+
+			if w.Code != tt.wantCode {
+				t.Errorf("Incorrect return code. Expected <%v> got <%v>", tt.wantCode, w.Code)
+			}
+			if logger.GetLevel() != tt.wantLevel {
+				t.Errorf("Logger level = <%v>, want <%v>", logger.GetLevel(), tt.wantLevel)
+			}
+		})
+	}
+}
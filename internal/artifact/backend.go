@@ -0,0 +1,125 @@
+package artifact
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/sajeevany/graph-snapper/internal/config"
+	"strings"
+	"sync"
+)
+
+//ErrNotFound - the snapshot or image doesn't exist
+var ErrNotFound = errors.New("artifact not found")
+
+//Backend - storage of images and snapshot metadata. Images are keyed by account and SHA256 so that identical images
+//are stored once per account. Get methods return ErrNotFound for missing entries
+type Backend interface {
+	PutImage(ctx context.Context, accountID, sum string, img []byte) error
+	GetImage(ctx context.Context, accountID, sum string) ([]byte, error)
+	HasImage(ctx context.Context, accountID, sum string) (bool, error)
+	DeleteImage(ctx context.Context, accountID, sum string) error
+
+	PutSnapshot(ctx context.Context, snapshot SnapshotV1) error
+	GetSnapshot(ctx context.Context, accountID, id string) (SnapshotV1, error)
+	ListSnapshots(ctx context.Context, accountID string) ([]SnapshotV1, error)
+	DeleteSnapshot(ctx context.Context, accountID, id string) error
+}
+
+//NewBackend - Returns the backend defined by the artifacts config
+func NewBackend(conf config.Artifacts) (Backend, error) {
+	switch strings.ToLower(conf.Backend) {
+	case config.FilesystemArtifactBackend:
+		return NewFilesystemBackend(conf.Dir)
+	case config.MemoryArtifactBackend:
+		return NewMemoryBackend(), nil
+	default:
+		return nil, fmt.Errorf("unsupported artifact backend <%v>", conf.Backend)
+	}
+}
+
+//MemoryBackend - keeps artifacts in memory. Intended for tests and single instance development setups
+type MemoryBackend struct {
+	mu        sync.RWMutex
+	images    map[string][]byte
+	snapshots map[string]map[string]SnapshotV1
+}
+
+func NewMemoryBackend() *MemoryBackend {
+	return &MemoryBackend{
+		images:    make(map[string][]byte),
+		snapshots: make(map[string]map[string]SnapshotV1),
+	}
+}
+
+func (m *MemoryBackend) PutImage(ctx context.Context, accountID, sum string, img []byte) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.images[imageKey(accountID, sum)] = append([]byte(nil), img...)
+	return nil
+}
+
+func (m *MemoryBackend) GetImage(ctx context.Context, accountID, sum string) ([]byte, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	img, exists := m.images[imageKey(accountID, sum)]
+	if !exists {
+		return nil, ErrNotFound
+	}
+	return append([]byte(nil), img...), nil
+}
+
+func (m *MemoryBackend) HasImage(ctx context.Context, accountID, sum string) (bool, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	_, exists := m.images[imageKey(accountID, sum)]
+	return exists, nil
+}
+
+func (m *MemoryBackend) DeleteImage(ctx context.Context, accountID, sum string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.images, imageKey(accountID, sum))
+	return nil
+}
+
+func (m *MemoryBackend) PutSnapshot(ctx context.Context, snapshot SnapshotV1) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.snapshots[snapshot.AccountID] == nil {
+		m.snapshots[snapshot.AccountID] = make(map[string]SnapshotV1)
+	}
+	m.snapshots[snapshot.AccountID][snapshot.ID] = snapshot
+	return nil
+}
+
+func (m *MemoryBackend) GetSnapshot(ctx context.Context, accountID, id string) (SnapshotV1, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	snapshot, exists := m.snapshots[accountID][id]
+	if !exists {
+		return SnapshotV1{}, ErrNotFound
+	}
+	return snapshot, nil
+}
+
+func (m *MemoryBackend) ListSnapshots(ctx context.Context, accountID string) ([]SnapshotV1, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	snapshots := make([]SnapshotV1, 0, len(m.snapshots[accountID]))
+	for _, s := range m.snapshots[accountID] {
+		snapshots = append(snapshots, s)
+	}
+	return snapshots, nil
+}
+
+func (m *MemoryBackend) DeleteSnapshot(ctx context.Context, accountID, id string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.snapshots[accountID], id)
+	return nil
+}
+
+func imageKey(accountID, sum string) string {
+	return accountID + "/" + sum
+}
@@ -0,0 +1,214 @@
+package artifact
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+const (
+	imagesDir    = "images"
+	snapshotsDir = "snapshots"
+	metaSuffix   = ".json"
+
+	dirPerm  = 0750
+	filePerm = 0640
+)
+
+//sumRegex - hex encoded SHA256. Ensures image paths stay within the account directory
+var sumRegex = regexp.MustCompile(`^[0-9a-f]{64}$`)
+
+//FilesystemBackend - stores each account under <dir>/<account ID>. Images are written to images/<SHA256> and metadata
+//to snapshots/<snapshot ID>.json. Files are written to a temporary file and renamed so readers never see partial files
+type FilesystemBackend struct {
+	dir string
+}
+
+//NewFilesystemBackend - Returns a backend rooted at dir. The directory is created if it doesn't exist
+func NewFilesystemBackend(dir string) (*FilesystemBackend, error) {
+	if err := os.MkdirAll(dir, dirPerm); err != nil {
+		return nil, fmt.Errorf("unable to create artifact dir <%v>. err <%v>", dir, err)
+	}
+	return &FilesystemBackend{dir: dir}, nil
+}
+
+func (f *FilesystemBackend) PutImage(ctx context.Context, accountID, sum string, img []byte) error {
+	path, err := f.imagePath(accountID, sum)
+	if err != nil {
+		return err
+	}
+	return writeFile(path, img)
+}
+
+func (f *FilesystemBackend) GetImage(ctx context.Context, accountID, sum string) ([]byte, error) {
+	path, err := f.imagePath(accountID, sum)
+	if err != nil {
+		return nil, err
+	}
+	return readFile(path)
+}
+
+func (f *FilesystemBackend) HasImage(ctx context.Context, accountID, sum string) (bool, error) {
+	path, err := f.imagePath(accountID, sum)
+	if err != nil {
+		return false, err
+	}
+	_, sErr := os.Stat(path)
+	if os.IsNotExist(sErr) {
+		return false, nil
+	}
+	return sErr == nil, sErr
+}
+
+func (f *FilesystemBackend) DeleteImage(ctx context.Context, accountID, sum string) error {
+	path, err := f.imagePath(accountID, sum)
+	if err != nil {
+		return err
+	}
+	return removeFile(path)
+}
+
+func (f *FilesystemBackend) PutSnapshot(ctx context.Context, snapshot SnapshotV1) error {
+	path, err := f.snapshotPath(snapshot.AccountID, snapshot.ID)
+	if err != nil {
+		return err
+	}
+	data, err := json.Marshal(snapshot)
+	if err != nil {
+		return err
+	}
+	return writeFile(path, data)
+}
+
+func (f *FilesystemBackend) GetSnapshot(ctx context.Context, accountID, id string) (SnapshotV1, error) {
+	path, err := f.snapshotPath(accountID, id)
+	if err != nil {
+		return SnapshotV1{}, err
+	}
+	data, err := readFile(path)
+	if err != nil {
+		return SnapshotV1{}, err
+	}
+	var snapshot SnapshotV1
+	if uErr := json.Unmarshal(data, &snapshot); uErr != nil {
+		return SnapshotV1{}, fmt.Errorf("unable to parse snapshot <%v>. err <%v>", path, uErr)
+	}
+	return snapshot, nil
+}
+
+func (f *FilesystemBackend) ListSnapshots(ctx context.Context, accountID string) ([]SnapshotV1, error) {
+	dir, err := f.accountDir(accountID)
+	if err != nil {
+		return nil, err
+	}
+
+	files, err := ioutil.ReadDir(filepath.Join(dir, snapshotsDir))
+	if os.IsNotExist(err) {
+		return []SnapshotV1{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	snapshots := make([]SnapshotV1, 0, len(files))
+	for _, file := range files {
+		if file.IsDir() || !strings.HasSuffix(file.Name(), metaSuffix) {
+			continue
+		}
+		snapshot, gErr := f.GetSnapshot(ctx, accountID, strings.TrimSuffix(file.Name(), metaSuffix))
+		if gErr == ErrNotFound {
+			//Deleted by retention since the directory was read
+			continue
+		}
+		if gErr != nil {
+			return nil, gErr
+		}
+		snapshots = append(snapshots, snapshot)
+	}
+	return snapshots, nil
+}
+
+func (f *FilesystemBackend) DeleteSnapshot(ctx context.Context, accountID, id string) error {
+	path, err := f.snapshotPath(accountID, id)
+	if err != nil {
+		return err
+	}
+	return removeFile(path)
+}
+
+//accountDir - returns the directory of the account. IDs are escaped so that they can't leave the root directory
+func (f *FilesystemBackend) accountDir(accountID string) (string, error) {
+	name := url.PathEscape(accountID)
+	if name == "" || name == "." || name == ".." {
+		return "", fmt.Errorf("invalid account ID <%v>", accountID)
+	}
+	return filepath.Join(f.dir, name), nil
+}
+
+func (f *FilesystemBackend) imagePath(accountID, sum string) (string, error) {
+	if !sumRegex.MatchString(sum) {
+		return "", fmt.Errorf("invalid image hash <%v>", sum)
+	}
+	dir, err := f.accountDir(accountID)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, imagesDir, sum), nil
+}
+
+func (f *FilesystemBackend) snapshotPath(accountID, id string) (string, error) {
+	name := url.PathEscape(id)
+	if name == "" || name == "." || name == ".." {
+		return "", fmt.Errorf("invalid snapshot ID <%v>", id)
+	}
+	dir, err := f.accountDir(accountID)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, snapshotsDir, name+metaSuffix), nil
+}
+
+//writeFile - writes to a temporary file in the same directory and renames it into place
+func writeFile(path string, data []byte) error {
+	if err := os.MkdirAll(filepath.Dir(path), dirPerm); err != nil {
+		return err
+	}
+	tmp, err := ioutil.TempFile(filepath.Dir(path), ".tmp-")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, wErr := tmp.Write(data); wErr != nil {
+		tmp.Close()
+		return wErr
+	}
+	if cErr := tmp.Close(); cErr != nil {
+		return cErr
+	}
+	if pErr := os.Chmod(tmp.Name(), filePerm); pErr != nil {
+		return pErr
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+func readFile(path string) ([]byte, error) {
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, ErrNotFound
+	}
+	return data, err
+}
+
+func removeFile(path string) error {
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,77 @@
+package artifact
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestFilesystemBackend(t *testing.T) {
+
+	dir, err := ioutil.TempDir("", "artifacts")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	backend, err := NewFilesystemBackend(dir)
+	if err != nil {
+		t.Fatalf("NewFilesystemBackend() unexpected error = %v", err)
+	}
+	ctx := context.Background()
+	sum := "6105d6cc76af400325e94d588ce511be5bfdbb73b437dc51eca43917d7a43e3d"
+	snapshot := SnapshotV1{ID: "s1", AccountID: "team/ops", DashboardUID: "cpu01", PanelID: 2, SHA256: sum, CapturedAt: time.Date(2021, 3, 1, 0, 0, 0, 0, time.UTC)}
+
+	if has, hErr := backend.HasImage(ctx, snapshot.AccountID, sum); has || hErr != nil {
+		t.Errorf("HasImage() of missing image = %v, %v", has, hErr)
+	}
+	if pErr := backend.PutImage(ctx, snapshot.AccountID, sum, []byte("image")); pErr != nil {
+		t.Fatalf("PutImage() unexpected error = %v", pErr)
+	}
+	if pErr := backend.PutSnapshot(ctx, snapshot); pErr != nil {
+		t.Fatalf("PutSnapshot() unexpected error = %v", pErr)
+	}
+
+	//Account IDs are escaped so that they stay within the root directory
+	if _, sErr := os.Stat(filepath.Join(dir, "team%2Fops", imagesDir, sum)); sErr != nil {
+		t.Errorf("image wasn't written to the escaped account dir. <%v>", sErr)
+	}
+
+	if has, hErr := backend.HasImage(ctx, snapshot.AccountID, sum); !has || hErr != nil {
+		t.Errorf("HasImage() = %v, %v, want true", has, hErr)
+	}
+	if img, gErr := backend.GetImage(ctx, snapshot.AccountID, sum); gErr != nil || string(img) != "image" {
+		t.Errorf("GetImage() = %s, %v", img, gErr)
+	}
+	snapshots, lErr := backend.ListSnapshots(ctx, snapshot.AccountID)
+	if lErr != nil || len(snapshots) != 1 || !snapshots[0].CapturedAt.Equal(snapshot.CapturedAt) || snapshots[0].PanelID != 2 {
+		t.Errorf("ListSnapshots() = %+v, %v", snapshots, lErr)
+	}
+	if snapshots, lErr := backend.ListSnapshots(ctx, "unknown"); lErr != nil || len(snapshots) != 0 {
+		t.Errorf("ListSnapshots() of unknown account = %+v, %v", snapshots, lErr)
+	}
+
+	if dErr := backend.DeleteSnapshot(ctx, snapshot.AccountID, snapshot.ID); dErr != nil {
+		t.Errorf("DeleteSnapshot() unexpected error = %v", dErr)
+	}
+	if dErr := backend.DeleteImage(ctx, snapshot.AccountID, sum); dErr != nil {
+		t.Errorf("DeleteImage() unexpected error = %v", dErr)
+	}
+	if _, gErr := backend.GetSnapshot(ctx, snapshot.AccountID, snapshot.ID); gErr != ErrNotFound {
+		t.Errorf("GetSnapshot() of deleted snapshot error = %v, want ErrNotFound", gErr)
+	}
+	if _, gErr := backend.GetImage(ctx, snapshot.AccountID, sum); gErr != ErrNotFound {
+		t.Errorf("GetImage() of deleted image error = %v, want ErrNotFound", gErr)
+	}
+
+	//Paths outside the account dir are rejected
+	if _, gErr := backend.GetImage(ctx, snapshot.AccountID, "../../etc/passwd"); gErr == nil || gErr == ErrNotFound {
+		t.Errorf("GetImage() of invalid hash error = %v, want invalid hash", gErr)
+	}
+	if _, gErr := backend.GetSnapshot(ctx, "..", "s1"); gErr == nil || gErr == ErrNotFound {
+		t.Errorf("GetSnapshot() of invalid account error = %v, want invalid account", gErr)
+	}
+}
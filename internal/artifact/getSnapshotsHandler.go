@@ -0,0 +1,211 @@
+package artifact
+
+import (
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"github.com/sajeevany/graph-snapper/internal/logging"
+	"github.com/sirupsen/logrus"
+	"net/http"
+	"strconv"
+)
+
+const (
+	ListSnapshotsEndpoint     = "/:id/snapshots"
+	GetSnapshotImageEndpoint  = "/:id/snapshots/:snapshotID/image"
+	GetSnapshotExportEndpoint = "/:id/snapshots/:snapshotID/exports/:format"
+	GetSnapshotDiffEndpoint   = "/:id/snapshots/:snapshotID/diff"
+)
+
+//SnapshotsViewV1 - Snapshots of an account
+type SnapshotsViewV1 struct {
+	AccountID string       `json:"AccountID"`
+	Snapshots []SnapshotV1 `json:"Snapshots"`
+}
+
+//@Summary List account snapshots
+//@Description Non-authenticated endpoint that returns the metadata of an account's stored snapshots ordered from newest to oldest
+//@Produce json
+//@Param id path string true "Account ID"
+//@Param jobID query string false "Only return snapshots of the job"
+//@Param dashboardUID query string false "Only return snapshots of the dashboard"
+//@Param panelID query int false "Only return snapshots of the panel"
+//@Success 200 {object} SnapshotsViewV1
+//@Fail 400 {object} gin.H
+//@Fail 500 {object} gin.H
+//@Router /account/:id/snapshots [get]
+//@Tags snapshot
+func ListSnapshotsV1(baseLogger *logrus.Logger, store *Store) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+
+		//Use request scoped logger
+		logger := logging.FromContext(ctx.Request.Context(), baseLogger)
+
+		accountID := ctx.Param("id")
+		jobID, dashboardUID := ctx.Query("jobID"), ctx.Query("dashboardUID")
+		panelID := 0
+		if p := ctx.Query("panelID"); p != "" {
+			id, err := strconv.Atoi(p)
+			if err != nil {
+				msg := fmt.Sprintf("Query parameter panelID <%v> isn't a number", p)
+				logger.Debug(msg)
+				ctx.JSON(http.StatusBadRequest, gin.H{"error": msg})
+				return
+			}
+			panelID = id
+		}
+
+		snapshots, err := store.List(ctx.Request.Context(), accountID)
+		if err != nil {
+			hrErrMsg := fmt.Sprintf("unable to read snapshots for account <%v>", accountID)
+			logger.Errorf("%v. err <%v>", hrErrMsg, err)
+			ctx.JSON(http.StatusInternalServerError, gin.H{
+				"error":              err.Error(),
+				"humanReadableError": hrErrMsg,
+			})
+			return
+		}
+
+		filtered := []SnapshotV1{}
+		for _, s := range snapshots {
+			if (jobID == "" || s.JobID == jobID) && (dashboardUID == "" || s.DashboardUID == dashboardUID) && (panelID == 0 || s.PanelID == panelID) {
+				filtered = append(filtered, s)
+			}
+		}
+
+		ctx.JSON(http.StatusOK, SnapshotsViewV1{AccountID: accountID, Snapshots: filtered})
+	}
+}
+
+//@Summary Get snapshot image
+//@Description Non-authenticated endpoint that returns the captured image of a snapshot. The ETag is the image's SHA256
+//@Produce png
+//@Param id path string true "Account ID"
+//@Param snapshotID path string true "Snapshot ID"
+//@Success 200 {file} file
+//@Fail 404 {object} gin.H
+//@Fail 500 {object} gin.H
+//@Router /account/:id/snapshots/:snapshotID/image [get]
+//@Tags snapshot
+func GetSnapshotImageV1(baseLogger *logrus.Logger, store *Store) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+
+		//Use request scoped logger
+		logger := logging.FromContext(ctx.Request.Context(), baseLogger)
+
+		accountID, snapshotID := ctx.Param("id"), ctx.Param("snapshotID")
+
+		snapshot, img, err := store.GetImage(ctx.Request.Context(), accountID, snapshotID)
+		if err == ErrNotFound {
+			logger.Debugf("snapshot <%v> of account <%v> does not exist. Returning 404", snapshotID, accountID)
+			ctx.JSON(http.StatusNotFound, gin.H{
+				"error":              fmt.Sprintf("snapshot <%v> doesn't exist", snapshotID),
+				"humanReadableError": fmt.Sprintf("No snapshot exists with ID %v on account %v", snapshotID, accountID),
+			})
+			return
+		}
+		if err != nil {
+			hrErrMsg := fmt.Sprintf("unable to read snapshot <%v> of account <%v>", snapshotID, accountID)
+			logger.Errorf("%v. err <%v>", hrErrMsg, err)
+			ctx.JSON(http.StatusInternalServerError, gin.H{
+				"error":              err.Error(),
+				"humanReadableError": hrErrMsg,
+			})
+			return
+		}
+
+		//Images never change once stored
+		ctx.Header("ETag", fmt.Sprintf("%q", snapshot.SHA256))
+		ctx.Header("Cache-Control", "private, max-age=31536000, immutable")
+		ctx.Data(http.StatusOK, snapshot.ContentType, img)
+	}
+}
+
+//@Summary Get snapshot export
+//@Description Non-authenticated endpoint that returns the query result exported with a snapshot. The ETag is the export's SHA256
+//@Produce json
+//@Param id path string true "Account ID"
+//@Param snapshotID path string true "Snapshot ID"
+//@Param format path string true "Export format. csv or json"
+//@Success 200 {file} file
+//@Fail 404 {object} gin.H
+//@Fail 500 {object} gin.H
+//@Router /account/:id/snapshots/:snapshotID/exports/:format [get]
+//@Tags snapshot
+func GetSnapshotExportV1(baseLogger *logrus.Logger, store *Store) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+
+		//Use request scoped logger
+		logger := logging.FromContext(ctx.Request.Context(), baseLogger)
+
+		accountID, snapshotID, format := ctx.Param("id"), ctx.Param("snapshotID"), ctx.Param("format")
+
+		snapshot, export, data, err := store.GetExport(ctx.Request.Context(), accountID, snapshotID, format)
+		if err == ErrNotFound {
+			logger.Debugf("snapshot <%v> of account <%v> doesn't have a <%v> export. Returning 404", snapshotID, accountID, format)
+			ctx.JSON(http.StatusNotFound, gin.H{
+				"error":              fmt.Sprintf("snapshot <%v> export <%v> doesn't exist", snapshotID, format),
+				"humanReadableError": fmt.Sprintf("No snapshot with a %v export exists with ID %v on account %v", format, snapshotID, accountID),
+			})
+			return
+		}
+		if err != nil {
+			hrErrMsg := fmt.Sprintf("unable to read snapshot <%v> export <%v> of account <%v>", snapshotID, format, accountID)
+			logger.Errorf("%v. err <%v>", hrErrMsg, err)
+			ctx.JSON(http.StatusInternalServerError, gin.H{
+				"error":              err.Error(),
+				"humanReadableError": hrErrMsg,
+			})
+			return
+		}
+
+		//Exports never change once stored
+		ctx.Header("ETag", fmt.Sprintf("%q", export.SHA256))
+		ctx.Header("Cache-Control", "private, max-age=31536000, immutable")
+		ctx.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", snapshot.ID+"."+export.Format))
+		ctx.Data(http.StatusOK, export.ContentType, data)
+	}
+}
+
+//@Summary Get snapshot diff image
+//@Description Non-authenticated endpoint that returns the image highlighting what changed since the previous capture of the panel. Only snapshots captured with a diff image have one. The ETag is the image's SHA256
+//@Produce png
+//@Param id path string true "Account ID"
+//@Param snapshotID path string true "Snapshot ID"
+//@Success 200 {file} file
+//@Fail 404 {object} gin.H
+//@Fail 500 {object} gin.H
+//@Router /account/:id/snapshots/:snapshotID/diff [get]
+//@Tags snapshot
+func GetSnapshotDiffV1(baseLogger *logrus.Logger, store *Store) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+
+		//Use request scoped logger
+		logger := logging.FromContext(ctx.Request.Context(), baseLogger)
+
+		accountID, snapshotID := ctx.Param("id"), ctx.Param("snapshotID")
+
+		snapshot, img, err := store.GetDiffImage(ctx.Request.Context(), accountID, snapshotID)
+		if err == ErrNotFound {
+			logger.Debugf("snapshot <%v> of account <%v> doesn't have a diff image. Returning 404", snapshotID, accountID)
+			ctx.JSON(http.StatusNotFound, gin.H{
+				"error":              fmt.Sprintf("snapshot <%v> diff image doesn't exist", snapshotID),
+				"humanReadableError": fmt.Sprintf("No snapshot with a diff image exists with ID %v on account %v", snapshotID, accountID),
+			})
+			return
+		}
+		if err != nil {
+			hrErrMsg := fmt.Sprintf("unable to read snapshot <%v> diff image of account <%v>", snapshotID, accountID)
+			logger.Errorf("%v. err <%v>", hrErrMsg, err)
+			ctx.JSON(http.StatusInternalServerError, gin.H{
+				"error":              err.Error(),
+				"humanReadableError": hrErrMsg,
+			})
+			return
+		}
+
+		//Diff images never change once stored
+		ctx.Header("ETag", fmt.Sprintf("%q", snapshot.Diff.SHA256))
+		ctx.Header("Cache-Control", "private, max-age=31536000, immutable")
+		ctx.Data(http.StatusOK, PNGContentType, img)
+	}
+}
@@ -0,0 +1,96 @@
+package artifact
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/gin-gonic/gin"
+	"github.com/sajeevany/graph-snapper/internal/config"
+	"github.com/sirupsen/logrus"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestSnapshotHandlers(t *testing.T) {
+
+	store := NewStoreWithBackend(NewMemoryBackend(), config.Artifacts{})
+	logger := logrus.New()
+	saved := map[string]SnapshotV1{}
+	for _, s := range []SnapshotV1{
+		{AccountID: "abc", JobID: "daily", DashboardUID: "cpu01", PanelID: 1},
+		{AccountID: "abc", JobID: "daily", DashboardUID: "cpu01", PanelID: 2},
+		{AccountID: "abc", JobID: "weekly", DashboardUID: "mem01", PanelID: 1},
+	} {
+		snapshot, err := store.Save(context.Background(), logrus.NewEntry(logger), s, []byte(s.DashboardUID))
+		if err != nil {
+			t.Fatal(err)
+		}
+		saved[s.JobID+s.DashboardUID] = snapshot
+	}
+	exported, err := store.SaveWithExports(context.Background(), logrus.NewEntry(logger), SnapshotV1{AccountID: "abc", JobID: "capacity", DashboardUID: "disk01", PanelID: 1},
+		[]byte("disk01"), []DataExport{{Format: CSVExportFormat, Data: []byte("Time,sda\n")}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	diffed, err := store.SaveCapture(context.Background(), logrus.NewEntry(logger), SnapshotV1{AccountID: "abc", JobID: "capacity", DashboardUID: "disk01", PanelID: 1,
+		Diff: &DiffV1{PreviousID: exported.ID, Score: 1}}, Capture{Image: []byte("disk02"), DiffImage: []byte("diff")})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/account"+ListSnapshotsEndpoint, ListSnapshotsV1(logger, store))
+	router.GET("/account"+GetSnapshotImageEndpoint, GetSnapshotImageV1(logger, store))
+	router.GET("/account"+GetSnapshotExportEndpoint, GetSnapshotExportV1(logger, store))
+	router.GET("/account"+GetSnapshotDiffEndpoint, GetSnapshotDiffV1(logger, store))
+
+	tests := []struct {
+		name       string
+		url        string
+		wantStatus int
+		wantCount  int
+		wantBody   string
+		wantType   string
+	}{
+		{name: "Test0 - List all", url: "/account/abc/snapshots", wantStatus: http.StatusOK, wantCount: 5},
+		{name: "Test1 - Filter by job and panel", url: "/account/abc/snapshots?jobID=daily&panelID=2", wantStatus: http.StatusOK, wantCount: 1},
+		{name: "Test2 - Filter by dashboard", url: "/account/abc/snapshots?dashboardUID=mem01", wantStatus: http.StatusOK, wantCount: 1},
+		{name: "Test3 - Unknown account", url: "/account/xyz/snapshots", wantStatus: http.StatusOK, wantCount: 0},
+		{name: "Test4 - Invalid panel filter", url: "/account/abc/snapshots?panelID=one", wantStatus: http.StatusBadRequest},
+		{name: "Test5 - Image", url: "/account/abc/snapshots/" + saved["weeklymem01"].ID + "/image", wantStatus: http.StatusOK, wantBody: "mem01", wantType: PNGContentType},
+		{name: "Test6 - Unknown snapshot", url: "/account/abc/snapshots/missing/image", wantStatus: http.StatusNotFound},
+		{name: "Test7 - Snapshot of another account", url: "/account/xyz/snapshots/" + saved["weeklymem01"].ID + "/image", wantStatus: http.StatusNotFound},
+		{name: "Test8 - Export", url: "/account/abc/snapshots/" + exported.ID + "/exports/csv", wantStatus: http.StatusOK, wantBody: "Time,sda\n", wantType: "text/csv"},
+		{name: "Test9 - Format that wasn't exported", url: "/account/abc/snapshots/" + exported.ID + "/exports/json", wantStatus: http.StatusNotFound},
+		{name: "Test10 - Diff image", url: "/account/abc/snapshots/" + diffed.ID + "/diff", wantStatus: http.StatusOK, wantBody: "diff", wantType: PNGContentType},
+		{name: "Test11 - Snapshot without a diff image", url: "/account/abc/snapshots/" + exported.ID + "/diff", wantStatus: http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, tt.url, nil))
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %v, want %v. body <%v>", w.Code, tt.wantStatus, w.Body.String())
+			}
+			if tt.wantStatus != http.StatusOK {
+				return
+			}
+			if tt.wantBody != "" {
+				if w.Body.String() != tt.wantBody || w.Header().Get("Content-Type") != tt.wantType || w.Header().Get("ETag") == "" {
+					t.Errorf("body = <%v> <%v> <%v>", w.Body.String(), w.Header().Get("Content-Type"), w.Header().Get("ETag"))
+				}
+				return
+			}
+			var view SnapshotsViewV1
+			if err := json.Unmarshal(w.Body.Bytes(), &view); err != nil {
+				t.Fatal(err)
+			}
+			if len(view.Snapshots) != tt.wantCount {
+				t.Errorf("snapshots = %+v, want %v", view.Snapshots, tt.wantCount)
+			}
+		})
+	}
+}
@@ -0,0 +1,137 @@
+package artifact
+
+import (
+	"github.com/sirupsen/logrus"
+	"time"
+)
+
+const (
+	//PNGContentType - content type of images rendered by grafana
+	PNGContentType = "image/png"
+	//SVGContentType - content type of charts drawn from panel data
+	SVGContentType = "image/svg+xml"
+
+	//CSVExportFormat - query result with a time column and a column per series
+	CSVExportFormat = "csv"
+	//JSONExportFormat - query result as a list of series and their points
+	JSONExportFormat = "json"
+)
+
+//exportContentTypes - content type of each export format
+var exportContentTypes = map[string]string{
+	CSVExportFormat:  "text/csv",
+	JSONExportFormat: "application/json",
+}
+
+//SnapshotV1 - Metadata of a captured panel image. Images are stored once per account and SHA256
+type SnapshotV1 struct {
+	ID             string
+	AccountID      string
+	JobID          string `json:"JobID,omitempty"`  //Optional. Job the snapshot was captured for
+	Source         string `json:"Source,omitempty"` //Type of source the image was rendered by. Empty for snapshots captured before sources were pluggable
+	DashboardUID   string
+	DashboardTitle string `json:"DashboardTitle,omitempty"` //Optional. Title when the panel was captured
+	PanelID        int
+	PanelTitle     string `json:"PanelTitle,omitempty"` //Optional. Title when the panel was captured
+	From           string //Time range the panel was rendered with. ie now-7d or epoch milliseconds
+	To             string
+	CapturedAt     time.Time
+	SHA256         string //Hex encoded hash of the image
+	Size           int
+	ContentType    string
+	Exports        []ExportV1 `json:"Exports,omitempty"`        //Optional. Query results stored with the image
+	Diff           *DiffV1    `json:"Diff,omitempty"`           //Optional. Difference from the last published capture of the panel
+	RolledBack     bool       `json:"RolledBack,omitempty"`     //Set when the snapshot's publish was rolled back. Reports don't list it
+	PublishSkipped bool       `json:"PublishSkipped,omitempty"` //Set when the capture wasn't published because it was unchanged
+}
+
+//DiffV1 - Difference of a snapshot from the last published capture of the panel by the same job
+type DiffV1 struct {
+	PreviousID    string  //Snapshot the capture was compared with
+	Score         float64 //Fraction of pixels that changed. 0 when identical and 1 when the captures can't be compared
+	ChangedPixels int
+	SHA256        string `json:"SHA256,omitempty"` //Optional. Hash of the PNG highlighting the changed pixels
+	Size          int    `json:"Size,omitempty"`
+}
+
+//ExportV1 - Query result stored with a snapshot. Exports are deduplicated within an account like images
+type ExportV1 struct {
+	Format      string //csv or json
+	ContentType string
+	SHA256      string
+	Size        int
+}
+
+//DataExport - Query result of a snapshot in an export format
+type DataExport struct {
+	Format string
+	Data   []byte
+}
+
+//IsExportFormat - returns true if the format is csv or json
+func IsExportFormat(format string) bool {
+	_, exists := exportContentTypes[format]
+	return exists
+}
+
+//Export - returns the snapshot's export in the format
+func (s SnapshotV1) Export(format string) (ExportV1, bool) {
+	for _, e := range s.Exports {
+		if e.Format == format {
+			return e, true
+		}
+	}
+	return ExportV1{}, false
+}
+
+//blobs - returns the hashes of the image, exports and diff image the snapshot references
+func (s SnapshotV1) blobs() []string {
+	sums := []string{s.SHA256}
+	for _, e := range s.Exports {
+		sums = append(sums, e.SHA256)
+	}
+	if s.Diff != nil && s.Diff.SHA256 != "" {
+		sums = append(sums, s.Diff.SHA256)
+	}
+	return sums
+}
+
+//IsPreviousCapture - returns true if the other snapshot is a capture of the same panel by the same job
+func (s SnapshotV1) IsPreviousCapture(other SnapshotV1) bool {
+	return s.AccountID == other.AccountID && s.JobID == other.JobID && s.Source == other.Source &&
+		s.DashboardUID == other.DashboardUID && s.PanelID == other.PanelID && s.ID != other.ID
+}
+
+//IsPublished - returns true unless the snapshot's publish was skipped or rolled back
+func (s SnapshotV1) IsPublished() bool {
+	return !s.PublishSkipped && !s.RolledBack
+}
+
+//ImageExtension - returns the file extension of the snapshot's image without a dot. ie png
+func (s SnapshotV1) ImageExtension() string {
+	if s.ContentType == SVGContentType {
+		return "svg"
+	}
+	return "png"
+}
+
+func (s SnapshotV1) GetFields() logrus.Fields {
+	return logrus.Fields{
+		"ID":             s.ID,
+		"AccountID":      s.AccountID,
+		"JobID":          s.JobID,
+		"Source":         s.Source,
+		"DashboardUID":   s.DashboardUID,
+		"DashboardTitle": s.DashboardTitle,
+		"PanelID":        s.PanelID,
+		"PanelTitle":     s.PanelTitle,
+		"From":           s.From,
+		"To":             s.To,
+		"CapturedAt":     s.CapturedAt,
+		"SHA256":         s.SHA256,
+		"Size":           s.Size,
+		"ContentType":    s.ContentType,
+		"Exports":        len(s.Exports),
+		"Diff":           s.Diff != nil,
+	}
+}
@@ -0,0 +1,330 @@
+package artifact
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"github.com/google/uuid"
+	"github.com/sajeevany/graph-snapper/internal/config"
+	"github.com/sirupsen/logrus"
+	"sort"
+	"sync"
+	"time"
+)
+
+//Store - Saves snapshot images with their metadata, deduplicates identical images within an account and applies the
+//account's retention limits after each save
+type Store struct {
+	backend Backend
+	conf    config.Artifacts
+	now     func() time.Time
+
+	//mu - serializes saves and deletes so that images aren't removed while a new snapshot is referencing them
+	mu sync.Mutex
+}
+
+//NewStore - Returns a store using the backend defined by the artifacts config
+func NewStore(conf config.Artifacts) (*Store, error) {
+	backend, err := NewBackend(conf)
+	if err != nil {
+		return nil, err
+	}
+	return NewStoreWithBackend(backend, conf), nil
+}
+
+//NewStoreWithBackend - Returns a store using the backend. The backend setting of conf is ignored
+func NewStoreWithBackend(backend Backend, conf config.Artifacts) *Store {
+	return &Store{
+		backend: backend,
+		conf:    conf,
+		now:     time.Now,
+	}
+}
+
+//Save - Stores the image and its metadata. ID, SHA256, Size and CapturedAt are set by the store unless CapturedAt is
+//already set. The image is only written if the account doesn't already have an identical one
+func (s *Store) Save(ctx context.Context, logger *logrus.Entry, snapshot SnapshotV1, img []byte) (SnapshotV1, error) {
+	return s.SaveWithExports(ctx, logger, snapshot, img, nil)
+}
+
+//SaveWithExports - Stores the image and query result exports with the snapshot metadata. Exports are deduplicated like
+//images and replace any exports set on the snapshot
+func (s *Store) SaveWithExports(ctx context.Context, logger *logrus.Entry, snapshot SnapshotV1, img []byte, exports []DataExport) (SnapshotV1, error) {
+	return s.SaveCapture(ctx, logger, snapshot, Capture{Image: img, Exports: exports})
+}
+
+//Capture - Image and data stored with a snapshot
+type Capture struct {
+	Image     []byte
+	Exports   []DataExport
+	DiffImage []byte //Optional. Highlighted difference from the previous capture. Stored if the snapshot has a Diff
+}
+
+//SaveCapture - Stores the capture with the snapshot metadata. Exports and the diff image are deduplicated like images
+func (s *Store) SaveCapture(ctx context.Context, logger *logrus.Entry, snapshot SnapshotV1, capture Capture) (SnapshotV1, error) {
+
+	img, exports := capture.Image, capture.Exports
+
+	if snapshot.AccountID == "" {
+		return SnapshotV1{}, fmt.Errorf("snapshot account ID is empty")
+	}
+
+	snapshot.ID = uuid.New().String()
+	snapshot.SHA256 = hash(img)
+	snapshot.Size = len(img)
+	if snapshot.CapturedAt.IsZero() {
+		snapshot.CapturedAt = s.now().UTC()
+	}
+	if snapshot.ContentType == "" {
+		snapshot.ContentType = PNGContentType
+	}
+
+	blobs := map[string][]byte{snapshot.SHA256: img}
+	snapshot.Exports = nil
+	for _, e := range exports {
+		contentType, supported := exportContentTypes[e.Format]
+		if !supported {
+			return SnapshotV1{}, fmt.Errorf("unsupported export format <%v>", e.Format)
+		}
+		sum := hash(e.Data)
+		snapshot.Exports = append(snapshot.Exports, ExportV1{Format: e.Format, ContentType: contentType, SHA256: sum, Size: len(e.Data)})
+		blobs[sum] = e.Data
+	}
+	if snapshot.Diff != nil {
+		diff := *snapshot.Diff
+		diff.SHA256, diff.Size = "", 0
+		if len(capture.DiffImage) > 0 {
+			diff.SHA256, diff.Size = hash(capture.DiffImage), len(capture.DiffImage)
+			blobs[diff.SHA256] = capture.DiffImage
+		}
+		snapshot.Diff = &diff
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	//written - blobs this save created. They're removed if the metadata can't be stored
+	var written []string
+	removeWritten := func() {
+		for _, sum := range written {
+			s.backend.DeleteImage(ctx, snapshot.AccountID, sum)
+		}
+	}
+	for sum, data := range blobs {
+		exists, err := s.backend.HasImage(ctx, snapshot.AccountID, sum)
+		if err != nil {
+			removeWritten()
+			return SnapshotV1{}, fmt.Errorf("unable to check for existing image. err <%v>", err)
+		}
+		if exists {
+			logger.WithFields(snapshot.GetFields()).Debugf("Identical image or export <%v> is already stored. Reusing it", sum)
+			continue
+		}
+		if pErr := s.backend.PutImage(ctx, snapshot.AccountID, sum, data); pErr != nil {
+			removeWritten()
+			return SnapshotV1{}, fmt.Errorf("unable to store image. err <%v>", pErr)
+		}
+		written = append(written, sum)
+	}
+
+	if pErr := s.backend.PutSnapshot(ctx, snapshot); pErr != nil {
+		removeWritten()
+		return SnapshotV1{}, fmt.Errorf("unable to store snapshot metadata. err <%v>", pErr)
+	}
+	logger.WithFields(snapshot.GetFields()).Debug("Stored snapshot")
+
+	//Retention failures don't lose the new snapshot. They're retried on the next save
+	if _, rErr := s.applyRetention(ctx, logger, snapshot.AccountID, snapshot.ID); rErr != nil {
+		logger.Errorf("Unable to apply retention to account <%v>. err <%v>", snapshot.AccountID, rErr)
+	}
+
+	return snapshot, nil
+}
+
+//List - Returns the snapshots of an account ordered from newest to oldest
+func (s *Store) List(ctx context.Context, accountID string) ([]SnapshotV1, error) {
+	snapshots, err := s.backend.ListSnapshots(ctx, accountID)
+	if err != nil {
+		return nil, err
+	}
+	sortNewestFirst(snapshots)
+	return snapshots, nil
+}
+
+//Previous - Returns the newest published snapshot captured of the same panel by the same job as the snapshot and its
+//image. Captures whose publish was skipped or rolled back are passed over so that small changes add up until they're
+//published. Returns ErrNotFound if no capture of the panel was published before
+func (s *Store) Previous(ctx context.Context, snapshot SnapshotV1) (SnapshotV1, []byte, error) {
+	snapshots, err := s.List(ctx, snapshot.AccountID)
+	if err != nil {
+		return SnapshotV1{}, nil, err
+	}
+	for _, previous := range snapshots {
+		if !snapshot.IsPreviousCapture(previous) || !previous.IsPublished() {
+			continue
+		}
+		img, err := s.backend.GetImage(ctx, snapshot.AccountID, previous.SHA256)
+		if err != nil {
+			return SnapshotV1{}, nil, err
+		}
+		return previous, img, nil
+	}
+	return SnapshotV1{}, nil, ErrNotFound
+}
+
+//MarkRolledBack - Marks the snapshots as rolled back so that reports no longer list them. Snapshots removed by retention
+//are skipped
+func (s *Store) MarkRolledBack(ctx context.Context, accountID string, ids []string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, id := range ids {
+		snapshot, err := s.backend.GetSnapshot(ctx, accountID, id)
+		if err == ErrNotFound {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		snapshot.RolledBack = true
+		if pErr := s.backend.PutSnapshot(ctx, snapshot); pErr != nil {
+			return pErr
+		}
+	}
+	return nil
+}
+
+//Get - Returns the snapshot metadata or ErrNotFound
+func (s *Store) Get(ctx context.Context, accountID, id string) (SnapshotV1, error) {
+	return s.backend.GetSnapshot(ctx, accountID, id)
+}
+
+//GetImage - Returns the snapshot metadata and image or ErrNotFound
+func (s *Store) GetImage(ctx context.Context, accountID, id string) (SnapshotV1, []byte, error) {
+	snapshot, err := s.backend.GetSnapshot(ctx, accountID, id)
+	if err != nil {
+		return SnapshotV1{}, nil, err
+	}
+	img, err := s.backend.GetImage(ctx, accountID, snapshot.SHA256)
+	if err != nil {
+		return SnapshotV1{}, nil, err
+	}
+	return snapshot, img, nil
+}
+
+//GetExport - Returns the snapshot metadata and its export in the format or ErrNotFound
+func (s *Store) GetExport(ctx context.Context, accountID, id, format string) (SnapshotV1, ExportV1, []byte, error) {
+	snapshot, err := s.backend.GetSnapshot(ctx, accountID, id)
+	if err != nil {
+		return SnapshotV1{}, ExportV1{}, nil, err
+	}
+	export, exists := snapshot.Export(format)
+	if !exists {
+		return SnapshotV1{}, ExportV1{}, nil, ErrNotFound
+	}
+	data, err := s.backend.GetImage(ctx, accountID, export.SHA256)
+	if err != nil {
+		return SnapshotV1{}, ExportV1{}, nil, err
+	}
+	return snapshot, export, data, nil
+}
+
+//GetDiffImage - Returns the snapshot metadata and the image highlighting its difference from the previous capture or
+//ErrNotFound
+func (s *Store) GetDiffImage(ctx context.Context, accountID, id string) (SnapshotV1, []byte, error) {
+	snapshot, err := s.backend.GetSnapshot(ctx, accountID, id)
+	if err != nil {
+		return SnapshotV1{}, nil, err
+	}
+	if snapshot.Diff == nil || snapshot.Diff.SHA256 == "" {
+		return SnapshotV1{}, nil, ErrNotFound
+	}
+	img, err := s.backend.GetImage(ctx, accountID, snapshot.Diff.SHA256)
+	if err != nil {
+		return SnapshotV1{}, nil, err
+	}
+	return snapshot, img, nil
+}
+
+//ApplyRetention - Deletes the snapshots of an account exceeding its retention limits. Returns the number of deleted
+//snapshots
+func (s *Store) ApplyRetention(ctx context.Context, logger *logrus.Entry, accountID string) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.applyRetention(ctx, logger, accountID, "")
+}
+
+//applyRetention - Keeps the newest MaxSnapshots snapshots captured within MaxAgeDays. Images are deleted once no
+//remaining snapshot references them. Exports are deleted the same way. The snapshot with keepID is never expired so that
+//a save with an old CapturedAt doesn't delete the snapshot it returns. It takes one of the MaxSnapshots slots. Callers
+//must hold mu
+func (s *Store) applyRetention(ctx context.Context, logger *logrus.Entry, accountID, keepID string) (int, error) {
+
+	retention := s.conf.RetentionFor(accountID)
+	if retention.MaxSnapshots == 0 && retention.MaxAgeDays == 0 {
+		return 0, nil
+	}
+
+	snapshots, err := s.backend.ListSnapshots(ctx, accountID)
+	if err != nil {
+		return 0, err
+	}
+	sortNewestFirst(snapshots)
+	sort.SliceStable(snapshots, func(i, j int) bool {
+		return snapshots[i].ID == keepID && snapshots[j].ID != keepID
+	})
+
+	cutoff := s.now().AddDate(0, 0, -retention.MaxAgeDays)
+	var kept, expired []SnapshotV1
+	for i, snapshot := range snapshots {
+		tooMany := retention.MaxSnapshots != 0 && i >= retention.MaxSnapshots
+		tooOld := retention.MaxAgeDays != 0 && snapshot.CapturedAt.Before(cutoff)
+		if snapshot.ID != keepID && (tooMany || tooOld) {
+			expired = append(expired, snapshot)
+		} else {
+			kept = append(kept, snapshot)
+		}
+	}
+	if len(expired) == 0 {
+		return 0, nil
+	}
+
+	referenced := make(map[string]bool, len(kept))
+	for _, snapshot := range kept {
+		for _, sum := range snapshot.blobs() {
+			referenced[sum] = true
+		}
+	}
+
+	for _, snapshot := range expired {
+		if dErr := s.backend.DeleteSnapshot(ctx, accountID, snapshot.ID); dErr != nil {
+			return 0, fmt.Errorf("unable to delete snapshot <%v>. err <%v>", snapshot.ID, dErr)
+		}
+		for _, sum := range snapshot.blobs() {
+			if referenced[sum] {
+				continue
+			}
+			if dErr := s.backend.DeleteImage(ctx, accountID, sum); dErr != nil {
+				return 0, fmt.Errorf("unable to delete image <%v>. err <%v>", sum, dErr)
+			}
+			//Another expired snapshot could share the image
+			referenced[sum] = true
+		}
+	}
+	logger.Debugf("Retention <%+v> deleted <%v> snapshots of account <%v>", retention, len(expired), accountID)
+
+	return len(expired), nil
+}
+
+//hash - returns the hex encoded SHA256 of the data
+func hash(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func sortNewestFirst(snapshots []SnapshotV1) {
+	sort.SliceStable(snapshots, func(i, j int) bool {
+		return snapshots[i].CapturedAt.After(snapshots[j].CapturedAt)
+	})
+}
@@ -0,0 +1,308 @@
+package artifact
+
+import (
+	"context"
+	"github.com/sajeevany/graph-snapper/internal/config"
+	"github.com/sirupsen/logrus"
+	"testing"
+	"time"
+)
+
+func TestStore_SaveDeduplicates(t *testing.T) {
+
+	backend := NewMemoryBackend()
+	store := NewStoreWithBackend(backend, config.Artifacts{})
+	logger := logrus.NewEntry(logrus.New())
+	ctx := context.Background()
+
+	first, err := store.Save(ctx, logger, SnapshotV1{AccountID: "abc", DashboardUID: "cpu01", PanelID: 1}, []byte("image"))
+	if err != nil {
+		t.Fatalf("Save() unexpected error = %v", err)
+	}
+	second, err := store.Save(ctx, logger, SnapshotV1{AccountID: "abc", DashboardUID: "cpu01", PanelID: 1}, []byte("image"))
+	if err != nil {
+		t.Fatalf("Save() unexpected error = %v", err)
+	}
+	other, err := store.Save(ctx, logger, SnapshotV1{AccountID: "def", DashboardUID: "cpu01", PanelID: 1}, []byte("image"))
+	if err != nil {
+		t.Fatalf("Save() unexpected error = %v", err)
+	}
+
+	if first.ID == second.ID {
+		t.Errorf("Save() returned the same snapshot ID <%v> twice", first.ID)
+	}
+	if first.SHA256 != "6105d6cc76af400325e94d588ce511be5bfdbb73b437dc51eca43917d7a43e3d" || first.SHA256 != second.SHA256 || first.Size != 5 {
+		t.Errorf("Save() SHA256 and size = <%v> <%v>, <%v>", first.SHA256, first.Size, second.SHA256)
+	}
+	if first.ContentType != PNGContentType || first.CapturedAt.IsZero() {
+		t.Errorf("Save() didn't set defaults. <%+v>", first)
+	}
+
+	//Identical images are stored once per account
+	if len(backend.images) != 2 {
+		t.Errorf("backend holds <%v> images, want 2", len(backend.images))
+	}
+	if _, img, gErr := store.GetImage(ctx, "def", other.ID); gErr != nil || string(img) != "image" {
+		t.Errorf("GetImage() = %s, %v", img, gErr)
+	}
+	if _, _, gErr := store.GetImage(ctx, "def", first.ID); gErr != ErrNotFound {
+		t.Errorf("GetImage() of another account's snapshot error = %v, want ErrNotFound", gErr)
+	}
+}
+
+func TestStore_Retention(t *testing.T) {
+
+	now := time.Date(2021, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name       string
+		conf       config.Artifacts
+		accountID  string
+		wantKept   []string
+		wantImages int
+	}{
+		{
+			name:       "Test0 - No limits",
+			conf:       config.Artifacts{},
+			accountID:  "abc",
+			wantKept:   []string{"today", "yesterday", "lastWeek", "lastMonth"},
+			wantImages: 3,
+		},
+		{
+			name:       "Test1 - Count limit keeps the newest and the image shared with a kept snapshot",
+			conf:       config.Artifacts{Retention: config.Retention{MaxSnapshots: 2}},
+			accountID:  "abc",
+			wantKept:   []string{"today", "yesterday"},
+			wantImages: 2,
+		},
+		{
+			name:       "Test2 - Age limit",
+			conf:       config.Artifacts{Retention: config.Retention{MaxAgeDays: 10}},
+			accountID:  "abc",
+			wantKept:   []string{"today", "yesterday", "lastWeek"},
+			wantImages: 2,
+		},
+		{
+			name: "Test3 - Account override replaces the default",
+			conf: config.Artifacts{
+				Retention:        config.Retention{MaxSnapshots: 1},
+				AccountRetention: map[string]config.Retention{"abc": {MaxSnapshots: 3}},
+			},
+			accountID:  "abc",
+			wantKept:   []string{"today", "yesterday", "lastWeek"},
+			wantImages: 2,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+
+			backend := NewMemoryBackend()
+			store := NewStoreWithBackend(backend, config.Artifacts{})
+			store.now = func() time.Time { return now }
+			logger := logrus.NewEntry(logrus.New())
+			ctx := context.Background()
+
+			//lastWeek shares its image with today
+			for _, s := range []struct {
+				jobID string
+				age   time.Duration
+				img   string
+			}{
+				{"lastMonth", 30 * 24 * time.Hour, "a"},
+				{"lastWeek", 7 * 24 * time.Hour, "c"},
+				{"yesterday", 24 * time.Hour, "b"},
+				{"today", 0, "c"},
+			} {
+				if _, err := store.Save(ctx, logger, SnapshotV1{AccountID: tt.accountID, JobID: s.jobID, CapturedAt: now.Add(-s.age)}, []byte(s.img)); err != nil {
+					t.Fatalf("Save() unexpected error = %v", err)
+				}
+			}
+
+			store.conf = tt.conf
+			if _, err := store.ApplyRetention(ctx, logger, tt.accountID); err != nil {
+				t.Fatalf("ApplyRetention() unexpected error = %v", err)
+			}
+
+			snapshots, err := store.List(ctx, tt.accountID)
+			if err != nil {
+				t.Fatalf("List() unexpected error = %v", err)
+			}
+			var kept []string
+			for _, s := range snapshots {
+				kept = append(kept, s.JobID)
+			}
+			if len(kept) != len(tt.wantKept) {
+				t.Fatalf("List() = %v, want %v", kept, tt.wantKept)
+			}
+			for i := range kept {
+				if kept[i] != tt.wantKept[i] {
+					t.Errorf("List() = %v, want %v", kept, tt.wantKept)
+				}
+			}
+			if len(backend.images) != tt.wantImages {
+				t.Errorf("backend holds <%v> images, want %v", len(backend.images), tt.wantImages)
+			}
+		})
+	}
+}
+
+func TestStore_RetentionKeepsSavedSnapshot(t *testing.T) {
+
+	now := time.Date(2020, 6, 1, 12, 0, 0, 0, time.UTC)
+	store := NewStoreWithBackend(NewMemoryBackend(), config.Artifacts{Retention: config.Retention{MaxSnapshots: 1, MaxAgeDays: 10}})
+	store.now = func() time.Time { return now }
+	logger := logrus.NewEntry(logrus.New())
+	ctx := context.Background()
+
+	current, err := store.Save(ctx, logger, SnapshotV1{AccountID: "abc", JobID: "current", CapturedAt: now}, []byte("a"))
+	if err != nil {
+		t.Fatalf("Save() unexpected error = %v", err)
+	}
+	//Captured before the retention window and older than the current snapshot
+	old, err := store.Save(ctx, logger, SnapshotV1{AccountID: "abc", JobID: "old", CapturedAt: now.AddDate(0, 0, -30)}, []byte("b"))
+	if err != nil {
+		t.Fatalf("Save() unexpected error = %v", err)
+	}
+
+	if _, err := store.Get(ctx, "abc", old.ID); err != nil {
+		t.Errorf("Get() of the saved snapshot unexpected error = %v", err)
+	}
+	if _, err := store.Get(ctx, "abc", current.ID); err == nil {
+		t.Errorf("Get() of snapshot <%v> beyond MaxSnapshots expected error", current.ID)
+	}
+
+	//Later retention runs expire it like any other snapshot
+	if _, err := store.ApplyRetention(ctx, logger, "abc"); err != nil {
+		t.Fatalf("ApplyRetention() unexpected error = %v", err)
+	}
+	if _, err := store.Get(ctx, "abc", old.ID); err == nil {
+		t.Errorf("Get() of expired snapshot <%v> expected error", old.ID)
+	}
+}
+
+func TestStore_Exports(t *testing.T) {
+
+	backend := NewMemoryBackend()
+	store := NewStoreWithBackend(backend, config.Artifacts{})
+	logger := logrus.NewEntry(logrus.New())
+	ctx := context.Background()
+
+	first, err := store.SaveWithExports(ctx, logger, SnapshotV1{AccountID: "abc", JobID: "first"}, []byte("image"), []DataExport{
+		{Format: CSVExportFormat, Data: []byte("Time,a\n")},
+		{Format: JSONExportFormat, Data: []byte("[]")},
+	})
+	if err != nil {
+		t.Fatalf("SaveWithExports() unexpected error = %v", err)
+	}
+	if len(first.Exports) != 2 || first.Exports[0].ContentType != "text/csv" || first.Exports[1].Size != 2 {
+		t.Errorf("SaveWithExports() exports = %+v", first.Exports)
+	}
+	if _, export, data, gErr := store.GetExport(ctx, "abc", first.ID, JSONExportFormat); gErr != nil || string(data) != "[]" || export.ContentType != "application/json" {
+		t.Errorf("GetExport() = %+v %s, %v", export, data, gErr)
+	}
+	if _, err := store.SaveWithExports(ctx, logger, SnapshotV1{AccountID: "abc"}, []byte("image"), []DataExport{{Format: "xlsx"}}); err == nil {
+		t.Errorf("SaveWithExports() of an unsupported format didn't return an error")
+	}
+
+	//The second snapshot shares the json export. Only the first snapshot's image and csv are unreferenced once it's removed
+	if _, err := store.SaveWithExports(ctx, logger, SnapshotV1{AccountID: "abc", JobID: "second", CapturedAt: first.CapturedAt.Add(time.Minute)}, []byte("image2"),
+		[]DataExport{{Format: JSONExportFormat, Data: []byte("[]")}}); err != nil {
+		t.Fatalf("SaveWithExports() unexpected error = %v", err)
+	}
+	if len(backend.images) != 4 {
+		t.Errorf("backend holds <%v> blobs before retention, want 4", len(backend.images))
+	}
+	store.conf = config.Artifacts{Retention: config.Retention{MaxSnapshots: 1}}
+	if _, err := store.ApplyRetention(ctx, logger, "abc"); err != nil {
+		t.Fatalf("ApplyRetention() unexpected error = %v", err)
+	}
+	if len(backend.images) != 2 {
+		t.Errorf("backend holds <%v> blobs after retention, want 2", len(backend.images))
+	}
+}
+
+func TestStore_Diff(t *testing.T) {
+
+	backend := NewMemoryBackend()
+	store := NewStoreWithBackend(backend, config.Artifacts{})
+	logger := logrus.NewEntry(logrus.New())
+	ctx := context.Background()
+
+	panel := SnapshotV1{AccountID: "abc", JobID: "daily", Source: "grafana", DashboardUID: "cpu01", PanelID: 1}
+	if _, _, err := store.Previous(ctx, panel); err != ErrNotFound {
+		t.Errorf("Previous() of an uncaptured panel error = %v, want ErrNotFound", err)
+	}
+
+	first, err := store.Save(ctx, logger, panel, []byte("image"))
+	if err != nil {
+		t.Fatalf("Save() unexpected error = %v", err)
+	}
+	//Captures of other panels and jobs aren't previous captures
+	other := panel
+	other.JobID = "weekly"
+	if _, err := store.Save(ctx, logger, other, []byte("weekly")); err != nil {
+		t.Fatalf("Save() unexpected error = %v", err)
+	}
+
+	previous, img, err := store.Previous(ctx, panel)
+	if err != nil || previous.ID != first.ID || string(img) != "image" {
+		t.Fatalf("Previous() = %+v %s, %v", previous, img, err)
+	}
+
+	panel.CapturedAt = first.CapturedAt.Add(time.Minute)
+	panel.Diff = &DiffV1{PreviousID: first.ID, Score: 0.5, ChangedPixels: 2, SHA256: "ignored"}
+	second, err := store.SaveCapture(ctx, logger, panel, Capture{Image: []byte("image2"), DiffImage: []byte("diff")})
+	if err != nil {
+		t.Fatalf("SaveCapture() unexpected error = %v", err)
+	}
+	if second.Diff.SHA256 != hash([]byte("diff")) || second.Diff.Size != 4 || second.Diff.Score != 0.5 {
+		t.Errorf("SaveCapture() diff = %+v", second.Diff)
+	}
+	if _, diffImg, gErr := store.GetDiffImage(ctx, "abc", second.ID); gErr != nil || string(diffImg) != "diff" {
+		t.Errorf("GetDiffImage() = %s, %v", diffImg, gErr)
+	}
+	if _, _, gErr := store.GetDiffImage(ctx, "abc", first.ID); gErr != ErrNotFound {
+		t.Errorf("GetDiffImage() of a snapshot without a diff error = %v, want ErrNotFound", gErr)
+	}
+	if previous, _, err := store.Previous(ctx, panel); err != nil || previous.ID != second.ID {
+		t.Errorf("Previous() = %+v, %v, want the newest capture", previous, err)
+	}
+
+	//The diff image is removed with the snapshot
+	store.conf = config.Artifacts{Retention: config.Retention{MaxSnapshots: 1}}
+	if _, err := store.ApplyRetention(ctx, logger, "abc"); err != nil {
+		t.Fatalf("ApplyRetention() unexpected error = %v", err)
+	}
+	if len(backend.images) != 2 {
+		t.Errorf("backend holds <%v> blobs after retention, want 2", len(backend.images))
+	}
+}
+
+func TestStore_PreviousSkipsUnpublished(t *testing.T) {
+
+	store := NewStoreWithBackend(NewMemoryBackend(), config.Artifacts{})
+	logger := logrus.NewEntry(logrus.New())
+	ctx := context.Background()
+
+	panel := SnapshotV1{AccountID: "abc", JobID: "daily", Source: "grafana", DashboardUID: "cpu01", PanelID: 1}
+	published, err := store.Save(ctx, logger, panel, []byte("published"))
+	if err != nil {
+		t.Fatalf("Save() unexpected error = %v", err)
+	}
+
+	//Newer captures whose publish was skipped or rolled back aren't compared with
+	for i, unpublished := range []SnapshotV1{{PublishSkipped: true}, {RolledBack: true}} {
+		capture := panel
+		capture.CapturedAt = published.CapturedAt.Add(time.Duration(i+1) * time.Minute)
+		capture.PublishSkipped, capture.RolledBack = unpublished.PublishSkipped, unpublished.RolledBack
+		if _, sErr := store.Save(ctx, logger, capture, []byte("unpublished")); sErr != nil {
+			t.Fatalf("Save() unexpected error = %v", sErr)
+		}
+	}
+
+	previous, img, err := store.Previous(ctx, panel)
+	if err != nil || previous.ID != published.ID || string(img) != "published" {
+		t.Errorf("Previous() = %+v %s, %v, want the newest published capture <%v>", previous, img, err, published.ID)
+	}
+}
@@ -0,0 +1,184 @@
+package audit
+
+import (
+	"context"
+	"fmt"
+	as "github.com/aerospike/aerospike-client-go"
+	"github.com/aerospike/aerospike-client-go/types"
+	"github.com/sajeevany/graph-snapper/internal/config"
+	"github.com/sajeevany/graph-snapper/internal/db/aerospike"
+	"github.com/sajeevany/graph-snapper/internal/logging"
+	"github.com/sajeevany/graph-snapper/internal/tracing"
+	"github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel/attribute"
+	semconv "go.opentelemetry.io/otel/semconv/v1.17.0"
+	"time"
+)
+
+const (
+	//Bin names. Aerospike limits bin names to 14 characters
+	idBinName        = "ID"
+	accountIDBinName = "AccountID"
+	actorBinName     = "Actor"
+	requestIDBinName = "RequestID"
+	timestampBinName = "Timestamp"
+	actionBinName    = "Action"
+	diffBinName      = "Diff"
+
+	beforeKey = "Before"
+	afterKey  = "After"
+
+	accountIndexSuffix = "_account_idx"
+)
+
+//AerospikeSink - stores each event as its own record in the audit set. Records are only ever created so the trail is
+//append only. Events are listed through a secondary index on the account ID bin.
+type AerospikeSink struct {
+	logger    *logrus.Logger
+	client    *aerospike.ASClient
+	namespace config.AerospikeNamespace
+}
+
+//NewAerospikeSink - Returns a sink writing to the namespace. Creates the account ID index if it doesn't exist
+func NewAerospikeSink(logger *logrus.Logger, client *aerospike.ASClient, namespace config.AerospikeNamespace) (*AerospikeSink, error) {
+
+	indexName := namespace.SetName + accountIndexSuffix
+	task, err := client.Client.CreateIndex(nil, namespace.Namespace, namespace.SetName, indexName, accountIDBinName, as.STRING)
+	if err != nil {
+		if ae, ok := err.(types.AerospikeError); !ok || ae.ResultCode() != types.INDEX_FOUND {
+			logger.WithFields(namespace.GetFields()).Errorf("Unable to create audit index <%v>. err <%v>", indexName, err)
+			return nil, err
+		}
+		logger.WithFields(namespace.GetFields()).Debugf("Audit index <%v> already exists", indexName)
+	} else if iErr := <-task.OnComplete(); iErr != nil {
+		logger.WithFields(namespace.GetFields()).Errorf("Audit index <%v> creation failed. err <%v>", indexName, iErr)
+		return nil, iErr
+	}
+
+	return &AerospikeSink{
+		logger:    logger,
+		client:    client,
+		namespace: namespace,
+	}, nil
+}
+
+func (a *AerospikeSink) Append(ctx context.Context, event EventV1) (err error) {
+
+	logger := logging.FromContext(ctx, a.logger)
+	_, span := tracing.StartSpan(ctx, "aerospike.AppendAuditEvent", a.spanAttributes("put")...)
+	defer func() { tracing.EndSpan(span, err) }()
+
+	key, err := as.NewKey(a.namespace.Namespace, a.namespace.SetName, event.ID)
+	if err != nil {
+		logger.Errorf("Unexpected error when creating audit key <%v>. err <%v>", event.ID, err)
+		return err
+	}
+
+	//Fail rather than overwrite if an event with the same ID already exists
+	policy := as.NewWritePolicy(0, 0)
+	policy.RecordExistsAction = as.CREATE_ONLY
+
+	if pErr := a.client.Client.PutBins(policy, key, toBins(event)...); pErr != nil {
+		logger.WithFields(event.GetFields()).Errorf("Unable to write audit event. err <%v>", pErr)
+		return pErr
+	}
+	return nil
+}
+
+func (a *AerospikeSink) List(ctx context.Context, accountID string, from, to time.Time) (events []EventV1, err error) {
+
+	logger := logging.FromContext(ctx, a.logger)
+	_, span := tracing.StartSpan(ctx, "aerospike.ListAuditEvents", a.spanAttributes("query")...)
+	defer func() { tracing.EndSpan(span, err) }()
+
+	stmt := as.NewStatement(a.namespace.Namespace, a.namespace.SetName)
+	if fErr := stmt.SetFilter(as.NewEqualFilter(accountIDBinName, accountID)); fErr != nil {
+		return nil, fErr
+	}
+
+	rs, qErr := a.client.Client.Query(nil, stmt)
+	if qErr != nil {
+		logger.Errorf("Unable to query audit events for account <%v>. err <%v>", accountID, qErr)
+		return nil, qErr
+	}
+	defer rs.Close()
+
+	for res := range rs.Results() {
+		if res.Err != nil {
+			logger.Errorf("Error reading audit event for account <%v>. err <%v>", accountID, res.Err)
+			return nil, res.Err
+		}
+		event := fromBins(res.Record.Bins)
+		if inRange(event.Timestamp, from, to) {
+			events = append(events, event)
+		}
+	}
+	sortByTimestamp(events)
+
+	return events, nil
+}
+
+func (a *AerospikeSink) spanAttributes(operation string) []attribute.KeyValue {
+	return []attribute.KeyValue{
+		semconv.DBSystemKey.String("aerospike"),
+		semconv.DBNameKey.String(a.namespace.Namespace),
+		semconv.DBOperationKey.String(operation),
+		attribute.String("db.aerospike.set", a.namespace.SetName),
+	}
+}
+
+func toBins(event EventV1) []*as.Bin {
+	diff := make(map[string]interface{}, len(event.Diff))
+	for path, change := range event.Diff {
+		c := make(map[string]interface{}, 2)
+		if change.Before != nil {
+			c[beforeKey] = change.Before
+		}
+		if change.After != nil {
+			c[afterKey] = change.After
+		}
+		diff[path] = c
+	}
+
+	return []*as.Bin{
+		as.NewBin(idBinName, event.ID),
+		as.NewBin(accountIDBinName, event.AccountID),
+		as.NewBin(actorBinName, event.Actor),
+		as.NewBin(requestIDBinName, event.RequestID),
+		as.NewBin(timestampBinName, event.Timestamp.UnixNano()),
+		as.NewBin(actionBinName, event.Action),
+		as.NewBin(diffBinName, diff),
+	}
+}
+
+func fromBins(bins as.BinMap) EventV1 {
+	event := EventV1{
+		ID:        stringBin(bins[idBinName]),
+		AccountID: stringBin(bins[accountIDBinName]),
+		Actor:     stringBin(bins[actorBinName]),
+		RequestID: stringBin(bins[requestIDBinName]),
+		Action:    stringBin(bins[actionBinName]),
+		Diff:      make(map[string]ChangeV1),
+	}
+	if ts, ok := bins[timestampBinName].(int); ok {
+		event.Timestamp = time.Unix(0, int64(ts)).UTC()
+	}
+	if diff, ok := bins[diffBinName].(map[interface{}]interface{}); ok {
+		for path, c := range diff {
+			change := ChangeV1{}
+			if cm, ok := c.(map[interface{}]interface{}); ok {
+				change.Before = cm[beforeKey]
+				change.After = cm[afterKey]
+			}
+			event.Diff[fmt.Sprintf("%v", path)] = change
+		}
+	}
+	return event
+}
+
+func stringBin(val interface{}) string {
+	if s, ok := val.(string); ok {
+		return s
+	}
+	return ""
+}
@@ -0,0 +1,67 @@
+package audit
+
+import (
+	"context"
+	"github.com/sajeevany/graph-snapper/internal/config"
+	"github.com/sajeevany/graph-snapper/internal/redact"
+	"github.com/sajeevany/graph-snapper/internal/test"
+	"github.com/sirupsen/logrus"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestAerospikeSinkIntegration(t *testing.T) {
+
+	//Skip test if user wants to only run regression tests
+	if testing.Short() {
+		t.Skip()
+	}
+
+	//Setup common requirements. In this case it's a specific aerospike image.
+	ctx := context.Background()
+	aeroContainer, aeroClient := test.StartAerospikeTestContainer(t, ctx)
+	defer aeroContainer.Terminate(ctx)
+
+	sink, err := NewAerospikeSink(logrus.New(), aeroClient, config.AerospikeNamespace{Namespace: "test", SetName: config.DefaultAuditSetName})
+	if err != nil {
+		t.Fatalf("SETUP FAILURE: unable to create aerospike sink. err <%v>", err)
+	}
+
+	start := time.Date(2020, 6, 1, 0, 0, 0, 0, time.UTC)
+	events := []EventV1{
+		{ID: "e1", AccountID: "abc", Actor: "admin", RequestID: "r1", Timestamp: start.Add(time.Hour), Action: CredentialsSetAction,
+			Diff: map[string]ChangeV1{"GrafanaAPIUsers.gu0.Auth.BearerToken.Token": {Before: redact.Mask, After: redact.Mask}}},
+		{ID: "e0", AccountID: "abc", Actor: "admin", RequestID: "r0", Timestamp: start, Action: AccountCreateAction,
+			Diff: map[string]ChangeV1{"Email": {After: "a@b.com"}}},
+		{ID: "e2", AccountID: "def", Actor: "admin", RequestID: "r2", Timestamp: start, Action: AccountCreateAction,
+			Diff: map[string]ChangeV1{}},
+	}
+	for _, e := range events {
+		if aErr := sink.Append(ctx, e); aErr != nil {
+			t.Fatalf("Append() err <%v>", aErr)
+		}
+	}
+
+	//Appending an existing event must fail
+	if aErr := sink.Append(ctx, events[0]); aErr == nil {
+		t.Errorf("Expected appending a duplicate event to fail")
+	}
+
+	got, lErr := sink.List(ctx, "abc", time.Time{}, time.Time{})
+	if lErr != nil {
+		t.Fatalf("List() err <%v>", lErr)
+	}
+	want := []EventV1{events[1], events[0]}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("List() = %+v, want %+v", got, want)
+	}
+
+	got, lErr = sink.List(ctx, "abc", start.Add(time.Minute), time.Time{})
+	if lErr != nil {
+		t.Fatalf("List() err <%v>", lErr)
+	}
+	if len(got) != 1 || got[0].ID != "e1" {
+		t.Errorf("List() with from = %+v, want only e1", got)
+	}
+}
@@ -0,0 +1,95 @@
+package audit
+
+import (
+	"fmt"
+	"github.com/sajeevany/graph-snapper/internal/redact"
+	"reflect"
+	"strings"
+)
+
+const (
+	//secretPathSegment - any value nested under this segment holds credentials and is masked
+	secretPathSegment = "Auth"
+	//proxyPathSegment - proxy urls can hold a username and password. Their password is masked
+	proxyPathSegment = "Proxy"
+)
+
+//Diff - Returns the flattened fields that differ between before and after keyed by their dotted path. Secret values
+//are compared unmasked so that a changed secret is reported but only masked values are returned
+func Diff(before, after map[string]interface{}) map[string]ChangeV1 {
+
+	flatBefore := make(map[string]interface{})
+	flatten("", before, flatBefore)
+	flatAfter := make(map[string]interface{})
+	flatten("", after, flatAfter)
+
+	diff := make(map[string]ChangeV1)
+	for path, b := range flatBefore {
+		a, exists := flatAfter[path]
+		if exists && reflect.DeepEqual(a, b) {
+			continue
+		}
+		change := ChangeV1{Before: maskIfSecret(path, b)}
+		if exists {
+			change.After = maskIfSecret(path, a)
+		}
+		diff[path] = change
+	}
+	for path, a := range flatAfter {
+		if _, exists := flatBefore[path]; !exists {
+			diff[path] = ChangeV1{After: maskIfSecret(path, a)}
+		}
+	}
+
+	return diff
+}
+
+func flatten(prefix string, val interface{}, out map[string]interface{}) {
+	switch v := val.(type) {
+	case map[string]interface{}:
+		for k, nested := range v {
+			flatten(join(prefix, k), nested, out)
+		}
+	case map[string]string:
+		for k, nested := range v {
+			flatten(join(prefix, k), nested, out)
+		}
+	case map[interface{}]interface{}:
+		for k, nested := range v {
+			flatten(join(prefix, fmt.Sprintf("%v", k)), nested, out)
+		}
+	case nil:
+	case string:
+		//Empty values are treated as unset
+		if v != "" {
+			out[prefix] = v
+		}
+	case bool:
+		//Disabled flags are treated as unset
+		if v {
+			out[prefix] = v
+		}
+	default:
+		out[prefix] = v
+	}
+}
+
+func join(prefix, key string) string {
+	if prefix == "" {
+		return key
+	}
+	return prefix + "." + key
+}
+
+func maskIfSecret(path string, val interface{}) interface{} {
+	segments := strings.Split(path, ".")
+	for _, segment := range segments {
+		if segment == secretPathSegment || redact.IsSensitiveKey(segment) {
+			return redact.Mask
+		}
+	}
+	if proxy, isString := val.(string); isString && segments[len(segments)-1] == proxyPathSegment {
+		return redact.URL(proxy)
+	}
+	return val
+}
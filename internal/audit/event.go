@@ -0,0 +1,80 @@
+package audit
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/sajeevany/graph-snapper/internal/logging"
+	"github.com/sirupsen/logrus"
+	"time"
+)
+
+const (
+	//Audited actions
+	AccountCreateAction  = "ACCOUNT_CREATE"
+	CredentialsSetAction = "CREDENTIALS_SET"
+
+	//ActorHeader - header identifying who made the change. Set by the caller or an authenticating proxy
+	ActorHeader = "X-Actor"
+	//AnonymousActor - actor recorded when no actor header was provided
+	AnonymousActor = "anonymous"
+)
+
+//EventV1 - Append only record of a change made to an account
+type EventV1 struct {
+	ID        string              `json:"ID"`
+	AccountID string              `json:"AccountID"`
+	Actor     string              `json:"Actor"`
+	RequestID string              `json:"RequestID"`
+	Timestamp time.Time           `json:"Timestamp"`
+	Action    string              `json:"Action"`
+	Diff      map[string]ChangeV1 `json:"Diff"`
+}
+
+//ChangeV1 - Value of a field before and after a change. Secrets are masked
+type ChangeV1 struct {
+	Before interface{} `json:"Before,omitempty"`
+	After  interface{} `json:"After,omitempty"`
+}
+
+func (e EventV1) GetFields() logrus.Fields {
+	return logrus.Fields{
+		"ID":        e.ID,
+		"AccountID": e.AccountID,
+		"Actor":     e.Actor,
+		"RequestID": e.RequestID,
+		"Timestamp": e.Timestamp,
+		"Action":    e.Action,
+		"Diff":      e.Diff,
+	}
+}
+
+//NewEventV1 - Returns an event for the request with a redacted diff of before and after
+func NewEventV1(ctx *gin.Context, accountID, action string, before, after map[string]interface{}) EventV1 {
+
+	actor := ctx.GetHeader(ActorHeader)
+	if actor == "" {
+		actor = AnonymousActor
+	}
+
+	return EventV1{
+		ID:        uuid.New().String(),
+		AccountID: accountID,
+		Actor:     actor,
+		RequestID: ctx.GetString(logging.RequestIDKey),
+		Timestamp: time.Now().UTC(),
+		Action:    action,
+		Diff:      Diff(before, after),
+	}
+}
+
+//Record - Appends an event for the request to the sink. Audit is recorded after the change has been persisted so
+//failures are logged instead of failing the request
+func Record(ctx *gin.Context, logger *logrus.Entry, sink Sink, accountID, action string, before, after map[string]interface{}) {
+
+	event := NewEventV1(ctx, accountID, action, before, after)
+	if err := sink.Append(ctx.Request.Context(), event); err != nil {
+		logger.WithFields(event.GetFields()).Errorf("Unable to record audit event. err <%v>", err)
+		return
+	}
+	logger.WithFields(event.GetFields()).Debug("Recorded audit event")
+}
@@ -0,0 +1,92 @@
+package audit
+
+import (
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"github.com/sajeevany/graph-snapper/internal/logging"
+	"github.com/sirupsen/logrus"
+	"net/http"
+	"time"
+)
+
+const GetAuditEndpoint = "/:id/audit"
+
+//AuditLogViewV1 - Audit events of an account
+type AuditLogViewV1 struct {
+	AccountID string    `json:"AccountID"`
+	Events    []EventV1 `json:"Events"`
+}
+
+//@Summary Get account audit log
+//@Description Non-authenticated endpoint that returns the account and credential changes made to an account ordered by time. Secrets are masked.
+//@Produce json
+//@Param id path string true "id"
+//@Param from query string false "Inclusive RFC3339 start time"
+//@Param to query string false "Inclusive RFC3339 end time"
+//@Success 200 {object} AuditLogViewV1
+//@Fail 400 {object} gin.H
+//@Fail 500 {object} gin.H
+//@Fail 501 {object} gin.H
+//@Router /account/:id/audit [get]
+//@Tags account
+func GetAuditV1(baseLogger *logrus.Logger, sink Sink) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+
+		//Use request scoped logger
+		logger := logging.FromContext(ctx.Request.Context(), baseLogger)
+
+		//Validate that id parameter has been set
+		accountId := ctx.Param("id")
+		if accountId == "" {
+			msg := fmt.Sprintf("Query parameter %v hasn't been set", "id")
+			logger.Debug(msg)
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": msg})
+			return
+		}
+
+		//Parse optional time range
+		from, fErr := parseTimeParam(ctx, "from")
+		to, tErr := parseTimeParam(ctx, "to")
+		if fErr != nil || tErr != nil {
+			msg := fmt.Sprintf("Query parameters from <%v> and to <%v> must be RFC3339 timestamps. ie 2020-06-01T00:00:00Z", ctx.Query("from"), ctx.Query("to"))
+			logger.Debug(msg)
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": msg})
+			return
+		}
+		if !from.IsZero() && !to.IsZero() && from.After(to) {
+			msg := fmt.Sprintf("Query parameter from <%v> is after to <%v>", from, to)
+			logger.Debug(msg)
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": msg})
+			return
+		}
+
+		events, err := sink.List(ctx.Request.Context(), accountId, from, to)
+		if err == ErrListUnsupported {
+			ctx.JSON(http.StatusNotImplemented, gin.H{"error": err.Error()})
+			return
+		}
+		if err != nil {
+			hrErrMsg := fmt.Sprintf("unable to read audit events for account <%v>. err <%v>", accountId, err)
+			logger.Errorf(hrErrMsg)
+			ctx.JSON(http.StatusInternalServerError, gin.H{
+				"error":              err.Error(),
+				"humanReadableError": hrErrMsg,
+			})
+			return
+		}
+		if events == nil {
+			events = []EventV1{}
+		}
+
+		ctx.JSON(http.StatusOK, AuditLogViewV1{AccountID: accountId, Events: events})
+	}
+}
+
+//parseTimeParam - returns the zero time if the query parameter isn't set
+func parseTimeParam(ctx *gin.Context, name string) (time.Time, error) {
+	val := ctx.Query(name)
+	if val == "" {
+		return time.Time{}, nil
+	}
+	return time.Parse(time.RFC3339, val)
+}
@@ -0,0 +1,109 @@
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestGetAuditV1(t *testing.T) {
+
+	//Setup events across a few days for two accounts
+	start := time.Date(2020, 6, 1, 0, 0, 0, 0, time.UTC)
+	sink := NewMemorySink()
+	for i := 0; i < 3; i++ {
+		for _, act := range []string{"abc", "def"} {
+			_ = sink.Append(context.Background(), EventV1{
+				ID:        fmt.Sprintf("%s-%d", act, i),
+				AccountID: act,
+				Timestamp: start.Add(time.Duration(i) * 24 * time.Hour),
+				Action:    CredentialsSetAction,
+			})
+		}
+	}
+
+	tests := []struct {
+		name     string
+		sink     Sink
+		query    string
+		wantCode int
+		wantIDs  []string
+	}{
+		{
+			name:     "test0 all events of account ordered by time",
+			sink:     sink,
+			query:    "",
+			wantCode: http.StatusOK,
+			wantIDs:  []string{"abc-0", "abc-1", "abc-2"},
+		},
+		{
+			name:     "test1 inclusive time range",
+			sink:     sink,
+			query:    "?from=2020-06-02T00:00:00Z&to=2020-06-03T00:00:00Z",
+			wantCode: http.StatusOK,
+			wantIDs:  []string{"abc-1", "abc-2"},
+		},
+		{
+			name:     "test2 open ended time range",
+			sink:     sink,
+			query:    "?to=2020-06-01T12:00:00Z",
+			wantCode: http.StatusOK,
+			wantIDs:  []string{"abc-0"},
+		},
+		{
+			name:     "test3 invalid time",
+			sink:     sink,
+			query:    "?from=yesterday",
+			wantCode: http.StatusBadRequest,
+		},
+		{
+			name:     "test4 from is after to",
+			sink:     sink,
+			query:    "?from=2020-06-03T00:00:00Z&to=2020-06-02T00:00:00Z",
+			wantCode: http.StatusBadRequest,
+		},
+		{
+			name:     "test5 log sink can't list",
+			sink:     NewLogSink(logrus.New()),
+			query:    "",
+			wantCode: http.StatusNotImplemented,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gin.SetMode(gin.TestMode)
+			w := httptest.NewRecorder()
+			_, r := gin.CreateTestContext(w)
+			r.GET("/api/v1/account/:id/audit", GetAuditV1(logrus.New(), tt.sink))
+
+			req, _ := http.NewRequest(http.MethodGet, "/api/v1/account/abc/audit"+tt.query, nil)
+			r.ServeHTTP(w, req)
+
+			if w.Code != tt.wantCode {
+				t.Fatalf("Incorrect return code. Expected <%v> got <%v>. Body <%v>", tt.wantCode, w.Code, w.Body.String())
+			}
+			if tt.wantCode != http.StatusOK {
+				return
+			}
+
+			var view AuditLogViewV1
+			if err := json.Unmarshal(w.Body.Bytes(), &view); err != nil {
+				t.Fatalf("Unable to unmarshal response err <%v>", err)
+			}
+			if len(view.Events) != len(tt.wantIDs) {
+				t.Fatalf("Got <%v> events, want <%v>. Events <%+v>", len(view.Events), len(tt.wantIDs), view.Events)
+			}
+			for i, id := range tt.wantIDs {
+				if view.Events[i].ID != id {
+					t.Errorf("Event <%v> ID = <%v>, want <%v>", i, view.Events[i].ID, id)
+				}
+			}
+		})
+	}
+}
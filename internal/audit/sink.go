@@ -0,0 +1,102 @@
+package audit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/sajeevany/graph-snapper/internal/config"
+	"github.com/sajeevany/graph-snapper/internal/db/aerospike"
+	"github.com/sirupsen/logrus"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+//ErrListUnsupported - returned by sinks that can only append events
+var ErrListUnsupported = errors.New("audit sink does not support listing events")
+
+//Sink - append only storage of audit events
+type Sink interface {
+	//Append - stores the event. Events are never modified once appended
+	Append(ctx context.Context, event EventV1) error
+	//List - returns the events of an account with a timestamp within [from, to] ordered by timestamp. Zero times are unbounded
+	List(ctx context.Context, accountID string, from, to time.Time) ([]EventV1, error)
+}
+
+//NewSink - Returns the sink defined by the audit config
+func NewSink(logger *logrus.Logger, conf config.Audit, aeroConf config.AerospikeCfg, aeroClient *aerospike.ASClient) (Sink, error) {
+	switch strings.ToLower(conf.Sink) {
+	case config.AerospikeAuditSink:
+		return NewAerospikeSink(logger, aeroClient, aeroConf.GetAuditNamespace())
+	case config.LogAuditSink:
+		return NewLogSink(logger), nil
+	default:
+		return nil, fmt.Errorf("unsupported audit sink <%v>", conf.Sink)
+	}
+}
+
+//LogSink - writes events to the service log. Events can't be listed
+type LogSink struct {
+	logger *logrus.Logger
+}
+
+func NewLogSink(logger *logrus.Logger) *LogSink {
+	return &LogSink{logger: logger}
+}
+
+func (l *LogSink) Append(ctx context.Context, event EventV1) error {
+	l.logger.WithFields(event.GetFields()).Info("Audit event")
+	return nil
+}
+
+func (l *LogSink) List(ctx context.Context, accountID string, from, to time.Time) ([]EventV1, error) {
+	return nil, ErrListUnsupported
+}
+
+//MemorySink - keeps events in memory. Intended for tests and single instance development setups
+type MemorySink struct {
+	mu     sync.RWMutex
+	events []EventV1
+}
+
+func NewMemorySink() *MemorySink {
+	return &MemorySink{}
+}
+
+func (m *MemorySink) Append(ctx context.Context, event EventV1) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.events = append(m.events, event)
+	return nil
+}
+
+func (m *MemorySink) List(ctx context.Context, accountID string, from, to time.Time) ([]EventV1, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var events []EventV1
+	for _, e := range m.events {
+		if e.AccountID == accountID && inRange(e.Timestamp, from, to) {
+			events = append(events, e)
+		}
+	}
+	sortByTimestamp(events)
+	return events, nil
+}
+
+func inRange(t, from, to time.Time) bool {
+	if !from.IsZero() && t.Before(from) {
+		return false
+	}
+	if !to.IsZero() && t.After(to) {
+		return false
+	}
+	return true
+}
+
+func sortByTimestamp(events []EventV1) {
+	sort.SliceStable(events, func(i, j int) bool {
+		return events[i].Timestamp.Before(events[j].Timestamp)
+	})
+}
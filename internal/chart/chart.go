@@ -0,0 +1,309 @@
+package chart
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"gonum.org/v1/plot"
+	"gonum.org/v1/plot/plotter"
+	"gonum.org/v1/plot/vg"
+	"gonum.org/v1/plot/vg/draw"
+	"gonum.org/v1/plot/vg/vgimg"
+	"gonum.org/v1/plot/vg/vgsvg"
+	"image/color"
+	"math"
+	"time"
+)
+
+const (
+	//PNGContentType - content type of charts rendered by PNG
+	PNGContentType = "image/png"
+	//SVGContentType - content type of charts rendered by SVG
+	SVGContentType = "image/svg+xml"
+
+	defaultWidth  = 1000
+	defaultHeight = 500
+	minDimension  = 100
+
+	marginBottom = 24
+	legendRow    = 14
+	legendOffset = 4
+	fontSize     = 10
+	lineWidth    = 2
+	dashLength   = 4
+	pixelDPI     = 72
+)
+
+//palette - series colors. Reused in order when there are more series than colors
+var palette = []color.RGBA{
+	{R: 0x73, G: 0xbf, B: 0x69, A: 0xff},
+	{R: 0xf2, G: 0xcc, B: 0x0c, A: 0xff},
+	{R: 0x56, G: 0x94, B: 0xf2, A: 0xff},
+	{R: 0xff, G: 0x78, B: 0x3d, A: 0xff},
+	{R: 0xf2, G: 0x49, B: 0x5c, A: 0xff},
+	{R: 0x5c, G: 0xc9, B: 0xc4, A: 0xff},
+	{R: 0xb8, G: 0x77, B: 0xd9, A: 0xff},
+	{R: 0x70, G: 0x5d, B: 0xa0, A: 0xff},
+}
+
+//Chart - Time series line chart rendered without a browser
+type Chart struct {
+	Title  string
+	Width  int //Optional. Image width in pixels. Defaults to 1000
+	Height int //Optional. Image height in pixels. Defaults to 500
+	Series []Series
+	From   time.Time //Optional. Start of the x axis. Defaults to the first point
+	To     time.Time //Optional. End of the x axis. Defaults to the last point
+
+	Unit       string         //Optional. Grafana unit of the values used to format axis labels. ie percent, bytes or ms
+	Thresholds []Threshold    //Optional. Drawn as dashed lines across the plot
+	HideLegend bool           //Optional. Omits the legend below the plot
+	Location   *time.Location //Optional. Timezone of time labels. Defaults to UTC
+}
+
+//SizeError - the chart is too small to draw its series and legend
+type SizeError struct {
+	Series        int
+	Width, Height int
+	MinHeight     int
+}
+
+func (e *SizeError) Error() string {
+	return fmt.Sprintf("chart of <%v> series must be at least %vx%v pixels. got %vx%v", e.Series, minDimension, e.MinHeight, e.Width, e.Height)
+}
+
+//IsSizeError - returns true if the chart is too small to draw its series and legend
+func IsSizeError(err error) bool {
+	var sErr *SizeError
+	return errors.As(err, &sErr)
+}
+
+//Threshold - Value marked by a horizontal line
+type Threshold struct {
+	Value float64
+	Color color.RGBA
+}
+
+//Series - Named line of a chart
+type Series struct {
+	Name   string
+	Points []Point
+}
+
+//Point - Value at a time. NaN values are drawn as gaps
+type Point struct {
+	Time  time.Time
+	Value float64
+}
+
+//PNG - Renders the chart to a PNG image. Series are listed in a legend below the plot unless it's hidden
+func (c Chart) PNG() ([]byte, error) {
+	return c.render(func(w, h vg.Length) vg.CanvasWriterTo {
+		//At 72 dpi a point is a pixel
+		return vgimg.PngCanvas{Canvas: vgimg.NewWith(vgimg.UseWH(w, h), vgimg.UseDPI(pixelDPI))}
+	})
+}
+
+//SVG - Renders the chart to an SVG image with the same layout as PNG
+func (c Chart) SVG() ([]byte, error) {
+	return c.render(func(w, h vg.Length) vg.CanvasWriterTo {
+		return vgsvg.New(w, h)
+	})
+}
+
+//render - draws the plot above the legend on a canvas of the chart's size and returns the encoded canvas
+func (c Chart) render(newCanvas func(w, h vg.Length) vg.CanvasWriterTo) ([]byte, error) {
+
+	width, height := c.Width, c.Height
+	if width == 0 {
+		width = defaultWidth
+	}
+	if height == 0 {
+		height = defaultHeight
+	}
+
+	legendHeight := 0
+	if !c.HideLegend {
+		legendHeight = legendRow * len(c.Series)
+	}
+	if width < minDimension || height < minDimension+marginBottom+legendHeight {
+		return nil, &SizeError{Series: len(c.Series), Width: width, Height: height, MinHeight: minDimension + marginBottom + legendHeight}
+	}
+
+	p, legend, err := c.newPlot()
+	if err != nil {
+		return nil, err
+	}
+	canvas := newCanvas(vg.Points(float64(width)), vg.Points(float64(height)))
+	dc := draw.New(canvas)
+	dc.FillPolygon(p.BackgroundColor, []vg.Point{dc.Min, {X: dc.Max.X, Y: dc.Min.Y}, dc.Max, {X: dc.Min.X, Y: dc.Max.Y}})
+	p.Draw(draw.Crop(dc, 0, 0, vg.Points(float64(legendHeight)), 0))
+	if legendHeight != 0 {
+		legend.Draw(draw.Crop(dc, 0, 0, 0, -vg.Points(float64(height-legendHeight))))
+	}
+
+	var buf bytes.Buffer
+	if _, err := canvas.WriteTo(&buf); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+//newPlot - returns the plot of the series and thresholds and the legend of the series. Times are plotted as unix
+//seconds
+func (c Chart) newPlot() (*plot.Plot, plot.Legend, error) {
+
+	from, to := c.timeRange()
+	min, max := c.valueRange()
+	location := c.Location
+	if location == nil {
+		location = time.UTC
+	}
+
+	p := plot.New()
+	p.Title.Text = c.Title
+	p.Title.TextStyle.Font.Size = fontSize
+	p.X.Tick.Label.Font.Size = fontSize
+	p.Y.Tick.Label.Font.Size = fontSize
+	p.X.Min, p.X.Max = unixSeconds(from), unixSeconds(to)
+	p.X.Tick.Marker = plot.TimeTicks{Format: timeLayout(to.Sub(from)), Time: plot.UnixTimeIn(location)}
+	p.Y.Min, p.Y.Max = min, max
+	p.Y.Tick.Marker = plot.TickerFunc(func(min, max float64) []plot.Tick {
+		ticks := plot.DefaultTicks{}.Ticks(min, max)
+		for i, t := range ticks {
+			if t.Label != "" {
+				ticks[i].Label = formatValue(t.Value, c.Unit)
+			}
+		}
+		return ticks
+	})
+	grid := plotter.NewGrid()
+	grid.Vertical.Color = color.Gray{Y: 0xe5}
+	grid.Horizontal.Color = color.Gray{Y: 0xe5}
+	p.Add(grid)
+
+	//Thresholds are drawn behind the series
+	for _, t := range c.Thresholds {
+		if math.IsNaN(t.Value) || t.Value < min || t.Value > max {
+			continue
+		}
+		line, err := plotter.NewLine(plotter.XYs{{X: p.X.Min, Y: t.Value}, {X: p.X.Max, Y: t.Value}})
+		if err != nil {
+			return nil, plot.Legend{}, err
+		}
+		line.Color = t.Color
+		line.Dashes = []vg.Length{dashLength, dashLength}
+		p.Add(line)
+	}
+
+	legend := plot.NewLegend()
+	legend.Top, legend.Left = true, true
+	legend.XOffs = legendOffset
+	legend.TextStyle.Font.Size = fontSize
+	for i, s := range c.Series {
+		style := draw.LineStyle{Color: palette[i%len(palette)], Width: lineWidth}
+		for _, segment := range segments(s.Points) {
+			//Lines need two points so points between gaps are drawn as dots
+			if len(segment) == 1 {
+				dot, err := plotter.NewScatter(segment)
+				if err != nil {
+					return nil, plot.Legend{}, err
+				}
+				dot.GlyphStyle = draw.GlyphStyle{Color: style.Color, Radius: lineWidth / 2, Shape: draw.CircleGlyph{}}
+				p.Add(dot)
+				continue
+			}
+			line, err := plotter.NewLine(segment)
+			if err != nil {
+				return nil, plot.Legend{}, err
+			}
+			line.LineStyle = style
+			p.Add(line)
+		}
+		legend.Add(s.Name, &plotter.Line{LineStyle: style})
+	}
+
+	//Adding plotters widens the axes to their data so the ranges are set again
+	p.X.Min, p.X.Max = unixSeconds(from), unixSeconds(to)
+	p.Y.Min, p.Y.Max = min, max
+	return p, legend, nil
+}
+
+//segments - returns the finite points of the series split at gaps
+func segments(points []Point) []plotter.XYs {
+	var all []plotter.XYs
+	var cur plotter.XYs
+	for _, p := range points {
+		if math.IsNaN(p.Value) || math.IsInf(p.Value, 0) {
+			if len(cur) != 0 {
+				all = append(all, cur)
+			}
+			cur = nil
+			continue
+		}
+		cur = append(cur, plotter.XY{X: unixSeconds(p.Time), Y: p.Value})
+	}
+	if len(cur) != 0 {
+		all = append(all, cur)
+	}
+	return all
+}
+
+func unixSeconds(t time.Time) float64 {
+	return float64(t.UnixNano()) / float64(time.Second)
+}
+
+//timeRange - returns the x axis range. Defaults to the range of the points and is never empty
+func (c Chart) timeRange() (time.Time, time.Time) {
+	from, to := c.From, c.To
+	for _, s := range c.Series {
+		for _, p := range s.Points {
+			if c.From.IsZero() && (from.IsZero() || p.Time.Before(from)) {
+				from = p.Time
+			}
+			if c.To.IsZero() && (to.IsZero() || p.Time.After(to)) {
+				to = p.Time
+			}
+		}
+	}
+	if from.IsZero() && to.IsZero() {
+		to = time.Now()
+	}
+	if !to.After(from) {
+		from = to.Add(-time.Hour)
+	}
+	return from, to
+}
+
+//valueRange - returns the minimum and maximum finite values. Defaults to 0 to 1
+func (c Chart) valueRange() (float64, float64) {
+	min, max := math.Inf(1), math.Inf(-1)
+	for _, s := range c.Series {
+		for _, p := range s.Points {
+			if math.IsNaN(p.Value) || math.IsInf(p.Value, 0) {
+				continue
+			}
+			min = math.Min(min, p.Value)
+			max = math.Max(max, p.Value)
+		}
+	}
+	if math.IsInf(min, 1) {
+		return 0, 1
+	}
+	if min == max {
+		return min - 1, max + 1
+	}
+	return min, max
+}
+
+//timeLayout - returns a time label layout that suits the length of the range
+func timeLayout(d time.Duration) string {
+	switch {
+	case d <= 2*24*time.Hour:
+		return "15:04"
+	case d <= 365*24*time.Hour:
+		return "01-02"
+	default:
+		return "2006-01"
+	}
+}
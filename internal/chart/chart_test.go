@@ -0,0 +1,170 @@
+package chart
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"math"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestChart_PNG(t *testing.T) {
+
+	start := time.Date(2020, 7, 1, 10, 0, 0, 0, time.UTC)
+	series := func(name string, values ...float64) Series {
+		s := Series{Name: name}
+		for i, v := range values {
+			s.Points = append(s.Points, Point{Time: start.Add(time.Duration(i) * time.Minute), Value: v})
+		}
+		return s
+	}
+
+	tests := []struct {
+		name       string
+		chart      Chart
+		wantWidth  int
+		wantHeight int
+		wantColors int
+		wantErr    bool
+	}{
+		{
+			name:       "test0 default size with a line per series",
+			chart:      Chart{Title: "cpu", Series: []Series{series("a", 1, 2, 3), series("b", 3, 2, 1)}},
+			wantWidth:  defaultWidth,
+			wantHeight: defaultHeight,
+			wantColors: 2,
+		},
+		{
+			name:       "test1 gaps and a flat line",
+			chart:      Chart{Width: 300, Height: 200, Series: []Series{series("a", 5, math.NaN(), 5, 5)}},
+			wantWidth:  300,
+			wantHeight: 200,
+			wantColors: 1,
+		},
+		{
+			name:       "test2 no data",
+			chart:      Chart{Width: 300, Height: 200},
+			wantWidth:  300,
+			wantHeight: 200,
+		},
+		{
+			name:    "test3 too small for the legend",
+			chart:   Chart{Width: 300, Height: 130, Series: []Series{series("a", 1), series("b", 1), series("c", 1)}},
+			wantErr: true,
+		},
+		{
+			name:       "test4 hidden legend and a threshold",
+			chart:      Chart{Width: 300, Height: 130, HideLegend: true, Series: []Series{series("a", 1, 3), series("b", 2, 1), series("c", 0, 2)}, Thresholds: []Threshold{{Value: 2, Color: color.RGBA{R: 0xff, A: 0xff}}}},
+			wantWidth:  300,
+			wantHeight: 130,
+			wantColors: 3,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := tt.chart.PNG()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("PNG() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+
+			img, err := png.Decode(bytes.NewReader(data))
+			if err != nil {
+				t.Fatalf("PNG() returned an invalid png. err <%v>", err)
+			}
+			if b := img.Bounds(); b.Dx() != tt.wantWidth || b.Dy() != tt.wantHeight {
+				t.Errorf("PNG() size = %vx%v, want %vx%v", b.Dx(), b.Dy(), tt.wantWidth, tt.wantHeight)
+			}
+			if got := countSeriesColors(img); got != tt.wantColors {
+				t.Errorf("PNG() drew <%v> series colors, want %v", got, tt.wantColors)
+			}
+		})
+	}
+}
+
+func TestChart_SVG(t *testing.T) {
+
+	start := time.Date(2020, 7, 1, 10, 0, 0, 0, time.UTC)
+	c := Chart{
+		Title:      "cpu <user & system>",
+		Unit:       "percent",
+		Thresholds: []Threshold{{Value: 80, Color: color.RGBA{R: 0xf2, G: 0x49, B: 0x5c, A: 0xff}}},
+		Series: []Series{
+			{Name: "a", Points: []Point{{Time: start, Value: 10}, {Time: start.Add(time.Minute), Value: math.NaN()}, {Time: start.Add(2 * time.Minute), Value: 90}, {Time: start.Add(3 * time.Minute), Value: 50}}},
+			{Name: "b", Points: []Point{{Time: start, Value: 20}, {Time: start.Add(3 * time.Minute), Value: 30}}},
+		},
+	}
+
+	data, err := c.SVG()
+	if err != nil {
+		t.Fatalf("SVG() unexpected error = %v", err)
+	}
+
+	var doc struct {
+		Width  string   `xml:"width,attr"`
+		Height string   `xml:"height,attr"`
+		Texts  []string `xml:"g>text"`
+		Paths  []struct {
+			Style string `xml:"style,attr"`
+		} `xml:"g>path"`
+	}
+	if err := xml.Unmarshal(data, &doc); err != nil {
+		t.Fatalf("SVG() returned invalid xml. err <%v>", err)
+	}
+	if doc.Width != "1000pt" || doc.Height != "500pt" {
+		t.Errorf("SVG() size = %vx%v, want 1000ptx500pt", doc.Width, doc.Height)
+	}
+	//The gap leaves a single point of the first series which is drawn as a dot
+	dots, dashed := 0, 0
+	dotStyle := fmt.Sprintf("fill:#%02X%02X%02X", palette[0].R, palette[0].G, palette[0].B)
+	for _, p := range doc.Paths {
+		if p.Style == dotStyle {
+			dots++
+		}
+		if strings.Contains(p.Style, "stroke-dasharray") {
+			dashed++
+		}
+	}
+	if dots != 1 {
+		t.Errorf("SVG() drew <%v> dots, want 1", dots)
+	}
+	if dashed != 1 {
+		t.Errorf("SVG() drew <%v> thresholds, want 1", dashed)
+	}
+	wantTexts := map[string]bool{"cpu <user & system>": false, "50%": false, "a": false, "b": false}
+	for _, text := range doc.Texts {
+		if _, exists := wantTexts[text]; exists {
+			wantTexts[text] = true
+		}
+	}
+	for text, found := range wantTexts {
+		if !found {
+			t.Errorf("SVG() is missing text <%v>", text)
+		}
+	}
+}
+
+//countSeriesColors - returns the number of palette colors used in the image
+func countSeriesColors(img image.Image) int {
+	found := make(map[int]bool)
+	b := img.Bounds()
+	for x := b.Min.X; x < b.Max.X; x++ {
+		for y := b.Min.Y; y < b.Max.Y; y++ {
+			r, g, bl, _ := img.At(x, y).RGBA()
+			for i, c := range palette {
+				pr, pg, pb, _ := c.RGBA()
+				if r == pr && g == pg && bl == pb {
+					found[i] = true
+				}
+			}
+		}
+	}
+	return len(found)
+}
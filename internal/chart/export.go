@@ -0,0 +1,85 @@
+package chart
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"math"
+	"sort"
+	"strconv"
+	"time"
+)
+
+//SeriesV1 - Exported series. Values which aren't finite are exported as null
+type SeriesV1 struct {
+	Name   string
+	Points []PointV1
+}
+
+//PointV1 - Exported point
+type PointV1 struct {
+	Time  time.Time
+	Value *float64
+}
+
+//CSV - Returns the series as CSV with a Time column and a column per series. Rows are ordered by time and values a
+//series doesn't have at a time are left empty. Times are RFC3339 in UTC
+func CSV(series []Series) ([]byte, error) {
+
+	header := []string{"Time"}
+	rows := map[time.Time][]string{}
+	for i, s := range series {
+		header = append(header, s.Name)
+		for _, p := range s.Points {
+			row, exists := rows[p.Time]
+			if !exists {
+				row = make([]string, len(series)+1)
+				row[0] = p.Time.UTC().Format(time.RFC3339Nano)
+				rows[p.Time] = row
+			}
+			if isFinite(p.Value) {
+				row[i+1] = strconv.FormatFloat(p.Value, 'f', -1, 64)
+			}
+		}
+	}
+
+	times := make([]time.Time, 0, len(rows))
+	for t := range rows {
+		times = append(times, t)
+	}
+	sort.Slice(times, func(i, j int) bool { return times[i].Before(times[j]) })
+
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	if err := w.Write(header); err != nil {
+		return nil, err
+	}
+	for _, t := range times {
+		if err := w.Write(rows[t]); err != nil {
+			return nil, err
+		}
+	}
+	w.Flush()
+	return buf.Bytes(), w.Error()
+}
+
+//JSON - Returns the series as a JSON list of SeriesV1
+func JSON(series []Series) ([]byte, error) {
+	exported := make([]SeriesV1, len(series))
+	for i, s := range series {
+		points := make([]PointV1, len(s.Points))
+		for j, p := range s.Points {
+			points[j] = PointV1{Time: p.Time.UTC()}
+			if isFinite(p.Value) {
+				v := p.Value
+				points[j].Value = &v
+			}
+		}
+		exported[i] = SeriesV1{Name: s.Name, Points: points}
+	}
+	return json.Marshal(exported)
+}
+
+func isFinite(v float64) bool {
+	return !math.IsNaN(v) && !math.IsInf(v, 0)
+}
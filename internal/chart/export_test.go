@@ -0,0 +1,45 @@
+package chart
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestExport(t *testing.T) {
+
+	start := time.Date(2021, 3, 10, 10, 0, 0, 0, time.UTC)
+	series := []Series{
+		{Name: `up{job="node"}`, Points: []Point{{Time: start, Value: 1}, {Time: start.Add(time.Minute), Value: math.NaN()}}},
+		{Name: "load", Points: []Point{{Time: start.Add(time.Minute), Value: 0.25}, {Time: start, Value: 1.5}}},
+	}
+
+	tests := []struct {
+		name   string
+		encode func([]Series) ([]byte, error)
+		want   string
+	}{
+		{
+			name:   "test0 csv joined by time",
+			encode: CSV,
+			want:   "Time,\"up{job=\"\"node\"\"}\",load\n2021-03-10T10:00:00Z,1,1.5\n2021-03-10T10:01:00Z,,0.25\n",
+		},
+		{
+			name:   "test1 json with null gaps",
+			encode: JSON,
+			want: `[{"Name":"up{job=\"node\"}","Points":[{"Time":"2021-03-10T10:00:00Z","Value":1},{"Time":"2021-03-10T10:01:00Z","Value":null}]},` +
+				`{"Name":"load","Points":[{"Time":"2021-03-10T10:01:00Z","Value":0.25},{"Time":"2021-03-10T10:00:00Z","Value":1.5}]}]`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.encode(series)
+			if err != nil {
+				t.Fatalf("encode() unexpected error = %v", err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("encode() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
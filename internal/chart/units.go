@@ -0,0 +1,105 @@
+package chart
+
+import (
+	"math"
+	"strconv"
+	"strings"
+)
+
+const (
+	customSuffixUnit = "suffix:"
+	customPrefixUnit = "prefix:"
+)
+
+var (
+	siPrefixes  = []string{"", "k", "M", "G", "T", "P"}
+	iecPrefixes = []string{"", "Ki", "Mi", "Gi", "Ti", "Pi"}
+)
+
+//formatValue - returns a short axis label of the value in a grafana unit. ie 1.5K, 20%, 512 MiB or 250 ms. Units
+//that aren't supported are formatted as short values
+func formatValue(v float64, unit string) string {
+	switch {
+	case unit == "percent":
+		return formatNumber(v) + "%"
+	case unit == "percentunit":
+		return formatNumber(v*100) + "%"
+	case unit == "bytes":
+		return scaled(v, 1024, iecPrefixes, "B")
+	case unit == "decbytes":
+		return scaled(v, 1000, siPrefixes, "B")
+	case unit == "bits":
+		return scaled(v, 1024, iecPrefixes, "b")
+	case unit == "decbits":
+		return scaled(v, 1000, siPrefixes, "b")
+	case unit == "Bps":
+		return scaled(v, 1000, siPrefixes, "B/s")
+	case unit == "binBps":
+		return scaled(v, 1024, iecPrefixes, "B/s")
+	case unit == "bps":
+		return scaled(v, 1000, siPrefixes, "b/s")
+	case unit == "ms":
+		return duration(v / 1000)
+	case unit == "s":
+		return duration(v)
+	case unit == "reqps":
+		return short(v) + " req/s"
+	case unit == "ops":
+		return short(v) + " ops/s"
+	case strings.HasPrefix(unit, customSuffixUnit):
+		return short(v) + strings.TrimPrefix(unit, customSuffixUnit)
+	case strings.HasPrefix(unit, customPrefixUnit):
+		return strings.TrimPrefix(unit, customPrefixUnit) + short(v)
+	default:
+		return short(v)
+	}
+}
+
+//short - returns the value with a K, M or G suffix. ie 1.5K, 20M or 0.25
+func short(v float64) string {
+	abs := math.Abs(v)
+	switch {
+	case abs >= 1e9:
+		return strconv.FormatFloat(v/1e9, 'f', -1, 64) + "G"
+	case abs >= 1e6:
+		return strconv.FormatFloat(v/1e6, 'f', -1, 64) + "M"
+	case abs >= 1e3:
+		return strconv.FormatFloat(v/1e3, 'f', -1, 64) + "K"
+	default:
+		return strconv.FormatFloat(v, 'g', 4, 64)
+	}
+}
+
+//scaled - divides the value by factor until it's below factor and adds the matching prefix. ie 1536 B is 1.5 KiB
+func scaled(v, factor float64, prefixes []string, symbol string) string {
+	i := 0
+	for math.Abs(v) >= factor && i < len(prefixes)-1 {
+		v /= factor
+		i++
+	}
+	return formatNumber(v) + " " + prefixes[i] + symbol
+}
+
+//duration - returns seconds in the largest unit below the value. ie 250 ms, 1.5 min or 2 day
+func duration(seconds float64) string {
+	abs := math.Abs(seconds)
+	switch {
+	case abs == 0:
+		return "0 s"
+	case abs < 1:
+		return formatNumber(seconds*1000) + " ms"
+	case abs < 60:
+		return formatNumber(seconds) + " s"
+	case abs < 3600:
+		return formatNumber(seconds/60) + " min"
+	case abs < 86400:
+		return formatNumber(seconds/3600) + " hour"
+	default:
+		return formatNumber(seconds/86400) + " day"
+	}
+}
+
+//formatNumber - returns the value rounded to 2 decimal places without trailing zeros
+func formatNumber(v float64) string {
+	return strconv.FormatFloat(math.Round(v*100)/100, 'f', -1, 64)
+}
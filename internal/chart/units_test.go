@@ -0,0 +1,73 @@
+package chart
+
+import "testing"
+
+func Test_formatValue(t *testing.T) {
+	tests := []struct {
+		name  string
+		value float64
+		unit  string
+		want  string
+	}{
+		{
+			name:  "test0 short",
+			value: 1500,
+			want:  "1.5K",
+		},
+		{
+			name:  "test1 percent",
+			value: 42.5,
+			unit:  "percent",
+			want:  "42.5%",
+		},
+		{
+			name:  "test2 percent of 1",
+			value: 0.25,
+			unit:  "percentunit",
+			want:  "25%",
+		},
+		{
+			name:  "test3 binary bytes",
+			value: 1536,
+			unit:  "bytes",
+			want:  "1.5 KiB",
+		},
+		{
+			name:  "test4 decimal bytes per second",
+			value: 2500000,
+			unit:  "Bps",
+			want:  "2.5 MB/s",
+		},
+		{
+			name:  "test5 milliseconds",
+			value: 250,
+			unit:  "ms",
+			want:  "250 ms",
+		},
+		{
+			name:  "test6 seconds in minutes",
+			value: 90,
+			unit:  "s",
+			want:  "1.5 min",
+		},
+		{
+			name:  "test7 custom suffix",
+			value: 20,
+			unit:  "suffix: rpm",
+			want:  "20 rpm",
+		},
+		{
+			name:  "test8 unsupported unit",
+			value: 20,
+			unit:  "velocityms",
+			want:  "20",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := formatValue(tt.value, tt.unit); got != tt.want {
+				t.Errorf("formatValue() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
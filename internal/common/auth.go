@@ -3,27 +3,60 @@ package common
 import (
 	"fmt"
 	"github.com/sajeevany/graph-snapper/internal/logging"
+	"github.com/sajeevany/graph-snapper/internal/redact"
 	"github.com/sirupsen/logrus"
 	"net/http"
+	"strings"
 )
 
 const (
-	BearerTokenAuthType = "BEARER_TOKEN"
-	BasicAuthType       = "BASIC"
+	BearerTokenAuthType             = "BEARER_TOKEN"
+	BasicAuthType                   = "BASIC"
+	PersonalAccessTokenAuthType     = "PERSONAL_ACCESS_TOKEN"
+	ServiceAccountTokenAuthType     = "SERVICE_ACCOUNT_TOKEN"
+	OAuth2ClientCredentialsAuthType = "OAUTH2_CLIENT_CREDENTIALS"
 
 	//Should be identical to json name of Auth fields so that direct unmarshalling will work
-	BearerTokenASName = "BearerToken"
-	BasicASName       = "Basic"
+	AuthTypeASName                = "Type"
+	BearerTokenASName             = "BearerToken"
+	BasicASName                   = "Basic"
+	PersonalAccessTokenASName     = "PersonalAccessToken"
+	ServiceAccountTokenASName     = "ServiceAccountToken"
+	OAuth2ClientCredentialsASName = "OAuth2ClientCredentials"
+
+	//grafanaServiceAccountTokenPrefix - prefix of tokens issued to grafana service accounts
+	grafanaServiceAccountTokenPrefix = "glsa_"
 )
 
-//Auth
+//Auth - Credentials of a single auth type. Type is optional and inferred from the populated credentials when empty.
+//Records with more than one type of credentials are ambiguous and rejected
 type Auth struct {
-	BearerToken BearerToken
-	Basic       Basic
+	Type                    string `json:"Type,omitempty"` //Optional. BASIC, BEARER_TOKEN, PERSONAL_ACCESS_TOKEN, SERVICE_ACCOUNT_TOKEN or OAUTH2_CLIENT_CREDENTIALS
+	BearerToken             BearerToken
+	Basic                   Basic
+	PersonalAccessToken     PersonalAccessToken     `json:"PersonalAccessToken"`     //Confluence or grafana personal access token
+	ServiceAccountToken     ServiceAccountToken     `json:"ServiceAccountToken"`     //Grafana service account token
+	OAuth2ClientCredentials OAuth2ClientCredentials `json:"OAuth2ClientCredentials"` //Token is fetched from the token url and cached until it expires
 }
 
 func (a Auth) GetRedactedLog() logrus.Fields {
 
+	if a.PersonalAccessToken != (PersonalAccessToken{}) {
+		return logrus.Fields{
+			"PersonalAccessToken": logging.RedactNonEmpty(a.PersonalAccessToken.Token),
+		}
+	}
+
+	if a.ServiceAccountToken != (ServiceAccountToken{}) {
+		return logrus.Fields{
+			"ServiceAccountToken": logging.RedactNonEmpty(a.ServiceAccountToken.Token),
+		}
+	}
+
+	if a.OAuth2ClientCredentials != (OAuth2ClientCredentials{}) {
+		return a.OAuth2ClientCredentials.GetFields()
+	}
+
 	if a.BearerToken != (BearerToken{}) {
 		return logrus.Fields{
 			"BearerToken": logging.RedactNonEmpty(a.BearerToken.Token),
@@ -42,71 +75,155 @@ func (a Auth) GetRedactedLog() logrus.Fields {
 	}
 }
 
+//Redacted - returns loggable auth fields without secrets
+func (a Auth) Redacted() interface{} {
+	return a.GetFields()
+}
+
 func (a Auth) GetRedactedView() Auth {
 	return Auth{
-		BearerToken: a.BearerToken.getRedactedView(),
-		Basic:       a.Basic.getRedactedView(),
+		Type:                    a.Type,
+		BearerToken:             a.BearerToken.getRedactedView(),
+		Basic:                   a.Basic.getRedactedView(),
+		PersonalAccessToken:     a.PersonalAccessToken.getRedactedView(),
+		ServiceAccountToken:     a.ServiceAccountToken.getRedactedView(),
+		OAuth2ClientCredentials: a.OAuth2ClientCredentials.getRedactedView(),
 	}
 }
 
-//IsValid - returns the validity check result of the highest priority auth type provided
+//IsValid - returns true if the credentials are of a single type and valid for that type
 func (a Auth) IsValid() bool {
-	if a.BearerToken != (BearerToken{}) {
-		return a.BearerToken.IsValid()
+
+	authType, err := a.GetAuthType()
+	if err != nil {
+		return false
 	}
 
-	if a.Basic != (Basic{}) {
+	switch authType {
+	case BearerTokenAuthType:
+		return a.BearerToken.IsValid()
+	case BasicAuthType:
 		return a.Basic.IsValid()
+	case PersonalAccessTokenAuthType:
+		return a.PersonalAccessToken.IsValid()
+	case ServiceAccountTokenAuthType:
+		return a.ServiceAccountToken.IsValid()
+	case OAuth2ClientCredentialsAuthType:
+		return a.OAuth2ClientCredentials.IsValid()
+	default:
+		return false
+	}
+}
+
+//GetAuthType - returns the tagged auth type or the type of the populated credentials if untagged. Returns an error if
+//more than one type of credentials is populated or the tag doesn't match the populated credentials. Returns an empty
+//type if no credentials are set
+func (a Auth) GetAuthType() (string, error) {
+
+	populated := a.populatedAuthTypes()
+	if len(populated) > 1 {
+		return "", fmt.Errorf("ambiguous auth. Expected a single auth type but found <%v>", strings.Join(populated, ", "))
 	}
 
-	return false
+	if a.Type == "" {
+		if len(populated) == 0 {
+			return "", nil
+		}
+		return populated[0], nil
+	}
+
+	authType := strings.ToUpper(a.Type)
+	switch authType {
+	case BearerTokenAuthType, BasicAuthType, PersonalAccessTokenAuthType, ServiceAccountTokenAuthType, OAuth2ClientCredentialsAuthType:
+	default:
+		return "", fmt.Errorf("unknown auth type <%v>", a.Type)
+	}
+	if len(populated) == 1 && populated[0] != authType {
+		return "", fmt.Errorf("auth type <%v> doesn't match the provided <%v> credentials", a.Type, populated[0])
+	}
+
+	return authType, nil
+}
+
+func (a Auth) populatedAuthTypes() []string {
+	var populated []string
+	if a.BearerToken != (BearerToken{}) {
+		populated = append(populated, BearerTokenAuthType)
+	}
+	if a.Basic != (Basic{}) {
+		populated = append(populated, BasicAuthType)
+	}
+	if a.PersonalAccessToken != (PersonalAccessToken{}) {
+		populated = append(populated, PersonalAccessTokenAuthType)
+	}
+	if a.ServiceAccountToken != (ServiceAccountToken{}) {
+		populated = append(populated, ServiceAccountTokenAuthType)
+	}
+	if a.OAuth2ClientCredentials != (OAuth2ClientCredentials{}) {
+		populated = append(populated, OAuth2ClientCredentialsAuthType)
+	}
+	return populated
 }
 
-//SetAuthHeader - sets authentication header with the highest priority
-func SetAuthHeader(logger *logrus.Logger, auth Auth, req *http.Request) {
+//SetAuthHeader - sets the authentication header of the auth type. Returns an error for ambiguous auth. OAuth2 client
+//credentials are rejected since their access token is fetched with the endpoint's connection settings by
+//upstream.Client.SetAuthHeader
+func SetAuthHeader(logger *logrus.Entry, auth Auth, req *http.Request) error {
+
+	authType, err := auth.GetAuthType()
+	if err != nil {
+		logger.Debugf("Unable to determine auth type. <%v>", err)
+		return err
+	}
 
-	switch getHighestPriorityAuthType(auth) {
+	switch authType {
 	case BearerTokenAuthType:
 		logger.Debug("Request has bearer token. Setting bearer auth header.")
-		setBearerAuthHeader(req, auth.BearerToken)
+		setBearerAuthHeader(req, auth.BearerToken.Token)
 	case BasicAuthType:
 		logger.Debug("Request has basic auth type. Setting basic auth header.")
 		req.SetBasicAuth(auth.Basic.Username, auth.Basic.Password)
+	case PersonalAccessTokenAuthType:
+		logger.Debug("Request has personal access token. Setting bearer auth header.")
+		setBearerAuthHeader(req, auth.PersonalAccessToken.Token)
+	case ServiceAccountTokenAuthType:
+		logger.Debug("Request has service account token. Setting bearer auth header.")
+		setBearerAuthHeader(req, auth.ServiceAccountToken.Token)
+	case OAuth2ClientCredentialsAuthType:
+		logger.Debug("Request has oauth2 client credentials. Access tokens can only be fetched by the upstream client.")
+		return fmt.Errorf("oauth2 client credentials must be exchanged for an access token by the upstream client")
 	default:
 		logger.Info("No authentication type was found. Skipping set auth header.")
 	}
-}
-
-func getHighestPriorityAuthType(auth Auth) string {
-
-	if auth.BearerToken != (BearerToken{}) {
-		return BearerTokenAuthType
-	}
-
-	if auth.Basic != (Basic{}) {
-		return BasicAuthType
-	}
 
-	return ""
+	return nil
 }
 
-func setBearerAuthHeader(req *http.Request, token BearerToken) {
-	bearer := fmt.Sprintf("Bearer %s", token.Token)
+func setBearerAuthHeader(req *http.Request, token string) {
+	bearer := fmt.Sprintf("Bearer %s", token)
 	req.Header.Add("Authorization", bearer)
 }
 
 func (a Auth) GetFields() logrus.Fields {
 	return logrus.Fields{
-		"Basic":       a.Basic.GetFields(),
-		"BearerToken": a.BearerToken.GetFields(),
+		"Type":                    a.Type,
+		"Basic":                   a.Basic.GetFields(),
+		"BearerToken":             a.BearerToken.GetFields(),
+		"PersonalAccessToken":     a.PersonalAccessToken.GetFields(),
+		"ServiceAccountToken":     a.ServiceAccountToken.GetFields(),
+		"OAuth2ClientCredentials": a.OAuth2ClientCredentials.GetFields(),
 	}
 }
 
 func (a Auth) ToAerospikeBinMap() map[string]interface{} {
 
-	authBM := make(map[string]interface{}, 2)
+	authBM := make(map[string]interface{}, 6)
+	authBM[AuthTypeASName] = a.Type
 	authBM[BearerTokenASName] = a.BearerToken.ToAerospikeBinMap()
 	authBM[BasicASName] = a.Basic.ToAerospikeBinMap()
+	authBM[PersonalAccessTokenASName] = a.PersonalAccessToken.ToAerospikeBinMap()
+	authBM[ServiceAccountTokenASName] = a.ServiceAccountToken.ToAerospikeBinMap()
+	authBM[OAuth2ClientCredentialsASName] = a.OAuth2ClientCredentials.ToAerospikeBinMap()
 
 	return authBM
 }
@@ -123,6 +240,17 @@ func (b Basic) GetFields() logrus.Fields {
 	}
 }
 
+//Redacted - returns loggable basic auth fields without secrets
+func (b Basic) Redacted() interface{} {
+	return b.GetFields()
+}
+
+//Format - prints a redacted copy
+func (b Basic) Format(f fmt.State, verb rune) {
+	type basic Basic
+	redact.Format(f, verb, basic(b.getRedactedView()))
+}
+
 func (b Basic) ToAerospikeBinMap() map[string]string {
 	return map[string]string{
 		"Username": b.Username,
@@ -151,6 +279,17 @@ func (a BearerToken) GetFields() logrus.Fields {
 	}
 }
 
+//Redacted - returns loggable bearer token fields without secrets
+func (a BearerToken) Redacted() interface{} {
+	return a.GetFields()
+}
+
+//Format - prints a redacted copy
+func (a BearerToken) Format(f fmt.State, verb rune) {
+	type bearerToken BearerToken
+	redact.Format(f, verb, bearerToken(a.getRedactedView()))
+}
+
 func (bt BearerToken) ToAerospikeBinMap() map[string]string {
 	return map[string]string{
 		"Token": bt.Token,
@@ -166,3 +305,82 @@ func (a BearerToken) getRedactedView() BearerToken {
 		Token: logging.RedactNonEmpty(a.Token),
 	}
 }
+
+//PersonalAccessToken - Confluence or grafana personal access token. Sent as a bearer token
+type PersonalAccessToken struct {
+	Token string
+}
+
+func (p PersonalAccessToken) GetFields() logrus.Fields {
+	return logrus.Fields{
+		"Token": logging.RedactNonEmpty(p.Token),
+	}
+}
+
+//Redacted - returns loggable personal access token fields without secrets
+func (p PersonalAccessToken) Redacted() interface{} {
+	return p.GetFields()
+}
+
+//Format - prints a redacted copy
+func (p PersonalAccessToken) Format(f fmt.State, verb rune) {
+	type personalAccessToken PersonalAccessToken
+	redact.Format(f, verb, personalAccessToken(p.getRedactedView()))
+}
+
+func (p PersonalAccessToken) ToAerospikeBinMap() map[string]string {
+	return map[string]string{
+		"Token": p.Token,
+	}
+}
+
+//IsValid - tokens are sent in a header and can't contain whitespace
+func (p PersonalAccessToken) IsValid() bool {
+	return p.Token != "" && !strings.ContainsAny(p.Token, " \t\r\n")
+}
+
+func (p PersonalAccessToken) getRedactedView() PersonalAccessToken {
+	return PersonalAccessToken{
+		Token: logging.RedactNonEmpty(p.Token),
+	}
+}
+
+//ServiceAccountToken - Grafana service account token. Sent as a bearer token
+type ServiceAccountToken struct {
+	Token string
+}
+
+func (s ServiceAccountToken) GetFields() logrus.Fields {
+	return logrus.Fields{
+		"Token": logging.RedactNonEmpty(s.Token),
+	}
+}
+
+//Redacted - returns loggable service account token fields without secrets
+func (s ServiceAccountToken) Redacted() interface{} {
+	return s.GetFields()
+}
+
+//Format - prints a redacted copy
+func (s ServiceAccountToken) Format(f fmt.State, verb rune) {
+	type serviceAccountToken ServiceAccountToken
+	redact.Format(f, verb, serviceAccountToken(s.getRedactedView()))
+}
+
+func (s ServiceAccountToken) ToAerospikeBinMap() map[string]string {
+	return map[string]string{
+		"Token": s.Token,
+	}
+}
+
+//IsValid - grafana service account tokens are prefixed with glsa_
+func (s ServiceAccountToken) IsValid() bool {
+	return strings.HasPrefix(s.Token, grafanaServiceAccountTokenPrefix) && len(s.Token) > len(grafanaServiceAccountTokenPrefix) &&
+		!strings.ContainsAny(s.Token, " \t\r\n")
+}
+
+func (s ServiceAccountToken) getRedactedView() ServiceAccountToken {
+	return ServiceAccountToken{
+		Token: logging.RedactNonEmpty(s.Token),
+	}
+}
@@ -13,6 +13,7 @@ func TestSetAuthHeader(t *testing.T) {
 	type args struct {
 		auth             Auth
 		expectedAuthType string
+		wantErr          bool
 	}
 	tests := []struct {
 		name string
@@ -44,7 +45,7 @@ func TestSetAuthHeader(t *testing.T) {
 			},
 		},
 		{
-			name: "Test 2: Both are set. Expect bearer header",
+			name: "Test 2: Both are set. Expect ambiguous auth error and no header",
 			args: args{
 				auth: Auth{
 					BearerToken: BearerToken{
@@ -55,7 +56,8 @@ func TestSetAuthHeader(t *testing.T) {
 						Password: "password",
 					},
 				},
-				expectedAuthType: BearerTokenAuthType,
+				expectedAuthType: "",
+				wantErr:          true,
 			},
 		},
 		{
@@ -72,7 +74,9 @@ func TestSetAuthHeader(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			req, _ := http.NewRequest("GET", "URL", nil)
-			SetAuthHeader(logrus.StandardLogger(), tt.args.auth, req)
+			if err := SetAuthHeader(logrus.NewEntry(logrus.StandardLogger()), tt.args.auth, req); (err != nil) != tt.args.wantErr {
+				t.Errorf("SetAuthHeader() error = %v, wantErr %v", err, tt.args.wantErr)
+			}
 
 			switch tt.args.expectedAuthType {
 			case BasicAuthType:
@@ -112,3 +116,117 @@ func TestSetAuthHeader(t *testing.T) {
 		})
 	}
 }
+
+func TestAuth_IsValid(t *testing.T) {
+	tests := []struct {
+		name     string
+		auth     Auth
+		wantType string
+		wantErr  bool
+		want     bool
+	}{
+		{
+			name:     "Test 0: untagged basic auth is inferred",
+			auth:     Auth{Basic: Basic{Username: "user", Password: "pass"}},
+			wantType: BasicAuthType,
+			want:     true,
+		},
+		{
+			name:     "Test 1: tagged personal access token",
+			auth:     Auth{Type: "personal_access_token", PersonalAccessToken: PersonalAccessToken{Token: "NjU0MzIxOTg3"}},
+			wantType: PersonalAccessTokenAuthType,
+			want:     true,
+		},
+		{
+			name:     "Test 2: grafana service account token",
+			auth:     Auth{ServiceAccountToken: ServiceAccountToken{Token: "glsa_abc123_def456"}},
+			wantType: ServiceAccountTokenAuthType,
+			want:     true,
+		},
+		{
+			name:     "Test 3: service account token without glsa prefix",
+			auth:     Auth{ServiceAccountToken: ServiceAccountToken{Token: "eyJrIjoi"}},
+			wantType: ServiceAccountTokenAuthType,
+			want:     false,
+		},
+		{
+			name:     "Test 4: oauth2 client credentials",
+			auth:     Auth{OAuth2ClientCredentials: OAuth2ClientCredentials{TokenURL: "https://idp.local/oauth2/token", ClientID: "id", ClientSecret: "secret"}},
+			wantType: OAuth2ClientCredentialsAuthType,
+			want:     true,
+		},
+		{
+			name:     "Test 5: oauth2 client credentials without token url",
+			auth:     Auth{OAuth2ClientCredentials: OAuth2ClientCredentials{ClientID: "id", ClientSecret: "secret"}},
+			wantType: OAuth2ClientCredentialsAuthType,
+			want:     false,
+		},
+		{
+			name:    "Test 6: basic and bearer are ambiguous",
+			auth:    Auth{BearerToken: BearerToken{Token: "tolkien"}, Basic: Basic{Username: "user", Password: "pass"}},
+			wantErr: true,
+			want:    false,
+		},
+		{
+			name:    "Test 7: tag doesn't match credentials",
+			auth:    Auth{Type: BasicAuthType, BearerToken: BearerToken{Token: "tolkien"}},
+			wantErr: true,
+			want:    false,
+		},
+		{
+			name:    "Test 8: unknown tag",
+			auth:    Auth{Type: "KERBEROS", BearerToken: BearerToken{Token: "tolkien"}},
+			wantErr: true,
+			want:    false,
+		},
+		{
+			name: "Test 9: no credentials",
+			auth: Auth{},
+			want: false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotType, err := tt.auth.GetAuthType()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("GetAuthType() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if gotType != tt.wantType {
+				t.Errorf("GetAuthType() = %v, want %v", gotType, tt.wantType)
+			}
+			if got := tt.auth.IsValid(); got != tt.want {
+				t.Errorf("IsValid() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSetAuthHeaderTokenTypes(t *testing.T) {
+	tests := []struct {
+		name string
+		auth Auth
+		want string
+	}{
+		{
+			name: "Test 0: personal access token is sent as a bearer token",
+			auth: Auth{PersonalAccessToken: PersonalAccessToken{Token: "NjU0MzIxOTg3"}},
+			want: "Bearer NjU0MzIxOTg3",
+		},
+		{
+			name: "Test 1: service account token is sent as a bearer token",
+			auth: Auth{Type: ServiceAccountTokenAuthType, ServiceAccountToken: ServiceAccountToken{Token: "glsa_abc123_def456"}},
+			want: "Bearer glsa_abc123_def456",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, _ := http.NewRequest("GET", "URL", nil)
+			if err := SetAuthHeader(logrus.NewEntry(logrus.StandardLogger()), tt.auth, req); err != nil {
+				t.Fatalf("SetAuthHeader() unexpected error = %v", err)
+			}
+			if got := req.Header.Get("Authorization"); got != tt.want {
+				t.Errorf("Authorization = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,95 @@
+package common
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestConfluenceCloudUserV1_IsValid(t *testing.T) {
+	tests := []struct {
+		name string
+		user ConfluenceCloudUserV1
+		want bool
+	}{
+		{
+			name: "test0 valid entry",
+			user: ConfluenceCloudUserV1{
+				SiteURL:  "https://example.atlassian.net",
+				Email:    "user@example.com",
+				APIToken: "qwerty",
+			},
+			want: true,
+		},
+		{
+			name: "test1 site url with trailing slash",
+			user: ConfluenceCloudUserV1{
+				SiteURL:  "https://example.atlassian.net/",
+				Email:    "user@example.com",
+				APIToken: "qwerty",
+			},
+			want: true,
+		},
+		{
+			name: "test2 http site url",
+			user: ConfluenceCloudUserV1{
+				SiteURL:  "http://example.atlassian.net",
+				Email:    "user@example.com",
+				APIToken: "qwerty",
+			},
+			want: false,
+		},
+		{
+			name: "test3 site url including the wiki path",
+			user: ConfluenceCloudUserV1{
+				SiteURL:  "https://example.atlassian.net/wiki",
+				Email:    "user@example.com",
+				APIToken: "qwerty",
+			},
+			want: false,
+		},
+		{
+			name: "test4 invalid email",
+			user: ConfluenceCloudUserV1{
+				SiteURL:  "https://example.atlassian.net",
+				Email:    "user",
+				APIToken: "qwerty",
+			},
+			want: false,
+		},
+		{
+			name: "test5 empty api token",
+			user: ConfluenceCloudUserV1{
+				SiteURL: "https://example.atlassian.net",
+				Email:   "user@example.com",
+			},
+			want: false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.user.IsValid(); got != tt.want {
+				t.Errorf("IsValid() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestConfluenceCloudUserV1_BaseURL(t *testing.T) {
+	user := ConfluenceCloudUserV1{SiteURL: "https://example.atlassian.net/"}
+	if got, want := user.BaseURL(), "https://example.atlassian.net/wiki"; got != want {
+		t.Errorf("BaseURL() = %v, want %v", got, want)
+	}
+	if host, port := user.HostPort(); host != "example.atlassian.net" || port != 443 {
+		t.Errorf("HostPort() = %v, %v, want example.atlassian.net, 443", host, port)
+	}
+}
+
+func TestConfluenceCloudUserV1_FormatIsRedacted(t *testing.T) {
+	user := ConfluenceCloudUserV1{SiteURL: "https://example.atlassian.net", Email: "user@example.com", APIToken: "qwerty"}
+	for _, verb := range []string{"%v", "%+v", "%#v"} {
+		if got := fmt.Sprintf(verb, user); strings.Contains(got, "qwerty") {
+			t.Errorf("Sprintf(%v) leaked the api token <%v>", verb, got)
+		}
+	}
+}
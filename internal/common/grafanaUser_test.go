@@ -97,6 +97,88 @@ func TestAddGrafanaReadUserV1_IsValid(t *testing.T) {
 		},
 		{
 			name: "test5 valid entry",
+			user: GrafanaUserV1{
+				Auth: Auth{
+					BearerToken: BearerToken{
+						Token: "abcdefg",
+					},
+				},
+				Host:        "10.2.3.4",
+				Port:        8090,
+				Description: "blah",
+			},
+			want: true,
+		},
+		{
+			name: "test6 unsupported scheme",
+			user: GrafanaUserV1{
+				Auth: Auth{
+					BearerToken: BearerToken{
+						Token: "abcdefg",
+					},
+				},
+				Scheme:      "ftp",
+				Host:        "10.2.3.4",
+				Port:        8090,
+				Description: "blah",
+			},
+			want: false,
+		},
+		{
+			name: "test7 context path with query",
+			user: GrafanaUserV1{
+				Auth: Auth{
+					BearerToken: BearerToken{
+						Token: "abcdefg",
+					},
+				},
+				Scheme:      HTTPSScheme,
+				Host:        "10.2.3.4",
+				Port:        8090,
+				ContextPath: "/grafana?orgId=1",
+				Description: "blah",
+			},
+			want: false,
+		},
+		{
+			name: "test8 client cert without key",
+			user: GrafanaUserV1{
+				Auth: Auth{
+					BearerToken: BearerToken{
+						Token: "abcdefg",
+					},
+				},
+				Scheme: HTTPSScheme,
+				Host:   "10.2.3.4",
+				Port:   8090,
+				TLS: TLSConfigV1{
+					ClientCert: "-----BEGIN CERTIFICATE-----",
+				},
+				Description: "blah",
+			},
+			want: false,
+		},
+		{
+			name: "test9 valid https entry",
+			user: GrafanaUserV1{
+				Auth: Auth{
+					BearerToken: BearerToken{
+						Token: "abcdefg",
+					},
+				},
+				Scheme:      HTTPSScheme,
+				Host:        "10.2.3.4",
+				Port:        443,
+				ContextPath: "/grafana",
+				TLS: TLSConfigV1{
+					InsecureSkipVerify: true,
+				},
+				Description: "blah",
+			},
+			want: true,
+		},
+		{
+			name: "test10 ambiguous basic and bearer auth",
 			user: GrafanaUserV1{
 				Auth: Auth{
 					BearerToken: BearerToken{
@@ -111,7 +193,21 @@ func TestAddGrafanaReadUserV1_IsValid(t *testing.T) {
 				Port:        8090,
 				Description: "blah",
 			},
-			want: true,
+			want: false,
+		},
+		{
+			name: "test11 negative org id",
+			user: GrafanaUserV1{
+				Auth: Auth{
+					BearerToken: BearerToken{
+						Token: "abcdefg",
+					},
+				},
+				Host:  "10.2.3.4",
+				Port:  8090,
+				OrgID: -1,
+			},
+			want: false,
 		},
 	}
 	for _, tt := range tests {
@@ -0,0 +1,62 @@
+package common
+
+import (
+	"github.com/sirupsen/logrus"
+	"path"
+	"regexp"
+	"strings"
+)
+
+//reportPathRegex - relative report directory. Slash separated segments of letters, numbers, dots, underscores and hyphens
+var reportPathRegex = regexp.MustCompile(`^[A-Za-z0-9._-]+(/[A-Za-z0-9._-]+)*$`)
+
+//HTMLReportV1 - Static HTML report written below the configured reports directory. The index lists the account's
+//stored snapshots with their images. Intended for air-gapped environments
+type HTMLReportV1 struct {
+	Path        string //Directory relative to the account's reports directory. ie ops/weekly
+	Title       string //Optional. Heading of the index page. Defaults to the report name
+	JobID       string //Optional. Only lists snapshots captured for this job
+	Description string
+}
+
+func (r HTMLReportV1) GetFields() logrus.Fields {
+	return logrus.Fields{
+		"Path":        r.Path,
+		"Title":       r.Title,
+		"JobID":       r.JobID,
+		"Description": r.Description,
+	}
+}
+
+//RedactedView - reports don't hold secrets so the report is returned as is
+func (r HTMLReportV1) RedactedView() interface{} {
+	return r
+}
+
+//ToBinMap - returns the values stored in aerospike
+func (r HTMLReportV1) ToBinMap() map[string]interface{} {
+	return map[string]interface{}{
+		"Path":        r.Path,
+		"Title":       r.Title,
+		"JobID":       r.JobID,
+		"Description": r.Description,
+	}
+}
+
+//IsValid - Path must stay within the account's reports directory
+func (r HTMLReportV1) IsValid() bool {
+	return IsReportPathValid(r.Path)
+}
+
+//IsReportPathValid - returns true if the path is relative, clean and doesn't contain . or .. segments
+func IsReportPathValid(p string) bool {
+	if !reportPathRegex.MatchString(p) || path.Clean(p) != p {
+		return false
+	}
+	for _, segment := range strings.Split(p, "/") {
+		if segment == "." || segment == ".." {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,28 @@
+package common
+
+import "testing"
+
+func TestIsReportPathValid(t *testing.T) {
+	tests := []struct {
+		name string
+		path string
+		want bool
+	}{
+		{name: "test0 single directory", path: "weekly", want: true},
+		{name: "test1 nested directories", path: "ops/weekly-2021_q1", want: true},
+		{name: "test2 empty", path: "", want: false},
+		{name: "test3 absolute", path: "/var/www", want: false},
+		{name: "test4 parent segment", path: "ops/../../etc", want: false},
+		{name: "test5 current segment", path: "./ops", want: false},
+		{name: "test6 trailing slash", path: "ops/", want: false},
+		{name: "test7 unsupported characters", path: "ops weekly", want: false},
+		{name: "test8 parent directory", path: "..", want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IsReportPathValid(tt.path); got != tt.want {
+				t.Errorf("IsReportPathValid(%v) = %v, want %v", tt.path, got, tt.want)
+			}
+		})
+	}
+}
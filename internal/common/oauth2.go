@@ -0,0 +1,71 @@
+package common
+
+import (
+	"fmt"
+	"github.com/sajeevany/graph-snapper/internal/logging"
+	"github.com/sajeevany/graph-snapper/internal/redact"
+	"github.com/sirupsen/logrus"
+	"net/url"
+	"strings"
+)
+
+//OAuth2ClientCredentials - OAuth2 client credentials grant. The client id and secret are exchanged for an access token
+//at the token url by the upstream client, which caches the token until it expires
+type OAuth2ClientCredentials struct {
+	TokenURL     string
+	ClientID     string
+	ClientSecret string
+	Scope        string //Optional. Space delimited scopes requested with the token
+}
+
+func (o OAuth2ClientCredentials) GetFields() logrus.Fields {
+	return logrus.Fields{
+		"TokenURL":     o.TokenURL,
+		"ClientID":     o.ClientID,
+		"ClientSecret": logging.RedactNonEmpty(o.ClientSecret),
+		"Scope":        o.Scope,
+	}
+}
+
+//Redacted - returns loggable client credentials fields without secrets
+func (o OAuth2ClientCredentials) Redacted() interface{} {
+	return o.GetFields()
+}
+
+//Format - prints a redacted copy
+func (o OAuth2ClientCredentials) Format(f fmt.State, verb rune) {
+	type oauth2ClientCredentials OAuth2ClientCredentials
+	redact.Format(f, verb, oauth2ClientCredentials(o.getRedactedView()))
+}
+
+func (o OAuth2ClientCredentials) ToAerospikeBinMap() map[string]string {
+	return map[string]string{
+		"TokenURL":     o.TokenURL,
+		"ClientID":     o.ClientID,
+		"ClientSecret": o.ClientSecret,
+		"Scope":        o.Scope,
+	}
+}
+
+//IsValid - requires an absolute http or https token url and a client id and secret
+func (o OAuth2ClientCredentials) IsValid() bool {
+	u, err := url.Parse(o.TokenURL)
+	if err != nil || u.Host == "" {
+		return false
+	}
+	switch strings.ToLower(u.Scheme) {
+	case HTTPScheme, HTTPSScheme:
+	default:
+		return false
+	}
+	return o.ClientID != "" && o.ClientSecret != ""
+}
+
+func (o OAuth2ClientCredentials) getRedactedView() OAuth2ClientCredentials {
+	return OAuth2ClientCredentials{
+		TokenURL:     o.TokenURL,
+		ClientID:     o.ClientID,
+		ClientSecret: logging.RedactNonEmpty(o.ClientSecret),
+		Scope:        o.Scope,
+	}
+}
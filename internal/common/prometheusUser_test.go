@@ -0,0 +1,48 @@
+package common
+
+import "testing"
+
+func TestPrometheusUserV1_IsValid(t *testing.T) {
+	tests := []struct {
+		name string
+		user PrometheusUserV1
+		want bool
+	}{
+		{
+			name: "test0 without auth",
+			user: PrometheusUserV1{Host: "prometheus", Port: 9090},
+			want: true,
+		},
+		{
+			name: "test1 basic auth",
+			user: PrometheusUserV1{Auth: Auth{Basic: Basic{Username: "user", Password: "pass"}}, Host: "prometheus", Port: 9090},
+			want: true,
+		},
+		{
+			name: "test2 ambiguous auth",
+			user: PrometheusUserV1{
+				Auth: Auth{Basic: Basic{Username: "user", Password: "pass"}, BearerToken: BearerToken{Token: "token"}},
+				Host: "prometheus",
+				Port: 9090,
+			},
+			want: false,
+		},
+		{
+			name: "test3 empty host",
+			user: PrometheusUserV1{Port: 9090},
+			want: false,
+		},
+		{
+			name: "test4 invalid scheme",
+			user: PrometheusUserV1{Scheme: "ftp", Host: "prometheus", Port: 9090},
+			want: false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.user.IsValid(); got != tt.want {
+				t.Errorf("IsValid() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,147 @@
+package common
+
+import (
+	"testing"
+	"time"
+)
+
+func TestS3UserV1_IsValid(t *testing.T) {
+	valid := S3UserV1{
+		Host:            "minio",
+		Port:            9000,
+		Region:          "us-east-1",
+		Bucket:          "snapshots",
+		PathStyle:       true,
+		AccessKeyID:     "minio",
+		SecretAccessKey: "minio123",
+	}
+	tests := []struct {
+		name   string
+		modify func(u *S3UserV1)
+		want   bool
+	}{
+		{
+			name:   "test0 valid entry",
+			modify: func(u *S3UserV1) {},
+			want:   true,
+		},
+		{
+			name:   "test1 valid key template",
+			modify: func(u *S3UserV1) { u.KeyTemplate = "graphs/{account}/{dashboard}/{date}-{time}/{panel}.png" },
+			want:   true,
+		},
+		{
+			name:   "test2 unknown placeholder",
+			modify: func(u *S3UserV1) { u.KeyTemplate = "{account}/{host}.png" },
+			want:   false,
+		},
+		{
+			name:   "test3 key template starting with a slash",
+			modify: func(u *S3UserV1) { u.KeyTemplate = "/{account}/{panel}.png" },
+			want:   false,
+		},
+		{
+			name:   "test4 unbalanced braces",
+			modify: func(u *S3UserV1) { u.KeyTemplate = "{account/{panel}.png" },
+			want:   false,
+		},
+		{
+			name:   "test5 upper case bucket",
+			modify: func(u *S3UserV1) { u.Bucket = "Snapshots" },
+			want:   false,
+		},
+		{
+			name:   "test6 missing secret access key",
+			modify: func(u *S3UserV1) { u.SecretAccessKey = "" },
+			want:   false,
+		},
+		{
+			name:   "test7 missing region",
+			modify: func(u *S3UserV1) { u.Region = "" },
+			want:   false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			u := valid
+			tt.modify(&u)
+			if got := u.IsValid(); got != tt.want {
+				t.Errorf("IsValid() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestS3UserV1_ObjectKey(t *testing.T) {
+	values := S3KeyValuesV1{
+		Account:    "acct1",
+		Job:        "nightly",
+		Dashboard:  "cpu/../01",
+		Panel:      4,
+		Snapshot:   "f3b1",
+		SHA256:     "abc",
+		CapturedAt: time.Date(2020, 6, 1, 23, 30, 5, 0, time.FixedZone("EST", -5*60*60)),
+	}
+	tests := []struct {
+		name     string
+		template string
+		ext      string
+		want     string
+	}{
+		{
+			name: "test0 default template",
+			want: "acct1/cpu_.._01/2020-06-02/4.png",
+		},
+		{
+			name:     "test1 custom template",
+			template: "reports/{job}/{date}T{time}-{snapshot}-{sha256}.png",
+			want:     "reports/nightly/2020-06-02T043005-f3b1-abc.png",
+		},
+		{
+			name: "test2 default template with an svg chart",
+			ext:  "svg",
+			want: "acct1/cpu_.._01/2020-06-02/4.svg",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			u := S3UserV1{KeyTemplate: tt.template}
+			v := values
+			v.Ext = tt.ext
+			if got := u.ObjectKey(v); got != tt.want {
+				t.Errorf("ObjectKey() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestS3UserV1_KeyPrefix(t *testing.T) {
+	tests := []struct {
+		name     string
+		template string
+		want     string
+	}{
+		{
+			name: "test0 default template",
+			want: "",
+		},
+		{
+			name:     "test1 static prefix",
+			template: "reports/graphs/{account}/{panel}.png",
+			want:     "reports/graphs/",
+		},
+		{
+			name:     "test2 partial segment",
+			template: "reports/acct-{account}.png",
+			want:     "reports/",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			u := S3UserV1{KeyTemplate: tt.template}
+			if got := u.KeyPrefix(); got != tt.want {
+				t.Errorf("KeyPrefix() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,94 @@
+package common
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"github.com/sajeevany/graph-snapper/internal/redact"
+	"github.com/sirupsen/logrus"
+)
+
+//TLSConfigV1 - TLS options used when connecting to a service over https
+type TLSConfigV1 struct {
+	CACert             string //PEM encoded CA bundle trusted in addition to the system roots
+	InsecureSkipVerify bool   //Disables server certificate verification. Intended for development only
+	ClientCert         string //PEM encoded client certificate used for mutual TLS
+	ClientKey          string //PEM encoded client private key used for mutual TLS
+}
+
+func (t TLSConfigV1) GetFields() logrus.Fields {
+	return logrus.Fields{
+		"CACert":             t.CACert != "",
+		"InsecureSkipVerify": t.InsecureSkipVerify,
+		"ClientCert":         t.ClientCert != "",
+		"ClientKey":          redact.NonEmpty(t.ClientKey),
+	}
+}
+
+//Redacted - returns loggable TLS fields without the client key
+func (t TLSConfigV1) Redacted() interface{} {
+	return t.GetFields()
+}
+
+//Format - prints a redacted copy
+func (t TLSConfigV1) Format(f fmt.State, verb rune) {
+	type tlsConfig TLSConfigV1
+	redact.Format(f, verb, tlsConfig(t.GetRedactedView()))
+}
+
+//IsValid - returns true if the CA bundle and client key pair, when set, can be parsed
+func (t TLSConfigV1) IsValid() bool {
+	_, err := t.ToTLSConfig()
+	return err == nil
+}
+
+//ToTLSConfig - converts to a crypto/tls config. The system roots are used when a CA bundle isn't provided
+func (t TLSConfigV1) ToTLSConfig() (*tls.Config, error) {
+
+	conf := &tls.Config{
+		InsecureSkipVerify: t.InsecureSkipVerify,
+	}
+
+	if t.CACert != "" {
+		pool, err := x509.SystemCertPool()
+		if err != nil || pool == nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM([]byte(t.CACert)) {
+			return nil, fmt.Errorf("CACert does not contain any PEM encoded certificates")
+		}
+		conf.RootCAs = pool
+	}
+
+	if t.ClientCert != "" || t.ClientKey != "" {
+		if t.ClientCert == "" || t.ClientKey == "" {
+			return nil, fmt.Errorf("ClientCert and ClientKey must both be set for mutual TLS")
+		}
+		cert, err := tls.X509KeyPair([]byte(t.ClientCert), []byte(t.ClientKey))
+		if err != nil {
+			return nil, fmt.Errorf("unable to parse client certificate and key. err <%v>", err)
+		}
+		conf.Certificates = []tls.Certificate{cert}
+	}
+
+	return conf, nil
+}
+
+func (t TLSConfigV1) ToAerospikeBinMap() map[string]interface{} {
+	return map[string]interface{}{
+		"CACert":             t.CACert,
+		"InsecureSkipVerify": t.InsecureSkipVerify,
+		"ClientCert":         t.ClientCert,
+		"ClientKey":          t.ClientKey,
+	}
+}
+
+//GetRedactedView - returns a copy with the client key masked. Certificates are public and are returned as is
+func (t TLSConfigV1) GetRedactedView() TLSConfigV1 {
+	return TLSConfigV1{
+		CACert:             t.CACert,
+		InsecureSkipVerify: t.InsecureSkipVerify,
+		ClientCert:         t.ClientCert,
+		ClientKey:          redact.NonEmpty(t.ClientKey),
+	}
+}
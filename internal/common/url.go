@@ -0,0 +1,61 @@
+package common
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+const (
+	HTTPScheme  = "http"
+	HTTPSScheme = "https"
+)
+
+//BuildBaseURL - Returns scheme://host:port/contextPath without a trailing slash. Defaults to http when scheme is empty
+func BuildBaseURL(scheme, host string, port int, contextPath string) string {
+	if scheme == "" {
+		scheme = HTTPScheme
+	}
+	return fmt.Sprintf("%v://%v:%v%v", strings.ToLower(scheme), host, port, normalizeContextPath(contextPath))
+}
+
+//IsSchemeValid - returns true if the scheme is empty, http or https
+func IsSchemeValid(scheme string) bool {
+	switch strings.ToLower(scheme) {
+	case "", HTTPScheme, HTTPSScheme:
+		return true
+	default:
+		return false
+	}
+}
+
+//IsContextPathValid - returns true if the context path is a plain path without a query or fragment
+func IsContextPathValid(contextPath string) bool {
+	return !strings.ContainsAny(contextPath, "?# ")
+}
+
+//normalizeContextPath - returns the path with a leading slash and without a trailing slash. ie confluence/ -> /confluence
+func normalizeContextPath(contextPath string) string {
+	p := strings.Trim(contextPath, "/")
+	if p == "" {
+		return ""
+	}
+	return "/" + p
+}
+
+//IsProxyValid - returns true if the proxy is empty or an absolute http, https or socks5 url
+func IsProxyValid(proxy string) bool {
+	if proxy == "" {
+		return true
+	}
+	u, err := url.Parse(proxy)
+	if err != nil || u.Host == "" {
+		return false
+	}
+	switch strings.ToLower(u.Scheme) {
+	case HTTPScheme, HTTPSScheme, "socks5":
+		return true
+	default:
+		return false
+	}
+}
@@ -2,6 +2,7 @@ package config
 
 import (
 	"fmt"
+	"github.com/sajeevany/graph-snapper/internal/redact"
 	"github.com/sirupsen/logrus"
 	"strconv"
 )
@@ -13,14 +14,27 @@ type AerospikeCfg struct {
 	ConnectionRetries         int                `json:"connectionRetries"`
 	ConnectionRetryIntervalMS int                `json:"connectionRetryIntervalMS"`
 	AccountNamespace          AerospikeNamespace `json:"accountNamespace"`
+	AuditNamespace            AerospikeNamespace `json:"auditNamespace"` //Optional. Defaults to the account namespace with set DefaultAuditSetName
 }
 
 func (as AerospikeCfg) GetFields() logrus.Fields {
 	return logrus.Fields{
 		"host":             as.Host,
 		"port":             as.Port,
-		"password":         as.Password,
+		"password":         redact.NonEmpty(as.Password),
 		"accountNamespace": as.AccountNamespace.GetFields(),
+		"auditNamespace":   as.AuditNamespace.GetFields(),
+	}
+}
+
+//GetAuditNamespace - returns the configured audit namespace or the account namespace with the default audit set
+func (as AerospikeCfg) GetAuditNamespace() AerospikeNamespace {
+	if as.AuditNamespace.Namespace != "" {
+		return as.AuditNamespace
+	}
+	return AerospikeNamespace{
+		Namespace: as.AccountNamespace.Namespace,
+		SetName:   DefaultAuditSetName,
 	}
 }
 
@@ -56,6 +70,12 @@ func (as AerospikeCfg) IsValid(currentPath string, invalidArgs map[string]string
 		isValid = false
 	}
 
+	//Audit namespace is optional but must not share the account set
+	if as.GetAuditNamespace() == as.AccountNamespace {
+		AddInvalidArgWithCause(currentPath, "AuditNamespace", as.AuditNamespace.SetName, "value must not be the same set as AccountNamespace", invalidArgs)
+		isValid = false
+	}
+
 	return isValid
 }
 
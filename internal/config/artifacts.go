@@ -0,0 +1,93 @@
+package config
+
+import (
+	"fmt"
+	"github.com/sirupsen/logrus"
+	"strconv"
+	"strings"
+)
+
+const (
+	//Supported artifact store backends
+	FilesystemArtifactBackend = "filesystem"
+	MemoryArtifactBackend     = "memory"
+)
+
+//Artifacts - storage of captured snapshot images and their metadata
+type Artifacts struct {
+	Backend          string               `json:"backend"`
+	Dir              string               `json:"dir"`              //Root directory of the filesystem backend
+	Retention        Retention            `json:"retention"`        //Default limits applied to every account
+	AccountRetention map[string]Retention `json:"accountRetention"` //Optional. Limits by account ID replacing the default
+}
+
+//Retention - snapshots exceeding either limit are deleted after each capture. 0 disables a limit
+type Retention struct {
+	MaxSnapshots int `json:"maxSnapshots"`
+	MaxAgeDays   int `json:"maxAgeDays"`
+}
+
+func (a Artifacts) GetFields() logrus.Fields {
+	return logrus.Fields{
+		"backend":          a.Backend,
+		"dir":              a.Dir,
+		"retention":        a.Retention,
+		"accountRetention": a.AccountRetention,
+	}
+}
+
+//RetentionFor - returns the retention limits of the account
+func (a Artifacts) RetentionFor(accountID string) Retention {
+	if r, exists := a.AccountRetention[accountID]; exists {
+		return r
+	}
+	return a.Retention
+}
+
+//IsValid - Returns true/false and a non-empty map of all invalid args. Nested args are set in the form of Parent.Child.SubChild
+//Inputs:
+//    currentPath - json path defined up and including this attribute. ie conf.artifacts
+//    invalidArgs - map of invalid arguments (currentPath + field name) mapped to invalid reasons
+func (a Artifacts) IsValid(currentPath string, invalidArgs map[string]string) bool {
+
+	isValid := true
+
+	switch strings.ToLower(a.Backend) {
+	case FilesystemArtifactBackend:
+		if a.Dir == "" {
+			AddInvalidArgWithCause(currentPath, "Dir", a.Dir, "value is empty", invalidArgs)
+			isValid = false
+		}
+	case MemoryArtifactBackend:
+	default:
+		AddInvalidArgWithCause(currentPath, "Backend", a.Backend, fmt.Sprintf("value must be one of %v, %v", FilesystemArtifactBackend, MemoryArtifactBackend), invalidArgs)
+		isValid = false
+	}
+
+	if !a.Retention.IsValid(fmt.Sprintf("%s.%s", currentPath, "Retention"), invalidArgs) {
+		isValid = false
+	}
+	for accountID, r := range a.AccountRetention {
+		if !r.IsValid(fmt.Sprintf("%s.%s.%s", currentPath, "AccountRetention", accountID), invalidArgs) {
+			isValid = false
+		}
+	}
+
+	return isValid
+}
+
+func (r Retention) IsValid(currentPath string, invalidArgs map[string]string) bool {
+
+	isValid := true
+
+	if r.MaxSnapshots < 0 {
+		AddInvalidArgWithCause(currentPath, "MaxSnapshots", strconv.Itoa(r.MaxSnapshots), "value is negative", invalidArgs)
+		isValid = false
+	}
+	if r.MaxAgeDays < 0 {
+		AddInvalidArgWithCause(currentPath, "MaxAgeDays", strconv.Itoa(r.MaxAgeDays), "value is negative", invalidArgs)
+		isValid = false
+	}
+
+	return isValid
+}
@@ -0,0 +1,44 @@
+package config
+
+import (
+	"fmt"
+	"github.com/sirupsen/logrus"
+	"strings"
+)
+
+const (
+	//Supported audit sinks
+	AerospikeAuditSink = "aerospike"
+	LogAuditSink       = "log"
+
+	//DefaultAuditSetName - set used for audit events when an audit namespace isn't configured
+	DefaultAuditSetName = "audit"
+)
+
+type Audit struct {
+	Sink string `json:"sink"`
+}
+
+func (a Audit) GetFields() logrus.Fields {
+	return logrus.Fields{
+		"sink": a.Sink,
+	}
+}
+
+//IsValid - Returns true/false and a non-empty map of all invalid args. Nested args are set in the form of Parent.Child.SubChild
+//Inputs:
+//    currentPath - json path defined up and including this attribute. ie conf.audit
+//    invalidArgs - map of invalid arguments (currentPath + field name) mapped to invalid reasons
+func (a Audit) IsValid(currentPath string, invalidArgs map[string]string) bool {
+
+	isValid := true
+
+	switch strings.ToLower(a.Sink) {
+	case AerospikeAuditSink, LogAuditSink:
+	default:
+		AddInvalidArgWithCause(currentPath, "Sink", a.Sink, fmt.Sprintf("value must be one of %v, %v", AerospikeAuditSink, LogAuditSink), invalidArgs)
+		isValid = false
+	}
+
+	return isValid
+}
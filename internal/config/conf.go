@@ -3,8 +3,14 @@ package config
 import "github.com/sirupsen/logrus"
 
 type Conf struct {
-	Aerospike AerospikeCfg `json:"aerospike"`
-	Logging   Logging      `json:"logging"`
+	Aerospike       AerospikeCfg    `json:"aerospike"`
+	Logging         Logging         `json:"logging"`
+	Tracing         Tracing         `json:"tracing"`
+	Audit           Audit           `json:"audit"`
+	Upstream        Upstream        `json:"upstream"`
+	CredentialCheck CredentialCheck `json:"credentialCheck"`
+	Artifacts       Artifacts       `json:"artifacts"`
+	Reports         Reports         `json:"reports"`
 }
 
 func NewConfWithDefaults() Conf {
@@ -14,14 +20,60 @@ func NewConfWithDefaults() Conf {
 			ConnectionRetryIntervalMS: 10,
 		},
 		Logging: Logging{
-			Level: "debug",
+			Level:  "debug",
+			Format: TextLogFormat,
+			File: LogFile{
+				Enabled:    false,
+				Path:       "/app/logs/graph-snapper.log",
+				MaxSizeMB:  100,
+				MaxBackups: 5,
+				MaxAgeDays: 30,
+			},
+		},
+		Tracing: Tracing{
+			Enabled:     false,
+			Exporter:    NoneTraceExporter,
+			ServiceName: "graph-snapper",
+			SampleRatio: 1,
+		},
+		Audit: Audit{
+			Sink: AerospikeAuditSink,
+		},
+		Upstream: Upstream{
+			TimeoutMS:           10000,
+			Retries:             2,
+			RetryBackoffMS:      200,
+			MaxIdleConnsPerHost: 10,
+			IdleConnTimeoutMS:   90000,
+		},
+		CredentialCheck: CredentialCheck{
+			Workers:   8,
+			TimeoutMS: 30000,
+		},
+		Artifacts: Artifacts{
+			Backend: FilesystemArtifactBackend,
+			Dir:     "/app/artifacts",
+			Retention: Retention{
+				MaxSnapshots: 1000,
+				MaxAgeDays:   90,
+			},
+		},
+		Reports: Reports{
+			Dir: "/app/reports",
 		},
 	}
 }
 
 func (c Conf) GetFields() logrus.Fields {
 	return logrus.Fields{
-		"aerospike": c.Aerospike.GetFields(),
+		"aerospike":       c.Aerospike.GetFields(),
+		"logging":         c.Logging.GetFields(),
+		"tracing":         c.Tracing.GetFields(),
+		"audit":           c.Audit.GetFields(),
+		"upstream":        c.Upstream.GetFields(),
+		"credentialCheck": c.CredentialCheck.GetFields(),
+		"artifacts":       c.Artifacts.GetFields(),
+		"reports":         c.Reports.GetFields(),
 	}
 }
 
@@ -32,6 +84,13 @@ func (c Conf) IsValid(logger *logrus.Logger) (bool, map[string]string) {
 
 	aeroIsValid := c.Aerospike.IsValid("conf.aerospike", invalidArgs)
 	logIsValid := c.Logging.IsValid("conf.logging", invalidArgs)
+	traceIsValid := c.Tracing.IsValid("conf.tracing", invalidArgs)
+	auditIsValid := c.Audit.IsValid("conf.audit", invalidArgs)
+	upstreamIsValid := c.Upstream.IsValid("conf.upstream", invalidArgs)
+	credCheckIsValid := c.CredentialCheck.IsValid("conf.credentialCheck", invalidArgs)
+	artifactsIsValid := c.Artifacts.IsValid("conf.artifacts", invalidArgs)
+	reportsIsValid := c.Reports.IsValid("conf.reports", invalidArgs)
 
-	return aeroIsValid && logIsValid, invalidArgs
+	return aeroIsValid && logIsValid && traceIsValid && auditIsValid && upstreamIsValid && credCheckIsValid && artifactsIsValid &&
+		reportsIsValid, invalidArgs
 }
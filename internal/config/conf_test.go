@@ -76,6 +76,104 @@ func TestAerospikePortfolioConfig_AddInvalidArg(t *testing.T) {
 				asConf:   Logging{Level: "car"},
 			},
 		},
+		{
+			testName: "TestAerospikePortfolioConfig_AddInvalidArg_3: logging format and enabled log file are invalid",
+			expectedResult: expectedResult{
+				ok: false,
+				invalidArgs: []string{
+					"conf.logging.Format",
+					"conf.logging.File.Path",
+					"conf.logging.File.MaxSizeMB",
+				},
+			},
+			setup: setup{
+				jsonPath: "conf.logging",
+				asConf: Logging{
+					Level:  "info",
+					Format: "xml",
+					File:   LogFile{Enabled: true},
+				},
+			},
+		},
+		{
+			testName: "TestAerospikePortfolioConfig_AddInvalidArg_4: enabled otlp tracing is missing endpoint and has invalid sample ratio",
+			expectedResult: expectedResult{
+				ok: false,
+				invalidArgs: []string{
+					"conf.tracing.Endpoint",
+					"conf.tracing.SampleRatio",
+				},
+			},
+			setup: setup{
+				jsonPath: "conf.tracing",
+				asConf: Tracing{
+					Enabled:     true,
+					Exporter:    OTLPTraceExporter,
+					ServiceName: "graph-snapper",
+					SampleRatio: 2,
+				},
+			},
+		},
+		{
+			testName: "TestAerospikePortfolioConfig_AddInvalidArg_5: disabled tracing is not validated",
+			expectedResult: expectedResult{
+				ok:          true,
+				invalidArgs: []string{},
+			},
+			setup: setup{
+				jsonPath: "conf.tracing",
+				asConf:   Tracing{Exporter: "carrier pigeon"},
+			},
+		},
+		{
+			testName: "TestAerospikePortfolioConfig_AddInvalidArg_6: upstream timeout and retries are invalid",
+			expectedResult: expectedResult{
+				ok: false,
+				invalidArgs: []string{
+					"conf.upstream.TimeoutMS",
+					"conf.upstream.Retries",
+				},
+			},
+			setup: setup{
+				jsonPath: "conf.upstream",
+				asConf: Upstream{
+					TimeoutMS: 0,
+					Retries:   -1,
+				},
+			},
+		},
+		{
+			testName: "TestAerospikePortfolioConfig_AddInvalidArg_7: filesystem artifact store is missing dir and has negative retention",
+			expectedResult: expectedResult{
+				ok: false,
+				invalidArgs: []string{
+					"conf.artifacts.Dir",
+					"conf.artifacts.Retention.MaxSnapshots",
+					"conf.artifacts.AccountRetention.acct1.MaxAgeDays",
+				},
+			},
+			setup: setup{
+				jsonPath: "conf.artifacts",
+				asConf: Artifacts{
+					Backend:          FilesystemArtifactBackend,
+					Retention:        Retention{MaxSnapshots: -1},
+					AccountRetention: map[string]Retention{"acct1": {MaxAgeDays: -30}},
+				},
+			},
+		},
+		{
+			testName: "TestAerospikePortfolioConfig_AddInvalidArg_8: reports dir is empty",
+			expectedResult: expectedResult{
+				ok: false,
+				invalidArgs: []string{
+					"conf.reports.Dir",
+				},
+			},
+			setup: setup{
+				jsonPath: "conf.reports",
+				asConf:   Reports{},
+			},
+		},
 	}
 
 	// Execute testName
@@ -0,0 +1,42 @@
+package config
+
+import (
+	"github.com/sirupsen/logrus"
+	"strconv"
+)
+
+//CredentialCheck - limits applied when checking credentials against grafana and confluence
+type CredentialCheck struct {
+	Workers       int  `json:"workers"`       //Maximum number of checks run concurrently per request
+	TimeoutMS     int  `json:"timeoutMS"`     //Deadline for all checks of a request
+	VerifyOnWrite bool `json:"verifyOnWrite"` //Requires credentials to pass checks before they're stored. ie as if ?verify=true was always set
+}
+
+func (c CredentialCheck) GetFields() logrus.Fields {
+	return logrus.Fields{
+		"workers":       c.Workers,
+		"timeoutMS":     c.TimeoutMS,
+		"verifyOnWrite": c.VerifyOnWrite,
+	}
+}
+
+//IsValid - Returns true/false and a non-empty map of all invalid args. Nested args are set in the form of Parent.Child.SubChild
+//Inputs:
+//    currentPath - json path defined up and including this attribute. ie conf.credentialCheck
+//    invalidArgs - map of invalid arguments (currentPath + field name) mapped to invalid reasons
+func (c CredentialCheck) IsValid(currentPath string, invalidArgs map[string]string) bool {
+
+	isValid := true
+
+	if c.Workers <= 0 || c.Workers > 100 {
+		AddInvalidArgWithCause(currentPath, "Workers", strconv.Itoa(c.Workers), "value is 0, negative or exceeds maximum of 100", invalidArgs)
+		isValid = false
+	}
+
+	if c.TimeoutMS <= 0 {
+		AddInvalidArgWithCause(currentPath, "TimeoutMS", strconv.Itoa(c.TimeoutMS), "value is 0 or negative", invalidArgs)
+		isValid = false
+	}
+
+	return isValid
+}
@@ -1,14 +1,29 @@
 package config
 
-import "github.com/sirupsen/logrus"
+import (
+	"fmt"
+	"github.com/sirupsen/logrus"
+	"strconv"
+	"strings"
+)
+
+const (
+	//Supported log formats
+	TextLogFormat = "text"
+	JSONLogFormat = "json"
+)
 
 type Logging struct {
-	Level string `json:"level"`
+	Level  string  `json:"level"`
+	Format string  `json:"format"`
+	File   LogFile `json:"file"`
 }
 
 func (l Logging) GetFields() logrus.Fields {
 	return logrus.Fields{
-		"level": l.Level,
+		"level":  l.Level,
+		"format": l.Format,
+		"file":   l.File.GetFields(),
 	}
 }
 
@@ -26,6 +41,18 @@ func (l Logging) IsValid(currentPath string, invalidArgs map[string]string) bool
 		isValid = false
 	}
 
+	//Format is optional and defaults to text
+	switch strings.ToLower(l.Format) {
+	case "", TextLogFormat, JSONLogFormat:
+	default:
+		AddInvalidArgWithCause(currentPath, "Format", l.Format, fmt.Sprintf("value must be one of %v, %v", TextLogFormat, JSONLogFormat), invalidArgs)
+		isValid = false
+	}
+
+	if !l.File.IsValid(fmt.Sprintf("%s.%s", currentPath, "File"), invalidArgs) {
+		isValid = false
+	}
+
 	return isValid
 }
 
@@ -33,3 +60,57 @@ func isLoggingLevelInvalid(level string) bool {
 	_, err := logrus.ParseLevel(level)
 	return err != nil
 }
+
+//LogFile - Optional log file output. Files are rotated once they reach MaxSizeMB
+type LogFile struct {
+	Enabled    bool   `json:"enabled"`
+	Path       string `json:"path"`
+	MaxSizeMB  int    `json:"maxSizeMB"`
+	MaxBackups int    `json:"maxBackups"`
+	MaxAgeDays int    `json:"maxAgeDays"`
+	Compress   bool   `json:"compress"`
+}
+
+func (f LogFile) GetFields() logrus.Fields {
+	return logrus.Fields{
+		"enabled":    f.Enabled,
+		"path":       f.Path,
+		"maxSizeMB":  f.MaxSizeMB,
+		"maxBackups": f.MaxBackups,
+		"maxAgeDays": f.MaxAgeDays,
+		"compress":   f.Compress,
+	}
+}
+
+//IsValid - Returns true/false and a non-empty map of all invalid args. Nested args are set in the form of Parent.Child.SubChild
+func (f LogFile) IsValid(currentPath string, invalidArgs map[string]string) bool {
+
+	//Nothing is used when file output is disabled. Skip validation
+	if !f.Enabled {
+		return true
+	}
+
+	isValid := true
+
+	if f.Path == "" {
+		AddInvalidArgWithCause(currentPath, "Path", f.Path, "value is empty", invalidArgs)
+		isValid = false
+	}
+
+	if f.MaxSizeMB <= 0 {
+		AddInvalidArgWithCause(currentPath, "MaxSizeMB", strconv.Itoa(f.MaxSizeMB), "value is 0 or negative", invalidArgs)
+		isValid = false
+	}
+
+	if f.MaxBackups < 0 {
+		AddInvalidArgWithCause(currentPath, "MaxBackups", strconv.Itoa(f.MaxBackups), "value is negative", invalidArgs)
+		isValid = false
+	}
+
+	if f.MaxAgeDays < 0 {
+		AddInvalidArgWithCause(currentPath, "MaxAgeDays", strconv.Itoa(f.MaxAgeDays), "value is negative", invalidArgs)
+		isValid = false
+	}
+
+	return isValid
+}
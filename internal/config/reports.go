@@ -0,0 +1,26 @@
+package config
+
+import "github.com/sirupsen/logrus"
+
+//Reports - static HTML reports written for air-gapped environments
+type Reports struct {
+	Dir string `json:"dir"` //Root directory. Each account's reports are written below <dir>/<account ID>
+}
+
+func (r Reports) GetFields() logrus.Fields {
+	return logrus.Fields{
+		"dir": r.Dir,
+	}
+}
+
+//IsValid - Returns true/false and a non-empty map of all invalid args. Nested args are set in the form of Parent.Child.SubChild
+//Inputs:
+//    currentPath - json path defined up and including this attribute. ie conf.reports
+//    invalidArgs - map of invalid arguments (currentPath + field name) mapped to invalid reasons
+func (r Reports) IsValid(currentPath string, invalidArgs map[string]string) bool {
+	if r.Dir == "" {
+		AddInvalidArgWithCause(currentPath, "Dir", r.Dir, "value is empty", invalidArgs)
+		return false
+	}
+	return true
+}
@@ -0,0 +1,74 @@
+package config
+
+import (
+	"fmt"
+	"github.com/sirupsen/logrus"
+	"strings"
+)
+
+const (
+	//Supported trace exporters
+	NoneTraceExporter   = "none"
+	StdoutTraceExporter = "stdout"
+	OTLPTraceExporter   = "otlp"
+)
+
+type Tracing struct {
+	Enabled     bool    `json:"enabled"`
+	Exporter    string  `json:"exporter"`
+	Endpoint    string  `json:"endpoint"` //OTLP/HTTP collector in the form host:port. ie otel-collector:4318
+	URLPath     string  `json:"urlPath"`  //Optional OTLP/HTTP path. Defaults to /v1/traces
+	Insecure    bool    `json:"insecure"`
+	ServiceName string  `json:"serviceName"`
+	SampleRatio float64 `json:"sampleRatio"`
+}
+
+func (t Tracing) GetFields() logrus.Fields {
+	return logrus.Fields{
+		"enabled":     t.Enabled,
+		"exporter":    t.Exporter,
+		"endpoint":    t.Endpoint,
+		"urlPath":     t.URLPath,
+		"insecure":    t.Insecure,
+		"serviceName": t.ServiceName,
+		"sampleRatio": t.SampleRatio,
+	}
+}
+
+//IsValid - Returns true/false and a non-empty map of all invalid args. Nested args are set in the form of Parent.Child.SubChild
+//Inputs:
+//    currentPath - json path defined up and including this attribute. ie conf.tracing
+//    invalidArgs - map of invalid arguments (currentPath + field name) mapped to invalid reasons
+func (t Tracing) IsValid(currentPath string, invalidArgs map[string]string) bool {
+
+	//Nothing is used when tracing is disabled. Skip validation
+	if !t.Enabled {
+		return true
+	}
+
+	isValid := true
+
+	switch strings.ToLower(t.Exporter) {
+	case NoneTraceExporter, StdoutTraceExporter:
+	case OTLPTraceExporter:
+		if t.Endpoint == "" {
+			AddInvalidArgWithCause(currentPath, "Endpoint", t.Endpoint, "value is empty and is required by the otlp exporter", invalidArgs)
+			isValid = false
+		}
+	default:
+		AddInvalidArgWithCause(currentPath, "Exporter", t.Exporter, fmt.Sprintf("value must be one of %v, %v, %v", NoneTraceExporter, StdoutTraceExporter, OTLPTraceExporter), invalidArgs)
+		isValid = false
+	}
+
+	if t.ServiceName == "" {
+		AddInvalidArgWithCause(currentPath, "ServiceName", t.ServiceName, "value is empty", invalidArgs)
+		isValid = false
+	}
+
+	if t.SampleRatio < 0 || t.SampleRatio > 1 {
+		AddInvalidArgWithCause(currentPath, "SampleRatio", fmt.Sprintf("%v", t.SampleRatio), "value must be between 0 and 1", invalidArgs)
+		isValid = false
+	}
+
+	return isValid
+}
@@ -0,0 +1,61 @@
+package config
+
+import (
+	"github.com/sirupsen/logrus"
+	"strconv"
+)
+
+//Upstream - http client settings used for calls to grafana, confluence and other upstream services
+type Upstream struct {
+	TimeoutMS           int `json:"timeoutMS"`           //Deadline applied to each call unless the caller's deadline is sooner
+	Retries             int `json:"retries"`             //Number of retries of idempotent calls after network or server errors
+	RetryBackoffMS      int `json:"retryBackoffMS"`      //Wait before the first retry. Doubled after each attempt
+	MaxIdleConnsPerHost int `json:"maxIdleConnsPerHost"` //Pooled keep-alive connections per upstream host
+	IdleConnTimeoutMS   int `json:"idleConnTimeoutMS"`
+}
+
+func (u Upstream) GetFields() logrus.Fields {
+	return logrus.Fields{
+		"timeoutMS":           u.TimeoutMS,
+		"retries":             u.Retries,
+		"retryBackoffMS":      u.RetryBackoffMS,
+		"maxIdleConnsPerHost": u.MaxIdleConnsPerHost,
+		"idleConnTimeoutMS":   u.IdleConnTimeoutMS,
+	}
+}
+
+//IsValid - Returns true/false and a non-empty map of all invalid args. Nested args are set in the form of Parent.Child.SubChild
+//Inputs:
+//    currentPath - json path defined up and including this attribute. ie conf.upstream
+//    invalidArgs - map of invalid arguments (currentPath + field name) mapped to invalid reasons
+func (u Upstream) IsValid(currentPath string, invalidArgs map[string]string) bool {
+
+	isValid := true
+
+	if u.TimeoutMS <= 0 {
+		AddInvalidArgWithCause(currentPath, "TimeoutMS", strconv.Itoa(u.TimeoutMS), "value is 0 or negative", invalidArgs)
+		isValid = false
+	}
+
+	if u.Retries < 0 || u.Retries > 10 {
+		AddInvalidArgWithCause(currentPath, "Retries", strconv.Itoa(u.Retries), "value is negative or exceeds maximum of 10", invalidArgs)
+		isValid = false
+	}
+
+	if u.RetryBackoffMS < 0 {
+		AddInvalidArgWithCause(currentPath, "RetryBackoffMS", strconv.Itoa(u.RetryBackoffMS), "value is negative", invalidArgs)
+		isValid = false
+	}
+
+	if u.MaxIdleConnsPerHost < 0 {
+		AddInvalidArgWithCause(currentPath, "MaxIdleConnsPerHost", strconv.Itoa(u.MaxIdleConnsPerHost), "value is negative", invalidArgs)
+		isValid = false
+	}
+
+	if u.IdleConnTimeoutMS < 0 {
+		AddInvalidArgWithCause(currentPath, "IdleConnTimeoutMS", strconv.Itoa(u.IdleConnTimeoutMS), "value is negative", invalidArgs)
+		isValid = false
+	}
+
+	return isValid
+}
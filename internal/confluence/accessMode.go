@@ -1,31 +1,50 @@
 package confluence
 
 import (
+	"context"
+	"encoding/json"
 	"fmt"
 	"github.com/sajeevany/graph-snapper/internal/common"
+	"github.com/sajeevany/graph-snapper/internal/upstream"
 	"github.com/sirupsen/logrus"
 	"io/ioutil"
 	"net/http"
 	"strings"
 )
 
-const AccessModeURL = "/rest/api/accessmode"
+const (
+	AccessModeURL = "/rest/api/accessmode"
 
-func HasWriteAccess(logger *logrus.Logger, host string, port int, auth common.Auth) (bool, error) {
+	//ServiceName - name used for confluence in upstream errors and spans
+	ServiceName = "confluence"
+
+	//Access modes returned by AccessModeURL
+	ReadWriteAccessMode = "READ_WRITE"
+	ReadOnlyAccessMode  = "READ_ONLY"
+)
+
+func HasWriteAccess(ctx context.Context, logger *logrus.Entry, client *upstream.Client, user common.ConfluenceServerUserV1) (bool, error) {
 
 	logger.Debug("Starting a confluence server valid login API key check")
 
-	client := http.Client{}
-	req, err := buildAccessModeRequest(logger, host, port, auth)
+	req, err := buildAccessModeRequest(ctx, logger, client, user)
 	if err != nil {
 		logger.Debugf("An error was found when creating http request to validate confluence user. <%v>", err)
+		//The identity provider rejected the oauth2 client credentials
+		if upstream.IsAuthError(err) {
+			return false, nil
+		}
 		return false, err
 	}
 
 	//execute
-	resp, rErr := client.Do(req)
+	resp, rErr := client.Do(ctx, logger, Endpoint(user), "confluence.HasWriteAccess", req)
 	if rErr != nil {
-		logger.Debugf("Error when calling request to <%v>. err <%v>", req.URL, err)
+		if upstream.IsAuthError(rErr) {
+			logger.Debugf("confluence rejected credentials. <%v>", rErr)
+			return false, nil
+		}
+		logger.Debugf("Error when calling request to <%v>. err <%v>", req.URL, rErr)
 		return false, rErr
 	}
 	logger.Debug("accessMode API request executed. Checking status code.")
@@ -36,17 +55,14 @@ func HasWriteAccess(logger *logrus.Logger, host string, port int, auth common.Au
 	case http.StatusOK:
 
 		logger.Debug("confluence user access mode check returned 200")
-		//Check if the resultant json string contains "write"
-		hasWAccess, err2 := respHasWrite(resp)
-		if err2 != nil {
-			logger.Debugf("Error when reading response body <%v>, err <%v>", resp.Body, err2)
-			return hasWAccess, err2
+		mode, pErr := readAccessMode(resp)
+		if pErr != nil {
+			logger.Debugf("Unable to parse access mode response. err <%v>", pErr)
+			return false, pErr
 		}
+		logger.Debugf("confluence access mode is <%v>", mode)
 
-		return hasWAccess, nil
-	case http.StatusUnauthorized:
-		logger.Debugf("Unauthorized (401) response body <%v>", resp.Body)
-		return false, nil
+		return mode == ReadWriteAccessMode, nil
 	default:
 		logger.Debugf("Unexpected response status code <%v>", resp.StatusCode)
 		return false, nil
@@ -54,34 +70,64 @@ func HasWriteAccess(logger *logrus.Logger, host string, port int, auth common.Au
 
 }
 
-func buildAccessModeRequest(logger *logrus.Logger, host string, port int, auth common.Auth) (*http.Request, error) {
+//Endpoint - returns the upstream connection settings of the confluence user
+func Endpoint(user common.ConfluenceServerUserV1) upstream.Endpoint {
+	return upstream.Endpoint{
+		Service: ServiceName,
+		Host:    user.Host,
+		Port:    user.Port,
+		TLS:     user.TLS,
+		Proxy:   user.Proxy,
+	}
+}
+
+func buildAccessModeRequest(ctx context.Context, logger *logrus.Entry, client *upstream.Client, user common.ConfluenceServerUserV1) (*http.Request, error) {
 
 	//Build request url
-	reqURL := fmt.Sprintf("http://%v:%v%v", host, port, AccessModeURL)
+	reqURL := user.BaseURL() + AccessModeURL
 
 	//Create request
-	req, err := http.NewRequest(http.MethodGet, reqURL, nil)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, reqURL, nil)
 	if err != nil {
 		logger.Debugf("An error was found when creating http request to validate confluence user. <%v>", err)
 		return nil, err
 	}
 
 	//add headers
-	common.SetAuthHeader(logger, auth, req)
+	if aErr := client.SetAuthHeader(logger, Endpoint(user), user.Auth, req); aErr != nil {
+		logger.Debugf("Unable to set auth header to validate confluence user. <%v>", aErr)
+		return nil, aErr
+	}
 	req.Header.Set("Content-Type", "application/json")
 
 	return req, nil
 }
 
-//Checks if response has the word write in it
-func respHasWrite(resp *http.Response) (bool, error) {
+//readAccessMode - Parses the access mode. Confluence returns a json string, ie "READ_WRITE". Some proxies and newer
+//versions wrap it in an object, ie {"accessMode":"READ_WRITE"}
+func readAccessMode(resp *http.Response) (string, error) {
 
 	bodyBytes, bErr := ioutil.ReadAll(resp.Body)
 	if bErr != nil {
-		return false, bErr
+		return "", bErr
+	}
+
+	var mode string
+	if err := json.Unmarshal(bodyBytes, &mode); err != nil {
+		var wrapped struct {
+			AccessMode string `json:"accessMode"`
+		}
+		if wErr := json.Unmarshal(bodyBytes, &wrapped); wErr != nil || wrapped.AccessMode == "" {
+			return "", fmt.Errorf("unexpected access mode response <%.100s>", string(bodyBytes))
+		}
+		mode = wrapped.AccessMode
 	}
-	body := strings.ToLower(string(bodyBytes))
-	hasWAccess := strings.Contains(body, "write")
 
-	return hasWAccess, nil
+	mode = strings.ToUpper(mode)
+	switch mode {
+	case ReadWriteAccessMode, ReadOnlyAccessMode:
+		return mode, nil
+	default:
+		return "", fmt.Errorf("unknown access mode <%v>", mode)
+	}
 }
@@ -0,0 +1,64 @@
+package confluence
+
+import (
+	"context"
+	"github.com/sajeevany/graph-snapper/internal/common"
+	"github.com/sajeevany/graph-snapper/internal/config"
+	"github.com/sajeevany/graph-snapper/internal/test"
+	"github.com/sajeevany/graph-snapper/internal/upstream"
+	"github.com/sirupsen/logrus"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestHasWriteAccess(t *testing.T) {
+	tests := []struct {
+		name    string
+		status  int
+		body    string
+		want    bool
+		wantErr bool
+	}{
+		{name: "test0 read write", status: http.StatusOK, body: `"READ_WRITE"`, want: true},
+		{name: "test1 read only", status: http.StatusOK, body: `"READ_ONLY"`, want: false},
+		{name: "test2 wrapped read write", status: http.StatusOK, body: `{"accessMode":"READ_WRITE"}`, want: true},
+		{name: "test3 text mentioning write isn't trusted", status: http.StatusOK, body: `<html>Please rewrite your request</html>`, want: false, wantErr: true},
+		{name: "test4 unknown access mode", status: http.StatusOK, body: `"MAINTENANCE"`, want: false, wantErr: true},
+		{name: "test5 unauthorized", status: http.StatusUnauthorized, body: ``, want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tt.status)
+				w.Write([]byte(tt.body))
+			}))
+			defer server.Close()
+
+			got, err := HasWriteAccess(context.Background(), testLogger(), testClient(), testUser(t, server.URL))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("HasWriteAccess() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("HasWriteAccess() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func testLogger() *logrus.Entry {
+	return logrus.NewEntry(logrus.New())
+}
+
+func testClient() *upstream.Client {
+	return upstream.New(config.NewConfWithDefaults().Upstream)
+}
+
+func testUser(t *testing.T, rawURL string) common.ConfluenceServerUserV1 {
+	host, port := test.SplitServerURL(t, rawURL)
+	return common.ConfluenceServerUserV1{
+		Host: host,
+		Port: port,
+		Auth: common.Auth{Basic: common.Basic{Username: "user", Password: "pass"}},
+	}
+}
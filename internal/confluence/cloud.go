@@ -0,0 +1,79 @@
+package confluence
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/sajeevany/graph-snapper/internal/common"
+	"github.com/sajeevany/graph-snapper/internal/upstream"
+	"github.com/sirupsen/logrus"
+	"net/http"
+)
+
+const (
+	//CurrentUserURL - returns the calling user. Atlassian Cloud doesn't expose the access mode endpoint
+	CurrentUserURL = "/rest/api/user/current"
+
+	//Atlassian Cloud user types returned by CurrentUserURL
+	knownUserType     = "known"
+	anonymousUserType = "anonymous"
+)
+
+type currentUserResp struct {
+	Type      string `json:"type"`
+	AccountID string `json:"accountId"`
+}
+
+//IsValidCloudLogin - Checks that the email and API token of an Atlassian Cloud user are accepted. Cloud answers
+//requests with unknown credentials as the anonymous user so the user type is checked in addition to the status code
+func IsValidCloudLogin(ctx context.Context, logger *logrus.Entry, client *upstream.Client, user common.ConfluenceCloudUserV1) (bool, error) {
+
+	logger.Debug("Starting a confluence cloud valid login check")
+
+	site := CloudSite(user)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, site.BaseURL+CurrentUserURL, nil)
+	if err != nil {
+		logger.Debugf("An error was found when creating http request to validate confluence cloud user. <%v>", err)
+		return false, err
+	}
+	if aErr := client.SetAuthHeader(logger, site.Endpoint, site.Auth, req); aErr != nil {
+		logger.Debugf("Unable to set auth header to validate confluence cloud user. <%v>", aErr)
+		//The identity provider rejected the oauth2 client credentials
+		if upstream.IsAuthError(aErr) {
+			return false, nil
+		}
+		return false, aErr
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, rErr := client.Do(ctx, logger, site.Endpoint, "confluence.IsValidCloudLogin", req)
+	if rErr != nil {
+		if upstream.IsAuthError(rErr) {
+			logger.Debugf("confluence cloud rejected credentials. <%v>", rErr)
+			return false, nil
+		}
+		logger.Debugf("Error when calling request to <%v>. err <%v>", req.URL, rErr)
+		return false, rErr
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		logger.Debugf("Unexpected response status code <%v>", resp.StatusCode)
+		return false, nil
+	}
+
+	var cu currentUserResp
+	if dErr := json.NewDecoder(resp.Body).Decode(&cu); dErr != nil {
+		return false, fmt.Errorf("unable to parse current user response. err <%v>", dErr)
+	}
+	logger.Debugf("confluence cloud user type is <%v>", cu.Type)
+
+	switch cu.Type {
+	case knownUserType:
+		return cu.AccountID != "", nil
+	case anonymousUserType:
+		return false, nil
+	default:
+		return false, fmt.Errorf("unknown confluence cloud user type <%v>", cu.Type)
+	}
+}
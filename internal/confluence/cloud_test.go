@@ -0,0 +1,88 @@
+package confluence
+
+import (
+	"context"
+	"github.com/sajeevany/graph-snapper/internal/common"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func TestIsValidCloudLogin(t *testing.T) {
+	tests := []struct {
+		name    string
+		status  int
+		body    string
+		want    bool
+		wantErr bool
+	}{
+		{
+			name:   "test0 known user",
+			status: http.StatusOK,
+			body:   `{"type":"known","accountId":"5b10a2844c20165700ede21g","email":"user@example.com"}`,
+			want:   true,
+		},
+		{
+			name:   "test1 anonymous user",
+			status: http.StatusOK,
+			body:   `{"type":"anonymous","displayName":"Anonymous"}`,
+			want:   false,
+		},
+		{
+			name:   "test2 rejected api token",
+			status: http.StatusUnauthorized,
+			want:   false,
+		},
+		{
+			name:    "test3 unexpected response",
+			status:  http.StatusOK,
+			body:    `<html></html>`,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				email, token, ok := r.BasicAuth()
+				if r.URL.Path != "/wiki"+CurrentUserURL || !ok || email != "user@example.com" || token != "apiToken" {
+					w.WriteHeader(http.StatusNotFound)
+					return
+				}
+				w.WriteHeader(tt.status)
+				w.Write([]byte(tt.body))
+			}))
+			defer server.Close()
+
+			user := common.ConfluenceCloudUserV1{SiteURL: server.URL + "/", Email: "user@example.com", APIToken: "apiToken"}
+			got, err := IsValidCloudLogin(context.Background(), testLogger(), testClient(), user)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("IsValidCloudLogin() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("IsValidCloudLogin() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCheckSpacePermissionsOnCloud(t *testing.T) {
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/wiki/rest/api/space/OPS" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Write([]byte(`{"key":"OPS","operations":[{"operation":"create","targetType":"page"}]}`))
+	}))
+	defer server.Close()
+
+	user := common.ConfluenceCloudUserV1{SiteURL: server.URL, Email: "user@example.com", APIToken: "apiToken"}
+	got, err := CheckSpacePermissions(context.Background(), testLogger(), testClient(), CloudSite(user), "OPS")
+	if err != nil {
+		t.Fatalf("CheckSpacePermissions() unexpected error = %v", err)
+	}
+	if want := []string{CreateAttachmentPermission}; !reflect.DeepEqual(got.Missing, want) {
+		t.Errorf("CheckSpacePermissions() missing = %v, want %v", got.Missing, want)
+	}
+}
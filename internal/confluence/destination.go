@@ -0,0 +1,305 @@
+package confluence
+
+import (
+	"context"
+	"fmt"
+	"github.com/sajeevany/graph-snapper/internal/common"
+	"github.com/sajeevany/graph-snapper/internal/destination"
+	"github.com/sajeevany/graph-snapper/internal/plugin"
+	"github.com/sajeevany/graph-snapper/internal/upstream"
+	"github.com/sirupsen/logrus"
+	"html"
+	"strconv"
+	"strings"
+)
+
+const (
+	//ServerDestinationType - destination type of confluence server and data center users
+	ServerDestinationType = "confluence-server"
+	//CloudDestinationType - destination type of Atlassian Cloud users
+	CloudDestinationType = "confluence-cloud"
+
+	//shortIDLen - length of the snapshot ID prefix which makes page titles unique
+	shortIDLen = 8
+)
+
+//ServerDestination - Publishes bundles as pages of a confluence server space
+type ServerDestination struct {
+	client *upstream.Client
+}
+
+//NewServerDestination - returns the confluence server destination using the client for all calls
+func NewServerDestination(client *upstream.Client) *ServerDestination {
+	return &ServerDestination{client: client}
+}
+
+func (d *ServerDestination) Type() string {
+	return ServerDestinationType
+}
+
+func (d *ServerDestination) NewCredential() destination.Credential {
+	return &common.ConfluenceServerUserV1{}
+}
+
+func (d *ServerDestination) Validate(cred destination.Credential) error {
+	user, err := serverUser(cred)
+	if err != nil {
+		return err
+	}
+	if !user.IsValid() {
+		return fmt.Errorf("confluence user <%#v> is invalid", user)
+	}
+	return nil
+}
+
+//CheckAccess - Checks that the user has write access and, when a space is set, can create pages and attachments in it
+func (d *ServerDestination) CheckAccess(ctx context.Context, logger *logrus.Entry, cred destination.Credential) plugin.CheckResultV1 {
+
+	user, err := serverUser(cred)
+	if err != nil {
+		return plugin.Failed(err.Error())
+	}
+
+	hasWriteAccess, rErr := HasWriteAccess(ctx, logger, d.client, user)
+	if rErr != nil {
+		logger.WithFields(user.GetFields()).Errorf("Error checking if confluence user has write access. <%v>", rErr)
+		return plugin.Failed(rErr.Error())
+	}
+	if !hasWriteAccess {
+		logger.Debug("User does not have write access")
+		return plugin.Failed("User does not have access mode READ_WRITE")
+	}
+
+	logger.Debug("User has write access")
+	if user.SpaceKey == "" {
+		return plugin.Passed(nil)
+	}
+	return checkSpace(ctx, logger, d.client, ServerSite(user), user.SpaceKey)
+}
+
+func (d *ServerDestination) Publish(ctx context.Context, logger *logrus.Entry, name string, cred destination.Credential, bundle destination.Bundle) (destination.ReceiptV1, error) {
+	user, err := serverUser(cred)
+	if err != nil {
+		return destination.ReceiptV1{}, err
+	}
+	return publishPage(ctx, logger, d.client, ServerSite(user), user.SpaceKey, bundle)
+}
+
+func (d *ServerDestination) Rollback(ctx context.Context, logger *logrus.Entry, name string, cred destination.Credential, bundle destination.Bundle, receipt destination.ReceiptV1) error {
+	user, err := serverUser(cred)
+	if err != nil {
+		return err
+	}
+	return deletePages(ctx, logger, d.client, ServerSite(user), receipt)
+}
+
+//CloudDestination - Publishes bundles as pages of an Atlassian Cloud space
+type CloudDestination struct {
+	client *upstream.Client
+}
+
+//NewCloudDestination - returns the confluence cloud destination using the client for all calls
+func NewCloudDestination(client *upstream.Client) *CloudDestination {
+	return &CloudDestination{client: client}
+}
+
+func (d *CloudDestination) Type() string {
+	return CloudDestinationType
+}
+
+func (d *CloudDestination) NewCredential() destination.Credential {
+	return &common.ConfluenceCloudUserV1{}
+}
+
+func (d *CloudDestination) Validate(cred destination.Credential) error {
+	user, err := cloudUser(cred)
+	if err != nil {
+		return err
+	}
+	if !user.IsValid() {
+		return fmt.Errorf("confluence cloud user <%#v> is invalid", user)
+	}
+	return nil
+}
+
+//CheckAccess - Checks that the email and API token are accepted and, when a space is set, can create pages and
+//attachments in it
+func (d *CloudDestination) CheckAccess(ctx context.Context, logger *logrus.Entry, cred destination.Credential) plugin.CheckResultV1 {
+
+	user, err := cloudUser(cred)
+	if err != nil {
+		return plugin.Failed(err.Error())
+	}
+
+	isValid, rErr := IsValidCloudLogin(ctx, logger, d.client, user)
+	if rErr != nil {
+		logger.WithFields(user.GetFields()).Errorf("Error checking if confluence cloud user can log in. <%v>", rErr)
+		return plugin.Failed(rErr.Error())
+	}
+	if !isValid {
+		logger.Debug("Confluence cloud rejected the email and API token")
+		return plugin.Failed("Unauthorized. Email and API token were not accepted")
+	}
+
+	if user.SpaceKey == "" {
+		return plugin.Passed(nil)
+	}
+	return checkSpace(ctx, logger, d.client, CloudSite(user), user.SpaceKey)
+}
+
+func (d *CloudDestination) Publish(ctx context.Context, logger *logrus.Entry, name string, cred destination.Credential, bundle destination.Bundle) (destination.ReceiptV1, error) {
+	user, err := cloudUser(cred)
+	if err != nil {
+		return destination.ReceiptV1{}, err
+	}
+	return publishPage(ctx, logger, d.client, CloudSite(user), user.SpaceKey, bundle)
+}
+
+func (d *CloudDestination) Rollback(ctx context.Context, logger *logrus.Entry, name string, cred destination.Credential, bundle destination.Bundle, receipt destination.ReceiptV1) error {
+	user, err := cloudUser(cred)
+	if err != nil {
+		return err
+	}
+	return deletePages(ctx, logger, d.client, CloudSite(user), receipt)
+}
+
+func serverUser(cred destination.Credential) (common.ConfluenceServerUserV1, error) {
+	user, ok := cred.(common.ConfluenceServerUserV1)
+	if !ok {
+		return common.ConfluenceServerUserV1{}, fmt.Errorf("credential <%T> isn't a confluence server user", cred)
+	}
+	return user, nil
+}
+
+func cloudUser(cred destination.Credential) (common.ConfluenceCloudUserV1, error) {
+	user, ok := cred.(common.ConfluenceCloudUserV1)
+	if !ok {
+		return common.ConfluenceCloudUserV1{}, fmt.Errorf("credential <%T> isn't a confluence cloud user", cred)
+	}
+	return user, nil
+}
+
+//checkSpace - Checks that the user can publish pages and attachments to the space
+func checkSpace(ctx context.Context, logger *logrus.Entry, client *upstream.Client, site Site, spaceKey string) plugin.CheckResultV1 {
+
+	perms, pErr := CheckSpacePermissions(ctx, logger, client, site, spaceKey)
+	if pErr != nil {
+		logger.Errorf("Error checking confluence space <%v> permissions. <%v>", spaceKey, pErr)
+		return plugin.Failed(pErr.Error())
+	}
+
+	if len(perms.Missing) != 0 {
+		logger.Debugf("User is missing permissions <%v> in space <%v>", perms.Missing, spaceKey)
+		result := plugin.Failed(fmt.Sprintf("User is missing permissions %v in space %v", perms.Missing, spaceKey))
+		result.Details = &perms
+		return result
+	}
+
+	return plugin.Passed(&perms)
+}
+
+//publishPage - Creates a page listing the bundle's snapshots and attaches their images and data exports. The page is
+//deleted if a file can't be attached
+func publishPage(ctx context.Context, logger *logrus.Entry, client *upstream.Client, site Site, spaceKey string, bundle destination.Bundle) (destination.ReceiptV1, error) {
+
+	if spaceKey == "" {
+		return destination.ReceiptV1{}, fmt.Errorf("credential doesn't set the space key pages are published to")
+	}
+	if len(bundle.Items) == 0 {
+		return destination.ReceiptV1{}, fmt.Errorf("bundle has no snapshots")
+	}
+
+	page, err := CreatePage(ctx, logger, client, site, spaceKey, pageTitle(bundle), pageBody(bundle))
+	if err != nil {
+		return destination.ReceiptV1{}, err
+	}
+
+	for _, item := range bundle.Items {
+		aErr := AttachFile(ctx, logger, client, site, page.ID, attachmentName(item), item.Snapshot.ContentType, item.Image)
+		for _, e := range item.Exports {
+			if aErr != nil {
+				break
+			}
+			export, _ := item.Snapshot.Export(e.Format)
+			aErr = AttachFile(ctx, logger, client, site, page.ID, exportAttachmentName(item, e.Format), export.ContentType, e.Data)
+		}
+		if aErr != nil {
+			if dErr := DeletePage(ctx, logger, client, site, page.ID); dErr != nil {
+				logger.Errorf("Unable to delete page <%v> after a failed attachment upload. <%v>", page.ID, dErr)
+			}
+			return destination.ReceiptV1{}, aErr
+		}
+	}
+
+	location := page.URL
+	if location == "" {
+		location = site.BaseURL + fmt.Sprintf(PageURL, page.ID)
+	}
+	return destination.ReceiptV1{Location: location, IDs: []string{page.ID}}, nil
+}
+
+func deletePages(ctx context.Context, logger *logrus.Entry, client *upstream.Client, site Site, receipt destination.ReceiptV1) error {
+	for _, id := range receipt.IDs {
+		if err := DeletePage(ctx, logger, client, site, id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+//pageTitle - returns a title unique to the bundle. ie weekly-ops 2020-07-01 10:00:00 UTC (1b9d6bcd)
+func pageTitle(bundle destination.Bundle) string {
+	first := bundle.Items[0].Snapshot
+	prefix := bundle.JobID
+	if prefix == "" {
+		prefix = first.DashboardTitle
+	}
+	if prefix == "" {
+		prefix = "Snapshot"
+	}
+	return fmt.Sprintf("%v %v (%v)", prefix, first.CapturedAt.UTC().Format("2006-01-02 15:04:05 UTC"), shortID(first.ID))
+}
+
+//pageBody - returns a heading, time range, image and data export links per snapshot in the storage format
+func pageBody(bundle destination.Bundle) string {
+	var b strings.Builder
+	for _, item := range bundle.Items {
+		s := item.Snapshot
+		heading := s.PanelTitle
+		if heading == "" {
+			heading = "Panel " + strconv.Itoa(s.PanelID)
+		}
+		dashboard := s.DashboardTitle
+		if dashboard == "" {
+			dashboard = s.DashboardUID
+		}
+		fmt.Fprintf(&b, "<h2>%s</h2>", html.EscapeString(heading))
+		fmt.Fprintf(&b, "<p>%s. From %s to %s</p>", html.EscapeString(dashboard), html.EscapeString(s.From), html.EscapeString(s.To))
+		fmt.Fprintf(&b, `<ac:image><ri:attachment ri:filename="%s" /></ac:image>`, html.EscapeString(attachmentName(item)))
+		if len(item.Exports) != 0 {
+			b.WriteString("<p>Data:")
+			for _, e := range item.Exports {
+				fmt.Fprintf(&b, ` <ac:link><ri:attachment ri:filename="%s" /></ac:link>`, html.EscapeString(exportAttachmentName(item, e.Format)))
+			}
+			b.WriteString("</p>")
+		}
+	}
+	return b.String()
+}
+
+//attachmentName - file name of the snapshot's image attachment
+func attachmentName(item destination.Item) string {
+	return item.Snapshot.ID + "." + item.Snapshot.ImageExtension()
+}
+
+//exportAttachmentName - file name of the snapshot's data export attachment
+func exportAttachmentName(item destination.Item, format string) string {
+	return item.Snapshot.ID + "." + format
+}
+
+func shortID(id string) string {
+	if len(id) > shortIDLen {
+		return id[:shortIDLen]
+	}
+	return id
+}
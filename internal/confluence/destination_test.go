@@ -0,0 +1,161 @@
+package confluence
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/sajeevany/graph-snapper/internal/artifact"
+	"github.com/sajeevany/graph-snapper/internal/destination"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestServerDestination_Publish(t *testing.T) {
+	tests := []struct {
+		name         string
+		spaceKey     string
+		attachStatus int
+		exports      bool
+		wantReceipt  destination.ReceiptV1
+		wantFiles    []string
+		wantDeleted  bool
+		wantErr      bool
+	}{
+		{
+			name:         "test0 page is created with the attached image",
+			spaceKey:     "OPS",
+			attachStatus: http.StatusOK,
+			wantReceipt:  destination.ReceiptV1{Location: "http://confluence/display/OPS/page", IDs: []string{"123"}},
+			wantFiles:    []string{"1b9d6bcd-bbfd-4b2d-9b5d-ab8dfbbd4bed.png"},
+		},
+		{
+			name:         "test1 page is deleted after a failed attachment",
+			spaceKey:     "OPS",
+			attachStatus: http.StatusForbidden,
+			wantDeleted:  true,
+			wantErr:      true,
+		},
+		{
+			name:    "test2 space key is required",
+			wantErr: true,
+		},
+		{
+			name:         "test3 data exports are attached next to the image",
+			spaceKey:     "OPS",
+			attachStatus: http.StatusOK,
+			exports:      true,
+			wantReceipt:  destination.ReceiptV1{Location: "http://confluence/display/OPS/page", IDs: []string{"123"}},
+			wantFiles:    []string{"1b9d6bcd-bbfd-4b2d-9b5d-ab8dfbbd4bed.png", "1b9d6bcd-bbfd-4b2d-9b5d-ab8dfbbd4bed.csv", "1b9d6bcd-bbfd-4b2d-9b5d-ab8dfbbd4bed.json"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var title, body string
+			var attachments []string
+			deleted := false
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				switch {
+				case r.Method == http.MethodPost && r.URL.Path == ContentURL:
+					var req createPageReq
+					if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+						w.WriteHeader(http.StatusBadRequest)
+						return
+					}
+					title = req.Title
+					body = req.Body.Storage.Value
+					w.Write([]byte(`{"id":"123","_links":{"base":"http://confluence","webui":"/display/OPS/page"}}`))
+				case r.Method == http.MethodPost && r.URL.Path == "/rest/api/content/123/child/attachment":
+					if r.Header.Get("X-Atlassian-Token") != "no-check" {
+						w.WriteHeader(http.StatusForbidden)
+						return
+					}
+					if _, header, err := r.FormFile("file"); err == nil {
+						attachments = append(attachments, header.Filename)
+					}
+					w.WriteHeader(tt.attachStatus)
+				case r.Method == http.MethodDelete && r.URL.Path == "/rest/api/content/123":
+					deleted = true
+					w.WriteHeader(http.StatusNoContent)
+				default:
+					w.WriteHeader(http.StatusNotFound)
+				}
+			}))
+			defer server.Close()
+
+			user := testUser(t, server.URL)
+			user.SpaceKey = tt.spaceKey
+			bundle := testBundle()
+			if tt.exports {
+				item := &bundle.Items[0]
+				item.Snapshot.Exports = []artifact.ExportV1{{Format: artifact.CSVExportFormat, ContentType: "text/csv"}, {Format: artifact.JSONExportFormat, ContentType: "application/json"}}
+				item.Exports = []artifact.DataExport{{Format: artifact.CSVExportFormat, Data: []byte("Time\n")}, {Format: artifact.JSONExportFormat, Data: []byte("[]")}}
+			}
+			got, err := NewServerDestination(testClient()).Publish(context.Background(), testLogger(), "csu_0", user, bundle)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Publish() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.wantReceipt) {
+				t.Errorf("Publish() receipt = %+v, want %+v", got, tt.wantReceipt)
+			}
+			if deleted != tt.wantDeleted {
+				t.Errorf("Publish() deleted page = %v, want %v", deleted, tt.wantDeleted)
+			}
+			if tt.spaceKey == "" {
+				return
+			}
+			if !strings.HasPrefix(title, "weekly-ops 2020-07-01 10:00:00 UTC (1b9d6bcd)") {
+				t.Errorf("Publish() page title = %v, want the job, capture time and snapshot ID", title)
+			}
+			if tt.wantFiles != nil && !reflect.DeepEqual(attachments, tt.wantFiles) {
+				t.Errorf("Publish() attachments = %v, want %v", attachments, tt.wantFiles)
+			}
+			if tt.exports && !strings.Contains(body, `<ac:link><ri:attachment ri:filename="1b9d6bcd-bbfd-4b2d-9b5d-ab8dfbbd4bed.csv" /></ac:link>`) {
+				t.Errorf("Publish() page body = %v, want a link to the csv export", body)
+			}
+		})
+	}
+}
+
+func TestServerDestination_Rollback(t *testing.T) {
+
+	var deleted []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodDelete {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		deleted = append(deleted, r.URL.Path)
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer server.Close()
+
+	receipt := destination.ReceiptV1{IDs: []string{"123"}}
+	if err := NewServerDestination(testClient()).Rollback(context.Background(), testLogger(), "csu_0", testUser(t, server.URL), testBundle(), receipt); err != nil {
+		t.Fatalf("Rollback() unexpected error = %v", err)
+	}
+	if want := []string{"/rest/api/content/123"}; !reflect.DeepEqual(deleted, want) {
+		t.Errorf("Rollback() deleted = %v, want %v", deleted, want)
+	}
+}
+
+func testBundle() destination.Bundle {
+	return destination.Bundle{
+		AccountID: "account",
+		JobID:     "weekly-ops",
+		Items: []destination.Item{{
+			Snapshot: artifact.SnapshotV1{
+				ID:          "1b9d6bcd-bbfd-4b2d-9b5d-ab8dfbbd4bed",
+				AccountID:   "account",
+				JobID:       "weekly-ops",
+				PanelID:     2,
+				PanelTitle:  "Latency",
+				CapturedAt:  time.Date(2020, 7, 1, 10, 0, 0, 0, time.UTC),
+				ContentType: artifact.PNGContentType,
+			},
+			Image: []byte("png"),
+		}},
+	}
+}
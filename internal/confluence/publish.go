@@ -0,0 +1,183 @@
+package confluence
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/sajeevany/graph-snapper/internal/upstream"
+	"github.com/sirupsen/logrus"
+	"io"
+	"io/ioutil"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+)
+
+const (
+	//ContentURL - creates pages
+	ContentURL = "/rest/api/content"
+	//PageURL - page by ID
+	PageURL = "/rest/api/content/%s"
+	//AttachmentURL - attachments of a page by page ID
+	AttachmentURL = "/rest/api/content/%s/child/attachment"
+
+	//storageRepresentation - confluence XHTML based storage format
+	storageRepresentation = "storage"
+)
+
+//PageV1 - Page created in a space
+type PageV1 struct {
+	ID  string
+	URL string //Browser url of the page. Empty if confluence didn't return one
+}
+
+type createPageReq struct {
+	Type  string `json:"type"`
+	Title string `json:"title"`
+	Space struct {
+		Key string `json:"key"`
+	} `json:"space"`
+	Body struct {
+		Storage struct {
+			Value          string `json:"value"`
+			Representation string `json:"representation"`
+		} `json:"storage"`
+	} `json:"body"`
+}
+
+type contentResp struct {
+	ID    string `json:"id"`
+	Links struct {
+		Base  string `json:"base"`
+		WebUI string `json:"webui"`
+	} `json:"_links"`
+}
+
+//CreatePage - Creates a page in the space. body is in the storage format. Titles must be unique within a space
+func CreatePage(ctx context.Context, logger *logrus.Entry, client *upstream.Client, site Site, spaceKey, title, body string) (PageV1, error) {
+
+	pageReq := createPageReq{Type: "page", Title: title}
+	pageReq.Space.Key = spaceKey
+	pageReq.Body.Storage.Value = body
+	pageReq.Body.Storage.Representation = storageRepresentation
+	reqBody, err := json.Marshal(pageReq)
+	if err != nil {
+		return PageV1{}, err
+	}
+
+	req, err := newRequest(ctx, logger, client, site, http.MethodPost, ContentURL, bytes.NewReader(reqBody))
+	if err != nil {
+		return PageV1{}, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := client.Do(ctx, logger, site.Endpoint, "confluence.CreatePage", req)
+	if err != nil {
+		logger.Debugf("Unable to create page <%v> in space <%v>. <%v>", title, spaceKey, err)
+		return PageV1{}, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated {
+		return PageV1{}, readError(resp, "create page")
+	}
+
+	var content contentResp
+	if dErr := json.NewDecoder(resp.Body).Decode(&content); dErr != nil {
+		return PageV1{}, fmt.Errorf("unable to parse create page response. err <%v>", dErr)
+	}
+	if content.ID == "" {
+		return PageV1{}, fmt.Errorf("create page response doesn't include the page id")
+	}
+
+	page := PageV1{ID: content.ID}
+	if content.Links.WebUI != "" {
+		page.URL = content.Links.Base + content.Links.WebUI
+	}
+	logger.Debugf("Created page <%v> in space <%v>", page.ID, spaceKey)
+	return page, nil
+}
+
+//AttachFile - Uploads the file as an attachment of the page
+func AttachFile(ctx context.Context, logger *logrus.Entry, client *upstream.Client, site Site, pageID, fileName, contentType string, data []byte) error {
+
+	var buf bytes.Buffer
+	form := multipart.NewWriter(&buf)
+	part, err := form.CreatePart(map[string][]string{
+		"Content-Disposition": {fmt.Sprintf(`form-data; name="file"; filename="%s"`, fileName)},
+		"Content-Type":        {contentType},
+	})
+	if err != nil {
+		return err
+	}
+	if _, wErr := part.Write(data); wErr != nil {
+		return wErr
+	}
+	if cErr := form.Close(); cErr != nil {
+		return cErr
+	}
+
+	req, err := newRequest(ctx, logger, client, site, http.MethodPost, fmt.Sprintf(AttachmentURL, url.PathEscape(pageID)), &buf)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", form.FormDataContentType())
+	//Confluence rejects attachment uploads without this header as XSRF attempts
+	req.Header.Set("X-Atlassian-Token", "no-check")
+
+	resp, err := client.Do(ctx, logger, site.Endpoint, "confluence.AttachFile", req)
+	if err != nil {
+		logger.Debugf("Unable to attach <%v> to page <%v>. <%v>", fileName, pageID, err)
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return readError(resp, "attach file")
+	}
+	return nil
+}
+
+//DeletePage - Deletes the page and its attachments. Deleting a missing page succeeds
+func DeletePage(ctx context.Context, logger *logrus.Entry, client *upstream.Client, site Site, pageID string) error {
+
+	req, err := newRequest(ctx, logger, client, site, http.MethodDelete, fmt.Sprintf(PageURL, url.PathEscape(pageID)), nil)
+	if err != nil {
+		return err
+	}
+
+	resp, err := client.Do(ctx, logger, site.Endpoint, "confluence.DeletePage", req)
+	if err != nil {
+		logger.Debugf("Unable to delete page <%v>. <%v>", pageID, err)
+		return err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK, http.StatusNoContent, http.StatusNotFound:
+		return nil
+	default:
+		return readError(resp, "delete page")
+	}
+}
+
+func newRequest(ctx context.Context, logger *logrus.Entry, client *upstream.Client, site Site, method, path string, body io.Reader) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, method, site.BaseURL+path, body)
+	if err != nil {
+		logger.Debugf("An error was found when creating confluence request. <%v>", err)
+		return nil, err
+	}
+	if aErr := client.SetAuthHeader(logger, site.Endpoint, site.Auth, req); aErr != nil {
+		logger.Debugf("Unable to set auth header of confluence request. <%v>", aErr)
+		return nil, aErr
+	}
+	req.Header.Set("Accept", "application/json")
+	return req, nil
+}
+
+//readError - returns the status code and the start of the response body of a failed request
+func readError(resp *http.Response, operation string) error {
+	body, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 4096))
+	return fmt.Errorf("confluence %v returned status code <%v>. <%.200s>", operation, resp.StatusCode, string(body))
+}
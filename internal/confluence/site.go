@@ -0,0 +1,55 @@
+package confluence
+
+import (
+	"github.com/sajeevany/graph-snapper/internal/common"
+	"github.com/sajeevany/graph-snapper/internal/upstream"
+)
+
+//Flavor - Confluence deployment type. Server and Cloud serve the same REST api from different base urls and differ in
+//the endpoints available to check credentials
+type Flavor string
+
+const (
+	ServerFlavor Flavor = "server"
+	CloudFlavor  Flavor = "cloud"
+)
+
+//Site - Location, credentials and connection settings used to call the REST api of a confluence instance. Publishing
+//and permission checks are built on a Site so that they work against either flavor
+type Site struct {
+	Flavor   Flavor
+	BaseURL  string //REST api paths are appended to this url. ie https://example.atlassian.net/wiki
+	Auth     common.Auth
+	Endpoint upstream.Endpoint
+}
+
+//ServerSite - returns the site of a confluence server or data center user
+func ServerSite(user common.ConfluenceServerUserV1) Site {
+	return Site{
+		Flavor:   ServerFlavor,
+		BaseURL:  user.BaseURL(),
+		Auth:     user.Auth,
+		Endpoint: Endpoint(user),
+	}
+}
+
+//CloudSite - returns the site of an Atlassian Cloud user
+func CloudSite(user common.ConfluenceCloudUserV1) Site {
+	return Site{
+		Flavor:   CloudFlavor,
+		BaseURL:  user.BaseURL(),
+		Auth:     user.Auth(),
+		Endpoint: CloudEndpoint(user),
+	}
+}
+
+//CloudEndpoint - returns the upstream connection settings of the Atlassian Cloud user
+func CloudEndpoint(user common.ConfluenceCloudUserV1) upstream.Endpoint {
+	host, port := user.HostPort()
+	return upstream.Endpoint{
+		Service: ServiceName,
+		Host:    host,
+		Port:    port,
+		Proxy:   user.Proxy,
+	}
+}
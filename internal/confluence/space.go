@@ -0,0 +1,102 @@
+package confluence
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/sajeevany/graph-snapper/internal/upstream"
+	"github.com/sirupsen/logrus"
+	"net/http"
+	"net/url"
+)
+
+const (
+	//SpaceURL - space lookup including the operations the user is permitted to perform in it
+	SpaceURL = "/rest/api/space/%s?expand=operations"
+
+	//Permissions required to publish snapshots
+	ViewSpacePermission        = "view space"
+	CreatePagePermission       = "create page"
+	CreateAttachmentPermission = "create attachment"
+)
+
+//SpacePermissionsV1 - Snapshot publishing permissions of a user in a space
+type SpacePermissionsV1 struct {
+	SpaceKey            string
+	CanCreatePage       bool
+	CanCreateAttachment bool
+	Missing             []string `json:"Missing,omitempty"` //Required permissions the user doesn't have
+}
+
+type spaceResp struct {
+	Key        string `json:"key"`
+	Operations []struct {
+		Operation  string `json:"operation"`
+		TargetType string `json:"targetType"`
+	} `json:"operations"`
+}
+
+//CheckSpacePermissions - Checks that the user can view the space and create pages and attachments in it. Missing
+//permissions are reported in the result. An error is only returned if the check couldn't be run
+func CheckSpacePermissions(ctx context.Context, logger *logrus.Entry, client *upstream.Client, site Site, spaceKey string) (SpacePermissionsV1, error) {
+
+	perms := SpacePermissionsV1{SpaceKey: spaceKey}
+
+	reqURL := site.BaseURL + fmt.Sprintf(SpaceURL, url.PathEscape(spaceKey))
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, reqURL, nil)
+	if err != nil {
+		logger.Debugf("An error was found when creating http request to check space permissions. <%v>", err)
+		return perms, err
+	}
+	if aErr := client.SetAuthHeader(logger, site.Endpoint, site.Auth, req); aErr != nil {
+		logger.Debugf("Unable to set auth header to check space permissions. <%v>", aErr)
+		return perms, aErr
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, rErr := client.Do(ctx, logger, site.Endpoint, "confluence.CheckSpacePermissions", req)
+	if rErr != nil {
+		if upstream.IsAuthError(rErr) {
+			perms.Missing = []string{ViewSpacePermission, CreatePagePermission, CreateAttachmentPermission}
+			return perms, nil
+		}
+		return perms, rErr
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotFound:
+		//Confluence hides spaces the user can't view
+		logger.Debugf("space <%v> doesn't exist or isn't visible to the user", spaceKey)
+		perms.Missing = []string{ViewSpacePermission, CreatePagePermission, CreateAttachmentPermission}
+		return perms, nil
+	default:
+		return perms, fmt.Errorf("unexpected space lookup status code <%v>", resp.StatusCode)
+	}
+
+	var space spaceResp
+	if dErr := json.NewDecoder(resp.Body).Decode(&space); dErr != nil {
+		return perms, fmt.Errorf("unable to parse space response. err <%v>", dErr)
+	}
+
+	for _, op := range space.Operations {
+		if op.Operation != "create" {
+			continue
+		}
+		switch op.TargetType {
+		case "page":
+			perms.CanCreatePage = true
+		case "attachment":
+			perms.CanCreateAttachment = true
+		}
+	}
+	if !perms.CanCreatePage {
+		perms.Missing = append(perms.Missing, CreatePagePermission)
+	}
+	if !perms.CanCreateAttachment {
+		perms.Missing = append(perms.Missing, CreateAttachmentPermission)
+	}
+
+	return perms, nil
+}
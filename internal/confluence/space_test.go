@@ -0,0 +1,62 @@
+package confluence
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func TestCheckSpacePermissions(t *testing.T) {
+	tests := []struct {
+		name        string
+		status      int
+		body        string
+		wantMissing []string
+		wantErr     bool
+	}{
+		{
+			name:   "test0 page and attachment create permissions",
+			status: http.StatusOK,
+			body:   `{"key":"OPS","operations":[{"operation":"read","targetType":"space"},{"operation":"create","targetType":"page"},{"operation":"create","targetType":"attachment"}]}`,
+		},
+		{
+			name:        "test1 missing attachment permission",
+			status:      http.StatusOK,
+			body:        `{"key":"OPS","operations":[{"operation":"read","targetType":"space"},{"operation":"create","targetType":"page"}]}`,
+			wantMissing: []string{CreateAttachmentPermission},
+		},
+		{
+			name:        "test2 hidden or missing space",
+			status:      http.StatusNotFound,
+			wantMissing: []string{ViewSpacePermission, CreatePagePermission, CreateAttachmentPermission},
+		},
+		{
+			name:    "test3 server error",
+			status:  http.StatusBadRequest,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path != "/rest/api/space/OPS" || r.URL.Query().Get("expand") != "operations" {
+					w.WriteHeader(http.StatusNotFound)
+					return
+				}
+				w.WriteHeader(tt.status)
+				w.Write([]byte(tt.body))
+			}))
+			defer server.Close()
+
+			got, err := CheckSpacePermissions(context.Background(), testLogger(), testClient(), ServerSite(testUser(t, server.URL)), "OPS")
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("CheckSpacePermissions() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got.Missing, tt.wantMissing) {
+				t.Errorf("CheckSpacePermissions() missing = %v, want %v", got.Missing, tt.wantMissing)
+			}
+		})
+	}
+}
@@ -1,29 +1,48 @@
 package credentials
 
 import (
+	"context"
 	"fmt"
 	"github.com/aerospike/aerospike-client-go"
 	"github.com/gin-gonic/gin"
+	"github.com/sajeevany/graph-snapper/internal/audit"
+	"github.com/sajeevany/graph-snapper/internal/config"
 	as "github.com/sajeevany/graph-snapper/internal/db/aerospike"
 	"github.com/sajeevany/graph-snapper/internal/db/aerospike/record"
+	"github.com/sajeevany/graph-snapper/internal/destination"
+	"github.com/sajeevany/graph-snapper/internal/logging"
+	"github.com/sajeevany/graph-snapper/internal/source"
+	"github.com/sajeevany/graph-snapper/internal/upstream"
 	"github.com/sirupsen/logrus"
 	"net/http"
+	"strconv"
 )
 
-const PutCredentialsEndpoint = "/{accountID}"
+const (
+	PutCredentialsEndpoint = "/{accountID}"
+
+	//VerifyQueryParam - when true credentials must pass connectivity checks before they're stored
+	VerifyQueryParam = "verify"
+)
 
 //@Summary Add credentials to an account
-//@Description Non-authenticated endpoint that adds grafana and confluence-server users to an account. Assumes entries are pre-validated
+//@Description Non-authenticated endpoint that adds source and destination credentials keyed by type to an account. Entries are only checked for connectivity when verify is true or verification is required by configuration
 //@Produce json
 //@Param account body SetCredentialsV1 true "Add credentials"
+//@Param verify query bool false "Reject the write unless every credential passes its source or destination access check"
 //@Success 200 {object} SetCredentialsV1
+//@Fail 400 {object} gin.H
 //@Fail 404 {object} gin.H
+//@Fail 422 {object} gin.H
 //@Fail 500 {object} gin.H
 //@Router /account/:id/credentials [put]
 //@Tags account
-func PutCredentialsV1(logger *logrus.Logger, aeroClient *as.ASClient) gin.HandlerFunc {
+func PutCredentialsV1(baseLogger *logrus.Logger, aeroClient *as.ASClient, auditor audit.Sink, client *upstream.Client, checkConf config.CredentialCheck) gin.HandlerFunc {
 	return func(ctx *gin.Context) {
 
+		//Use request scoped logger
+		logger := logging.FromContext(ctx.Request.Context(), baseLogger)
+
 		//Validate that id parameter has been set
 		accountId := ctx.Param("id")
 		if accountId == "" {
@@ -33,6 +52,19 @@ func PutCredentialsV1(logger *logrus.Logger, aeroClient *as.ASClient) gin.Handle
 			return
 		}
 
+		//Verification is mandatory when configured
+		verify := checkConf.VerifyOnWrite
+		if v := ctx.Query(VerifyQueryParam); v != "" && !verify {
+			parsed, pErr := strconv.ParseBool(v)
+			if pErr != nil {
+				msg := fmt.Sprintf("Query parameter %v <%v> is not a boolean", VerifyQueryParam, v)
+				logger.Debug(msg)
+				ctx.JSON(http.StatusBadRequest, gin.H{"error": msg})
+				return
+			}
+			verify = parsed
+		}
+
 		//Bind add credentials object
 		var addReq SetCredentialsV1
 		if bErr := ctx.BindJSON(&addReq); bErr != nil {
@@ -42,13 +74,24 @@ func PutCredentialsV1(logger *logrus.Logger, aeroClient *as.ASClient) gin.Handle
 			return
 		}
 
+		//Decode credentials with their registered types
+		sources, dests, dErr := addReq.decode()
+		if dErr != nil {
+			logger.WithFields(addReq.GetFields()).Errorf("Unable to decode credentials <%v>", dErr)
+			ctx.JSON(http.StatusBadRequest, gin.H{
+				"humanReadableError": decodeErrorMessage(),
+				"error":              dErr.Error(),
+			})
+			return
+		}
+
 		//Validate account. Returns account key since it validates if the record exists
-		vErr, returnCode, actKey, actKeyExists := validateRequest(logger, aeroClient, accountId, addReq)
+		vErr, returnCode, actKey, actKeyExists := validateRequest(ctx.Request.Context(), logger, aeroClient, accountId, sources, dests)
 		if vErr != nil {
 			if actKeyExists {
 				logger.WithFields(addReq.GetFields()).Errorf("Input credentials are invalid <%v>", vErr)
 				ctx.JSON(returnCode, gin.H{
-					"humanReadableError": "Input credentials variables are invalid. Host, user, password, apikey must be non empty. Port must be within 0 and 65535",
+					"humanReadableError": "Input credentials variables are invalid. Host, user, password, apikey must be non empty and each user must set a single auth type. Port must be within 0 and 65535",
 					"error":              vErr.Error(),
 				})
 				return
@@ -69,7 +112,30 @@ func PutCredentialsV1(logger *logrus.Logger, aeroClient *as.ASClient) gin.Handle
 			return
 		}
 
-		rec, aErr := setAccountUsers(logger, aeroClient, addReq, actKey)
+		//Check connectivity before storing
+		var checks record.CredentialChecksV1
+		if verify {
+			var passed bool
+			var cErr error
+			checks, passed, cErr = verifyUsers(ctx.Request.Context(), logger, client, checkConf, sources, dests)
+			if cErr != nil {
+				msg := fmt.Sprintf("Error verifying credentials. <%v>", cErr)
+				logger.Errorf(msg)
+				ctx.JSON(http.StatusInternalServerError, gin.H{"error": msg})
+				return
+			}
+			if !passed {
+				logger.WithFields(addReq.GetFields()).Info("Credentials failed verification. Skipping write")
+				ctx.JSON(http.StatusUnprocessableEntity, gin.H{
+					"humanReadableError": "One or more credentials failed verification. No credentials were stored",
+					"error":              "credential verification failed",
+					"results":            toCheckAccountCredentialsResultV1(checks),
+				})
+				return
+			}
+		}
+
+		rec, prevCreds, aErr := setAccountUsers(ctx.Request.Context(), logger, aeroClient, sources, dests, actKey, checks)
 		if aErr != nil {
 			hMsg := "Internal error when adding users to Aerospike data store"
 			logger.WithFields(addReq.GetFields()).Error(hMsg, aErr)
@@ -78,35 +144,49 @@ func PutCredentialsV1(logger *logrus.Logger, aeroClient *as.ASClient) gin.Handle
 				"error":              aErr.Error()})
 			return
 		}
+
+		//Record credential change
+		audit.Record(ctx, logger, auditor, accountId, audit.CredentialsSetAction, prevCreds.ToAuditMap(), rec.GetCredentials().ToAuditMap())
+
 		ctx.JSON(http.StatusOK, rec.ToRecordViewV1())
 	}
 }
 
 //Checks if input is in acceptable and a record exists with the specified key. Returns a non-zero return code if an error is present. Returns no error and a statusOk(200).
-func validateRequest(logger *logrus.Logger, aeroClient *as.ASClient, accountID string, addReq SetCredentialsV1) (error, int, *aerospike.Key, bool) {
+func validateRequest(ctx context.Context, logger *logrus.Entry, aeroClient *as.ASClient, accountID string, sources map[string]map[string]source.Credential, dests map[string]map[string]destination.Credential) (error, int, *aerospike.Key, bool) {
 
 	//Validate the account info. Checks if record exists with the ID
-	returnCode, aErr, actKey, actKeyExists := validateAcctID(logger, aeroClient, accountID)
+	returnCode, aErr, actKey, actKeyExists := validateAcctID(ctx, logger, aeroClient, accountID)
 	if aErr != nil || !actKeyExists {
 		//if an error occurred or the key doesn't exist return with the http code
 		return aErr, returnCode, nil, actKeyExists
 	}
 
-	//Validate grafana users
-	for _, gUser := range addReq.GrafanaAPIUsers {
-		//Validate input vars but don't validate for connectivity
-		if !gUser.IsValid() {
-			logger.WithFields(gUser.GetFields()).Errorf("Grafana user has invalid attributes")
-			return fmt.Errorf("grafana user <%#v> is invalid", gUser), http.StatusBadRequest, nil, actKeyExists
+	//Validate source credentials with their source's rules but don't validate for connectivity
+	for typeName, creds := range sources {
+		s, exists := source.Get(typeName)
+		if !exists {
+			return fmt.Errorf("unknown source type <%v>", typeName), http.StatusBadRequest, nil, actKeyExists
+		}
+		for name, cred := range creds {
+			if vErr := s.Validate(cred); vErr != nil {
+				logger.WithFields(cred.GetFields()).Errorf("Source credential <%v.%v> has invalid attributes", typeName, name)
+				return vErr, http.StatusBadRequest, nil, actKeyExists
+			}
 		}
 	}
 
-	//Validate confluence users
-	for _, csUser := range addReq.ConfluenceServerUsers {
-		//Validate input vars but don't validate for connectivity
-		if !csUser.IsValid() {
-			logger.WithFields(csUser.GetFields()).Errorf("Confluence user has invalid attributes")
-			return fmt.Errorf("confluence user <%#v> is invalid", csUser), http.StatusBadRequest, nil, actKeyExists
+	//Validate destination credentials with their destination's rules but don't validate for connectivity
+	for typeName, creds := range dests {
+		d, exists := destination.Get(typeName)
+		if !exists {
+			return fmt.Errorf("unknown destination type <%v>", typeName), http.StatusBadRequest, nil, actKeyExists
+		}
+		for name, cred := range creds {
+			if vErr := d.Validate(cred); vErr != nil {
+				logger.WithFields(cred.GetFields()).Errorf("Destination credential <%v.%v> has invalid attributes", typeName, name)
+				return vErr, http.StatusBadRequest, nil, actKeyExists
+			}
 		}
 	}
 
@@ -115,14 +195,14 @@ func validateRequest(logger *logrus.Logger, aeroClient *as.ASClient, accountID s
 }
 
 //Returns error if invalid. int value is the http return code to use
-func validateAcctID(logger *logrus.Logger, aeroClient *as.ASClient, id string) (int, error, *aerospike.Key, bool) {
+func validateAcctID(ctx context.Context, logger *logrus.Entry, aeroClient *as.ASClient, id string) (int, error, *aerospike.Key, bool) {
 
 	if id == "" {
 		return http.StatusBadRequest, fmt.Errorf("account ID is empty and must be defined"), nil, false
 	}
 
 	//check if account exists
-	actExists, actKey, rErr := aeroClient.GetReader().KeyExists(id)
+	actExists, actKey, rErr := aeroClient.GetReader().KeyExists(ctx, id)
 	if rErr != nil {
 		logger.Errorf("Error when reading from aerospike to check if key exists <%v>", rErr)
 		return http.StatusInternalServerError, rErr, actKey, actExists
@@ -137,25 +217,58 @@ func validateAcctID(logger *logrus.Logger, aeroClient *as.ASClient, id string) (
 	return http.StatusOK, nil, actKey, actExists
 }
 
-//setAccountUsers - adds specified users to the record at the specified account. Assumes that the record at the provided key has already been checked for existence
-func setAccountUsers(logger *logrus.Logger, client *as.ASClient, req SetCredentialsV1, actKey *aerospike.Key) (record.Record, error) {
+//setAccountUsers - adds specified users to the record at the specified account. Assumes that the record at the provided key has already been checked for existence.
+//Returns the updated record and the credentials held before the update
+func setAccountUsers(ctx context.Context, logger *logrus.Entry, client *as.ASClient, sources map[string]map[string]source.Credential, dests map[string]map[string]destination.Credential, actKey *aerospike.Key, checks record.CredentialChecksV1) (record.Record, record.CredentialsV1, error) {
 
 	logger.Debugf("Starting overwrite users to account with id <%v> operation", actKey.String())
 	//Get the current record
-	record, err := client.GetReader().ReadRecord(actKey)
+	rec, err := client.GetReader().ReadRecord(ctx, actKey)
 	if err != nil {
 		logger.Errorf("Failed to read record using key <%v>. err <%v>", actKey.String(), err)
-		return nil, err
+		return nil, record.CredentialsV1{}, err
 	}
+	prevCreds := rec.GetCredentials()
 
 	//Update the local record copy and overwrite it in the db
 	logger.Debugf("Record has been read for account with id <%v>. ", actKey.String())
-	record.SetUserCredentialsV1(logger, req.GrafanaAPIUsers, req.ConfluenceServerUsers)
-	if wErr := client.GetWriter().WriteRecordWithASKey(actKey, record); wErr != nil {
+	rec.SetUserCredentialsV1(logger, sources, dests)
+	rec.SetCredentialChecksV1(checks)
+	if wErr := client.GetWriter().WriteRecordWithASKey(ctx, actKey, rec); wErr != nil {
 		logger.Errorf("Error when writing record to db. err <%v>", wErr)
-		return record, wErr
+		return rec, prevCreds, wErr
 	}
 
 	logger.Debugf("Record written for setAccountUsers pk <%v>", actKey.String())
-	return record, nil
+	return rec, prevCreds, nil
+}
+
+//verifyUsers - Checks every user in the request. Returns the check results keyed by user name and true if all passed
+func verifyUsers(ctx context.Context, logger *logrus.Entry, client *upstream.Client, checkConf config.CredentialCheck, sources map[string]map[string]source.Credential, dests map[string]map[string]destination.Credential) (record.CredentialChecksV1, bool, error) {
+
+	creds := record.CredentialsV1{
+		Sources:      sources,
+		Destinations: dests,
+	}
+	names, checkReq, sErr := selectStoredUsers(creds, CheckAccountCredentialsV1{})
+	if sErr != nil {
+		return record.CredentialChecksV1{}, false, sErr
+	}
+
+	checks, cErr := checkStoredUsers(ctx, logger, client, checkConf, names, checkReq)
+	if cErr != nil {
+		return record.CredentialChecksV1{}, false, cErr
+	}
+
+	for _, all := range []map[string]map[string]record.CredentialCheckV1{checks.Sources, checks.Destinations} {
+		for _, typeChecks := range all {
+			for _, c := range typeChecks {
+				if !c.Result {
+					return checks, false, nil
+				}
+			}
+		}
+	}
+
+	return checks, true, nil
 }
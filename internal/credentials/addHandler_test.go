@@ -5,20 +5,29 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	aero "github.com/aerospike/aerospike-client-go"
 	"github.com/davecgh/go-spew/spew"
 	"github.com/gin-gonic/gin"
 	jsoniter "github.com/json-iterator/go"
 	"github.com/sajeevany/graph-snapper/internal/account"
+	"github.com/sajeevany/graph-snapper/internal/audit"
 	"github.com/sajeevany/graph-snapper/internal/common"
+	"github.com/sajeevany/graph-snapper/internal/config"
+	"github.com/sajeevany/graph-snapper/internal/confluence"
 	"github.com/sajeevany/graph-snapper/internal/db/aerospike"
 	"github.com/sajeevany/graph-snapper/internal/db/aerospike/record"
+	"github.com/sajeevany/graph-snapper/internal/destination"
+	"github.com/sajeevany/graph-snapper/internal/grafana"
 	"github.com/sajeevany/graph-snapper/internal/logging"
+	"github.com/sajeevany/graph-snapper/internal/source"
 	"github.com/sajeevany/graph-snapper/internal/test"
+	"github.com/sajeevany/graph-snapper/internal/upstream"
 	"github.com/sirupsen/logrus"
 	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
 	"reflect"
+	"strings"
 	"testing"
 	"time"
 )
@@ -32,6 +41,7 @@ func TestPutCredentialsV1Integration(t *testing.T) {
 	}
 
 	//Setup common requirements. In this case it's a specific aerospike image.
+	registerTypes()
 	ctx := context.Background()
 	aeroContainer, aeroClient := test.StartAerospikeTestContainer(t, ctx)
 	defer aeroContainer.Terminate(ctx)
@@ -44,7 +54,7 @@ func TestPutCredentialsV1Integration(t *testing.T) {
 	//Scenarios
 	tests := []struct {
 		name      string
-		setup     func(logger *logrus.Logger, client *aerospike.ASClient, accountKey string)
+		setup     func(ctx context.Context, logger *logrus.Logger, client *aerospike.ASClient, accountKey string)
 		cleanup   func(client *aerospike.ASClient)
 		accountID string
 		request   SetCredentialsV1
@@ -52,7 +62,7 @@ func TestPutCredentialsV1Integration(t *testing.T) {
 	}{
 		{
 			name: "test0 PutCredentialsV1 happy path",
-			setup: func(logger *logrus.Logger, client *aerospike.ASClient, accountKey string) {
+			setup: func(ctx context.Context, logger *logrus.Logger, client *aerospike.ASClient, accountKey string) {
 				recReq := record.AccountViewV1{
 					Email: "testUser@graphSnapper.com",
 					Alias: "Admin config account",
@@ -62,7 +72,7 @@ func TestPutCredentialsV1Integration(t *testing.T) {
 					Alias: "Admin config account",
 				}
 				//Create account
-				rec, err := account.CreateAccount(logger, client, accountKey, recReq)
+				rec, err := account.CreateAccount(ctx, logrus.NewEntry(logger), client, accountKey, recReq)
 				if err != nil {
 					t.Errorf("SETUP FAILURE: An error occurred when creating a new account record <%#v>, err <%v>", recReq, err)
 				}
@@ -135,6 +145,40 @@ func TestPutCredentialsV1Integration(t *testing.T) {
 							},
 						},
 					},
+					Sources: map[string]map[string]record.CredentialView{
+						grafana.SourceType: {
+							"gu_0": {
+								Credential: toJSONValue(t, common.GrafanaUserV1{
+									Auth: common.Auth{
+										BearerToken: common.BearerToken{
+											Token: logging.RedactNonEmpty("gu0APIToken"),
+										},
+										Basic: common.Basic{},
+									},
+									Host:        "test0.grafanahost.com",
+									Port:        8565,
+									Description: "test0 grafana auth",
+								}),
+							},
+						},
+					},
+					Destinations: map[string]map[string]record.CredentialView{
+						confluence.ServerDestinationType: {
+							"csu_0": {
+								Credential: toJSONValue(t, common.ConfluenceServerUserV1{
+									Host:        "test0.host.com",
+									Port:        9220,
+									Description: "test0 confluence",
+									Auth: common.Auth{
+										Basic: common.Basic{
+											Username: logging.RedactNonEmpty("confluenceUsername"),
+											Password: logging.RedactNonEmpty("confluencePassword"),
+										},
+									},
+								}),
+							},
+						},
+					},
 				},
 			},
 		},
@@ -143,7 +187,7 @@ func TestPutCredentialsV1Integration(t *testing.T) {
 		t.Run(tt.name, func(t *testing.T) {
 			//setup and queue cleanup
 			logger := logrus.New()
-			tt.setup(logger, aeroClient, tt.accountID)
+			tt.setup(ctx, logger, aeroClient, tt.accountID)
 			defer tt.cleanup(aeroClient)
 
 			//Build request
@@ -161,7 +205,7 @@ func TestPutCredentialsV1Integration(t *testing.T) {
 			w := httptest.NewRecorder()
 			gin.SetMode(gin.TestMode)
 			_, r := gin.CreateTestContext(w)
-			r.PUT("/api/v1/account/:id/credentials", PutCredentialsV1(logger, aeroClient))
+			r.PUT("/api/v1/account/:id/credentials", PutCredentialsV1(logger, aeroClient, audit.NewMemorySink(), upstream.New(config.NewConfWithDefaults().Upstream), config.NewConfWithDefaults().CredentialCheck))
 
 			//Run Test
 			r.ServeHTTP(w, req)
@@ -188,3 +232,235 @@ func TestPutCredentialsV1Integration(t *testing.T) {
 		})
 	}
 }
+
+//TestPutCredentialsV1IntegrationDoesNotLeak - forces write and decode failures of records holding credential secrets and
+//scans the logs and response for them
+func TestPutCredentialsV1IntegrationDoesNotLeak(t *testing.T) {
+
+	//Skip test if user wants to only run regression tests
+	if testing.Short() {
+		t.Skip()
+	}
+
+	registerTypes()
+	ctx := context.Background()
+	aeroContainer, aeroClient := test.StartAerospikeTestContainer(t, ctx)
+	defer aeroContainer.Terminate(ctx)
+
+	const secret = "hunter2-password"
+	createAccount := func(t *testing.T, client *aerospike.ASClient, accountID string) {
+		if _, err := account.CreateAccount(ctx, logrus.NewEntry(logrus.New()), client, accountID, record.AccountViewV1{Email: "testUser@graphSnapper.com"}); err != nil {
+			t.Fatalf("SETUP FAILURE: unable to create account <%v>. err <%v>", accountID, err)
+		}
+	}
+
+	tests := []struct {
+		name        string
+		setup       func(t *testing.T, client *aerospike.ASClient, accountID string)
+		description string
+	}{
+		{
+			//Aerospike rejects records larger than its write block
+			name:        "test0 write failure",
+			setup:       createAccount,
+			description: strings.Repeat("x", 2<<20),
+		},
+		{
+			name: "test1 stored record can't be decoded",
+			setup: func(t *testing.T, client *aerospike.ASClient, accountID string) {
+				createAccount(t, client, accountID)
+				key, err := aero.NewKey(client.AccountNamespace.Namespace, client.AccountNamespace.SetName, accountID)
+				if err != nil {
+					t.Fatal(err)
+				}
+				bin := aero.NewBin(record.AccountBinName, map[string]interface{}{"Email": []interface{}{secret}})
+				if err := client.Client.PutBins(nil, key, bin); err != nil {
+					t.Fatalf("SETUP FAILURE: unable to write invalid account bin. err <%v>", err)
+				}
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ns := aeroClient.AccountNamespace
+			defer func() {
+				tyme := time.Now()
+				aeroClient.Client.Truncate(nil, ns.Namespace, ns.SetName, &tyme)
+			}()
+			tt.setup(t, aeroClient, "abc")
+
+			var logs bytes.Buffer
+			logger := logging.Init()
+			logger.SetOutput(&logs)
+			logger.SetLevel(logrus.DebugLevel)
+
+			request := SetCredentialsV1{
+				Destinations: map[string]map[string]json.RawMessage{
+					confluence.ServerDestinationType: {
+						"csu_0": toRawMessage(t, common.ConfluenceServerUserV1{
+							Host:        "test0.host.com",
+							Port:        9220,
+							Description: tt.description,
+							Auth:        common.Auth{Basic: common.Basic{Username: "confluenceUsername", Password: secret}},
+						}),
+					},
+				},
+			}
+			j, mErr := jsoniter.Marshal(request)
+			if mErr != nil {
+				t.Fatalf("Error marshalling request <%v>", mErr)
+			}
+			req := httptest.NewRequest(http.MethodPut, "/api/v1/account/abc/credentials", bytes.NewBuffer(j))
+			req.Header.Add("Content-Type", "application/json")
+
+			w := httptest.NewRecorder()
+			gin.SetMode(gin.TestMode)
+			_, r := gin.CreateTestContext(w)
+			r.PUT("/api/v1/account/:id/credentials", PutCredentialsV1(logger, aeroClient, audit.NewMemorySink(), upstream.New(config.NewConfWithDefaults().Upstream), config.NewConfWithDefaults().CredentialCheck))
+			r.ServeHTTP(w, req)
+
+			if w.Code != http.StatusInternalServerError {
+				t.Errorf("Incorrect return code. Expected <%v> got <%v>", http.StatusInternalServerError, w.Code)
+			}
+			if strings.Contains(w.Body.String(), secret) {
+				t.Errorf("Response leaked the secret: %v", w.Body.String())
+			}
+			if strings.Contains(logs.String(), secret) {
+				t.Errorf("Logs leaked the secret")
+			}
+		})
+	}
+}
+
+func TestSetCredentialsV1_decodeLegacyUsers(t *testing.T) {
+
+	registerTypes()
+	grafanaUser := common.GrafanaUserV1{Auth: common.Auth{BearerToken: common.BearerToken{Token: "abc"}}, Host: "grafana", Port: 3000}
+	confluenceUser := common.ConfluenceServerUserV1{Auth: common.Auth{BearerToken: common.BearerToken{Token: "def"}}, Host: "confluence", Port: 8090}
+
+	//V1 clients written before credentials were keyed by type
+	var req SetCredentialsV1
+	body := `{"GrafanaAPIUsers":{"ops":{"Auth":{"BearerToken":{"Token":"abc"}},"Host":"grafana","Port":3000}},
+		"ConfluenceServerUsers":{"wiki":{"Auth":{"BearerToken":{"Token":"def"}},"Host":"confluence","Port":8090}}}`
+	if err := json.Unmarshal([]byte(body), &req); err != nil {
+		t.Fatalf("Unmarshal() unexpected error = %v", err)
+	}
+	if req.HasNoUsers() {
+		t.Errorf("HasNoUsers() = true for a request with deprecated user maps")
+	}
+	sources, dests, err := req.decode()
+	if err != nil {
+		t.Fatalf("decode() unexpected error = %v", err)
+	}
+	if got := sources[grafana.SourceType]["ops"]; !reflect.DeepEqual(got, grafanaUser) {
+		t.Errorf("decode() grafana source = %+v, want %+v", got, grafanaUser)
+	}
+	if got := dests[confluence.ServerDestinationType]["wiki"]; !reflect.DeepEqual(got, confluenceUser) {
+		t.Errorf("decode() confluence destination = %+v, want %+v", got, confluenceUser)
+	}
+
+	//A credential can't be set by both its type and the deprecated map
+	req.Sources = map[string]map[string]json.RawMessage{grafana.SourceType: {"ops": json.RawMessage(`{"Auth":{"BearerToken":{"Token":"abc"}},"Host":"grafana","Port":3000}`)}}
+	if _, _, err := req.decode(); err == nil {
+		t.Errorf("decode() of a credential set twice expected error")
+	}
+}
+
+func Test_verifyUsers(t *testing.T) {
+
+	registerTypes()
+
+	//Upstream accepting a single token for both grafana and confluence checks
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer valid" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.Write([]byte(`{"accessMode":"READ_WRITE"}`))
+	}))
+	defer server.Close()
+	host, port := test.SplitServerURL(t, server.URL)
+	auth := func(token string) common.Auth {
+		return common.Auth{BearerToken: common.BearerToken{Token: token}}
+	}
+
+	tests := []struct {
+		name       string
+		grafana    map[string]source.Credential
+		dests      map[string]map[string]destination.Credential
+		wantPassed bool
+		wantFailed []string
+	}{
+		{
+			name:    "test0 all users pass",
+			grafana: map[string]source.Credential{"gu_0": common.GrafanaUserV1{Auth: auth("valid"), Host: host, Port: port}},
+			dests: map[string]map[string]destination.Credential{
+				confluence.ServerDestinationType: {"csu_0": common.ConfluenceServerUserV1{Auth: auth("valid"), Host: host, Port: port}},
+			},
+			wantPassed: true,
+		},
+		{
+			name: "test1 typo'd api key is reported",
+			grafana: map[string]source.Credential{
+				"gu_0": common.GrafanaUserV1{Auth: auth("valid"), Host: host, Port: port},
+				"gu_1": common.GrafanaUserV1{Auth: auth("vaild"), Host: host, Port: port},
+			},
+			wantPassed: false,
+			wantFailed: []string{"gu_1"},
+		},
+		{
+			name:    "test2 rejected destination credential is reported",
+			grafana: map[string]source.Credential{"gu_0": common.GrafanaUserV1{Auth: auth("valid"), Host: host, Port: port}},
+			dests: map[string]map[string]destination.Credential{
+				confluence.ServerDestinationType: {"csu_0": common.ConfluenceServerUserV1{Auth: auth("vaild"), Host: host, Port: port}},
+			},
+			wantPassed: false,
+			wantFailed: []string{"csu_0"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sources := map[string]map[string]source.Credential{grafana.SourceType: tt.grafana}
+			checks, passed, err := verifyUsers(context.Background(), logrus.NewEntry(logrus.New()), upstream.New(config.NewConfWithDefaults().Upstream), config.NewConfWithDefaults().CredentialCheck, sources, tt.dests)
+			if err != nil {
+				t.Fatalf("verifyUsers() unexpected error = %v", err)
+			}
+			if passed != tt.wantPassed {
+				t.Errorf("verifyUsers() passed = %v, want %v", passed, tt.wantPassed)
+			}
+			var failed []string
+			for name, c := range checks.Sources[grafana.SourceType] {
+				if !c.Result {
+					failed = append(failed, name)
+				}
+			}
+			for name, c := range checks.Destinations[confluence.ServerDestinationType] {
+				if !c.Result {
+					failed = append(failed, name)
+				}
+			}
+			if !reflect.DeepEqual(failed, tt.wantFailed) {
+				t.Errorf("verifyUsers() failed users = %v, want %v", failed, tt.wantFailed)
+			}
+		})
+	}
+}
+
+//toRawMessage - returns the request body json of a source or destination credential
+func toRawMessage(t *testing.T, v interface{}) json.RawMessage {
+	data, err := json.Marshal(v)
+	if err != nil {
+		t.Fatalf("Unable to marshal <%v>. err <%v>", v, err)
+	}
+	return data
+}
+
+//toJSONValue - returns v as it's read back from a json response
+func toJSONValue(t *testing.T, v interface{}) interface{} {
+	var val interface{}
+	if err := json.Unmarshal(toRawMessage(t, v), &val); err != nil {
+		t.Fatalf("Unable to unmarshal <%v>. err <%v>", v, err)
+	}
+	return val
+}
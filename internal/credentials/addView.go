@@ -1,37 +1,163 @@
 package credentials
 
 import (
+	"encoding/json"
+	"fmt"
 	"github.com/sajeevany/graph-snapper/internal/common"
+	"github.com/sajeevany/graph-snapper/internal/confluence"
+	"github.com/sajeevany/graph-snapper/internal/destination"
+	"github.com/sajeevany/graph-snapper/internal/grafana"
+	"github.com/sajeevany/graph-snapper/internal/plugin"
+	"github.com/sajeevany/graph-snapper/internal/source"
 	"github.com/sirupsen/logrus"
+	"sort"
 )
 
-//AddedCredentials - Users added to specified account
+//AddedCredentials - Users added to specified account. The user maps are the fields of V1 clients written before
+//credentials were keyed by type. They're stored as credentials of the matching source or destination type
 type SetCredentialsV1 struct {
-	GrafanaAPIUsers       map[string]common.GrafanaUserV1          `json:"GrafanaAPIUsers"`
-	ConfluenceServerUsers map[string]common.ConfluenceServerUserV1 `json:"ConfluenceServerUsers"`
+	Sources      map[string]map[string]json.RawMessage `json:"Sources" swaggertype:"object"`      //Source credentials keyed by source type then credential name. ie grafana or prometheus
+	Destinations map[string]map[string]json.RawMessage `json:"Destinations" swaggertype:"object"` //Destination credentials keyed by destination type then credential name. ie confluence-server, confluence-cloud, s3 or html-report
+
+	GrafanaAPIUsers       map[string]common.GrafanaUserV1          `json:"GrafanaAPIUsers,omitempty"`       //Deprecated. Use Sources.grafana
+	ConfluenceServerUsers map[string]common.ConfluenceServerUserV1 `json:"ConfluenceServerUsers,omitempty"` //Deprecated. Use Destinations.confluence-server
 }
 
 func (a SetCredentialsV1) GetFields() logrus.Fields {
+	//Credentials aren't decoded yet so only their names are logged
+	legacySources, legacyDests := a.legacyCredentials()
+	return logrus.Fields{
+		"Sources":            credentialNames(a.Sources),
+		"Destinations":       credentialNames(a.Destinations),
+		"LegacySources":      legacyNames(legacySources),
+		"LegacyDestinations": legacyNames(legacyDests),
+	}
+}
 
-	//Convert grafana read users to loggable format
-	gru := make(logrus.Fields, len(a.GrafanaAPIUsers))
-	for i, v := range a.GrafanaAPIUsers {
-		gru[i] = v.GetFields()
+//HasNoUsers - returns true if no source or destination credentials were provided
+func (req SetCredentialsV1) HasNoUsers() bool {
+	legacySources, legacyDests := req.legacyCredentials()
+	for _, creds := range req.Sources {
+		if len(creds) != 0 {
+			return false
+		}
+	}
+	for _, creds := range req.Destinations {
+		if len(creds) != 0 {
+			return false
+		}
 	}
+	return len(legacySources) == 0 && len(legacyDests) == 0
+}
 
-	//Convert confluence-server write users to loggable format
-	csu := make(logrus.Fields, len(a.GrafanaAPIUsers))
-	for i, v := range a.ConfluenceServerUsers {
-		csu[i] = v.GetFields()
+//decode - decodes each source and destination credential with its registered type and adds the credentials of the
+//deprecated user maps
+func (req SetCredentialsV1) decode() (map[string]map[string]source.Credential, map[string]map[string]destination.Credential, error) {
+	sources, err := decodeNamedCredentials(req.Sources, "source", source.DecodeJSON)
+	if err != nil {
+		return nil, nil, err
+	}
+	dests, err := decodeNamedCredentials(req.Destinations, "destination", destination.DecodeJSON)
+	if err != nil {
+		return nil, nil, err
 	}
 
-	return logrus.Fields{
-		"GrafanaAPIUsers":       gru,
-		"ConfluenceServerUsers": csu,
+	legacySources, legacyDests := req.legacyCredentials()
+	if err := mergeCredentials(sources, legacySources, "source"); err != nil {
+		return nil, nil, err
+	}
+	if err := mergeCredentials(dests, legacyDests, "destination"); err != nil {
+		return nil, nil, err
 	}
+	return sources, dests, nil
 }
 
-//HasNoUsers - returns true if both grafana and confluence user arrays are empty
-func (req SetCredentialsV1) HasNoUsers() bool {
-	return len(req.GrafanaAPIUsers) == 0 && len(req.ConfluenceServerUsers) == 0
+//legacyCredentials - returns the credentials of the deprecated user maps keyed by type then name. Types without
+//credentials are omitted
+func (req SetCredentialsV1) legacyCredentials() (map[string]map[string]source.Credential, map[string]map[string]destination.Credential) {
+
+	sources := make(map[string]map[string]source.Credential)
+	add := func(all map[string]map[string]plugin.Credential, typeName, name string, cred plugin.Credential) {
+		if all[typeName] == nil {
+			all[typeName] = make(map[string]plugin.Credential)
+		}
+		all[typeName][name] = cred
+	}
+	for name, u := range req.GrafanaAPIUsers {
+		add(sources, grafana.SourceType, name, u)
+	}
+
+	dests := make(map[string]map[string]destination.Credential)
+	for name, u := range req.ConfluenceServerUsers {
+		add(dests, confluence.ServerDestinationType, name, u)
+	}
+	return sources, dests
+}
+
+//mergeCredentials - adds the legacy credentials to all. Returns an error if a credential is set by both
+func mergeCredentials(all, legacy map[string]map[string]plugin.Credential, kind string) error {
+	for typeName, creds := range legacy {
+		if all[typeName] == nil {
+			all[typeName] = make(map[string]plugin.Credential, len(creds))
+		}
+		for name, cred := range creds {
+			if _, exists := all[typeName][name]; exists {
+				return fmt.Errorf("%v credential <%v.%v> is set by both the %v type and its deprecated user map", kind, typeName, name, kind)
+			}
+			all[typeName][name] = cred
+		}
+	}
+	return nil
+}
+
+//decodeNamedCredentials - decodes credentials keyed by type then name. Types without credentials are omitted
+func decodeNamedCredentials(raw map[string]map[string]json.RawMessage, kind string, decode func(string, []byte) (plugin.Credential, error)) (map[string]map[string]plugin.Credential, error) {
+	all := make(map[string]map[string]plugin.Credential, len(raw))
+	for typeName, creds := range raw {
+		if len(creds) == 0 {
+			continue
+		}
+		all[typeName] = make(map[string]plugin.Credential, len(creds))
+		for name, data := range creds {
+			cred, err := decode(typeName, data)
+			if err != nil {
+				return nil, fmt.Errorf("%v credential <%v.%v> is invalid. %v", kind, typeName, name, err)
+			}
+			all[typeName][name] = cred
+		}
+	}
+	return all, nil
+}
+
+//credentialNames - returns the sorted credential names of each type
+func credentialNames(raw map[string]map[string]json.RawMessage) logrus.Fields {
+	fields := make(logrus.Fields, len(raw))
+	for typeName, creds := range raw {
+		names := make([]string, 0, len(creds))
+		for name := range creds {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		fields[typeName] = names
+	}
+	return fields
+}
+
+//legacyNames - returns the sorted names of each type's decoded credentials
+func legacyNames(all map[string]map[string]plugin.Credential) logrus.Fields {
+	fields := make(logrus.Fields, len(all))
+	for typeName, creds := range all {
+		names := make([]string, 0, len(creds))
+		for name := range creds {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		fields[typeName] = names
+	}
+	return fields
+}
+
+//decodeErrorMessage - human readable error returned when credentials can't be decoded
+func decodeErrorMessage() string {
+	return fmt.Sprintf("Credentials couldn't be decoded. Supported source types are %v and destination types are %v", source.Types(), destination.Types())
 }
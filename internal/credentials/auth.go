@@ -1,90 +1,63 @@
 package credentials
 
 import (
-	"github.com/sajeevany/graph-snapper/internal/confluence"
-	"github.com/sajeevany/graph-snapper/internal/grafana"
+	"context"
+	"fmt"
+	"github.com/sajeevany/graph-snapper/internal/plugin"
 	"github.com/sirupsen/logrus"
+	"sync"
+	"time"
 )
 
-func authGrafanaUsers(logger *logrus.Logger, users []CheckUserV1) []CheckUserResultV1 {
+//check - runs a single credential check. Source and destination checks share the same workers
+type check func(ctx context.Context, logger *logrus.Entry) plugin.CheckResultV1
 
-	results := make([]CheckUserResultV1, len(users))
+//accessCheck - access check of a registered source or destination
+type accessCheck func(ctx context.Context, logger *logrus.Entry, cred plugin.Credential) plugin.CheckResultV1
 
-	logger.Debug("authenticating grafana users")
-	for i, user := range users {
-		results[i] = authenticateGrafanaUser(logger, user)
-	}
-	logger.Debug("done authenticating grafana users")
-
-	return results
-}
-
-func authenticateGrafanaUser(logger *logrus.Logger, gu CheckUserV1) CheckUserResultV1 {
-
-	isValid, rErr := grafana.IsValidLogin(logger, gu.Auth, gu.Host, gu.Port)
-	logger.Infof("Received %v %v for %+v", isValid, rErr, gu)
-	if rErr != nil {
-		logger.WithFields(gu.GetFields()).Errorf("Error checking if grafana user has login access. <%v>", rErr)
-		return CheckUserResultV1{
-			Result:      false,
-			Cause:       rErr.Error(),
-			CheckUserV1: gu,
-		}
-	}
-
-	if isValid {
-		return CheckUserResultV1{
-			Result:      true,
-			CheckUserV1: gu,
-		}
-	} else {
-		return CheckUserResultV1{
-			Result:      false,
-			Cause:       "Unauthorized. Received 401",
-			CheckUserV1: gu,
+//newChecks - returns an access check per credential
+func newChecks(access accessCheck, creds []plugin.Credential) []check {
+	checks := make([]check, len(creds))
+	for i := range creds {
+		cred := creds[i]
+		checks[i] = func(ctx context.Context, logger *logrus.Entry) plugin.CheckResultV1 {
+			return access(ctx, logger, cred)
 		}
 	}
+	return checks
 }
 
-func authConfluenceUsers(logger *logrus.Logger, users []CheckUserV1) []CheckUserResultV1 {
+//runChecks - Runs the checks with at most workers in flight. Results are returned in the same order as checks. Checks
+//which haven't started when ctx is done are reported as failed without contacting the upstream service
+func runChecks(ctx context.Context, logger *logrus.Entry, workers int, checks []check) []plugin.CheckResultV1 {
 
-	results := make([]CheckUserResultV1, len(users))
+	results := make([]plugin.CheckResultV1, len(checks))
+	sem := make(chan struct{}, workers)
+	var wg sync.WaitGroup
 
-	logger.Debug("authenticating confluence server users")
-	for i, user := range users {
-		results[i] = authenticateConfluenceUser(logger, user)
-	}
-	logger.Debug("done authenticating confluence server users")
+	logger.Debugf("running <%v> credential checks with <%v> workers", len(checks), workers)
+	for i, c := range checks {
 
-	return results
-}
+		//Wait for a free worker
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+			results[i] = plugin.Failed(fmt.Sprintf("Check was not started before the deadline. <%v>", ctx.Err()))
+			continue
+		}
 
-func authenticateConfluenceUser(logger *logrus.Logger, cu CheckUserV1) CheckUserResultV1 {
+		wg.Add(1)
+		go func(i int, c check) {
+			defer wg.Done()
+			defer func() { <-sem }()
 
-	hasWriteAccess, rErr := confluence.HasWriteAccess(logger, cu.Host, cu.Port, cu.Auth)
-	if rErr != nil {
-		logger.WithFields(cu.GetFields()).Errorf("Error checking if confluence user has write access. <%v>", rErr)
-		return CheckUserResultV1{
-			Result:      false,
-			Cause:       rErr.Error(),
-			CheckUserV1: cu,
-		}
+			start := time.Now()
+			results[i] = c(ctx, logger)
+			results[i].LatencyMS = time.Since(start).Milliseconds()
+		}(i, c)
 	}
+	wg.Wait()
+	logger.Debug("done running credential checks")
 
-	//Check if user has write access
-	if hasWriteAccess {
-		logger.Debug("User has write access")
-		return CheckUserResultV1{
-			Result:      true,
-			CheckUserV1: cu,
-		}
-	} else {
-		//Confluence returns
-		logger.Debug("User does not have write access")
-		return CheckUserResultV1{
-			Result:      false,
-			Cause:       "User does not have access mode READ_WRITE",
-			CheckUserV1: cu,
-		}
-	}
+	return results
 }
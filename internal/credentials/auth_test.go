@@ -3,7 +3,12 @@ package credentials
 import (
 	"context"
 	"github.com/sajeevany/graph-snapper/internal/common"
+	"github.com/sajeevany/graph-snapper/internal/config"
+	"github.com/sajeevany/graph-snapper/internal/destination"
+	"github.com/sajeevany/graph-snapper/internal/grafana"
+	"github.com/sajeevany/graph-snapper/internal/plugin"
 	"github.com/sajeevany/graph-snapper/internal/test"
+	"github.com/sajeevany/graph-snapper/internal/upstream"
 	"github.com/sirupsen/logrus"
 	"reflect"
 	"testing"
@@ -22,20 +27,20 @@ func Test_authGrafanaUsersIntegration(t *testing.T) {
 	defer grafanaC.Terminate(ctx)
 
 	type args struct {
-		logger *logrus.Logger
-		users  []CheckUserV1
+		logger *logrus.Entry
+		users  []destination.Credential
 	}
 	tests := []struct {
 		name string
 		args args
-		want []CheckUserResultV1
+		want []plugin.CheckResultV1
 	}{
 		{
 			name: "test0 all invalid bearer token users",
 			args: args{
-				logger: logrus.New(),
-				users: []CheckUserV1{
-					{
+				logger: logrus.NewEntry(logrus.New()),
+				users: []destination.Credential{
+					common.GrafanaUserV1{
 						Auth: common.Auth{
 							BearerToken: common.BearerToken{
 								Token: "invalid token 1",
@@ -44,7 +49,7 @@ func Test_authGrafanaUsersIntegration(t *testing.T) {
 						Host: grafanaIP,
 						Port: grafanaPortInt,
 					},
-					{
+					common.GrafanaUserV1{
 						Auth: common.Auth{
 							BearerToken: common.BearerToken{
 								Token: "invalid token 2",
@@ -55,38 +60,26 @@ func Test_authGrafanaUsersIntegration(t *testing.T) {
 					},
 				},
 			},
-			want: []CheckUserResultV1{
+			want: []plugin.CheckResultV1{
 				{
 					Result: false,
 					Cause:  "Unauthorized. Received 401",
-					CheckUserV1: CheckUserV1{
-						Auth: common.Auth{
-							BearerToken: common.BearerToken{
-								Token: "invalid token 1",
-							},
-						},
-						Host: grafanaIP,
-						Port: grafanaPortInt,
-					},
 				}, {
 					Result: false,
 					Cause:  "Unauthorized. Received 401",
-					CheckUserV1: CheckUserV1{
-						Auth: common.Auth{
-							BearerToken: common.BearerToken{
-								Token: "invalid token 2",
-							},
-						},
-						Host: grafanaIP,
-						Port: grafanaPortInt,
-					},
 				},
 			},
 		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			got := authGrafanaUsers(tt.args.logger, tt.args.users)
+			s := grafana.NewSource(upstream.New(config.NewConfWithDefaults().Upstream))
+			got := runChecks(ctx, tt.args.logger, 2, newChecks(s.CheckAccess, tt.args.users))
+
+			//Latency varies between runs
+			for i := range got {
+				got[i].LatencyMS = 0
+			}
 			if !reflect.DeepEqual(got, tt.want) {
 				t.Errorf("authGrafanaUsers() got = %v, want %v", got, tt.want)
 			}
@@ -0,0 +1,203 @@
+package credentials
+
+import (
+	"context"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"github.com/sajeevany/graph-snapper/internal/config"
+	as "github.com/sajeevany/graph-snapper/internal/db/aerospike"
+	"github.com/sajeevany/graph-snapper/internal/db/aerospike/record"
+	"github.com/sajeevany/graph-snapper/internal/logging"
+	"github.com/sajeevany/graph-snapper/internal/plugin"
+	"github.com/sajeevany/graph-snapper/internal/upstream"
+	"github.com/sirupsen/logrus"
+	"net/http"
+	"sort"
+	"time"
+)
+
+const CheckAccountCredentialsEndpoint = "/:id/credentials/check"
+
+//@Summary Check stored credentials of an account
+//@Description Non-authenticated endpoint that checks all or the named source and destination credentials stored on an account. The result and time of each check is stored and returned by GET /account/:id
+//@Produce json
+//@Param id path string true "Account ID"
+//@Param credentials body CheckAccountCredentialsV1 false "Names of credentials to check. Checks all when empty"
+//@Success 200 {object} CheckAccountCredentialsResultV1
+//@Fail 400 {object} gin.H
+//@Fail 404 {object} gin.H
+//@Fail 500 {object} gin.H
+//@Router /account/:id/credentials/check [post]
+//@Tags account
+func CheckAccountV1(baseLogger *logrus.Logger, aeroClient *as.ASClient, client *upstream.Client, checkConf config.CredentialCheck) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+
+		//Use request scoped logger
+		logger := logging.FromContext(ctx.Request.Context(), baseLogger)
+
+		//Bind optional selection of credential names
+		var checkReq CheckAccountCredentialsV1
+		if ctx.Request.ContentLength != 0 {
+			if bErr := ctx.BindJSON(&checkReq); bErr != nil {
+				msg := fmt.Sprintf("Unable to bind request body to CheckAccountCredentialsV1 object %v", bErr)
+				logger.Errorf(msg)
+				ctx.JSON(http.StatusBadRequest, gin.H{"error": msg})
+				return
+			}
+		}
+
+		//Validate account
+		accountID := ctx.Param("id")
+		returnCode, aErr, actKey, _ := validateAcctID(ctx.Request.Context(), logger, aeroClient, accountID)
+		if aErr != nil {
+			logger.Errorf("Account id is invalid <%v>. err <%v>", accountID, aErr)
+			ctx.JSON(returnCode, gin.H{
+				"humanReadableError": fmt.Sprintf("No account exists with ID %v", accountID),
+				"error":              aErr.Error(),
+			})
+			return
+		}
+
+		rec, rErr := aeroClient.GetReader().ReadRecord(ctx.Request.Context(), actKey)
+		if rErr != nil {
+			hMsg := "Internal error when reading account from Aerospike data store"
+			logger.Errorf("%v. err <%v>", hMsg, rErr)
+			ctx.JSON(http.StatusInternalServerError, gin.H{
+				"humanReadableError": hMsg,
+				"error":              rErr.Error()})
+			return
+		}
+
+		//Select users
+		names, creds, sErr := selectStoredUsers(rec.GetCredentials(), checkReq)
+		if sErr != nil {
+			logger.WithFields(checkReq.GetFields()).Debugf("Unknown credentials requested. <%v>", sErr)
+			ctx.JSON(http.StatusNotFound, gin.H{
+				"humanReadableError": "One or more credential names don't exist on the account",
+				"error":              sErr.Error(),
+			})
+			return
+		}
+
+		//Run checks and store results
+		checks, cErr := checkStoredUsers(ctx.Request.Context(), logger, client, checkConf, names, creds)
+		if cErr != nil {
+			msg := fmt.Sprintf("Error validating credentials. <%v>", cErr)
+			logger.Errorf(msg)
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": msg})
+			return
+		}
+		//Only the checks are written so that credentials changed during the checks aren't overwritten
+		if wErr := aeroClient.GetWriter().WriteCredentialChecks(ctx.Request.Context(), actKey, rec.GetCredentials(), checks); wErr != nil {
+			hMsg := "Internal error when storing credential check results"
+			logger.Errorf("%v. err <%v>", hMsg, wErr)
+			ctx.JSON(http.StatusInternalServerError, gin.H{
+				"humanReadableError": hMsg,
+				"error":              wErr.Error()})
+			return
+		}
+
+		ctx.JSON(http.StatusOK, toCheckAccountCredentialsResultV1(checks))
+	}
+}
+
+//storedUserNames - Names of the selected stored users keyed by type in the order they're checked
+type storedUserNames struct {
+	sources      map[string][]string
+	destinations map[string][]string
+}
+
+//selectStoredUsers - Returns the sorted names and check inputs of the selected users. Returns an error naming any
+//requested users which aren't stored
+func selectStoredUsers(stored record.CredentialsV1, req CheckAccountCredentialsV1) (storedUserNames, credentialSet, error) {
+
+	all := req.checkAll()
+	sNames, sources, sMissing := selectTypedUsers(stored.Sources, req.Sources, all, record.SourcesBMKey)
+	dNames, dests, dMissing := selectTypedUsers(stored.Destinations, req.Destinations, all, record.DestinationsBMKey)
+
+	if missing := append(sMissing, dMissing...); len(missing) != 0 {
+		sort.Strings(missing)
+		return storedUserNames{}, credentialSet{}, fmt.Errorf("credentials <%v> don't exist", missing)
+	}
+
+	return storedUserNames{sources: sNames, destinations: dNames}, credentialSet{sources: sources, destinations: dests}, nil
+}
+
+//selectTypedUsers - Returns the sorted names and credentials selected by names or all stored credentials. Missing
+//credentials are returned as <bmKey>.<type>.<name>
+func selectTypedUsers(stored map[string]map[string]plugin.Credential, names map[string][]string, all bool, bmKey string) (map[string][]string, map[string][]plugin.Credential, []string) {
+
+	if all {
+		names = make(map[string][]string, len(stored))
+		for typeName, creds := range stored {
+			for name := range creds {
+				names[typeName] = append(names[typeName], name)
+			}
+		}
+	}
+
+	var missing []string
+	selected := make(map[string][]plugin.Credential, len(names))
+	for typeName, typeNames := range names {
+		sort.Strings(typeNames)
+		for _, name := range typeNames {
+			cred, exists := stored[typeName][name]
+			if !exists {
+				missing = append(missing, fmt.Sprintf("%v.%v.%v", bmKey, typeName, name))
+				continue
+			}
+			selected[typeName] = append(selected[typeName], cred)
+		}
+	}
+	return names, selected, missing
+}
+
+//checkStoredUsers - Checks the users and returns results keyed by the credential names
+func checkStoredUsers(ctx context.Context, logger *logrus.Entry, client *upstream.Client, checkConf config.CredentialCheck, names storedUserNames, creds credentialSet) (record.CredentialChecksV1, error) {
+
+	result, err := validateCredentials(ctx, logger, client, checkConf, creds)
+	if err != nil {
+		return record.CredentialChecksV1{}, err
+	}
+
+	checkedAt := time.Now().UTC().Format(time.RFC3339)
+	return record.CredentialChecksV1{
+		Sources:      toTypedChecks(result.SourceChecks, names.sources, checkedAt),
+		Destinations: toTypedChecks(result.DestinationChecks, names.destinations, checkedAt),
+	}, nil
+}
+
+//toTypedChecks - returns the results keyed by type then credential name
+func toTypedChecks(results map[string][]CredentialCheckResultV1, names map[string][]string, checkedAt string) map[string]map[string]record.CredentialCheckV1 {
+	checks := make(map[string]map[string]record.CredentialCheckV1, len(results))
+	for typeName, typeResults := range results {
+		checks[typeName] = make(map[string]record.CredentialCheckV1, len(typeResults))
+		for i, r := range typeResults {
+			checks[typeName][names[typeName][i]] = record.CredentialCheckV1{
+				Result:    r.Result,
+				Cause:     r.Cause,
+				LatencyMS: r.LatencyMS,
+				CheckedAt: checkedAt,
+			}
+		}
+	}
+	return checks
+}
+
+func toCheckAccountCredentialsResultV1(checks record.CredentialChecksV1) CheckAccountCredentialsResultV1 {
+	return CheckAccountCredentialsResultV1{
+		Sources:      toLastCheckViews(checks.Sources),
+		Destinations: toLastCheckViews(checks.Destinations),
+	}
+}
+
+func toLastCheckViews(checks map[string]map[string]record.CredentialCheckV1) map[string]map[string]record.LastCheckViewV1 {
+	views := make(map[string]map[string]record.LastCheckViewV1, len(checks))
+	for typeName, typeChecks := range checks {
+		views[typeName] = make(map[string]record.LastCheckViewV1, len(typeChecks))
+		for name, c := range typeChecks {
+			views[typeName][name] = record.LastCheckViewV1{Result: c.Result, Cause: c.Cause, LatencyMS: c.LatencyMS, CheckedAt: c.CheckedAt}
+		}
+	}
+	return views
+}
@@ -0,0 +1,217 @@
+package credentials
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"github.com/sajeevany/graph-snapper/internal/account"
+	"github.com/sajeevany/graph-snapper/internal/common"
+	"github.com/sajeevany/graph-snapper/internal/config"
+	"github.com/sajeevany/graph-snapper/internal/confluence"
+	"github.com/sajeevany/graph-snapper/internal/db/aerospike/record"
+	"github.com/sajeevany/graph-snapper/internal/destination"
+	"github.com/sajeevany/graph-snapper/internal/grafana"
+	"github.com/sajeevany/graph-snapper/internal/source"
+	"github.com/sajeevany/graph-snapper/internal/test"
+	"github.com/sajeevany/graph-snapper/internal/upstream"
+	"github.com/sirupsen/logrus"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sync"
+	"testing"
+)
+
+func Test_selectStoredUsers(t *testing.T) {
+
+	stored := record.CredentialsV1{
+		Sources: map[string]map[string]source.Credential{
+			grafana.SourceType: {
+				"gu_1": common.GrafanaUserV1{Host: "grafana1", Port: 3000},
+				"gu_0": common.GrafanaUserV1{Host: "grafana0", Port: 3000},
+			},
+		},
+		Destinations: map[string]map[string]destination.Credential{
+			confluence.ServerDestinationType: {
+				"csu_1": common.ConfluenceServerUserV1{Host: "confluence1", Port: 8090},
+				"csu_0": common.ConfluenceServerUserV1{Host: "confluence0", Port: 8090},
+			},
+			confluence.CloudDestinationType: {
+				"ccu_0": common.ConfluenceCloudUserV1{SiteURL: "https://example.atlassian.net", Email: "user@example.com", APIToken: "token"},
+			},
+		},
+	}
+
+	tests := []struct {
+		name      string
+		req       CheckAccountCredentialsV1
+		wantS     map[string][]string
+		wantD     map[string][]string
+		wantHosts []string
+		wantErr   bool
+	}{
+		{
+			name:  "test0 all users are selected in name order",
+			req:   CheckAccountCredentialsV1{},
+			wantS: map[string][]string{grafana.SourceType: {"gu_0", "gu_1"}},
+			wantD: map[string][]string{
+				confluence.ServerDestinationType: {"csu_0", "csu_1"},
+				confluence.CloudDestinationType:  {"ccu_0"},
+			},
+			wantHosts: []string{"grafana0", "grafana1", "https://example.atlassian.net", "confluence0", "confluence1"},
+		},
+		{
+			name:      "test1 named user",
+			req:       CheckAccountCredentialsV1{Sources: map[string][]string{grafana.SourceType: {"gu_1"}}},
+			wantS:     map[string][]string{grafana.SourceType: {"gu_1"}},
+			wantHosts: []string{"grafana1"},
+		},
+		{
+			name:    "test2 unknown user",
+			req:     CheckAccountCredentialsV1{Destinations: map[string][]string{confluence.ServerDestinationType: {"csu_0", "csu_9"}}},
+			wantErr: true,
+		},
+		{
+			name:      "test3 named destination credential",
+			req:       CheckAccountCredentialsV1{Destinations: map[string][]string{confluence.CloudDestinationType: {"ccu_0"}}},
+			wantD:     map[string][]string{confluence.CloudDestinationType: {"ccu_0"}},
+			wantHosts: []string{"https://example.atlassian.net"},
+		},
+		{
+			name:    "test4 unknown destination type",
+			req:     CheckAccountCredentialsV1{Destinations: map[string][]string{"s3": {"s3_0"}}},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			names, creds, err := selectStoredUsers(stored, tt.req)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("selectStoredUsers() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if len(names.sources) != len(tt.wantS) || (len(tt.wantS) != 0 && !reflect.DeepEqual(names.sources, tt.wantS)) {
+				t.Errorf("selectStoredUsers() source names = %v, want %v", names.sources, tt.wantS)
+			}
+			if len(names.destinations) != len(tt.wantD) || (len(tt.wantD) != 0 && !reflect.DeepEqual(names.destinations, tt.wantD)) {
+				t.Errorf("selectStoredUsers() destination names = %v, want %v", names.destinations, tt.wantD)
+			}
+
+			//Credentials are listed in type then name order
+			var hosts []string
+			for _, u := range creds.sources[grafana.SourceType] {
+				hosts = append(hosts, u.(common.GrafanaUserV1).Host)
+			}
+			for _, u := range creds.destinations[confluence.CloudDestinationType] {
+				hosts = append(hosts, u.(common.ConfluenceCloudUserV1).SiteURL)
+			}
+			for _, u := range creds.destinations[confluence.ServerDestinationType] {
+				hosts = append(hosts, u.(common.ConfluenceServerUserV1).Host)
+			}
+			if !reflect.DeepEqual(hosts, tt.wantHosts) {
+				t.Errorf("selectStoredUsers() hosts = %v, want %v", hosts, tt.wantHosts)
+			}
+		})
+	}
+}
+
+//TestCheckAccountV1Integration - Validates that stored credentials are checked and the results are shown on the account
+func TestCheckAccountV1Integration(t *testing.T) {
+
+	//Skip test if user wants to only run regression tests
+	if testing.Short() {
+		t.Skip()
+	}
+
+	ctx := context.Background()
+	aeroContainer, aeroClient := test.StartAerospikeTestContainer(t, ctx)
+	defer aeroContainer.Terminate(ctx)
+
+	//Grafana accepting a single token. The first check adds a credential to simulate a PUT during the checks
+	var addCredential func()
+	var added sync.Once
+	grafanaServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		added.Do(addCredential)
+		if r.Header.Get("Authorization") != "Bearer valid" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer grafanaServer.Close()
+	host, port := test.SplitServerURL(t, grafanaServer.URL)
+
+	//Create account with a valid and an invalid credential
+	logger := logrus.New()
+	accountID := "checkAccount"
+	rec, err := account.CreateAccount(ctx, logrus.NewEntry(logger), aeroClient, accountID, record.AccountViewV1{Email: "testUser@graphSnapper.com"})
+	if err != nil {
+		t.Fatalf("SETUP FAILURE: Unable to create account. err <%v>", err)
+	}
+	registerTypes()
+	rec.SetUserCredentialsV1(logrus.NewEntry(logger), map[string]map[string]source.Credential{
+		grafana.SourceType: {
+			"valid":   common.GrafanaUserV1{Auth: common.Auth{BearerToken: common.BearerToken{Token: "valid"}}, Host: host, Port: port},
+			"invalid": common.GrafanaUserV1{Auth: common.Auth{BearerToken: common.BearerToken{Token: "typo"}}, Host: host, Port: port},
+		},
+	}, nil)
+	if wErr := aeroClient.GetWriter().WriteRecord(ctx, accountID, rec); wErr != nil {
+		t.Fatalf("SETUP FAILURE: Unable to store credentials. err <%v>", wErr)
+	}
+	addCredential = func() {
+		concurrent := *rec
+		concurrent.Credentials.Sources = map[string]map[string]source.Credential{grafana.SourceType: {"added": common.GrafanaUserV1{Host: host, Port: port}}}
+		for name, cred := range rec.GetCredentials().Sources[grafana.SourceType] {
+			concurrent.Credentials.Sources[grafana.SourceType][name] = cred
+		}
+		if wErr := aeroClient.GetWriter().WriteRecord(ctx, accountID, &concurrent); wErr != nil {
+			t.Errorf("Unable to store credentials during the checks. err <%v>", wErr)
+		}
+	}
+
+	//Run check
+	gin.SetMode(gin.TestMode)
+	w := httptest.NewRecorder()
+	_, r := gin.CreateTestContext(w)
+	r.POST("/api/v1/account/:id/credentials/check", CheckAccountV1(logger, aeroClient, upstream.New(config.NewConfWithDefaults().Upstream), config.NewConfWithDefaults().CredentialCheck))
+	req, _ := http.NewRequest(http.MethodPost, fmt.Sprintf("/api/v1/account/%s/credentials/check", accountID), bytes.NewBufferString(`{}`))
+	req.Header.Add("Content-Type", "application/json")
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Incorrect return code. Expected <%v> got <%v>. body <%v>", http.StatusOK, w.Code, w.Body.String())
+	}
+	var result CheckAccountCredentialsResultV1
+	if uErr := json.Unmarshal(w.Body.Bytes(), &result); uErr != nil {
+		t.Fatalf("Unable to unmarshal response err <%v>", uErr)
+	}
+	if !result.Sources[grafana.SourceType]["valid"].Result || result.Sources[grafana.SourceType]["invalid"].Result {
+		t.Errorf("Unexpected check results <%+v>", result)
+	}
+
+	//Validate stored results
+	_, key, kErr := aeroClient.GetReader().KeyExists(ctx, accountID)
+	if kErr != nil {
+		t.Fatalf("Unable to create key. err <%v>", kErr)
+	}
+	stored, rErr := aeroClient.GetReader().ReadRecord(ctx, key)
+	if rErr != nil {
+		t.Fatalf("Unable to read record. err <%v>", rErr)
+	}
+	view := stored.ToRecordViewV1().Credentials.Sources[grafana.SourceType]
+	if view["valid"].LastCheck == nil || !view["valid"].LastCheck.Result || view["valid"].LastCheck.CheckedAt == "" {
+		t.Errorf("Expected stored passing check for <valid>. got <%+v>", view["valid"].LastCheck)
+	}
+	if view["invalid"].LastCheck == nil || view["invalid"].LastCheck.Result {
+		t.Errorf("Expected stored failing check for <invalid>. got <%+v>", view["invalid"].LastCheck)
+	}
+	//Credentials stored during the checks are kept
+	if addedView, exists := view["added"]; !exists || addedView.LastCheck != nil {
+		t.Errorf("Expected credential added during the checks to be kept without a check. got <%+v> exists <%v>", addedView, exists)
+	}
+}
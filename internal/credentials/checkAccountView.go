@@ -0,0 +1,40 @@
+package credentials
+
+import (
+	"github.com/sajeevany/graph-snapper/internal/db/aerospike/record"
+	"github.com/sirupsen/logrus"
+)
+
+//CheckAccountCredentialsV1 - Names of stored credentials to check. All stored credentials are checked when all are empty
+type CheckAccountCredentialsV1 struct {
+	Sources      map[string][]string `json:"Sources"`      //Source credential names keyed by source type
+	Destinations map[string][]string `json:"Destinations"` //Destination credential names keyed by destination type
+}
+
+func (c CheckAccountCredentialsV1) GetFields() logrus.Fields {
+	return logrus.Fields{
+		"Sources":      c.Sources,
+		"Destinations": c.Destinations,
+	}
+}
+
+//checkAll - returns true if no credential names were selected
+func (c CheckAccountCredentialsV1) checkAll() bool {
+	for _, names := range c.Sources {
+		if len(names) != 0 {
+			return false
+		}
+	}
+	for _, names := range c.Destinations {
+		if len(names) != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+//CheckAccountCredentialsResultV1 - Check result of each selected stored credential keyed by credential name
+type CheckAccountCredentialsResultV1 struct {
+	Sources      map[string]map[string]record.LastCheckViewV1 `json:"Sources"`      //Keyed by source type then credential name
+	Destinations map[string]map[string]record.LastCheckViewV1 `json:"Destinations"` //Keyed by destination type then credential name
+}
@@ -1,10 +1,19 @@
 package credentials
 
 import (
+	"context"
 	"fmt"
 	"github.com/gin-gonic/gin"
+	"github.com/sajeevany/graph-snapper/internal/config"
+	"github.com/sajeevany/graph-snapper/internal/destination"
+	"github.com/sajeevany/graph-snapper/internal/logging"
+	"github.com/sajeevany/graph-snapper/internal/plugin"
+	"github.com/sajeevany/graph-snapper/internal/source"
+	"github.com/sajeevany/graph-snapper/internal/upstream"
 	"github.com/sirupsen/logrus"
 	"net/http"
+	"sort"
+	"time"
 )
 
 const (
@@ -13,7 +22,7 @@ const (
 )
 
 //@Summary Check credentials for validity
-//@Description Non-authenticated endpoint Check credentials for validity. Returns an array of user objects with check result
+//@Description Non-authenticated endpoint Check credentials for validity. Returns the check result of each source and destination credential keyed by type. Users of the deprecated V1 user lists are returned in the matching user check lists
 //@Produce json
 //@Param credentials body CheckCredentialsV1 true "Check credentials"
 //@Success 200 {object} CheckUsersResultV1
@@ -21,8 +30,11 @@ const (
 //@Fail 500 {object} gin.H
 //@Router /credentials/check [post]
 //@Tags credentials
-func CheckV1(logger *logrus.Logger) gin.HandlerFunc {
+func CheckV1(baseLogger *logrus.Logger, client *upstream.Client, checkConf config.CredentialCheck) gin.HandlerFunc {
 	return func(ctx *gin.Context) {
+
+		//Use request scoped logger
+		logger := logging.FromContext(ctx.Request.Context(), baseLogger)
 		logger.Debug("Received check credentials request")
 
 		//Bind credentials object
@@ -34,33 +46,92 @@ func CheckV1(logger *logrus.Logger) gin.HandlerFunc {
 			return
 		}
 
+		//Decode credentials with their registered types
+		set, dErr := creds.decode()
+		if dErr != nil {
+			logger.Debugf("Unable to decode credentials. <%v>", dErr)
+			ctx.JSON(http.StatusBadRequest, gin.H{
+				"humanReadableError": decodeErrorMessage(),
+				"error":              dErr.Error(),
+			})
+			return
+		}
+
 		//Validate credentials
-		result, err := validateCredentials(logger, creds)
+		result, err := validateCredentials(ctx.Request.Context(), logger, client, checkConf, set)
 		if err != nil {
 			msg := fmt.Sprintf("Error validating credentials. <%v>", err)
 			logger.Errorf(msg)
 			ctx.JSON(http.StatusInternalServerError, gin.H{"error": msg})
 			return
 		}
+		creds.moveLegacyResults(&result)
 
 		ctx.JSON(http.StatusOK, result)
 	}
 }
 
-func validateCredentials(logger *logrus.Logger, creds CheckCredentialsV1) (CheckUsersResultV1, error) {
+//validateCredentials - Checks all users concurrently within the configured deadline. Results are returned in request order
+func validateCredentials(ctx context.Context, logger *logrus.Entry, client *upstream.Client, checkConf config.CredentialCheck, creds credentialSet) (CheckUsersResultV1, error) {
 
 	logger.Debug("Started credentials validation")
-	result := CheckUsersResultV1{}
 
-	//Check grafana users
-	if len(creds.GrafanaReadUsers) != 0 {
-		result.GrafanaReadUserCheck = authGrafanaUsers(logger, creds.GrafanaReadUsers)
+	ctx, cancel := context.WithTimeout(ctx, time.Duration(checkConf.TimeoutMS)*time.Millisecond)
+	defer cancel()
+
+	//Share workers between source and destination checks. Types are checked in name order
+	sourceTypes, destTypes := sortedTypes(creds.sources), sortedTypes(creds.destinations)
+	var checks []check
+	for _, typeName := range sourceTypes {
+		s, exists := source.Get(typeName)
+		if !exists {
+			return CheckUsersResultV1{}, fmt.Errorf("unknown source type <%v>", typeName)
+		}
+		checks = append(checks, newChecks(s.CheckAccess, creds.sources[typeName])...)
+	}
+	for _, typeName := range destTypes {
+		d, exists := destination.Get(typeName)
+		if !exists {
+			return CheckUsersResultV1{}, fmt.Errorf("unknown destination type <%v>", typeName)
+		}
+		checks = append(checks, newChecks(d.CheckAccess, creds.destinations[typeName])...)
 	}
+	results := runChecks(ctx, logger, checkConf.Workers, checks)
 
-	//Check confluence users
-	if len(creds.ConfluenceServerUsers) != 0 {
-		result.ConfluenceServerUserCheck = authConfluenceUsers(logger, creds.ConfluenceServerUsers)
+	//Split results by type
+	sourceChecks, offset := splitResults(sourceTypes, creds.sources, results, 0)
+	destChecks, _ := splitResults(destTypes, creds.destinations, results, offset)
+	return CheckUsersResultV1{SourceChecks: sourceChecks, DestinationChecks: destChecks}, nil
+}
+
+//splitResults - returns the results of each type starting at offset and the offset of the next results. Types without
+//credentials are omitted and nil is returned if there are none
+func splitResults(types []string, creds map[string][]plugin.Credential, results []plugin.CheckResultV1, offset int) (map[string][]CredentialCheckResultV1, int) {
+	var split map[string][]CredentialCheckResultV1
+	for _, typeName := range types {
+		typeCreds := creds[typeName]
+		if len(typeCreds) == 0 {
+			continue
+		}
+		typeResults := make([]CredentialCheckResultV1, len(typeCreds))
+		for i, cred := range typeCreds {
+			typeResults[i] = CredentialCheckResultV1{CheckResultV1: results[offset+i], Credential: cred.RedactedView()}
+		}
+		if split == nil {
+			split = make(map[string][]CredentialCheckResultV1, len(types))
+		}
+		split[typeName] = typeResults
+		offset += len(typeCreds)
 	}
+	return split, offset
+}
 
-	return result, nil
+//sortedTypes - returns the types in name order
+func sortedTypes(creds map[string][]plugin.Credential) []string {
+	types := make([]string, 0, len(creds))
+	for typeName := range creds {
+		types = append(types, typeName)
+	}
+	sort.Strings(types)
+	return types
 }
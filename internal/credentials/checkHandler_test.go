@@ -0,0 +1,284 @@
+package credentials
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/sajeevany/graph-snapper/internal/common"
+	"github.com/sajeevany/graph-snapper/internal/config"
+	"github.com/sajeevany/graph-snapper/internal/confluence"
+	"github.com/sajeevany/graph-snapper/internal/destination"
+	"github.com/sajeevany/graph-snapper/internal/grafana"
+	"github.com/sajeevany/graph-snapper/internal/source"
+	"github.com/sajeevany/graph-snapper/internal/test"
+	"github.com/sajeevany/graph-snapper/internal/upstream"
+	"github.com/sirupsen/logrus"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+//Test_validateCredentials - Validates concurrency limits, deadlines and result ordering against slow upstream services
+func Test_validateCredentials(t *testing.T) {
+
+	tests := []struct {
+		name          string
+		checkConf     config.CredentialCheck
+		sleep         time.Duration
+		numGrafana    int
+		numConfluence int
+		wantResult    bool
+		wantMaxTime   time.Duration
+	}{
+		{
+			name:          "test0 checks run concurrently",
+			checkConf:     config.CredentialCheck{Workers: 6, TimeoutMS: 5000},
+			sleep:         200 * time.Millisecond,
+			numGrafana:    4,
+			numConfluence: 2,
+			wantResult:    true,
+			wantMaxTime:   1000 * time.Millisecond,
+		},
+		{
+			name:          "test1 workers are bounded",
+			checkConf:     config.CredentialCheck{Workers: 2, TimeoutMS: 5000},
+			sleep:         100 * time.Millisecond,
+			numGrafana:    3,
+			numConfluence: 3,
+			wantResult:    true,
+			wantMaxTime:   2000 * time.Millisecond,
+		},
+		{
+			name:          "test2 deadline fails slow and unstarted checks",
+			checkConf:     config.CredentialCheck{Workers: 1, TimeoutMS: 100},
+			sleep:         2 * time.Second,
+			numGrafana:    2,
+			numConfluence: 1,
+			wantResult:    false,
+			wantMaxTime:   1000 * time.Millisecond,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+
+			//Slow upstream which records the maximum number of concurrent requests
+			var inFlight, maxInFlight int32
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				//Only the login and access mode checks are slow
+				if r.URL.Path != "/api/login/ping" && r.URL.Path != "/rest/api/accessmode" {
+					w.WriteHeader(http.StatusNotFound)
+					return
+				}
+				n := atomic.AddInt32(&inFlight, 1)
+				defer atomic.AddInt32(&inFlight, -1)
+				for {
+					max := atomic.LoadInt32(&maxInFlight)
+					if n <= max || atomic.CompareAndSwapInt32(&maxInFlight, max, n) {
+						break
+					}
+				}
+				select {
+				case <-time.After(tt.sleep):
+				case <-r.Context().Done():
+					return
+				}
+				w.Write([]byte(`{"accessMode":"READ_WRITE"}`))
+			}))
+			defer server.Close()
+			host, port := test.SplitServerURL(t, server.URL)
+
+			//Each user has a distinct description so that result order can be verified
+			registerTypes()
+			creds := credentialSet{sources: map[string][]destination.Credential{}, destinations: map[string][]destination.Credential{}}
+			for i := 0; i < tt.numGrafana; i++ {
+				creds.sources[grafana.SourceType] = append(creds.sources[grafana.SourceType], newGrafanaUser("grafana"+strconv.Itoa(i), host, port))
+			}
+			for i := 0; i < tt.numConfluence; i++ {
+				creds.destinations[confluence.ServerDestinationType] = append(creds.destinations[confluence.ServerDestinationType], common.ConfluenceServerUserV1{
+					Auth:        common.Auth{BearerToken: common.BearerToken{Token: "confluence" + strconv.Itoa(i)}},
+					Host:        host,
+					Port:        port,
+					Description: "confluence" + strconv.Itoa(i),
+				})
+			}
+
+			start := time.Now()
+			result, err := validateCredentials(context.Background(), logrus.NewEntry(logrus.New()), upstream.New(config.NewConfWithDefaults().Upstream), tt.checkConf, creds)
+			elapsed := time.Since(start)
+			if err != nil {
+				t.Fatalf("validateCredentials() unexpected error = %v", err)
+			}
+
+			if elapsed > tt.wantMaxTime {
+				t.Errorf("validateCredentials() took <%v>, want less than <%v>", elapsed, tt.wantMaxTime)
+			}
+			if max := int(atomic.LoadInt32(&maxInFlight)); max > tt.checkConf.Workers {
+				t.Errorf("max concurrent checks = %v, want at most %v", max, tt.checkConf.Workers)
+			}
+			assertResults(t, result.SourceChecks[grafana.SourceType], "grafana", tt.numGrafana, tt.wantResult)
+			assertResults(t, result.DestinationChecks[confluence.ServerDestinationType], "confluence", tt.numConfluence, tt.wantResult)
+		})
+	}
+}
+
+//Test_validateCredentialsConfluenceCloud - Validates that confluence cloud users are checked with their email and API token
+func Test_validateCredentialsConfluenceCloud(t *testing.T) {
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/login/ping":
+			w.WriteHeader(http.StatusOK)
+		case "/wiki/rest/api/user/current":
+			if email, token, _ := r.BasicAuth(); email != "user@example.com" || token != "valid" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			w.Write([]byte(`{"type":"known","accountId":"abc"}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+	host, port := test.SplitServerURL(t, server.URL)
+
+	cloudUser := func(token string) destination.Credential {
+		return common.ConfluenceCloudUserV1{
+			SiteURL:  server.URL,
+			Email:    "user@example.com",
+			APIToken: token,
+		}
+	}
+	registerTypes()
+	creds := credentialSet{
+		sources: map[string][]destination.Credential{
+			grafana.SourceType: {newGrafanaUser("grafana0", host, port)},
+		},
+		destinations: map[string][]destination.Credential{
+			confluence.CloudDestinationType: {cloudUser("valid"), cloudUser("typo")},
+		},
+	}
+
+	result, err := validateCredentials(context.Background(), logrus.NewEntry(logrus.New()), upstream.New(config.NewConfWithDefaults().Upstream), config.NewConfWithDefaults().CredentialCheck, creds)
+	if err != nil {
+		t.Fatalf("validateCredentials() unexpected error = %v", err)
+	}
+
+	cloudChecks := result.DestinationChecks[confluence.CloudDestinationType]
+	if len(result.SourceChecks[grafana.SourceType]) != 1 || len(result.DestinationChecks) != 1 || len(cloudChecks) != 2 {
+		t.Fatalf("validateCredentials() result groups = %v %v %v, want 1 1 2", len(result.SourceChecks[grafana.SourceType]), len(result.DestinationChecks), len(cloudChecks))
+	}
+	if !cloudChecks[0].Result || cloudChecks[1].Result {
+		t.Errorf("validateCredentials() confluence cloud results = %+v, want the valid token to pass and the typo to fail", cloudChecks)
+	}
+	if got := cloudChecks[0].Credential.(common.ConfluenceCloudUserV1).APIToken; got == "valid" {
+		t.Errorf("validateCredentials() returned the API token of a checked credential")
+	}
+}
+
+//Test_validateCredentialsLegacyUsers - Validates that users of the deprecated V1 lists are checked with their type and
+//returned in the user check lists without secrets
+func Test_validateCredentialsLegacyUsers(t *testing.T) {
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/login/ping":
+			if r.Header.Get("Authorization") != "Bearer valid" {
+				w.WriteHeader(http.StatusUnauthorized)
+			}
+		case "/rest/api/accessmode":
+			w.Write([]byte(`"READ_WRITE"`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+	host, port := test.SplitServerURL(t, server.URL)
+
+	legacyUser := func(token string) CheckUserV1 {
+		return CheckUserV1{Auth: common.Auth{BearerToken: common.BearerToken{Token: token}}, Host: host, Port: port}
+	}
+	registerTypes()
+	creds := CheckCredentialsV1{
+		Sources:               map[string][]json.RawMessage{grafana.SourceType: {json.RawMessage(`{"Auth":{"BearerToken":{"Token":"valid"}},"Host":"` + host + `","Port":` + strconv.Itoa(port) + `,"Description":"typed"}`)}},
+		GrafanaReadUsers:      []CheckUserV1{legacyUser("typo"), legacyUser("valid")},
+		ConfluenceServerUsers: []CheckUserV1{legacyUser("valid")},
+	}
+	set, err := creds.decode()
+	if err != nil {
+		t.Fatalf("decode() unexpected error = %v", err)
+	}
+
+	result, err := validateCredentials(context.Background(), logrus.NewEntry(logrus.New()), upstream.New(config.NewConfWithDefaults().Upstream), config.NewConfWithDefaults().CredentialCheck, set)
+	if err != nil {
+		t.Fatalf("validateCredentials() unexpected error = %v", err)
+	}
+	creds.moveLegacyResults(&result)
+
+	if typed := result.SourceChecks[grafana.SourceType]; len(typed) != 1 || !typed[0].Result {
+		t.Errorf("typed grafana results = %+v, want the typed credential only", typed)
+	}
+	if len(result.DestinationChecks) != 0 {
+		t.Errorf("destination results = %+v, want the deprecated confluence users in ConfluenceServerUserCheck", result.DestinationChecks)
+	}
+	if got := result.GrafanaReadUserCheck; len(got) != 2 || got[0].Result || !got[1].Result {
+		t.Errorf("GrafanaReadUserCheck = %+v, want the typo to fail and the valid token to pass", got)
+	}
+	if got := result.ConfluenceServerUserCheck; len(got) != 1 || !got[0].Result {
+		t.Fatalf("ConfluenceServerUserCheck = %+v, want the valid token to pass", got)
+	}
+	if token := result.ConfluenceServerUserCheck[0].Auth.BearerToken.Token; token == "valid" {
+		t.Errorf("ConfluenceServerUserCheck returned the token of a checked user")
+	}
+}
+
+//assertResults - Credentials are described by their prefix and position in the request
+func assertResults(t *testing.T, got []CredentialCheckResultV1, prefix string, want int, wantResult bool) {
+	if len(got) != want {
+		t.Fatalf("number of %v results = %v, want %v", prefix, len(got), want)
+	}
+	for i, r := range got {
+		var desc string
+		switch cred := r.Credential.(type) {
+		case common.GrafanaUserV1:
+			desc = cred.Description
+		case common.ConfluenceServerUserV1:
+			desc = cred.Description
+		default:
+			t.Fatalf("result <%v> credential is <%T>, want a grafana or confluence server user", i, r.Credential)
+		}
+		if wantDesc := prefix + strconv.Itoa(i); desc != wantDesc {
+			t.Errorf("result <%v> is for user <%v>, want <%v>", i, desc, wantDesc)
+		}
+		if r.Result != wantResult {
+			t.Errorf("result <%v> = %v, want %v. cause <%v>", i, r.Result, wantResult, r.Cause)
+		}
+		if r.Result && r.LatencyMS <= 0 {
+			t.Errorf("result <%v> latency = %v, want a positive value", i, r.LatencyMS)
+		}
+	}
+}
+
+var registerOnce sync.Once
+
+//registerTypes - registers the grafana source and confluence destinations once for all tests of the package
+func registerTypes() {
+	registerOnce.Do(func() {
+		client := upstream.New(config.NewConfWithDefaults().Upstream)
+		source.Register(grafana.NewSource(client))
+		destination.Register(confluence.NewServerDestination(client))
+		destination.Register(confluence.NewCloudDestination(client))
+	})
+}
+
+func newGrafanaUser(desc, host string, port int) common.GrafanaUserV1 {
+	return common.GrafanaUserV1{
+		Auth:        common.Auth{BearerToken: common.BearerToken{Token: desc}},
+		Host:        host,
+		Port:        port,
+		Description: desc,
+	}
+}
@@ -1,40 +1,207 @@
 package credentials
 
 import (
+	"encoding/json"
+	"fmt"
 	"github.com/sajeevany/graph-snapper/internal/common"
-	"github.com/sirupsen/logrus"
+	"github.com/sajeevany/graph-snapper/internal/confluence"
+	"github.com/sajeevany/graph-snapper/internal/destination"
+	"github.com/sajeevany/graph-snapper/internal/grafana"
+	"github.com/sajeevany/graph-snapper/internal/plugin"
+	"github.com/sajeevany/graph-snapper/internal/source"
 )
 
-//CheckCredentialsV1 - Set of credentials to be validated
+//CheckCredentialsV1 - Set of credentials to be validated. The user lists are the fields of V1 clients written before
+//credentials were keyed by type. They're checked with the matching source or destination type and their results are
+//returned in the matching user check lists
 type CheckCredentialsV1 struct {
-	GrafanaReadUsers      []CheckUserV1 `json:"GrafanaAPIUsers"`
-	ConfluenceServerUsers []CheckUserV1 `json:"ConfluenceServerUsers"`
+	Sources      map[string][]json.RawMessage `json:"Sources" swaggertype:"object"`      //Source credentials keyed by source type. ie grafana
+	Destinations map[string][]json.RawMessage `json:"Destinations" swaggertype:"object"` //Destination credentials keyed by destination type. ie s3
+
+	GrafanaReadUsers      []CheckUserV1 `json:"GrafanaAPIUsers,omitempty"`       //Deprecated. Use Sources.grafana
+	ConfluenceServerUsers []CheckUserV1 `json:"ConfluenceServerUsers,omitempty"` //Deprecated. Use Destinations.confluence-server
 }
 
-//GrafanaUser - Grafana user with read access
-type CheckUserV1 struct {
-	Auth common.Auth
-	Host string
-	Port int
+//credentialSet - Decoded credentials to check keyed by source or destination type
+type credentialSet struct {
+	sources      map[string][]plugin.Credential
+	destinations map[string][]plugin.Credential
+}
+
+//decode - decodes each source and destination credential with its registered type. Users of the deprecated lists
+//are checked after the credentials of their type
+func (c CheckCredentialsV1) decode() (credentialSet, error) {
+	sources, err := decodeCredentialLists(c.Sources, "source", source.DecodeJSON)
+	if err != nil {
+		return credentialSet{}, err
+	}
+	dests, err := decodeCredentialLists(c.Destinations, "destination", destination.DecodeJSON)
+	if err != nil {
+		return credentialSet{}, err
+	}
+
+	set := credentialSet{sources: sources, destinations: dests}
+	for _, l := range c.legacyUsers() {
+		creds := set.destinations
+		if l.isSource {
+			creds = set.sources
+		}
+		for _, u := range l.users {
+			creds[l.typeName] = append(creds[l.typeName], l.toCredential(u))
+		}
+	}
+	return set, nil
+}
+
+//legacyUserList - deprecated user list checked with a source or destination type
+type legacyUserList struct {
+	typeName     string
+	isSource     bool
+	users        []CheckUserV1
+	toCredential func(CheckUserV1) plugin.Credential
+	results      func(*CheckUsersResultV1) *[]CheckUserResultV1
+}
+
+func (c CheckCredentialsV1) legacyUsers() []legacyUserList {
+	return []legacyUserList{
+		{
+			typeName:     grafana.SourceType,
+			isSource:     true,
+			users:        c.GrafanaReadUsers,
+			toCredential: func(u CheckUserV1) plugin.Credential { return u.toGrafanaUserV1() },
+			results:      func(r *CheckUsersResultV1) *[]CheckUserResultV1 { return &r.GrafanaReadUserCheck },
+		},
+		{
+			typeName:     confluence.ServerDestinationType,
+			users:        c.ConfluenceServerUsers,
+			toCredential: func(u CheckUserV1) plugin.Credential { return u.toConfluenceServerUserV1() },
+			results:      func(r *CheckUsersResultV1) *[]CheckUserResultV1 { return &r.ConfluenceServerUserCheck },
+		},
+	}
+}
+
+//moveLegacyResults - moves the results of the deprecated user lists from the end of their type's checks to the
+//matching user check lists
+func (c CheckCredentialsV1) moveLegacyResults(result *CheckUsersResultV1) {
+	for _, l := range c.legacyUsers() {
+		if len(l.users) == 0 {
+			continue
+		}
+		checks := result.DestinationChecks
+		if l.isSource {
+			checks = result.SourceChecks
+		}
+		typeChecks := checks[l.typeName]
+		split := len(typeChecks) - len(l.users)
+
+		userChecks := make([]CheckUserResultV1, len(l.users))
+		for i, u := range l.users {
+			userChecks[i] = newCheckUserResultV1(typeChecks[split+i].CheckResultV1, u)
+		}
+		*l.results(result) = userChecks
+
+		if split == 0 {
+			delete(checks, l.typeName)
+		} else {
+			checks[l.typeName] = typeChecks[:split]
+		}
+	}
 }
 
-func (u CheckUserV1) GetFields() logrus.Fields {
-	return logrus.Fields{
-		"Auth": u.Auth.GetFields(),
-		"Host": u.Host,
-		"Port": u.Port,
+//decodeCredentialLists - decodes credentials keyed by type in request order
+func decodeCredentialLists(raw map[string][]json.RawMessage, kind string, decode func(string, []byte) (plugin.Credential, error)) (map[string][]plugin.Credential, error) {
+	all := make(map[string][]plugin.Credential, len(raw))
+	for typeName, creds := range raw {
+		for i, data := range creds {
+			cred, err := decode(typeName, data)
+			if err != nil {
+				return nil, fmt.Errorf("%v credential <%v[%v]> is invalid. %v", kind, typeName, i, err)
+			}
+			all[typeName] = append(all[typeName], cred)
+		}
 	}
+	return all, nil
 }
 
-//CheckCredentialsResultV1 - Check credentials result
+//CheckCredentialsResultV1 - Check credentials result. The user check lists hold the results of the deprecated user
+//lists in request order
 type CheckUsersResultV1 struct {
-	GrafanaReadUserCheck      []CheckUserResultV1
-	ConfluenceServerUserCheck []CheckUserResultV1
+	SourceChecks      map[string][]CredentialCheckResultV1 `json:"SourceChecks,omitempty"`      //Keyed by source type in request order
+	DestinationChecks map[string][]CredentialCheckResultV1 `json:"DestinationChecks,omitempty"` //Keyed by destination type in request order
+
+	GrafanaReadUserCheck      []CheckUserResultV1 `json:"GrafanaReadUserCheck,omitempty"`
+	ConfluenceServerUserCheck []CheckUserResultV1 `json:"ConfluenceServerUserCheck,omitempty"`
 }
 
-//CheckUserResultV1 - Grafana read user check result
+//CredentialCheckResultV1 - Source or destination credential check result. Grafana checks return the instance and
+//credential details of users which can log in as the details
+type CredentialCheckResultV1 struct {
+	plugin.CheckResultV1
+	Credential interface{} //Checked credential without secrets
+}
+
+//CheckUserV1 - User of the deprecated user lists. Its fields are converted to the credential of the list's type
+type CheckUserV1 struct {
+	Auth        common.Auth
+	Scheme      string `json:"Scheme,omitempty"`
+	Host        string
+	Port        int
+	ContextPath string             `json:"ContextPath,omitempty"`
+	TLS         common.TLSConfigV1 `json:"TLS"`
+	Proxy       string             `json:"Proxy,omitempty"`
+	SpaceKey    string             `json:"SpaceKey,omitempty"` //Optional. Confluence space checked for page and attachment create permissions
+	OrgID       int                `json:"OrgID,omitempty"`    //Optional. Grafana org the user must be a member of
+}
+
+func (u CheckUserV1) toGrafanaUserV1() common.GrafanaUserV1 {
+	return common.GrafanaUserV1{
+		Auth:        u.Auth,
+		Scheme:      u.Scheme,
+		Host:        u.Host,
+		Port:        u.Port,
+		ContextPath: u.ContextPath,
+		TLS:         u.TLS,
+		Proxy:       u.Proxy,
+		OrgID:       u.OrgID,
+	}
+}
+
+func (u CheckUserV1) toConfluenceServerUserV1() common.ConfluenceServerUserV1 {
+	return common.ConfluenceServerUserV1{
+		Auth:        u.Auth,
+		Scheme:      u.Scheme,
+		Host:        u.Host,
+		Port:        u.Port,
+		ContextPath: u.ContextPath,
+		TLS:         u.TLS,
+		Proxy:       u.Proxy,
+		SpaceKey:    u.SpaceKey,
+	}
+}
+
+//CheckUserResultV1 - Check result of a user of the deprecated user lists. The user is returned without secrets
 type CheckUserResultV1 struct {
-	Result bool
-	Cause  string `json:"Cause,omitempty"`
+	Result     bool
+	Cause      string                         `json:"Cause,omitempty"`
+	LatencyMS  int64                          //Time taken by the check in milliseconds
+	Grafana    *grafana.InfoV1                `json:"Grafana,omitempty"`    //Instance and credential details of valid grafana users
+	Confluence *confluence.SpacePermissionsV1 `json:"Confluence,omitempty"` //Space permissions of confluence users with a SpaceKey
 	CheckUserV1
 }
+
+func newCheckUserResultV1(check plugin.CheckResultV1, u CheckUserV1) CheckUserResultV1 {
+	u.Auth = u.Auth.GetRedactedView()
+	result := CheckUserResultV1{
+		Result:      check.Result,
+		Cause:       check.Cause,
+		LatencyMS:   check.LatencyMS,
+		CheckUserV1: u,
+	}
+	switch details := check.Details.(type) {
+	case *grafana.InfoV1:
+		result.Grafana = details
+	case *confluence.SpacePermissionsV1:
+		result.Confluence = details
+	}
+	return result
+}
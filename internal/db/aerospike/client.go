@@ -5,6 +5,8 @@ import (
 	"github.com/aerospike/aerospike-client-go"
 	"github.com/sajeevany/graph-snapper/internal/config"
 	"github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel/attribute"
+	semconv "go.opentelemetry.io/otel/semconv/v1.17.0"
 	"time"
 )
 
@@ -81,3 +83,13 @@ func (a *ASClient) GetWriter() DbWriter {
 func (a *ASClient) GetReader() DbReader {
 	return newAerospikeReader(a)
 }
+
+//spanAttributes - returns trace attributes describing an operation against the account namespace
+func (a *ASClient) spanAttributes(operation string) []attribute.KeyValue {
+	return []attribute.KeyValue{
+		semconv.DBSystemKey.String("aerospike"),
+		semconv.DBNameKey.String(a.AccountNamespace.Namespace),
+		semconv.DBOperationKey.String(operation),
+		attribute.String("db.aerospike.set", a.AccountNamespace.SetName),
+	}
+}
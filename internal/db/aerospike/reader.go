@@ -1,16 +1,21 @@
 package aerospike
 
 import (
+	"context"
 	"fmt"
 	"github.com/aerospike/aerospike-client-go"
 	"github.com/mitchellh/mapstructure"
 	"github.com/sajeevany/graph-snapper/internal/db/aerospike/record"
+	"github.com/sajeevany/graph-snapper/internal/logging"
+	"github.com/sajeevany/graph-snapper/internal/redact"
+	"github.com/sajeevany/graph-snapper/internal/tracing"
+	"github.com/sirupsen/logrus"
 	"strings"
 )
 
 type DbReader interface {
-	ReadRecord(key *aerospike.Key) (record.Record, error)
-	KeyExists(key string) (bool, *aerospike.Key, error)
+	ReadRecord(ctx context.Context, key *aerospike.Key) (record.Record, error)
+	KeyExists(ctx context.Context, key string) (bool, *aerospike.Key, error)
 }
 
 func newAerospikeReader(asClient *ASClient) DbReader {
@@ -24,13 +29,16 @@ type AerospikeReader struct {
 }
 
 //KeyExists - Returns true if key exists, with aerospike key and any error that occurs
-func (a *AerospikeReader) KeyExists(keyStr string) (bool, *aerospike.Key, error) {
+func (a *AerospikeReader) KeyExists(ctx context.Context, keyStr string) (exists bool, key *aerospike.Key, err error) {
 
-	logger := a.asClient.Logger
+	logger := logging.FromContext(ctx, a.asClient.Logger)
 	logger.Debugf("Checking if key <%v> exists", keyStr)
 
+	_, span := tracing.StartSpan(ctx, "aerospike.KeyExists", a.asClient.spanAttributes("exists")...)
+	defer func() { tracing.EndSpan(span, err) }()
+
 	//Create aerospike key to check
-	key, err := aerospike.NewKey(a.asClient.AccountNamespace.Namespace, a.asClient.AccountNamespace.SetName, keyStr)
+	key, err = aerospike.NewKey(a.asClient.AccountNamespace.Namespace, a.asClient.AccountNamespace.SetName, keyStr)
 	if err != nil {
 		logger.Errorf("Unexpected error when creating new key <%v> ", key.String())
 		return false, key, err
@@ -47,11 +55,14 @@ func (a *AerospikeReader) KeyExists(keyStr string) (bool, *aerospike.Key, error)
 	return exists, key, nil
 }
 
-func (a *AerospikeReader) ReadRecord(key *aerospike.Key) (record.Record, error) {
+func (a *AerospikeReader) ReadRecord(ctx context.Context, key *aerospike.Key) (rec record.Record, err error) {
 
-	logger := a.asClient.Logger
+	logger := logging.FromContext(ctx, a.asClient.Logger)
 	aeroClient := a.asClient.Client
 
+	_, span := tracing.StartSpan(ctx, "aerospike.ReadRecord", a.asClient.spanAttributes("get")...)
+	defer func() { tracing.EndSpan(span, err) }()
+
 	//Get bin map for key
 	logger.Debugf("Starting read record for key <%v>", key.String())
 	aRecord, rErr := aeroClient.Get(a.asClient.ReadPolicy, key)
@@ -60,17 +71,23 @@ func (a *AerospikeReader) ReadRecord(key *aerospike.Key) (record.Record, error)
 		return nil, rErr
 	}
 
-	//Get version
-	version := GetVersion(logger, aRecord.Bins)
+	return decodeRecord(logger, key, aRecord.Bins)
+}
+
+//decodeRecord - decodes the bins of the record version. Bin values hold credential secrets so only the key and decode
+//errors naming the invalid fields are logged
+func decodeRecord(logger *logrus.Entry, key *aerospike.Key, bins aerospike.BinMap) (record.Record, error) {
+
+	version := GetVersion(logger, bins)
 
 	switch strings.ToLower(version) {
 	case "":
 		vErr := fmt.Errorf("record does not have metadata.version set")
 		return nil, vErr
 	case record.VersionLevel_1:
-		rec, cErr := readV1Record(aRecord.Bins)
+		rec, cErr := readV1Record(bins)
 		if cErr != nil {
-			logger.Errorf("Error converting bin map <%v> to record. err <%v>", aRecord.Bins, cErr)
+			logger.Errorf("Error converting bins of key <%v> to record. err <%v>", key.String(), cErr)
 			return nil, cErr
 		}
 		logger.WithFields(rec.GetFields()).Debugf("Returning v1 record")
@@ -86,7 +103,12 @@ func readV1Record(bm aerospike.BinMap) (record.Record, error) {
 
 	var rec record.RecordV1
 	if cErr := mapstructure.Decode(bm, &rec); cErr != nil {
-		return nil, cErr
+		return nil, redact.DecodeError(cErr)
+	}
+
+	//Source and destination credentials are decoded by their registered type
+	if dErr := rec.DecodeCredentialsV1(bm); dErr != nil {
+		return nil, dErr
 	}
 
 	return &rec, nil
@@ -0,0 +1,78 @@
+package aerospike
+
+import (
+	"bytes"
+	"github.com/aerospike/aerospike-client-go"
+	"github.com/sajeevany/graph-snapper/internal/config"
+	"github.com/sajeevany/graph-snapper/internal/confluence"
+	"github.com/sajeevany/graph-snapper/internal/db/aerospike/record"
+	"github.com/sajeevany/graph-snapper/internal/destination"
+	"github.com/sajeevany/graph-snapper/internal/logging"
+	"github.com/sajeevany/graph-snapper/internal/upstream"
+	"github.com/sirupsen/logrus"
+	"strings"
+	"testing"
+)
+
+const secretPassword = "hunter2-password"
+
+//Test_decodeRecordDoesNotLeak - forces decode failures of records holding a secret and scans the logs and returned
+//error for it
+func Test_decodeRecordDoesNotLeak(t *testing.T) {
+
+	destination.Register(confluence.NewServerDestination(upstream.New(config.NewConfWithDefaults().Upstream)))
+
+	metadata := map[interface{}]interface{}{record.VersionAttrName: record.VersionLevel_1}
+	tests := []struct {
+		name string
+		bins aerospike.BinMap
+	}{
+		{
+			name: "test0 invalid credential field",
+			bins: aerospike.BinMap{
+				record.MetadataBinName: metadata,
+				record.CredentialsBinName: map[interface{}]interface{}{
+					record.DestinationsBMKey: map[interface{}]interface{}{
+						confluence.ServerDestinationType: map[interface{}]interface{}{
+							"main": map[interface{}]interface{}{
+								"Host": "confluence",
+								"Port": secretPassword,
+								"Auth": map[interface{}]interface{}{"Basic": map[interface{}]interface{}{"Username": "user", "Password": secretPassword}},
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "test1 invalid account field",
+			bins: aerospike.BinMap{
+				record.MetadataBinName: metadata,
+				record.AccountBinName:  map[interface{}]interface{}{"Email": []interface{}{secretPassword}},
+			},
+		},
+	}
+	key, err := aerospike.NewKey("test", "accounts", "abc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			logger := logging.Init()
+			logger.SetOutput(&buf)
+			logger.SetLevel(logrus.DebugLevel)
+
+			_, err := decodeRecord(logrus.NewEntry(logger), key, tt.bins)
+			if err == nil {
+				t.Fatalf("decodeRecord() didn't return an error")
+			}
+			if strings.Contains(err.Error(), secretPassword) {
+				t.Errorf("decodeRecord() error leaked the secret: %v", err)
+			}
+			if strings.Contains(buf.String(), secretPassword) {
+				t.Errorf("decodeRecord() logs leaked the secret: %v", buf.String())
+			}
+		})
+	}
+}
@@ -33,3 +33,11 @@ func (a AccountV1) getAccountBin() *aerospike.Bin {
 			"Alias": a.Alias,
 		})
 }
+
+//ToBinMap - converts account to a generic bin map
+func (a AccountV1) ToBinMap() map[string]interface{} {
+	return map[string]interface{}{
+		"Email": a.Email,
+		"Alias": a.Alias,
+	}
+}
@@ -1,6 +1,9 @@
 package record
 
-import "github.com/sirupsen/logrus"
+import (
+	"github.com/sajeevany/graph-snapper/internal/redact"
+	"github.com/sirupsen/logrus"
+)
 
 //DbAuth
 type DBAuth struct {
@@ -22,8 +25,8 @@ type Basic struct {
 
 func (a Basic) GetFields() logrus.Fields {
 	return logrus.Fields{
-		"Username": a.Username,
-		"Password": a.Password,
+		"Username": redact.NonEmpty(a.Username),
+		"Password": redact.NonEmpty(a.Password),
 	}
 }
 
@@ -33,6 +36,6 @@ type BearerToken struct {
 
 func (a BearerToken) GetFields() logrus.Fields {
 	return logrus.Fields{
-		"Token": a.Token,
+		"Token": redact.NonEmpty(a.Token),
 	}
 }
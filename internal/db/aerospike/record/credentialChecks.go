@@ -0,0 +1,128 @@
+package record
+
+import (
+	"github.com/aerospike/aerospike-client-go"
+	"github.com/sirupsen/logrus"
+)
+
+//CredentialChecksBinName - bin holding the last check result of each stored credential. Bin names are limited to 14 characters
+const CredentialChecksBinName = "CredChecks"
+
+//CredentialChecksV1 - Last check result of stored credentials keyed by credential name
+type CredentialChecksV1 struct {
+	Sources      map[string]map[string]CredentialCheckV1 //Keyed by source type then credential name
+	Destinations map[string]map[string]CredentialCheckV1 //Keyed by destination type then credential name
+}
+
+//CredentialCheckV1 - Result of the last connectivity check of a credential
+type CredentialCheckV1 struct {
+	Result    bool
+	Cause     string
+	LatencyMS int64
+	CheckedAt string //RFC3339 UTC time of the check
+}
+
+func (c CredentialCheckV1) toLastCheckViewV1() *LastCheckViewV1 {
+	return &LastCheckViewV1{
+		Result:    c.Result,
+		Cause:     c.Cause,
+		LatencyMS: c.LatencyMS,
+		CheckedAt: c.CheckedAt,
+	}
+}
+
+func (c CredentialCheckV1) toBinMap() map[string]interface{} {
+	return map[string]interface{}{
+		"Result":    c.Result,
+		"Cause":     c.Cause,
+		"LatencyMS": c.LatencyMS,
+		"CheckedAt": c.CheckedAt,
+	}
+}
+
+func (c CredentialChecksV1) GetFields() logrus.Fields {
+	return logrus.Fields{
+		SourcesBMKey:      checkCounts(c.Sources),
+		DestinationsBMKey: checkCounts(c.Destinations),
+	}
+}
+
+//OfUnchanged - returns the checks of credentials which are the same in the checked and current credentials so that
+//results of credentials changed while they were being checked aren't stored
+func (c CredentialChecksV1) OfUnchanged(checked, current CredentialsV1) CredentialChecksV1 {
+	return CredentialChecksV1{
+		Sources:      keepUnchangedChecks(c.Sources, checked.Sources, current.Sources),
+		Destinations: keepUnchangedChecks(c.Destinations, checked.Destinations, current.Destinations),
+	}
+}
+
+func (c CredentialChecksV1) getCredentialChecksBin() *aerospike.Bin {
+	return aerospike.NewBin(CredentialChecksBinName, map[string]interface{}{
+		SourcesBMKey:      typedChecksToBinMap(c.Sources),
+		DestinationsBMKey: typedChecksToBinMap(c.Destinations),
+	})
+}
+
+//setCheck - stores the check unless the credential already has one
+func setCheck(all *map[string]map[string]CredentialCheckV1, typeName, name string, check CredentialCheckV1) {
+	if *all == nil {
+		*all = make(map[string]map[string]CredentialCheckV1)
+	}
+	if (*all)[typeName] == nil {
+		(*all)[typeName] = make(map[string]CredentialCheckV1)
+	}
+	if _, exists := (*all)[typeName][name]; !exists {
+		(*all)[typeName][name] = check
+	}
+}
+
+func checkCounts(all map[string]map[string]CredentialCheckV1) logrus.Fields {
+	fields := logrus.Fields{}
+	for typeName, checks := range all {
+		fields[typeName] = len(checks)
+	}
+	return fields
+}
+
+func typedChecksToBinMap(all map[string]map[string]CredentialCheckV1) map[string]interface{} {
+	bm := make(map[string]interface{}, len(all))
+	for typeName, checks := range all {
+		bm[typeName] = checksToBinMap(checks)
+	}
+	return bm
+}
+
+func checksToBinMap(checks map[string]CredentialCheckV1) map[string]interface{} {
+	bm := make(map[string]interface{}, len(checks))
+	for name, check := range checks {
+		bm[name] = check.toBinMap()
+	}
+	return bm
+}
+
+//mergeTypedChecks - returns existing checks overwritten by updates of each type
+func mergeTypedChecks(existing, updates map[string]map[string]CredentialCheckV1) map[string]map[string]CredentialCheckV1 {
+	if len(updates) == 0 {
+		return existing
+	}
+	merged := make(map[string]map[string]CredentialCheckV1, len(existing)+len(updates))
+	for typeName, checks := range existing {
+		merged[typeName] = checks
+	}
+	for typeName, checks := range updates {
+		merged[typeName] = mergeChecks(existing[typeName], checks)
+	}
+	return merged
+}
+
+//mergeChecks - returns existing checks overwritten by updates
+func mergeChecks(existing, updates map[string]CredentialCheckV1) map[string]CredentialCheckV1 {
+	merged := make(map[string]CredentialCheckV1, len(existing)+len(updates))
+	for name, check := range existing {
+		merged[name] = check
+	}
+	for name, check := range updates {
+		merged[name] = check
+	}
+	return merged
+}
@@ -1,96 +1,240 @@
 package record
 
 import (
+	"fmt"
 	"github.com/aerospike/aerospike-client-go"
+	"github.com/mitchellh/mapstructure"
 	"github.com/sajeevany/graph-snapper/internal/common"
+	"github.com/sajeevany/graph-snapper/internal/destination"
+	"github.com/sajeevany/graph-snapper/internal/plugin"
+	"github.com/sajeevany/graph-snapper/internal/source"
 	"github.com/sirupsen/logrus"
 )
 
 const (
+	SourcesBMKey      = "Sources"
+	DestinationsBMKey = "Destinations"
+
+	//Keys credentials were stored under before they were keyed by source or destination type. Records holding them
+	//are migrated when read
 	GrafanaAPIUsersBMKey    = "GrafanaAPIUsers"
 	ConfluenceAPIUsersBMKey = "ConfluenceServerAPIUsers"
 )
 
+//legacySourceTypes - source type of each legacy bin map key
+var legacySourceTypes = map[string]string{
+	GrafanaAPIUsersBMKey: "grafana",
+}
+
+//legacyDestinationTypes - destination type of each legacy bin map key
+var legacyDestinationTypes = map[string]string{
+	ConfluenceAPIUsersBMKey: "confluence-server",
+}
+
 //CredentialsV1 - CredentialsV1 for various graph and storage services
 type CredentialsV1 struct {
-	GrafanaAPIUsers          map[string]common.GrafanaUserV1
-	ConfluenceServerAPIUsers map[string]common.ConfluenceServerUserV1
+	Sources      map[string]map[string]source.Credential      `mapstructure:"-"` //Keyed by source type then credential name. Decoded by DecodeCredentialsV1
+	Destinations map[string]map[string]destination.Credential `mapstructure:"-"` //Keyed by destination type then credential name. Decoded by DecodeCredentialsV1
 }
 
-func (c CredentialsV1) toCredentialsView1() CredentialsView1 {
-	cv := CredentialsView1{
-		GrafanaAPIUsers:       make(map[string]GrafanaAPIUser, len(c.GrafanaAPIUsers)),
-		ConfluenceServerUsers: make(map[string]ConfluenceServerUser, len(c.ConfluenceServerAPIUsers)),
+func (c CredentialsV1) toCredentialsView1(checks CredentialChecksV1) CredentialsView1 {
+	return CredentialsView1{
+		GrafanaAPIUsers:       c.toGrafanaAPIUsers(),
+		ConfluenceServerUsers: c.toConfluenceServerUsers(),
+		Sources:               toCredentialViews(c.Sources, checks.Sources),
+		Destinations:          toCredentialViews(c.Destinations, checks.Destinations),
 	}
+}
 
-	for i, v := range c.GrafanaAPIUsers {
-		cv.GrafanaAPIUsers[i] = GrafanaAPIUser{
-			Auth:        v.Auth.GetRedactedView(),
-			Host:        v.Host,
-			Port:        v.Port,
-			Description: v.Description,
+func (c CredentialsV1) toGrafanaAPIUsers() map[string]GrafanaAPIUser {
+	users := make(map[string]GrafanaAPIUser)
+	for name, cred := range c.Sources[legacySourceTypes[GrafanaAPIUsersBMKey]] {
+		if v, ok := cred.(common.GrafanaUserV1); ok {
+			users[name] = GrafanaAPIUser{
+				Auth:        v.Auth.GetRedactedView(),
+				Host:        v.Host,
+				Port:        v.Port,
+				Description: v.Description,
+			}
 		}
 	}
+	return users
+}
 
-	for i, v := range c.ConfluenceServerAPIUsers {
-		cv.ConfluenceServerUsers[i] = ConfluenceServerUser{
-			Auth:        v.Auth.GetRedactedView(),
-			Host:        v.Host,
-			Port:        v.Port,
-			Description: v.Description,
+func (c CredentialsV1) toConfluenceServerUsers() map[string]ConfluenceServerUser {
+	users := make(map[string]ConfluenceServerUser)
+	for name, cred := range c.Destinations[legacyDestinationTypes[ConfluenceAPIUsersBMKey]] {
+		if v, ok := cred.(common.ConfluenceServerUserV1); ok {
+			users[name] = ConfluenceServerUser{
+				Auth:        v.Auth.GetRedactedView(),
+				Host:        v.Host,
+				Port:        v.Port,
+				Description: v.Description,
+			}
 		}
 	}
-
-	return cv
+	return users
 }
 
 func (c CredentialsV1) GetFields() logrus.Fields {
-	//Add Grafana user creds
-	gFields := logrus.Fields{}
-	for i, v := range c.GrafanaAPIUsers {
-		gFields[i] = v.GetFields()
+	return logrus.Fields{
+		SourcesBMKey:      credentialFields(c.Sources),
+		DestinationsBMKey: credentialFields(c.Destinations),
 	}
+}
+
+func (c CredentialsV1) getCredentialBin() *aerospike.Bin {
+	return aerospike.NewBin(CredentialsBinName, c.ToBinMap())
+}
 
-	//Add Confluence user creds
-	csFields := logrus.Fields{}
-	for i, v := range c.ConfluenceServerAPIUsers {
-		csFields[i] = v.GetFields()
+//ToBinMap - converts credentials to the bin map stored in aerospike. Each credential provides its own bin map and
+//values are not redacted
+func (c CredentialsV1) ToBinMap() map[string]interface{} {
+	return map[string]interface{}{
+		SourcesBMKey:      credentialsToBinMap(c.Sources),
+		DestinationsBMKey: credentialsToBinMap(c.Destinations),
 	}
+}
 
-	return logrus.Fields{
-		GrafanaAPIUsersBMKey:    gFields,
-		ConfluenceAPIUsersBMKey: csFields,
+//ToAuditMap - converts credentials to the map audit diffs are computed from. Grafana sources and confluence-server
+//destinations are kept under their legacy keys so that the diff paths of audit events don't change with the bin layout
+func (c CredentialsV1) ToAuditMap() map[string]interface{} {
+	am := c.ToBinMap()
+	moveToLegacyKeys(am, SourcesBMKey, legacySourceTypes)
+	moveToLegacyKeys(am, DestinationsBMKey, legacyDestinationTypes)
+	return am
+}
+
+//moveToLegacyKeys - moves the credentials of each legacy type from the bin map's key to the legacy key
+func moveToLegacyKeys(bm map[string]interface{}, key string, legacyTypes map[string]string) {
+	typed := toStringMap(bm[key])
+	for legacyKey, typeName := range legacyTypes {
+		if creds, exists := typed[typeName]; exists {
+			bm[legacyKey] = creds
+			delete(typed, typeName)
+		}
 	}
 }
 
-func (c CredentialsV1) getCredentialBin() *aerospike.Bin {
+//DecodeCredentialsV1 - Decodes the source and destination credentials of the credentials bin with their registered
+//types. Credentials and checks stored under legacy keys are moved to their type
+func (r *RecordV1) DecodeCredentialsV1(bins map[string]interface{}) error {
+
+	credsBin := toStringMap(bins[CredentialsBinName])
+	checksBin := toStringMap(bins[CredentialChecksBinName])
 
-	//Create grafana users bin map
-	grafanaUsersBinMap := make(map[string]interface{})
-	for i, v := range c.GrafanaAPIUsers {
-		grafanaUsersBinMap[i] = map[string]interface{}{
-			"Auth":        v.Auth.ToAerospikeBinMap(),
-			"Host":        v.Host,
-			"Port":        v.Port,
-			"Description": v.Description,
+	r.Credentials.Sources = make(map[string]map[string]source.Credential)
+	for typeName, creds := range toStringMap(credsBin[SourcesBMKey]) {
+		if err := decodeCredentials(r.Credentials.Sources, typeName, creds, source.DecodeBinMap); err != nil {
+			return err
+		}
+	}
+	for key, typeName := range legacySourceTypes {
+		if err := decodeCredentials(r.Credentials.Sources, typeName, credsBin[key], source.DecodeBinMap); err != nil {
+			return err
+		}
+		if err := decodeLegacyChecks(&r.CredentialChecks.Sources, key, typeName, checksBin[key]); err != nil {
+			return err
 		}
 	}
 
-	//Create confluence server users bin map
-	confluenceServerUsersBinMap := make(map[string]interface{})
-	for i, v := range c.ConfluenceServerAPIUsers {
-		confluenceServerUsersBinMap[i] = map[string]interface{}{
-			"Auth":        v.Auth.ToAerospikeBinMap(),
-			"Host":        v.Host,
-			"Port":        v.Port,
-			"Description": v.Description,
+	r.Credentials.Destinations = make(map[string]map[string]destination.Credential)
+	for typeName, creds := range toStringMap(credsBin[DestinationsBMKey]) {
+		if err := decodeCredentials(r.Credentials.Destinations, typeName, creds, destination.DecodeBinMap); err != nil {
+			return err
+		}
+	}
+	for key, typeName := range legacyDestinationTypes {
+		if err := decodeCredentials(r.Credentials.Destinations, typeName, credsBin[key], destination.DecodeBinMap); err != nil {
+			return err
+		}
+		if err := decodeLegacyChecks(&r.CredentialChecks.Destinations, key, typeName, checksBin[key]); err != nil {
+			return err
 		}
 	}
 
-	return aerospike.NewBin(
-		CredentialsBinName,
-		map[string]interface{}{
-			GrafanaAPIUsersBMKey:    grafanaUsersBinMap,
-			ConfluenceAPIUsersBMKey: confluenceServerUsersBinMap,
-		})
+	return nil
+}
+
+//decodeCredentials - adds the credentials of the type to all. creds is a bin map keyed by credential name
+func decodeCredentials(all map[string]map[string]plugin.Credential, typeName string, creds interface{}, decode func(string, interface{}) (plugin.Credential, error)) error {
+	for name, bm := range toStringMap(creds) {
+		cred, err := decode(typeName, bm)
+		if err != nil {
+			return fmt.Errorf("unable to decode credential <%v.%v>. err <%v>", typeName, name, err)
+		}
+		if all[typeName] == nil {
+			all[typeName] = make(map[string]plugin.Credential)
+		}
+		all[typeName][name] = cred
+	}
+	return nil
+}
+
+//decodeLegacyChecks - moves the checks stored under the legacy key to the type
+func decodeLegacyChecks(all *map[string]map[string]CredentialCheckV1, key, typeName string, bm interface{}) error {
+	var checks map[string]CredentialCheckV1
+	if err := mapstructure.Decode(bm, &checks); err != nil {
+		return fmt.Errorf("unable to decode legacy credential checks <%v>. err <%v>", key, err)
+	}
+	for name, check := range checks {
+		setCheck(all, typeName, name, check)
+	}
+	return nil
+}
+
+func toCredentialViews(all map[string]map[string]plugin.Credential, checks map[string]map[string]CredentialCheckV1) map[string]map[string]CredentialView {
+	views := make(map[string]map[string]CredentialView, len(all))
+	for typeName, creds := range all {
+		typeViews := make(map[string]CredentialView, len(creds))
+		for name, cred := range creds {
+			view := CredentialView{Credential: cred.RedactedView()}
+			if check, exists := checks[typeName][name]; exists {
+				view.LastCheck = check.toLastCheckViewV1()
+			}
+			typeViews[name] = view
+		}
+		views[typeName] = typeViews
+	}
+	return views
+}
+
+func credentialFields(all map[string]map[string]plugin.Credential) logrus.Fields {
+	fields := logrus.Fields{}
+	for typeName, creds := range all {
+		typeFields := logrus.Fields{}
+		for name, cred := range creds {
+			typeFields[name] = cred.GetFields()
+		}
+		fields[typeName] = typeFields
+	}
+	return fields
+}
+
+func credentialsToBinMap(all map[string]map[string]plugin.Credential) map[string]interface{} {
+	bm := make(map[string]interface{}, len(all))
+	for typeName, creds := range all {
+		credsBinMap := make(map[string]interface{}, len(creds))
+		for name, cred := range creds {
+			credsBinMap[name] = cred.ToBinMap()
+		}
+		bm[typeName] = credsBinMap
+	}
+	return bm
+}
+
+//toStringMap - returns the bin map with string keys. Nested maps are returned by aerospike with interface keys
+func toStringMap(val interface{}) map[string]interface{} {
+	switch m := val.(type) {
+	case map[string]interface{}:
+		return m
+	case map[interface{}]interface{}:
+		sm := make(map[string]interface{}, len(m))
+		for k, v := range m {
+			sm[fmt.Sprintf("%v", k)] = v
+		}
+		return sm
+	default:
+		return nil
+	}
 }
@@ -2,8 +2,11 @@ package record
 
 import (
 	"github.com/aerospike/aerospike-client-go"
-	"github.com/sajeevany/graph-snapper/internal/common"
+	"github.com/sajeevany/graph-snapper/internal/destination"
+	"github.com/sajeevany/graph-snapper/internal/plugin"
+	"github.com/sajeevany/graph-snapper/internal/source"
 	"github.com/sirupsen/logrus"
+	"reflect"
 )
 
 const (
@@ -25,32 +28,40 @@ type Record interface {
 	GetFields() logrus.Fields
 	//ToASBinSlice - converts record to bin map. Used to write record to db in the latest record format
 	ToASBinSlice() []*aerospike.Bin
+	//ToCredentialChecksBin - converts the credential checks to a bin. Used to write only the checks
+	ToCredentialChecksBin() *aerospike.Bin
 	//ToRecordViewV1 - converts to v1 record view
 	ToRecordViewV1() RecordViewV1
 	//SetUserCredentialsV1 - Adds input credentials to record. Does not overwrite any existing records
-	SetUserCredentialsV1(*logrus.Logger, map[string]common.GrafanaUserV1, map[string]common.ConfluenceServerUserV1)
+	SetUserCredentialsV1(*logrus.Entry, map[string]map[string]source.Credential, map[string]map[string]destination.Credential)
+	//GetCredentials - returns the v1 credentials held by the record
+	GetCredentials() CredentialsV1
+	//SetCredentialChecksV1 - Stores the latest check results. Results of credentials which weren't checked are kept
+	SetCredentialChecksV1(CredentialChecksV1)
 }
 
 //Record - Aerospike configuration + credentials data
 type RecordV1 struct {
-	Metadata    MetadataV1    `json:"Metadata"`
-	Account     AccountV1     `json:"Account"`
-	Credentials CredentialsV1 `json:"Credentials"`
+	Metadata         MetadataV1         `json:"Metadata"`
+	Account          AccountV1          `json:"Account"`
+	Credentials      CredentialsV1      `json:"Credentials"`
+	CredentialChecks CredentialChecksV1 `json:"CredentialChecks" mapstructure:"CredChecks"`
 }
 
 func (r *RecordV1) ToRecordViewV1() RecordViewV1 {
 	return RecordViewV1{
 		Metadata:    r.Metadata.toMetadataView1(),
 		Account:     r.Account.toAccountView1(),
-		Credentials: r.Credentials.toCredentialsView1(),
+		Credentials: r.Credentials.toCredentialsView1(r.CredentialChecks),
 	}
 }
 
 func (r *RecordV1) GetFields() logrus.Fields {
 	return logrus.Fields{
-		"MetadataV1":    r.Metadata.GetFields(),
-		"AccountV1":     r.Account.GetFields(),
-		"CredentialsV1": r.Credentials.GetFields(),
+		"MetadataV1":         r.Metadata.GetFields(),
+		"AccountV1":          r.Account.GetFields(),
+		"CredentialsV1":      r.Credentials.GetFields(),
+		"CredentialChecksV1": r.CredentialChecks.GetFields(),
 	}
 }
 
@@ -60,18 +71,54 @@ func (r *RecordV1) ToASBinSlice() []*aerospike.Bin {
 		r.Metadata.getMetadataBin(),
 		r.Account.getAccountBin(),
 		r.Credentials.getCredentialBin(),
+		r.CredentialChecks.getCredentialChecksBin(),
 	}
 }
 
+func (r *RecordV1) ToCredentialChecksBin() *aerospike.Bin {
+	return r.CredentialChecks.getCredentialChecksBin()
+}
+
+func (r *RecordV1) GetCredentials() CredentialsV1 {
+	return r.Credentials
+}
+
 //Add user details to record. Does not overwrite existing users
-func (r *RecordV1) SetUserCredentialsV1(logger *logrus.Logger, grafanaUsers map[string]common.GrafanaUserV1, confluenceUsers map[string]common.ConfluenceServerUserV1) {
+func (r *RecordV1) SetUserCredentialsV1(logger *logrus.Entry, sources map[string]map[string]source.Credential, destinations map[string]map[string]destination.Credential) {
 
 	logger.Info("Populating record")
-	//Add the grafana users
-	r.Credentials.GrafanaAPIUsers = grafanaUsers
 
-	//Add the confluence users
-	r.Credentials.ConfluenceServerAPIUsers = confluenceUsers
+	//Check results no longer apply to removed or modified users
+	r.CredentialChecks.Sources = keepUnchangedChecks(r.CredentialChecks.Sources, r.Credentials.Sources, sources)
+	r.CredentialChecks.Destinations = keepUnchangedChecks(r.CredentialChecks.Destinations, r.Credentials.Destinations, destinations)
+
+	//Add the source credentials of every type
+	r.Credentials.Sources = sources
+
+	//Add the destination credentials of every type
+	r.Credentials.Destinations = destinations
 
 	logger.WithFields(r.GetFields()).Info("Record populated")
 }
+
+func (r *RecordV1) SetCredentialChecksV1(checks CredentialChecksV1) {
+	r.CredentialChecks.Sources = mergeTypedChecks(r.CredentialChecks.Sources, checks.Sources)
+	r.CredentialChecks.Destinations = mergeTypedChecks(r.CredentialChecks.Destinations, checks.Destinations)
+}
+
+//keepUnchangedChecks - returns the checks of credentials which are the same in prev and next
+func keepUnchangedChecks(checks map[string]map[string]CredentialCheckV1, prev, next map[string]map[string]plugin.Credential) map[string]map[string]CredentialCheckV1 {
+	kept := make(map[string]map[string]CredentialCheckV1, len(checks))
+	for typeName, typeChecks := range checks {
+		keptChecks := make(map[string]CredentialCheckV1, len(typeChecks))
+		for name, check := range typeChecks {
+			if p, exists := prev[typeName][name]; exists && reflect.DeepEqual(p, next[typeName][name]) {
+				keptChecks[name] = check
+			}
+		}
+		if len(keptChecks) != 0 {
+			kept[typeName] = keptChecks
+		}
+	}
+	return kept
+}
@@ -27,10 +27,13 @@ type AccountViewV1 struct {
 	Alias string `json:"Alias,omitempty"` //Optional arg. Won't be returned if missing.
 }
 
-//Credentials - Credentials for various graph and storage services
+//Credentials - Credentials for various graph and storage services. GrafanaAPIUsers and ConfluenceServerUsers hold the
+//grafana sources and confluence-server destinations in the shape returned before credentials were keyed by type
 type CredentialsView1 struct {
-	GrafanaAPIUsers       map[string]GrafanaAPIUser       `json:"GrafanaAPIUsers"`
-	ConfluenceServerUsers map[string]ConfluenceServerUser `json:"ConfluenceServerUser"`
+	GrafanaAPIUsers       map[string]GrafanaAPIUser            `json:"GrafanaAPIUsers"`
+	ConfluenceServerUsers map[string]ConfluenceServerUser      `json:"ConfluenceServerUser"`
+	Sources               map[string]map[string]CredentialView `json:"Sources"`      //Keyed by source type then credential name
+	Destinations          map[string]map[string]CredentialView `json:"Destinations"` //Keyed by destination type then credential name
 }
 
 //GrafanaAPIUser - Grafana user without API key information
@@ -48,6 +51,20 @@ type ConfluenceServerUser struct {
 	Description string
 }
 
+//CredentialView - Source or destination credential without secrets
+type CredentialView struct {
+	Credential interface{}
+	LastCheck  *LastCheckViewV1 `json:"LastCheck,omitempty"` //Result of the last stored credential check. Omitted if never checked
+}
+
+//LastCheckViewV1 - Result of the last connectivity check of a stored credential
+type LastCheckViewV1 struct {
+	Result    bool
+	Cause     string `json:"Cause,omitempty"`
+	LatencyMS int64
+	CheckedAt string
+}
+
 //IsValid - returns true if model is valid. Returns false if invalid and includes a non-nil error
 func (a AccountViewV1) IsValid() (bool, error) {
 
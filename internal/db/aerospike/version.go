@@ -5,6 +5,7 @@ import (
 	"github.com/aerospike/aerospike-client-go"
 	"github.com/sajeevany/graph-snapper/internal/db/aerospike/record"
 	"github.com/sirupsen/logrus"
+	"sort"
 )
 
 const (
@@ -12,10 +13,10 @@ const (
 )
 
 //GetVersion - returns version as a string. Empty if version not found.
-func GetVersion(logger *logrus.Logger, aeroRecord aerospike.BinMap) string {
+func GetVersion(logger *logrus.Entry, aeroRecord aerospike.BinMap) string {
 
 	if aeroRecord == nil || !hasMetadataBin(logger, aeroRecord) {
-		logger.Debugf("Aerospike record is empty or missing metadata bin. Returning empty value access version. Binmap <%v>", aeroRecord)
+		logger.Debugf("Aerospike record is empty or missing metadata bin. Returning empty value access version. Bins <%v>", binMapNames(aeroRecord))
 		return ""
 	}
 
@@ -35,7 +36,7 @@ func GetVersion(logger *logrus.Logger, aeroRecord aerospike.BinMap) string {
 
 }
 
-func hasMetadataBin(logger *logrus.Logger, aeroRecord aerospike.BinMap) bool {
+func hasMetadataBin(logger *logrus.Entry, aeroRecord aerospike.BinMap) bool {
 
 	if aeroRecord == nil {
 		logger.Debug("Aerospike record is empty. Returning false for hasMetadataBin")
@@ -46,3 +47,13 @@ func hasMetadataBin(logger *logrus.Logger, aeroRecord aerospike.BinMap) bool {
 
 	return ok
 }
+
+//binMapNames - returns the sorted bin names. Values hold credential secrets and aren't logged
+func binMapNames(bm aerospike.BinMap) []string {
+	names := make([]string, 0, len(bm))
+	for name := range bm {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -1,14 +1,23 @@
 package aerospike
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"github.com/aerospike/aerospike-client-go"
+	"github.com/aerospike/aerospike-client-go/types"
 	"github.com/sajeevany/graph-snapper/internal/db/aerospike/record"
+	"github.com/sajeevany/graph-snapper/internal/logging"
+	"github.com/sajeevany/graph-snapper/internal/tracing"
 )
 
+//maxGenerationRetries - how often a write which conflicts with a concurrent write of the record is retried
+const maxGenerationRetries = 3
+
 type DbWriter interface {
-	WriteRecord(key string, record record.Record) error
-	WriteRecordWithASKey(key *aerospike.Key, record record.Record) error
+	WriteRecord(ctx context.Context, key string, record record.Record) error
+	WriteRecordWithASKey(ctx context.Context, key *aerospike.Key, record record.Record) error
+	WriteCredentialChecks(ctx context.Context, key *aerospike.Key, checked record.CredentialsV1, checks record.CredentialChecksV1) error
 }
 
 func newAerospikeWriter(asClient *ASClient) DbWriter {
@@ -22,9 +31,9 @@ type AerospikeWriter struct {
 }
 
 //Writes record with specified key in the account namespace under the account set. Returns error if one is found
-func (a *AerospikeWriter) WriteRecord(key string, record record.Record) error {
+func (a *AerospikeWriter) WriteRecord(ctx context.Context, key string, record record.Record) error {
 
-	logger := a.asClient.Logger
+	logger := logging.FromContext(ctx, a.asClient.Logger)
 	logger.WithFields(record.GetFields()).Debug("Starting record create")
 
 	//Create key
@@ -34,21 +43,80 @@ func (a *AerospikeWriter) WriteRecord(key string, record record.Record) error {
 		return err
 	}
 
-	return a.WriteRecordWithASKey(asKey, record)
+	return a.WriteRecordWithASKey(ctx, asKey, record)
 }
 
-func (a *AerospikeWriter) WriteRecordWithASKey(asKey *aerospike.Key, record record.Record) error {
+func (a *AerospikeWriter) WriteRecordWithASKey(ctx context.Context, asKey *aerospike.Key, record record.Record) (err error) {
 
-	logger := a.asClient.Logger
+	logger := logging.FromContext(ctx, a.asClient.Logger)
 	logger.WithFields(record.GetFields()).Debug("Starting record create with aerospike key")
 
+	_, span := tracing.StartSpan(ctx, "aerospike.WriteRecord", a.asClient.spanAttributes("put")...)
+	defer func() { tracing.EndSpan(span, err) }()
+
 	//GetBins
 	recBM := record.ToASBinSlice()
 	if pErr := a.asClient.Client.PutBins(nil, asKey, recBM...); pErr != nil {
-		hErr := fmt.Sprintf("Unable to write bin map <%v> to aerospike namespace <%v> set <%v> key <%v>. err <%v>", recBM, asKey.Namespace(), asKey.SetName(), asKey.String(), pErr)
+		//Bin values hold credential secrets. Only the bin names are reported
+		hErr := fmt.Errorf("unable to write bins <%v> to aerospike namespace <%v> set <%v> key <%v>. err <%v>", binNames(recBM), asKey.Namespace(), asKey.SetName(), asKey.String(), pErr)
 		logger.WithFields(record.GetFields()).Error(hErr)
-		return fmt.Errorf(hErr)
+		return hErr
 	}
 
 	return nil
 }
+
+//WriteCredentialChecks - Merges the checks into the stored record and writes only its credential checks bin. Checks of
+//credentials changed since they were checked are dropped. The write expects the generation the record was read at and
+//is retried on the latest record if a concurrent write changed it
+func (a *AerospikeWriter) WriteCredentialChecks(ctx context.Context, asKey *aerospike.Key, checked record.CredentialsV1, checks record.CredentialChecksV1) (err error) {
+
+	logger := logging.FromContext(ctx, a.asClient.Logger)
+	logger.WithFields(checks.GetFields()).Debug("Starting credential checks write")
+
+	_, span := tracing.StartSpan(ctx, "aerospike.WriteCredentialChecks", a.asClient.spanAttributes("put")...)
+	defer func() { tracing.EndSpan(span, err) }()
+
+	for attempt := 0; ; attempt++ {
+		aRecord, rErr := a.asClient.Client.Get(a.asClient.ReadPolicy, asKey)
+		if rErr != nil {
+			logger.Errorf("Error when reading key <%v> before writing credential checks. err <%v>", asKey.String(), rErr)
+			return rErr
+		}
+		rec, dErr := decodeRecord(logger, asKey, aRecord.Bins)
+		if dErr != nil {
+			return dErr
+		}
+		rec.SetCredentialChecksV1(checks.OfUnchanged(checked, rec.GetCredentials()))
+
+		policy := *a.asClient.WritePolicy
+		policy.GenerationPolicy = aerospike.EXPECT_GEN_EQUAL
+		policy.Generation = aRecord.Generation
+		pErr := a.asClient.Client.PutBins(&policy, asKey, rec.ToCredentialChecksBin())
+		if pErr == nil {
+			return nil
+		}
+		if isGenerationError(pErr) && attempt < maxGenerationRetries {
+			logger.Debugf("Key <%v> changed while its credentials were checked. Retrying credential checks write", asKey.String())
+			continue
+		}
+		hErr := fmt.Errorf("unable to write credential checks to aerospike namespace <%v> set <%v> key <%v>. err <%v>", asKey.Namespace(), asKey.SetName(), asKey.String(), pErr)
+		logger.Error(hErr)
+		return hErr
+	}
+}
+
+//isGenerationError - returns true if the write failed because the record's generation changed
+func isGenerationError(err error) bool {
+	var asErr interface{ ResultCode() types.ResultCode }
+	return errors.As(err, &asErr) && asErr.ResultCode() == types.GENERATION_ERROR
+}
+
+//binNames - returns the names of the bins
+func binNames(bins []*aerospike.Bin) []string {
+	names := make([]string, 0, len(bins))
+	for _, bin := range bins {
+		names = append(names, bin.Name)
+	}
+	return names
+}
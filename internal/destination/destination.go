@@ -0,0 +1,48 @@
+package destination
+
+import (
+	"context"
+	"github.com/sajeevany/graph-snapper/internal/artifact"
+	"github.com/sajeevany/graph-snapper/internal/plugin"
+	"github.com/sirupsen/logrus"
+)
+
+//Destination - Document store snapshots are published to. Implementations are registered by type name and own the
+//clients they need so that handlers only deal with stored credentials
+type Destination interface {
+	//Type - unique name the destination is registered and its credentials are stored under. ie confluence-server
+	Type() string
+	//NewCredential - returns a pointer to an empty credential which request bodies and stored bin maps are decoded into
+	NewCredential() Credential
+	//Validate - returns an error describing why the credential can't be stored. Connectivity isn't checked
+	Validate(cred Credential) error
+	//CheckAccess - checks that the credential can publish to the destination. Failures are reported in the result
+	CheckAccess(ctx context.Context, logger *logrus.Entry, cred Credential) plugin.CheckResultV1
+	//Publish - publishes the bundle with the credential stored under name. A failed publish leaves nothing behind
+	Publish(ctx context.Context, logger *logrus.Entry, name string, cred Credential, bundle Bundle) (ReceiptV1, error)
+	//Rollback - removes what a successful publish of the bundle created
+	Rollback(ctx context.Context, logger *logrus.Entry, name string, cred Credential, bundle Bundle, receipt ReceiptV1) error
+}
+
+//Credential - Settings and secrets of a destination
+type Credential = plugin.Credential
+
+//Bundle - Snapshots of a single capture published together
+type Bundle struct {
+	AccountID string
+	JobID     string
+	Items     []Item
+}
+
+//Item - Stored snapshot with its image and query result exports
+type Item struct {
+	Snapshot artifact.SnapshotV1
+	Image    []byte
+	Exports  []artifact.DataExport //Optional. In the order of Snapshot.Exports
+}
+
+//ReceiptV1 - Where a bundle was published. Passed back to Rollback to remove it
+type ReceiptV1 struct {
+	Location string   //ie page url, bucket or report index
+	IDs      []string `json:"IDs,omitempty"` //Destination identifiers of what was created. ie page ID or object keys
+}
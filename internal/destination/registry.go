@@ -0,0 +1,40 @@
+package destination
+
+import (
+	"github.com/sajeevany/graph-snapper/internal/plugin"
+)
+
+var registry = plugin.NewRegistry("destination")
+
+//Register - makes the destination available by its type name. Panics if the type is empty or already registered
+func Register(d Destination) {
+	if d == nil {
+		panic("destination: Register destination is nil")
+	}
+	registry.Register(d)
+}
+
+//Get - returns the destination registered with the type name
+func Get(typeName string) (Destination, bool) {
+	p, exists := registry.Get(typeName)
+	if !exists {
+		return nil, false
+	}
+	return p.(Destination), true
+}
+
+//Types - returns the registered type names in sorted order
+func Types() []string {
+	return registry.Types()
+}
+
+//DecodeJSON - decodes a request body credential of the type
+func DecodeJSON(typeName string, data []byte) (Credential, error) {
+	return registry.DecodeJSON(typeName, data)
+}
+
+//DecodeBinMap - decodes a stored credential of the type. bm is the bin map returned by aerospike. Errors only name the
+//invalid fields so that stored secrets aren't logged
+func DecodeBinMap(typeName string, bm interface{}) (Credential, error) {
+	return registry.DecodeBinMap(typeName, bm)
+}
@@ -0,0 +1,154 @@
+package destination
+
+import (
+	"context"
+	"github.com/sajeevany/graph-snapper/internal/plugin"
+	"github.com/sirupsen/logrus"
+	"reflect"
+	"testing"
+)
+
+type fakeCredential struct {
+	Bucket string
+	Token  string
+}
+
+func (c fakeCredential) GetFields() logrus.Fields {
+	return logrus.Fields{"Bucket": c.Bucket}
+}
+
+func (c fakeCredential) ToBinMap() map[string]interface{} {
+	return map[string]interface{}{"Bucket": c.Bucket, "Token": c.Token}
+}
+
+func (c fakeCredential) RedactedView() interface{} {
+	c.Token = ""
+	return c
+}
+
+type fakeDestination struct {
+	typeName string
+}
+
+func (d fakeDestination) Type() string {
+	return d.typeName
+}
+
+func (d fakeDestination) NewCredential() Credential {
+	return &fakeCredential{}
+}
+
+func (d fakeDestination) Validate(cred Credential) error {
+	return nil
+}
+
+func (d fakeDestination) CheckAccess(ctx context.Context, logger *logrus.Entry, cred Credential) plugin.CheckResultV1 {
+	return plugin.Passed(nil)
+}
+
+func (d fakeDestination) Publish(ctx context.Context, logger *logrus.Entry, name string, cred Credential, bundle Bundle) (ReceiptV1, error) {
+	return ReceiptV1{}, nil
+}
+
+func (d fakeDestination) Rollback(ctx context.Context, logger *logrus.Entry, name string, cred Credential, bundle Bundle, receipt ReceiptV1) error {
+	return nil
+}
+
+func TestRegister(t *testing.T) {
+	tests := []struct {
+		name      string
+		dest      Destination
+		wantPanic bool
+	}{
+		{
+			name: "test0 new type",
+			dest: fakeDestination{typeName: "fake-register"},
+		},
+		{
+			name:      "test1 duplicate type",
+			dest:      fakeDestination{typeName: "fake-register"},
+			wantPanic: true,
+		},
+		{
+			name:      "test2 empty type",
+			dest:      fakeDestination{},
+			wantPanic: true,
+		},
+		{
+			name:      "test3 nil destination",
+			wantPanic: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			defer func() {
+				if r := recover(); (r != nil) != tt.wantPanic {
+					t.Errorf("Register() panic = %v, wantPanic %v", r, tt.wantPanic)
+				}
+			}()
+			Register(tt.dest)
+		})
+	}
+
+	if _, exists := Get("fake-register"); !exists {
+		t.Errorf("Get() didn't return the registered destination")
+	}
+}
+
+func TestDecode(t *testing.T) {
+
+	Register(fakeDestination{typeName: "fake-decode"})
+	want := fakeCredential{Bucket: "snapshots", Token: "secret"}
+
+	tests := []struct {
+		name     string
+		typeName string
+		decode   func(typeName string) (Credential, error)
+		want     Credential
+		wantErr  bool
+	}{
+		{
+			name:     "test0 request body",
+			typeName: "fake-decode",
+			decode: func(typeName string) (Credential, error) {
+				return DecodeJSON(typeName, []byte(`{"Bucket":"snapshots","Token":"secret"}`))
+			},
+			want: want,
+		},
+		{
+			name:     "test1 stored bin map",
+			typeName: "fake-decode",
+			decode: func(typeName string) (Credential, error) {
+				return DecodeBinMap(typeName, want.ToBinMap())
+			},
+			want: want,
+		},
+		{
+			name:     "test2 invalid request body",
+			typeName: "fake-decode",
+			decode: func(typeName string) (Credential, error) {
+				return DecodeJSON(typeName, []byte(`{"Bucket":1}`))
+			},
+			wantErr: true,
+		},
+		{
+			name:     "test3 unknown type",
+			typeName: "fake-unknown",
+			decode: func(typeName string) (Credential, error) {
+				return DecodeJSON(typeName, []byte(`{}`))
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.decode(tt.typeName)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("decode() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("decode() got = %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
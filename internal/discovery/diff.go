@@ -0,0 +1,70 @@
+package discovery
+
+import (
+	"context"
+	"fmt"
+	"github.com/sajeevany/graph-snapper/internal/artifact"
+	"github.com/sajeevany/graph-snapper/internal/config"
+	"github.com/sajeevany/graph-snapper/internal/imagediff"
+	"github.com/sirupsen/logrus"
+)
+
+//IsValid - returns true if the tolerance and threshold are between 0 and 1. Nil options are valid
+func (d *DiffOptionsV1) IsValid(currentPath string, invalidArgs map[string]string) bool {
+
+	if d == nil {
+		return true
+	}
+
+	valid := true
+	if d.Tolerance != nil && (*d.Tolerance < 0 || *d.Tolerance > 1) {
+		config.AddInvalidArgWithCause(currentPath, "Tolerance", fmt.Sprint(*d.Tolerance), "value isn't between 0 and 1", invalidArgs)
+		valid = false
+	}
+	if d.Threshold < 0 || d.Threshold > 1 {
+		config.AddInvalidArgWithCause(currentPath, "Threshold", fmt.Sprint(d.Threshold), "value isn't between 0 and 1", invalidArgs)
+		valid = false
+	}
+	return valid
+}
+
+//unchanged - returns true if the snapshot was compared and its score is at or below the threshold
+func (d *DiffOptionsV1) unchanged(snapshot artifact.SnapshotV1) bool {
+	return d != nil && snapshot.Diff != nil && snapshot.Diff.Score <= d.Threshold
+}
+
+//diffPrevious - compares the image with the last published capture of the snapshot's panel. Returns a nil diff if the
+//options are nil or no capture of the panel was published before. Failures are logged and the capture continues without a diff so that a
+//missing or unreadable previous image doesn't block the capture
+func diffPrevious(ctx context.Context, logger *logrus.Entry, store *artifact.Store, snapshot artifact.SnapshotV1, img []byte, opts *DiffOptionsV1) (*artifact.DiffV1, []byte) {
+
+	if opts == nil {
+		return nil, nil
+	}
+
+	previous, prevImg, err := store.Previous(ctx, snapshot)
+	if err == artifact.ErrNotFound {
+		logger.WithFields(snapshot.GetFields()).Debug("No capture of the panel was published before. Skipping diff")
+		return nil, nil
+	}
+	if err != nil {
+		logger.WithFields(snapshot.GetFields()).Errorf("Unable to read previous capture. Skipping diff. err <%v>", err)
+		return nil, nil
+	}
+
+	diff := &artifact.DiffV1{PreviousID: previous.ID, Score: 1}
+	if previous.ContentType != snapshot.ContentType {
+		logger.Debugf("Previous capture <%v> is a <%v> and can't be compared with a <%v>", previous.ID, previous.ContentType, snapshot.ContentType)
+		return diff, nil
+	}
+
+	result, err := imagediff.Compare(prevImg, img, snapshot.ContentType, imagediff.Options{Tolerance: opts.Tolerance, Highlight: opts.Image})
+	if err != nil {
+		logger.WithFields(snapshot.GetFields()).Errorf("Unable to compare with previous capture <%v>. Skipping diff. err <%v>", previous.ID, err)
+		return nil, nil
+	}
+	diff.Score, diff.ChangedPixels = result.Score, result.ChangedPixels
+	logger.Debugf("Capture differs from previous capture <%v> by <%v>", previous.ID, diff.Score)
+
+	return diff, result.Image
+}
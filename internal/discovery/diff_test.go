@@ -0,0 +1,148 @@
+package discovery
+
+import (
+	"bytes"
+	"context"
+	"github.com/sajeevany/graph-snapper/internal/artifact"
+	"github.com/sajeevany/graph-snapper/internal/config"
+	"github.com/sirupsen/logrus"
+	"image"
+	"image/color"
+	"image/png"
+	"testing"
+	"time"
+)
+
+func TestDiffOptionsV1_IsValid(t *testing.T) {
+	tolerance := func(v float64) *float64 { return &v }
+	tests := []struct {
+		name        string
+		opts        *DiffOptionsV1
+		want        bool
+		wantInvalid int
+	}{
+		{
+			name: "test0 no diff",
+			want: true,
+		},
+		{
+			name: "test1 defaults",
+			opts: &DiffOptionsV1{SkipUnchanged: true},
+			want: true,
+		},
+		{
+			name:        "test2 out of range",
+			opts:        &DiffOptionsV1{Tolerance: tolerance(-0.1), Threshold: 2},
+			want:        false,
+			wantInvalid: 2,
+		},
+		{
+			name: "test3 exact comparison",
+			opts: &DiffOptionsV1{Tolerance: tolerance(0)},
+			want: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			invalid := map[string]string{}
+			if got := tt.opts.IsValid("capture.Diff", invalid); got != tt.want || len(invalid) != tt.wantInvalid {
+				t.Errorf("IsValid() = %v with invalid args %v, want %v with %v invalid args", got, invalid, tt.want, tt.wantInvalid)
+			}
+		})
+	}
+}
+
+func Test_diffPrevious(t *testing.T) {
+
+	//square - returns a 10x10 white PNG with a black square of the size in the corner
+	square := func(size int) []byte {
+		img := image.NewRGBA(image.Rect(0, 0, 10, 10))
+		for x := 0; x < 10; x++ {
+			for y := 0; y < 10; y++ {
+				img.SetRGBA(x, y, color.RGBA{R: 255, G: 255, B: 255, A: 255})
+				if x < size && y < size {
+					img.SetRGBA(x, y, color.RGBA{A: 255})
+				}
+			}
+		}
+		var buf bytes.Buffer
+		if err := png.Encode(&buf, img); err != nil {
+			t.Fatal(err)
+		}
+		return buf.Bytes()
+	}
+
+	store := artifact.NewStoreWithBackend(artifact.NewMemoryBackend(), config.Artifacts{})
+	logger := logrus.NewEntry(logrus.New())
+	ctx := context.Background()
+	panel := artifact.SnapshotV1{AccountID: "abc", JobID: "daily", Source: "grafana", DashboardUID: "cpu01", PanelID: 1, ContentType: artifact.PNGContentType}
+
+	if diff, _ := diffPrevious(ctx, logger, store, panel, square(2), &DiffOptionsV1{}); diff != nil {
+		t.Errorf("diffPrevious() of an uncaptured panel = %+v, want nil", diff)
+	}
+	previous, err := store.Save(ctx, logger, panel, square(2))
+	if err != nil {
+		t.Fatal(err)
+	}
+	panel.CapturedAt = previous.CapturedAt.Add(time.Minute)
+
+	tests := []struct {
+		name          string
+		img           []byte
+		opts          *DiffOptionsV1
+		wantDiff      bool
+		wantScore     float64
+		wantImage     bool
+		wantUnchanged bool
+	}{
+		{
+			name: "test0 not requested",
+			img:  square(2),
+		},
+		{
+			name:          "test1 unchanged",
+			img:           square(2),
+			opts:          &DiffOptionsV1{},
+			wantDiff:      true,
+			wantUnchanged: true,
+		},
+		{
+			name:      "test2 changed with image",
+			img:       square(4),
+			opts:      &DiffOptionsV1{Image: true},
+			wantDiff:  true,
+			wantScore: 0.12,
+			wantImage: true,
+		},
+		{
+			name:          "test3 change within threshold",
+			img:           square(4),
+			opts:          &DiffOptionsV1{Threshold: 0.2},
+			wantDiff:      true,
+			wantScore:     0.12,
+			wantUnchanged: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			diff, diffImg := diffPrevious(ctx, logger, store, panel, tt.img, tt.opts)
+			if (diff != nil) != tt.wantDiff {
+				t.Fatalf("diffPrevious() = %+v, wantDiff %v", diff, tt.wantDiff)
+			}
+			if (diffImg != nil) != tt.wantImage {
+				t.Errorf("diffPrevious() image = %v bytes, wantImage %v", len(diffImg), tt.wantImage)
+			}
+			if diff == nil {
+				return
+			}
+			if diff.PreviousID != previous.ID || diff.Score != tt.wantScore {
+				t.Errorf("diffPrevious() = %+v, want score %v against <%v>", diff, tt.wantScore, previous.ID)
+			}
+			snapshot := panel
+			snapshot.Diff = diff
+			if got := tt.opts.unchanged(snapshot); got != tt.wantUnchanged {
+				t.Errorf("unchanged() = %v, want %v", got, tt.wantUnchanged)
+			}
+		})
+	}
+}
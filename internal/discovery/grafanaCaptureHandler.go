@@ -0,0 +1,214 @@
+package discovery
+
+import (
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"github.com/sajeevany/graph-snapper/internal/artifact"
+	as "github.com/sajeevany/graph-snapper/internal/db/aerospike"
+	"github.com/sajeevany/graph-snapper/internal/db/aerospike/record"
+	"github.com/sajeevany/graph-snapper/internal/destination"
+	"github.com/sajeevany/graph-snapper/internal/grafana"
+	"github.com/sajeevany/graph-snapper/internal/logging"
+	"github.com/sajeevany/graph-snapper/internal/source"
+	"github.com/sajeevany/graph-snapper/internal/upstream"
+	"github.com/sirupsen/logrus"
+	"net/http"
+	"sort"
+)
+
+const GrafanaCaptureEndpoint = "/:id/grafana/:credential/snapshots"
+
+//@Summary Capture a grafana panel
+//@Description Non-authenticated endpoint that renders a snapshot target with a stored grafana credential and stores the image in the artifact store. The snapshot is then published with the named destination credentials. Failed publishes are reported per credential and don't fail the capture. Atomic captures roll back successful publishes if any fail. Captures can be compared with the previous capture of the panel by the job and skip publishing when unchanged
+//@Accept json
+//@Produce json
+//@Param id path string true "Account ID"
+//@Param credential path string true "Grafana credential name"
+//@Param capture body CaptureRequestV1 true "Snapshot target"
+//@Success 201 {object} CaptureResultV1
+//@Fail 400 {object} gin.H
+//@Fail 404 {object} gin.H
+//@Fail 500 {object} gin.H
+//@Fail 502 {object} gin.H
+//@Router /account/:id/grafana/:credential/snapshots [post]
+//@Tags grafana
+func GrafanaCaptureV1(baseLogger *logrus.Logger, aeroClient *as.ASClient, client *upstream.Client, store *artifact.Store) gin.HandlerFunc {
+	grafanaSource := grafana.NewSource(client)
+	return func(ctx *gin.Context) {
+
+		//Use request scoped logger
+		logger := logging.FromContext(ctx.Request.Context(), baseLogger)
+
+		var capture CaptureRequestV1
+		if bErr := ctx.BindJSON(&capture); bErr != nil {
+			msg := fmt.Sprintf("Unable to bind request body to capture object %v", bErr)
+			logger.Errorf(msg)
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": msg})
+			return
+		}
+
+		invalidArgs := make(map[string]string)
+		if !capture.Target.IsValid("capture.Target", invalidArgs) || !capture.Diff.IsValid("capture.Diff", invalidArgs) {
+			logger.Debugf("snapshot target is invalid. <%v>", invalidArgs)
+			ctx.JSON(http.StatusBadRequest, gin.H{
+				"error":              invalidArgs,
+				"humanReadableError": "Snapshot target has invalid fields",
+			})
+			return
+		}
+
+		creds, found := getAccountCredentials(ctx, logger, aeroClient)
+		if !found {
+			return
+		}
+		user, found := findGrafanaUser(ctx, logger, creds)
+		if !found {
+			return
+		}
+		targets, found := findDestinations(ctx, logger, creds, capture.Destinations)
+		if !found {
+			return
+		}
+
+		captureSnapshot(ctx, logger, store, grafanaSource, user, capture.Target, capture.JobID, targets, capture.Atomic, capture.Diff)
+	}
+}
+
+//captureSnapshot - renders the target with the source, stores the image and publishes it with each target. Writes the
+//capture result or error response. Publishing is skipped if requested and the image didn't change since the last
+//published capture
+func captureSnapshot(ctx *gin.Context, logger *logrus.Entry, store *artifact.Store, s source.Source, cred source.Credential, target source.Target, jobID string, targets []publishTarget, atomic bool, diffOpts *DiffOptionsV1) {
+
+	render, err := s.Render(ctx.Request.Context(), logger, cred, target)
+	if err != nil {
+		writeSourceError(ctx, logger, s.Type(), err)
+		return
+	}
+
+	snapshot := artifact.SnapshotV1{
+		AccountID:      ctx.Param("id"),
+		JobID:          jobID,
+		Source:         s.Type(),
+		DashboardUID:   render.DashboardUID,
+		DashboardTitle: render.DashboardTitle,
+		PanelID:        render.PanelID,
+		PanelTitle:     render.PanelTitle,
+		From:           render.From,
+		To:             render.To,
+		ContentType:    render.ContentType,
+	}
+	if snapshot.ContentType == "" {
+		snapshot.ContentType = artifact.PNGContentType
+	}
+	diff, diffImg := diffPrevious(ctx.Request.Context(), logger, store, snapshot, render.Image, diffOpts)
+	snapshot.Diff = diff
+	unchanged := diffOpts.unchanged(snapshot)
+	//Recorded on the snapshot so that later captures are compared with the last published capture
+	snapshot.PublishSkipped = unchanged && diffOpts.SkipUnchanged && len(targets) > 0
+
+	snapshot, err = store.SaveCapture(ctx.Request.Context(), logger, snapshot, artifact.Capture{Image: render.Image, Exports: render.Exports, DiffImage: diffImg})
+	if err != nil {
+		hrErrMsg := "Unable to store the captured snapshot"
+		logger.Errorf("%v. err <%v>", hrErrMsg, err)
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"error":              err.Error(),
+			"humanReadableError": hrErrMsg,
+		})
+		return
+	}
+
+	result := CaptureResultV1{SnapshotV1: snapshot, Unchanged: unchanged}
+	if unchanged && diffOpts.SkipUnchanged {
+		logger.WithFields(snapshot.GetFields()).Infof("Capture is unchanged from <%v>. Skipping publish", snapshot.Diff.PreviousID)
+		ctx.JSON(http.StatusCreated, result)
+		return
+	}
+
+	bundle := destination.Bundle{
+		AccountID: snapshot.AccountID,
+		JobID:     snapshot.JobID,
+		Items:     []destination.Item{{Snapshot: snapshot, Image: render.Image, Exports: render.Exports}},
+	}
+	result.Published = publish(ctx, logger, targets, bundle, atomic)
+	ctx.JSON(http.StatusCreated, result)
+}
+
+//publishTarget - Stored destination credential a capture is published with
+type publishTarget struct {
+	dest destination.Destination
+	name string
+	cred destination.Credential
+}
+
+//findDestinations - returns the named destination credentials in type then request order. Writes the error response
+//and returns false if any don't exist
+func findDestinations(ctx *gin.Context, logger *logrus.Entry, creds record.CredentialsV1, names map[string][]string) ([]publishTarget, bool) {
+
+	types := make([]string, 0, len(names))
+	for typeName := range names {
+		types = append(types, typeName)
+	}
+	sort.Strings(types)
+
+	var targets []publishTarget
+	for _, typeName := range types {
+		d, registered := destination.Get(typeName)
+		if !registered {
+			logger.Debugf("Destination type <%v> isn't registered. Returning 400", typeName)
+			ctx.JSON(http.StatusBadRequest, gin.H{
+				"error":              fmt.Sprintf("unknown destination type <%v>", typeName),
+				"humanReadableError": fmt.Sprintf("Destination type %v isn't supported. Supported types are %v", typeName, destination.Types()),
+			})
+			return nil, false
+		}
+		for _, name := range names[typeName] {
+			cred, exists := creds.Destinations[typeName][name]
+			if !exists {
+				logger.Debugf("Destination credential <%v.%v> does not exist on account <%v>. Returning 404", typeName, name, ctx.Param("id"))
+				ctx.JSON(http.StatusNotFound, gin.H{
+					"error":              fmt.Sprintf("%v credential <%v> doesn't exist", typeName, name),
+					"humanReadableError": fmt.Sprintf("No %v credential named %v exists on account %v", typeName, name, ctx.Param("id")),
+				})
+				return nil, false
+			}
+			targets = append(targets, publishTarget{dest: d, name: name, cred: cred})
+		}
+	}
+	return targets, true
+}
+
+//publish - publishes the bundle with each target. Failures are reported per target. When atomic, successful
+//publishes are rolled back after any failure
+func publish(ctx *gin.Context, logger *logrus.Entry, targets []publishTarget, bundle destination.Bundle, atomic bool) []PublishResultV1 {
+
+	results := make([]PublishResultV1, len(targets))
+	failed := false
+	for i, t := range targets {
+		results[i] = PublishResultV1{Type: t.dest.Type(), Credential: t.name}
+		receipt, err := t.dest.Publish(ctx.Request.Context(), logger, t.name, t.cred, bundle)
+		if err != nil {
+			logger.Errorf("Unable to publish bundle of job <%v> with %v credential <%v>. err <%v>", bundle.JobID, t.dest.Type(), t.name, err)
+			results[i].Error = err.Error()
+			failed = true
+			continue
+		}
+		results[i].ReceiptV1 = receipt
+	}
+
+	if !atomic || !failed {
+		return results
+	}
+
+	for i, t := range targets {
+		if results[i].Error != "" {
+			continue
+		}
+		if err := t.dest.Rollback(ctx.Request.Context(), logger, t.name, t.cred, bundle, results[i].ReceiptV1); err != nil {
+			logger.Errorf("Unable to roll back publish with %v credential <%v>. err <%v>", t.dest.Type(), t.name, err)
+			results[i].Error = fmt.Sprintf("rollback failed. %v", err)
+			continue
+		}
+		results[i].RolledBack = true
+	}
+	return results
+}
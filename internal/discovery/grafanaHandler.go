@@ -0,0 +1,287 @@
+package discovery
+
+import (
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"github.com/sajeevany/graph-snapper/internal/common"
+	as "github.com/sajeevany/graph-snapper/internal/db/aerospike"
+	"github.com/sajeevany/graph-snapper/internal/db/aerospike/record"
+	"github.com/sajeevany/graph-snapper/internal/grafana"
+	"github.com/sajeevany/graph-snapper/internal/logging"
+	"github.com/sajeevany/graph-snapper/internal/upstream"
+	"github.com/sirupsen/logrus"
+	"net/http"
+	"net/url"
+)
+
+const (
+	GrafanaSearchEndpoint          = "/:id/grafana/:credential/search"
+	GrafanaFoldersEndpoint         = "/:id/grafana/:credential/folders"
+	GrafanaDashboardPanelsEndpoint = "/:id/grafana/:credential/dashboards/:uid/panels"
+	GrafanaCheckTargetEndpoint     = "/:id/grafana/:credential/targets/check"
+)
+
+//searchParams - grafana search parameters passed through to /api/search
+var searchParams = []string{"query", "tag", "type", "folderIds", "folderUIDs", "dashboardUIDs", "starred", "limit", "page"}
+
+//@Summary Search grafana dashboards
+//@Description Non-authenticated endpoint that searches dashboards and folders with a stored grafana credential. Supports grafana's query, tag, type, folderIds, folderUIDs, dashboardUIDs, starred, limit and page parameters
+//@Produce json
+//@Param id path string true "Account ID"
+//@Param credential path string true "Grafana credential name"
+//@Param query query string false "Search string"
+//@Param tag query string false "Dashboard tag. Can be repeated"
+//@Param type query string false "dash-db or dash-folder"
+//@Success 200 {array} grafana.SearchHitV1
+//@Fail 404 {object} gin.H
+//@Fail 500 {object} gin.H
+//@Fail 502 {object} gin.H
+//@Router /account/:id/grafana/:credential/search [get]
+//@Tags grafana
+func GrafanaSearchV1(baseLogger *logrus.Logger, aeroClient *as.ASClient, client *upstream.Client) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+
+		//Use request scoped logger
+		logger := logging.FromContext(ctx.Request.Context(), baseLogger)
+
+		user, found := getGrafanaUser(ctx, logger, aeroClient)
+		if !found {
+			return
+		}
+
+		hits, err := grafana.Search(ctx.Request.Context(), logger, client, user, filterSearchParams(ctx.Request.URL.Query()))
+		if err != nil {
+			writeGrafanaError(ctx, logger, err)
+			return
+		}
+
+		ctx.JSON(http.StatusOK, hits)
+	}
+}
+
+//@Summary List grafana folders
+//@Description Non-authenticated endpoint that lists the dashboard folders visible to a stored grafana credential
+//@Produce json
+//@Param id path string true "Account ID"
+//@Param credential path string true "Grafana credential name"
+//@Success 200 {array} grafana.FolderV1
+//@Fail 404 {object} gin.H
+//@Fail 500 {object} gin.H
+//@Fail 502 {object} gin.H
+//@Router /account/:id/grafana/:credential/folders [get]
+//@Tags grafana
+func GrafanaFoldersV1(baseLogger *logrus.Logger, aeroClient *as.ASClient, client *upstream.Client) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+
+		//Use request scoped logger
+		logger := logging.FromContext(ctx.Request.Context(), baseLogger)
+
+		user, found := getGrafanaUser(ctx, logger, aeroClient)
+		if !found {
+			return
+		}
+
+		folders, err := grafana.ListFolders(ctx.Request.Context(), logger, client, user)
+		if err != nil {
+			writeGrafanaError(ctx, logger, err)
+			return
+		}
+
+		ctx.JSON(http.StatusOK, folders)
+	}
+}
+
+//@Summary List grafana dashboard panels
+//@Description Non-authenticated endpoint that returns the id, title, type and position of each panel of a dashboard using a stored grafana credential
+//@Produce json
+//@Param id path string true "Account ID"
+//@Param credential path string true "Grafana credential name"
+//@Param uid path string true "Dashboard UID"
+//@Success 200 {object} grafana.DashboardPanelsV1
+//@Fail 404 {object} gin.H
+//@Fail 500 {object} gin.H
+//@Fail 502 {object} gin.H
+//@Router /account/:id/grafana/:credential/dashboards/:uid/panels [get]
+//@Tags grafana
+func GrafanaDashboardPanelsV1(baseLogger *logrus.Logger, aeroClient *as.ASClient, client *upstream.Client) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+
+		//Use request scoped logger
+		logger := logging.FromContext(ctx.Request.Context(), baseLogger)
+
+		user, found := getGrafanaUser(ctx, logger, aeroClient)
+		if !found {
+			return
+		}
+
+		panels, err := grafana.GetDashboardPanels(ctx.Request.Context(), logger, client, user, ctx.Param("uid"))
+		if err != nil {
+			writeGrafanaError(ctx, logger, err)
+			return
+		}
+
+		ctx.JSON(http.StatusOK, panels)
+	}
+}
+
+//@Summary Check a snapshot target
+//@Description Non-authenticated endpoint that validates a snapshot target's time range, timezone and theme and reports template variables the dashboard doesn't define
+//@Accept json
+//@Produce json
+//@Param id path string true "Account ID"
+//@Param credential path string true "Grafana credential name"
+//@Param target body grafana.SnapshotTargetV1 true "Snapshot target"
+//@Success 200 {object} TargetCheckV1
+//@Fail 400 {object} gin.H
+//@Fail 404 {object} gin.H
+//@Fail 500 {object} gin.H
+//@Fail 502 {object} gin.H
+//@Router /account/:id/grafana/:credential/targets/check [post]
+//@Tags grafana
+func GrafanaCheckTargetV1(baseLogger *logrus.Logger, aeroClient *as.ASClient, client *upstream.Client) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+
+		//Use request scoped logger
+		logger := logging.FromContext(ctx.Request.Context(), baseLogger)
+
+		var target grafana.SnapshotTargetV1
+		if bErr := ctx.BindJSON(&target); bErr != nil {
+			msg := fmt.Sprintf("Unable to bind request body to snapshot target object %v", bErr)
+			logger.Errorf(msg)
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": msg})
+			return
+		}
+
+		invalidArgs := make(map[string]string)
+		if !target.IsValid("target", invalidArgs) {
+			logger.Debugf("snapshot target is invalid. <%v>", invalidArgs)
+			ctx.JSON(http.StatusBadRequest, gin.H{
+				"error":              invalidArgs,
+				"humanReadableError": "Snapshot target has invalid fields",
+			})
+			return
+		}
+
+		user, found := getGrafanaUser(ctx, logger, aeroClient)
+		if !found {
+			return
+		}
+
+		unknown, err := grafana.FindUnknownVariables(ctx.Request.Context(), logger, client, user, target)
+		if err != nil {
+			writeGrafanaError(ctx, logger, err)
+			return
+		}
+
+		ctx.JSON(http.StatusOK, TargetCheckV1{
+			UnknownVariables: unknown,
+			RenderParams:     target.RenderParams().Encode(),
+		})
+	}
+}
+
+//getGrafanaUser - returns the stored grafana credential named in the path. Writes the error response and returns false if
+//the account or credential doesn't exist
+func getGrafanaUser(ctx *gin.Context, logger *logrus.Entry, aeroClient *as.ASClient) (common.GrafanaUserV1, bool) {
+
+	creds, found := getAccountCredentials(ctx, logger, aeroClient)
+	if !found {
+		return common.GrafanaUserV1{}, false
+	}
+	return findGrafanaUser(ctx, logger, creds)
+}
+
+//getAccountCredentials - returns the credentials stored on the account in the path. Writes the error response and returns
+//false if the account doesn't exist
+func getAccountCredentials(ctx *gin.Context, logger *logrus.Entry, aeroClient *as.ASClient) (record.CredentialsV1, bool) {
+
+	accountID := ctx.Param("id")
+
+	reader := aeroClient.GetReader()
+	exists, key, kErr := reader.KeyExists(ctx.Request.Context(), accountID)
+	if kErr != nil {
+		hrErrMsg := fmt.Sprintf("unable to check db for key <%v>", accountID)
+		logger.Errorf("%v. err <%v>", hrErrMsg, kErr)
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"error":              kErr.Error(),
+			"humanReadableError": hrErrMsg,
+		})
+		return record.CredentialsV1{}, false
+	}
+	if !exists {
+		logger.Debugf("account <%v> does not exist. Returning 404", accountID)
+		ctx.JSON(http.StatusNotFound, gin.H{
+			"error":              fmt.Sprintf("key <%v> doesn't exist", accountID),
+			"humanReadableError": fmt.Sprintf("No account exists with ID %v", accountID),
+		})
+		return record.CredentialsV1{}, false
+	}
+
+	rec, rErr := reader.ReadRecord(ctx.Request.Context(), key)
+	if rErr != nil {
+		hrErrMsg := "Internal error when reading account from Aerospike data store"
+		logger.Errorf("%v. err <%v>", hrErrMsg, rErr)
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"error":              rErr.Error(),
+			"humanReadableError": hrErrMsg,
+		})
+		return record.CredentialsV1{}, false
+	}
+
+	return rec.GetCredentials(), true
+}
+
+//findGrafanaUser - returns the grafana credential named in the path. Writes the error response and returns false if the
+//credential doesn't exist
+func findGrafanaUser(ctx *gin.Context, logger *logrus.Entry, creds record.CredentialsV1) (common.GrafanaUserV1, bool) {
+
+	accountID, credential := ctx.Param("id"), ctx.Param("credential")
+
+	user, exists := creds.Sources[grafana.SourceType][credential].(common.GrafanaUserV1)
+	if !exists {
+		logger.Debugf("grafana credential <%v> does not exist on account <%v>. Returning 404", credential, accountID)
+		ctx.JSON(http.StatusNotFound, gin.H{
+			"error":              fmt.Sprintf("grafana credential <%v> doesn't exist", credential),
+			"humanReadableError": fmt.Sprintf("No grafana credential named %v exists on account %v", credential, accountID),
+		})
+		return common.GrafanaUserV1{}, false
+	}
+
+	return user, true
+}
+
+//writeGrafanaError - grafana 404s are returned as is. All other grafana failures are reported as a bad gateway
+func writeGrafanaError(ctx *gin.Context, logger *logrus.Entry, err error) {
+
+	switch {
+	case grafana.IsNotFound(err):
+		logger.Debugf("grafana resource doesn't exist. <%v>", err)
+		ctx.JSON(http.StatusNotFound, gin.H{
+			"error":              err.Error(),
+			"humanReadableError": "The requested grafana resource doesn't exist or isn't visible to the credential",
+		})
+	case upstream.IsAuthError(err):
+		logger.Infof("grafana rejected the stored credential. <%v>", err)
+		ctx.JSON(http.StatusBadGateway, gin.H{
+			"error":              err.Error(),
+			"humanReadableError": "Grafana rejected the stored credential",
+		})
+	default:
+		logger.Errorf("grafana request failed. <%v>", err)
+		ctx.JSON(http.StatusBadGateway, gin.H{
+			"error":              err.Error(),
+			"humanReadableError": "Unable to complete the grafana request",
+		})
+	}
+}
+
+//filterSearchParams - returns the supported grafana search parameters
+func filterSearchParams(query url.Values) url.Values {
+	params := url.Values{}
+	for _, name := range searchParams {
+		if vals, exists := query[name]; exists {
+			params[name] = vals
+		}
+	}
+	return params
+}
@@ -0,0 +1,38 @@
+package discovery
+
+import (
+	"net/url"
+	"reflect"
+	"testing"
+)
+
+func Test_filterSearchParams(t *testing.T) {
+	tests := []struct {
+		name  string
+		query url.Values
+		want  url.Values
+	}{
+		{
+			name:  "test0 supported parameters are kept",
+			query: url.Values{"query": {"cpu"}, "tag": {"prod", "linux"}, "type": {"dash-db"}, "limit": {"10"}},
+			want:  url.Values{"query": {"cpu"}, "tag": {"prod", "linux"}, "type": {"dash-db"}, "limit": {"10"}},
+		},
+		{
+			name:  "test1 unsupported parameters are dropped",
+			query: url.Values{"query": {"cpu"}, "orgId": {"2"}, "permission": {"Edit"}},
+			want:  url.Values{"query": {"cpu"}},
+		},
+		{
+			name:  "test2 no parameters",
+			query: url.Values{},
+			want:  url.Values{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := filterSearchParams(tt.query); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("filterSearchParams() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,46 @@
+package discovery
+
+import (
+	"github.com/sajeevany/graph-snapper/internal/artifact"
+	"github.com/sajeevany/graph-snapper/internal/destination"
+	"github.com/sajeevany/graph-snapper/internal/grafana"
+)
+
+//TargetCheckV1 - Result of a snapshot target check
+type TargetCheckV1 struct {
+	UnknownVariables []string //Template variables the dashboard doesn't define. Capture fails while this isn't empty
+	RenderParams     string   //Url parameters the panel will be rendered with
+}
+
+//CaptureRequestV1 - Panel to capture and store
+type CaptureRequestV1 struct {
+	JobID        string //Optional. Recorded with the snapshot
+	Target       grafana.SnapshotTargetV1
+	Destinations map[string][]string `json:"Destinations,omitempty"` //Optional. Names of stored destination credentials keyed by destination type the snapshot is published to
+	Atomic       bool                `json:"Atomic,omitempty"`       //Optional. Rolls back successful publishes if any destination fails
+	Diff         *DiffOptionsV1      `json:"Diff,omitempty"`         //Optional. Compares the capture with the last published capture of the panel by the job
+}
+
+//DiffOptionsV1 - How a capture is compared with the last published capture of the panel by the same job
+type DiffOptionsV1 struct {
+	Tolerance     *float64 `json:"Tolerance,omitempty"`     //Optional. Perceived color difference from 0 to 1 a pixel must exceed to be changed. Defaults to 0.1. 0 counts any difference
+	Threshold     float64  `json:"Threshold,omitempty"`     //Optional. Diff score from 0 to 1 at or below which the capture is unchanged. Defaults to 0
+	Image         bool     `json:"Image,omitempty"`         //Optional. Stores an image highlighting the changed pixels
+	SkipUnchanged bool     `json:"SkipUnchanged,omitempty"` //Optional. Doesn't publish unchanged captures. They're still stored and later captures are compared with the last published capture
+}
+
+//CaptureResultV1 - Stored snapshot and the result of each publish
+type CaptureResultV1 struct {
+	artifact.SnapshotV1
+	Unchanged bool              `json:"Unchanged,omitempty"` //Set if the diff score is at or below the diff threshold
+	Published []PublishResultV1 `json:"Published,omitempty"`
+}
+
+//PublishResultV1 - Where the snapshot was published with a destination credential. Error is set if the publish failed
+type PublishResultV1 struct {
+	Type       string
+	Credential string
+	destination.ReceiptV1
+	Error      string `json:"Error,omitempty"`
+	RolledBack bool   `json:"RolledBack,omitempty"` //Set if the publish was removed because another destination failed
+}
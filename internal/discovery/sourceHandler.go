@@ -0,0 +1,199 @@
+package discovery
+
+import (
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"github.com/sajeevany/graph-snapper/internal/artifact"
+	as "github.com/sajeevany/graph-snapper/internal/db/aerospike"
+	"github.com/sajeevany/graph-snapper/internal/db/aerospike/record"
+	"github.com/sajeevany/graph-snapper/internal/logging"
+	"github.com/sajeevany/graph-snapper/internal/source"
+	"github.com/sajeevany/graph-snapper/internal/upstream"
+	"github.com/sirupsen/logrus"
+	"net/http"
+)
+
+const (
+	SourceDashboardsEndpoint = "/:id/sources/:type/:credential/dashboards"
+	SourceCaptureEndpoint    = "/:id/sources/:type/:credential/snapshots"
+)
+
+//@Summary List source dashboards
+//@Description Non-authenticated endpoint that lists the dashboards visible to a stored source credential. Prometheus lists metric names instead
+//@Produce json
+//@Param id path string true "Account ID"
+//@Param type path string true "Source type. ie grafana or prometheus"
+//@Param credential path string true "Source credential name"
+//@Param query query string false "Only dashboards matching the query are listed"
+//@Success 200 {array} source.DashboardV1
+//@Fail 400 {object} gin.H
+//@Fail 404 {object} gin.H
+//@Fail 500 {object} gin.H
+//@Fail 502 {object} gin.H
+//@Router /account/:id/sources/:type/:credential/dashboards [get]
+//@Tags sources
+func SourceDashboardsV1(baseLogger *logrus.Logger, aeroClient *as.ASClient) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+
+		//Use request scoped logger
+		logger := logging.FromContext(ctx.Request.Context(), baseLogger)
+
+		s, found := findSource(ctx, logger)
+		if !found {
+			return
+		}
+		creds, found := getAccountCredentials(ctx, logger, aeroClient)
+		if !found {
+			return
+		}
+		cred, found := findSourceCredential(ctx, logger, creds, s.Type())
+		if !found {
+			return
+		}
+
+		dashboards, err := s.ListDashboards(ctx.Request.Context(), logger, cred, ctx.Query("query"))
+		if err != nil {
+			writeSourceError(ctx, logger, s.Type(), err)
+			return
+		}
+
+		ctx.JSON(http.StatusOK, dashboards)
+	}
+}
+
+//@Summary Capture a source target
+//@Description Non-authenticated endpoint that renders a target with a stored source credential and stores the image in the artifact store. The target format depends on the source type. The snapshot is then published like grafana captures
+//@Accept json
+//@Produce json
+//@Param id path string true "Account ID"
+//@Param type path string true "Source type. ie grafana or prometheus"
+//@Param credential path string true "Source credential name"
+//@Param capture body SourceCaptureRequestV1 true "Source target"
+//@Success 201 {object} CaptureResultV1
+//@Fail 400 {object} gin.H
+//@Fail 404 {object} gin.H
+//@Fail 500 {object} gin.H
+//@Fail 502 {object} gin.H
+//@Router /account/:id/sources/:type/:credential/snapshots [post]
+//@Tags sources
+func SourceCaptureV1(baseLogger *logrus.Logger, aeroClient *as.ASClient, store *artifact.Store) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+
+		//Use request scoped logger
+		logger := logging.FromContext(ctx.Request.Context(), baseLogger)
+
+		s, found := findSource(ctx, logger)
+		if !found {
+			return
+		}
+
+		var capture SourceCaptureRequestV1
+		if bErr := ctx.BindJSON(&capture); bErr != nil {
+			msg := fmt.Sprintf("Unable to bind request body to capture object %v", bErr)
+			logger.Errorf(msg)
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": msg})
+			return
+		}
+
+		target, dErr := source.DecodeTarget(s.Type(), capture.Target)
+		if dErr != nil {
+			logger.Debugf("Unable to decode %v target. <%v>", s.Type(), dErr)
+			ctx.JSON(http.StatusBadRequest, gin.H{
+				"error":              dErr.Error(),
+				"humanReadableError": fmt.Sprintf("Target couldn't be decoded as a %v target", s.Type()),
+			})
+			return
+		}
+		invalidArgs := make(map[string]string)
+		if !target.IsValid("capture.Target", invalidArgs) || !capture.Diff.IsValid("capture.Diff", invalidArgs) {
+			logger.Debugf("%v target is invalid. <%v>", s.Type(), invalidArgs)
+			ctx.JSON(http.StatusBadRequest, gin.H{
+				"error":              invalidArgs,
+				"humanReadableError": "Snapshot target has invalid fields",
+			})
+			return
+		}
+
+		creds, found := getAccountCredentials(ctx, logger, aeroClient)
+		if !found {
+			return
+		}
+		cred, found := findSourceCredential(ctx, logger, creds, s.Type())
+		if !found {
+			return
+		}
+		targets, found := findDestinations(ctx, logger, creds, capture.Destinations)
+		if !found {
+			return
+		}
+
+		captureSnapshot(ctx, logger, store, s, cred, target, capture.JobID, targets, capture.Atomic, capture.Diff)
+	}
+}
+
+//findSource - returns the source of the type in the path. Writes the error response and returns false if the type isn't
+//registered
+func findSource(ctx *gin.Context, logger *logrus.Entry) (source.Source, bool) {
+
+	typeName := ctx.Param("type")
+	s, registered := source.Get(typeName)
+	if !registered {
+		logger.Debugf("Source type <%v> isn't registered. Returning 400", typeName)
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"error":              fmt.Sprintf("unknown source type <%v>", typeName),
+			"humanReadableError": fmt.Sprintf("Source type %v isn't supported. Supported types are %v", typeName, source.Types()),
+		})
+		return nil, false
+	}
+	return s, true
+}
+
+//findSourceCredential - returns the source credential of the type named in the path. Writes the error response and
+//returns false if the credential doesn't exist
+func findSourceCredential(ctx *gin.Context, logger *logrus.Entry, creds record.CredentialsV1, typeName string) (source.Credential, bool) {
+
+	accountID, credential := ctx.Param("id"), ctx.Param("credential")
+
+	cred, exists := creds.Sources[typeName][credential]
+	if !exists {
+		logger.Debugf("%v credential <%v> does not exist on account <%v>. Returning 404", typeName, credential, accountID)
+		ctx.JSON(http.StatusNotFound, gin.H{
+			"error":              fmt.Sprintf("%v credential <%v> doesn't exist", typeName, credential),
+			"humanReadableError": fmt.Sprintf("No %v credential named %v exists on account %v", typeName, credential, accountID),
+		})
+		return nil, false
+	}
+	return cred, true
+}
+
+//writeSourceError - targets the source can't render are bad requests and missing resources are returned as is. All
+//other source failures are reported as a bad gateway
+func writeSourceError(ctx *gin.Context, logger *logrus.Entry, typeName string, err error) {
+
+	switch {
+	case source.IsTargetError(err):
+		logger.Debugf("%v can't render the target. <%v>", typeName, err)
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"error":              err.Error(),
+			"humanReadableError": fmt.Sprintf("The target can't be rendered by %v as requested", typeName),
+		})
+	case source.IsNotFound(err):
+		logger.Debugf("%v resource doesn't exist. <%v>", typeName, err)
+		ctx.JSON(http.StatusNotFound, gin.H{
+			"error":              err.Error(),
+			"humanReadableError": fmt.Sprintf("The requested %v resource doesn't exist or isn't visible to the credential", typeName),
+		})
+	case upstream.IsAuthError(err):
+		logger.Infof("%v rejected the stored credential. <%v>", typeName, err)
+		ctx.JSON(http.StatusBadGateway, gin.H{
+			"error":              err.Error(),
+			"humanReadableError": fmt.Sprintf("%v rejected the stored credential", typeName),
+		})
+	default:
+		logger.Errorf("%v request failed. <%v>", typeName, err)
+		ctx.JSON(http.StatusBadGateway, gin.H{
+			"error":              err.Error(),
+			"humanReadableError": fmt.Sprintf("Unable to complete the %v request", typeName),
+		})
+	}
+}
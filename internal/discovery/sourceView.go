@@ -0,0 +1,12 @@
+package discovery
+
+import "encoding/json"
+
+//SourceCaptureRequestV1 - Target of any source type to capture and store
+type SourceCaptureRequestV1 struct {
+	JobID        string              //Optional. Recorded with the snapshot
+	Target       json.RawMessage     `swaggertype:"object"`          //Target of the source type. ie a grafana.SnapshotTargetV1 or prometheus.TargetV1
+	Destinations map[string][]string `json:"Destinations,omitempty"` //Optional. Names of stored destination credentials keyed by destination type the snapshot is published to
+	Atomic       bool                `json:"Atomic,omitempty"`       //Optional. Rolls back successful publishes if any destination fails
+	Diff         *DiffOptionsV1      `json:"Diff,omitempty"`         //Optional. Compares the capture with the previous capture of the panel by the job
+}
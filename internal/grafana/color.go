@@ -0,0 +1,71 @@
+package grafana
+
+import (
+	"image/color"
+	"strconv"
+	"strings"
+)
+
+//thresholdColors - grafana's named colors and the colors of graph panel threshold modes. Shades such as dark-red and
+//semi-dark-red use the base color
+var thresholdColors = map[string]color.RGBA{
+	"green":    {R: 0x73, G: 0xbf, B: 0x69, A: 0xff},
+	"red":      {R: 0xf2, G: 0x49, B: 0x5c, A: 0xff},
+	"yellow":   {R: 0xfa, G: 0xde, B: 0x2a, A: 0xff},
+	"orange":   {R: 0xff, G: 0x98, B: 0x30, A: 0xff},
+	"blue":     {R: 0x57, G: 0x94, B: 0xf2, A: 0xff},
+	"purple":   {R: 0xb8, G: 0x77, B: 0xd9, A: 0xff},
+	"text":     {R: 0x33, G: 0x33, B: 0x33, A: 0xff},
+	"ok":       {R: 0x73, G: 0xbf, B: 0x69, A: 0xff},
+	"warning":  {R: 0xff, G: 0x98, B: 0x30, A: 0xff},
+	"critical": {R: 0xf2, G: 0x49, B: 0x5c, A: 0xff},
+}
+
+//colorShades - prefixes of grafana's named color shades
+var colorShades = []string{"super-light-", "light-", "semi-dark-", "dark-"}
+
+//parseColor - returns a grafana named color, #rgb, #rrggbb or rgb(a) color. Unknown colors are returned as red
+func parseColor(val string) color.RGBA {
+
+	val = strings.ToLower(strings.TrimSpace(val))
+	for _, shade := range colorShades {
+		if strings.HasPrefix(val, shade) {
+			val = strings.TrimPrefix(val, shade)
+			break
+		}
+	}
+	if c, exists := thresholdColors[val]; exists {
+		return c
+	}
+
+	var channels []string
+	switch {
+	case strings.HasPrefix(val, "#") && len(val) == 4:
+		channels = []string{val[1:2] + val[1:2], val[2:3] + val[2:3], val[3:4] + val[3:4]}
+	case strings.HasPrefix(val, "#") && len(val) == 7:
+		channels = []string{val[1:3], val[3:5], val[5:7]}
+	case strings.HasPrefix(val, "rgb"):
+		//rgb(r, g, b) and rgba(r, g, b, a). Alpha is ignored
+		val = strings.TrimSuffix(val[strings.Index(val, "(")+1:], ")")
+		for _, c := range strings.Split(val, ",") {
+			n, err := strconv.Atoi(strings.TrimSpace(c))
+			if err != nil {
+				break
+			}
+			channels = append(channels, strconv.FormatInt(int64(n), 16))
+		}
+	}
+
+	rgb := make([]uint8, 0, 3)
+	for _, c := range channels {
+		n, err := strconv.ParseUint(c, 16, 8)
+		if err != nil {
+			break
+		}
+		rgb = append(rgb, uint8(n))
+	}
+	if len(rgb) < 3 {
+		return thresholdColors["red"]
+	}
+	return color.RGBA{R: rgb[0], G: rgb[1], B: rgb[2], A: 0xff}
+}
@@ -0,0 +1,190 @@
+package grafana
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/sajeevany/graph-snapper/internal/common"
+	"github.com/sajeevany/graph-snapper/internal/upstream"
+	"github.com/sirupsen/logrus"
+	"net/http"
+	"net/url"
+)
+
+const (
+	searchURL       = "/api/search"
+	foldersURL      = "/api/folders"
+	dashboardUIDURL = "/api/dashboards/uid/%s"
+
+	//rowPanelType - rows group panels and can't be rendered
+	rowPanelType = "row"
+)
+
+//StatusError - grafana responded with a status code other than 200
+type StatusError struct {
+	Path       string
+	StatusCode int
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("%v returned status code <%v>", e.Path, e.StatusCode)
+}
+
+//IsNotFound - returns true if grafana responded with a 404
+func IsNotFound(err error) bool {
+	var sErr *StatusError
+	return errors.As(err, &sErr) && sErr.StatusCode == http.StatusNotFound
+}
+
+//SearchHitV1 - Dashboard or folder matching a search
+type SearchHitV1 struct {
+	ID          int
+	UID         string
+	Title       string
+	URL         string
+	Type        string //dash-db or dash-folder
+	Tags        []string
+	FolderID    int    `json:"FolderID,omitempty"`
+	FolderUID   string `json:"FolderUID,omitempty"`
+	FolderTitle string `json:"FolderTitle,omitempty"`
+}
+
+//FolderV1 - Dashboard folder
+type FolderV1 struct {
+	ID    int
+	UID   string
+	Title string
+}
+
+//DashboardPanelsV1 - Renderable panels of a dashboard
+type DashboardPanelsV1 struct {
+	UID    string
+	Title  string
+	Panels []PanelV1
+}
+
+//PanelV1 - Dashboard panel. Panel IDs are used to render a single panel
+type PanelV1 struct {
+	ID      int
+	Title   string
+	Type    string
+	GridPos GridPosV1
+	Row     string `json:"Row,omitempty"` //Title of the row holding the panel
+}
+
+//GridPosV1 - Panel position and size in grid units
+type GridPosV1 struct {
+	H int
+	W int
+	X int
+	Y int
+}
+
+type dashboardResp struct {
+	Dashboard struct {
+		UID    string
+		Title  string
+		Panels []panelResp
+		Rows   []struct { //Dashboards created before grafana 5 hold their panels in rows
+			Title  string
+			Panels []panelResp
+		}
+		Templating struct {
+			List []struct {
+				Name string
+			}
+		}
+	}
+}
+
+type panelResp struct {
+	ID      int
+	Title   string
+	Type    string
+	GridPos GridPosV1
+	Panels  []panelResp //Panels of a collapsed row
+}
+
+//Search - Returns the dashboards and folders matching grafana search parameters. ie query, tag, type and folderIds
+func Search(ctx context.Context, logger *logrus.Entry, client *upstream.Client, user common.GrafanaUserV1, params url.Values) ([]SearchHitV1, error) {
+
+	path := searchURL
+	if len(params) != 0 {
+		path += "?" + params.Encode()
+	}
+
+	hits := []SearchHitV1{}
+	if err := getJSON(ctx, logger, client, user, path, "grafana.Search", &hits); err != nil {
+		logger.Debugf("grafana search failed. <%v>", err)
+		return nil, err
+	}
+	return hits, nil
+}
+
+//ListFolders - Returns the folders the user can view
+func ListFolders(ctx context.Context, logger *logrus.Entry, client *upstream.Client, user common.GrafanaUserV1) ([]FolderV1, error) {
+
+	folders := []FolderV1{}
+	if err := getJSON(ctx, logger, client, user, foldersURL, "grafana.ListFolders", &folders); err != nil {
+		logger.Debugf("grafana folder lookup failed. <%v>", err)
+		return nil, err
+	}
+	return folders, nil
+}
+
+//GetDashboardPanels - Returns the panels of the dashboard in layout order. Panels of collapsed rows are included and
+//rows themselves are omitted
+func GetDashboardPanels(ctx context.Context, logger *logrus.Entry, client *upstream.Client, user common.GrafanaUserV1, uid string) (DashboardPanelsV1, error) {
+
+	dash, err := getDashboard(ctx, logger, client, user, uid)
+	if err != nil {
+		return DashboardPanelsV1{}, err
+	}
+
+	result := DashboardPanelsV1{
+		UID:    dash.Dashboard.UID,
+		Title:  dash.Dashboard.Title,
+		Panels: []PanelV1{},
+	}
+
+	//Panels following an expanded row belong to it until the next row
+	row := ""
+	for _, p := range dash.Dashboard.Panels {
+		if p.Type == rowPanelType {
+			row = p.Title
+			for _, nested := range p.Panels {
+				result.Panels = append(result.Panels, nested.toPanelV1(row))
+			}
+			continue
+		}
+		result.Panels = append(result.Panels, p.toPanelV1(row))
+	}
+	for _, r := range dash.Dashboard.Rows {
+		for _, p := range r.Panels {
+			result.Panels = append(result.Panels, p.toPanelV1(r.Title))
+		}
+	}
+
+	return result, nil
+}
+
+func (p panelResp) toPanelV1(row string) PanelV1 {
+	return PanelV1{
+		ID:      p.ID,
+		Title:   p.Title,
+		Type:    p.Type,
+		GridPos: p.GridPos,
+		Row:     row,
+	}
+}
+
+//getDashboard - returns the dashboard model
+func getDashboard(ctx context.Context, logger *logrus.Entry, client *upstream.Client, user common.GrafanaUserV1, uid string) (dashboardResp, error) {
+
+	var dash dashboardResp
+	if err := getJSON(ctx, logger, client, user, fmt.Sprintf(dashboardUIDURL, url.PathEscape(uid)), "grafana.GetDashboard", &dash); err != nil {
+		logger.Debugf("grafana dashboard <%v> lookup failed. <%v>", uid, err)
+		return dashboardResp{}, err
+	}
+	return dash, nil
+}
@@ -0,0 +1,124 @@
+package grafana
+
+import (
+	"context"
+	"github.com/sajeevany/graph-snapper/internal/common"
+	"github.com/sajeevany/graph-snapper/internal/config"
+	"github.com/sajeevany/graph-snapper/internal/test"
+	"github.com/sajeevany/graph-snapper/internal/upstream"
+	"github.com/sirupsen/logrus"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"testing"
+)
+
+//newDiscoveryServer - fake grafana serving search, folder and dashboard lookups
+func newDiscoveryServer(t *testing.T) (*httptest.Server, common.GrafanaUserV1) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case searchURL:
+			if r.URL.Query().Get("query") != "cpu" || !reflect.DeepEqual(r.URL.Query()["tag"], []string{"prod", "linux"}) {
+				w.Write([]byte(`[]`))
+				return
+			}
+			w.Write([]byte(`[{"id":7,"uid":"cpu01","title":"CPU","uri":"db/cpu","url":"/d/cpu01/cpu","type":"dash-db","tags":["prod","linux"],"isStarred":false,"folderId":3,"folderUid":"ops","folderTitle":"Ops"}]`))
+		case foldersURL:
+			w.Write([]byte(`[{"id":3,"uid":"ops","title":"Ops"}]`))
+		case "/api/dashboards/uid/cpu01":
+			w.Write([]byte(`{"meta":{"slug":"cpu"},"dashboard":{"uid":"cpu01","title":"CPU","panels":[
+				{"id":1,"title":"Load","type":"graph","gridPos":{"h":8,"w":12,"x":0,"y":0}},
+				{"id":2,"title":"Details","type":"row","collapsed":true,"gridPos":{"h":1,"w":24,"x":0,"y":8},"panels":[
+					{"id":3,"title":"Steal","type":"timeseries","gridPos":{"h":8,"w":12,"x":0,"y":9}}
+				]},
+				{"id":4,"title":"Network","type":"row","collapsed":false,"gridPos":{"h":1,"w":24,"x":0,"y":9}},
+				{"id":5,"title":"Throughput","type":"stat","gridPos":{"h":4,"w":6,"x":0,"y":10}}
+			]}}`))
+		case "/api/dashboards/uid/legacy":
+			w.Write([]byte(`{"dashboard":{"uid":"legacy","title":"Legacy","rows":[{"title":"Disk","panels":[{"id":1,"title":"IOPS","type":"graph"}]}]}}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"message":"Dashboard not found"}`))
+		}
+	}))
+	host, port := test.SplitServerURL(t, server.URL)
+	return server, common.GrafanaUserV1{Auth: common.Auth{BearerToken: common.BearerToken{Token: "key"}}, Host: host, Port: port}
+}
+
+func TestSearch(t *testing.T) {
+	server, user := newDiscoveryServer(t)
+	defer server.Close()
+
+	got, err := Search(context.Background(), logrus.NewEntry(logrus.New()), upstream.New(config.NewConfWithDefaults().Upstream), user,
+		url.Values{"query": {"cpu"}, "tag": {"prod", "linux"}})
+	if err != nil {
+		t.Fatalf("Search() unexpected error = %v", err)
+	}
+	want := []SearchHitV1{{ID: 7, UID: "cpu01", Title: "CPU", URL: "/d/cpu01/cpu", Type: "dash-db", Tags: []string{"prod", "linux"}, FolderID: 3, FolderUID: "ops", FolderTitle: "Ops"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Search() = %+v, want %+v", got, want)
+	}
+}
+
+func TestListFolders(t *testing.T) {
+	server, user := newDiscoveryServer(t)
+	defer server.Close()
+
+	got, err := ListFolders(context.Background(), logrus.NewEntry(logrus.New()), upstream.New(config.NewConfWithDefaults().Upstream), user)
+	if err != nil {
+		t.Fatalf("ListFolders() unexpected error = %v", err)
+	}
+	if want := []FolderV1{{ID: 3, UID: "ops", Title: "Ops"}}; !reflect.DeepEqual(got, want) {
+		t.Errorf("ListFolders() = %+v, want %+v", got, want)
+	}
+}
+
+func TestGetDashboardPanels(t *testing.T) {
+	server, user := newDiscoveryServer(t)
+	defer server.Close()
+
+	tests := []struct {
+		name         string
+		uid          string
+		want         DashboardPanelsV1
+		wantNotFound bool
+	}{
+		{
+			name: "Test0 - Panels of collapsed and expanded rows are included",
+			uid:  "cpu01",
+			want: DashboardPanelsV1{UID: "cpu01", Title: "CPU", Panels: []PanelV1{
+				{ID: 1, Title: "Load", Type: "graph", GridPos: GridPosV1{H: 8, W: 12, X: 0, Y: 0}},
+				{ID: 3, Title: "Steal", Type: "timeseries", GridPos: GridPosV1{H: 8, W: 12, X: 0, Y: 9}, Row: "Details"},
+				{ID: 5, Title: "Throughput", Type: "stat", GridPos: GridPosV1{H: 4, W: 6, X: 0, Y: 10}, Row: "Network"},
+			}},
+		},
+		{
+			name: "Test1 - Pre grafana 5 rows",
+			uid:  "legacy",
+			want: DashboardPanelsV1{UID: "legacy", Title: "Legacy", Panels: []PanelV1{
+				{ID: 1, Title: "IOPS", Type: "graph", Row: "Disk"},
+			}},
+		},
+		{
+			name:         "Test2 - Unknown dashboard",
+			uid:          "missing",
+			wantNotFound: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := GetDashboardPanels(context.Background(), logrus.NewEntry(logrus.New()), upstream.New(config.NewConfWithDefaults().Upstream), user, tt.uid)
+			if IsNotFound(err) != tt.wantNotFound {
+				t.Fatalf("GetDashboardPanels() error = %v, wantNotFound %v", err, tt.wantNotFound)
+			}
+			if tt.wantNotFound {
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("GetDashboardPanels() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,164 @@
+package grafana
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/sajeevany/graph-snapper/internal/common"
+	"github.com/sajeevany/graph-snapper/internal/upstream"
+	"github.com/sirupsen/logrus"
+	"net/http"
+)
+
+const (
+	healthURL           = "/api/health"
+	orgURL              = "/api/org"
+	userURL             = "/api/user"
+	userOrgsURL         = "/api/user/orgs"
+	frontendSettingsURL = "/api/frontend/settings"
+)
+
+//InfoV1 - Grafana instance details and what the credential is allowed to do. Empty values could not be determined
+type InfoV1 struct {
+	Version           string
+	OrgID             int
+	OrgName           string
+	Login             string   `json:"Login,omitempty"` //Only available for user credentials
+	Role              string   `json:"Role,omitempty"`  //Admin, Editor or Viewer in OrgID
+	RendererAvailable bool     //True if the image renderer plugin or service is configured
+	CanSnapshot       *bool    `json:"CanSnapshot,omitempty"` //True if the credential has org access, a role allowed to render and the renderer is available. Unset if the role is unknown
+	Warnings          []string `json:"Warnings,omitempty"`
+}
+
+//renderRoles - org roles allowed to render panels to images
+var renderRoles = map[string]bool{
+	"Admin":  true,
+	"Editor": true,
+}
+
+type healthResp struct {
+	Version string `json:"version"`
+}
+
+type orgResp struct {
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+}
+
+type userResp struct {
+	Login string `json:"login"`
+}
+
+type userOrgResp struct {
+	OrgID int    `json:"orgId"`
+	Role  string `json:"role"`
+}
+
+type frontendSettingsResp struct {
+	RendererAvailable bool `json:"rendererAvailable"`
+}
+
+//Introspect - Collects the grafana version, the credential's org and role and renderer availability. Failed lookups
+//are reported as warnings so that a partial result is still returned
+func Introspect(ctx context.Context, logger *logrus.Entry, client *upstream.Client, user common.GrafanaUserV1) InfoV1 {
+
+	info := InfoV1{}
+
+	var health healthResp
+	if err := getJSON(ctx, logger, client, user, healthURL, "grafana.Health", &health); err != nil {
+		info.Warnings = append(info.Warnings, fmt.Sprintf("version is unavailable. <%v>", err))
+	}
+	info.Version = health.Version
+
+	var org orgResp
+	if err := getJSON(ctx, logger, client, user, orgURL, "grafana.Org", &org); err != nil {
+		info.Warnings = append(info.Warnings, fmt.Sprintf("org is unavailable. <%v>", err))
+	}
+	info.OrgID, info.OrgName = org.ID, org.Name
+
+	//API keys aren't users. Their role can't be looked up
+	var u userResp
+	var orgs []userOrgResp
+	if err := getJSON(ctx, logger, client, user, userURL, "grafana.User", &u); err != nil {
+		info.Warnings = append(info.Warnings, fmt.Sprintf("role is unavailable for this credential. <%v>", err))
+	} else if err := getJSON(ctx, logger, client, user, userOrgsURL, "grafana.UserOrgs", &orgs); err != nil {
+		info.Warnings = append(info.Warnings, fmt.Sprintf("role is unavailable. <%v>", err))
+	}
+	info.Login = u.Login
+	for _, o := range orgs {
+		if o.OrgID == info.OrgID {
+			info.Role = o.Role
+		}
+	}
+
+	var settings frontendSettingsResp
+	if err := getJSON(ctx, logger, client, user, frontendSettingsURL, "grafana.FrontendSettings", &settings); err != nil {
+		info.Warnings = append(info.Warnings, fmt.Sprintf("renderer availability is unknown. <%v>", err))
+	}
+	info.RendererAvailable = settings.RendererAvailable
+	if !info.RendererAvailable {
+		info.Warnings = append(info.Warnings, "image renderer isn't available. Snapshots can't be taken")
+	}
+
+	info.CanSnapshot = canSnapshot(info)
+	if info.CanSnapshot != nil && !*info.CanSnapshot && info.Role != "" && info.RendererAvailable {
+		info.Warnings = append(info.Warnings, fmt.Sprintf("role <%v> can't render panels. Snapshots can't be taken", info.Role))
+	}
+
+	logger.WithField("grafanaInfo", info).Debug("grafana introspection complete")
+	return info
+}
+
+//canSnapshot - returns false if panels can't be rendered in the credential's org or its role can't render. Returns nil
+//if rendering is possible but the role of the credential, ie an API key, can't be looked up
+func canSnapshot(info InfoV1) *bool {
+	can := info.RendererAvailable && info.OrgID != 0
+	switch {
+	case !can:
+	case info.Role == "":
+		return nil
+	default:
+		can = renderRoles[info.Role]
+	}
+	return &can
+}
+
+//getJSON - sends an authenticated GET to grafana and decodes the json response into out
+func getJSON(ctx context.Context, logger *logrus.Entry, client *upstream.Client, user common.GrafanaUserV1, path, spanName string, out interface{}) error {
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, user.BaseURL()+path, nil)
+	if err != nil {
+		return err
+	}
+	if aErr := setRequestHeaders(logger, client, user, req); aErr != nil {
+		return aErr
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := client.Do(ctx, logger, Endpoint(user), spanName, req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return &StatusError{Path: path, StatusCode: resp.StatusCode}
+	}
+
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+//CheckOrgMembership - Returns an error if the user has an org configured and grafana resolved requests to a different
+//org. API keys and service account tokens belong to a single org and ignore the org header
+func CheckOrgMembership(user common.GrafanaUserV1, info InfoV1) error {
+	if user.OrgID == 0 {
+		return nil
+	}
+	if info.OrgID == 0 {
+		return fmt.Errorf("org <%v> couldn't be verified", user.OrgID)
+	}
+	if info.OrgID != user.OrgID {
+		return fmt.Errorf("credential isn't a member of org <%v>. Requests are made in org <%v>", user.OrgID, info.OrgID)
+	}
+	return nil
+}
@@ -0,0 +1,188 @@
+package grafana
+
+import (
+	"context"
+	"github.com/sajeevany/graph-snapper/internal/common"
+	"github.com/sajeevany/graph-snapper/internal/config"
+	"github.com/sajeevany/graph-snapper/internal/test"
+	"github.com/sajeevany/graph-snapper/internal/upstream"
+	"github.com/sirupsen/logrus"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+//TestIntrospect - Validates version, org, role and renderer lookups against a fake grafana
+func TestIntrospect(t *testing.T) {
+
+	canSnapshot, cantSnapshot := true, false
+	tests := []struct {
+		name         string
+		auth         common.Auth
+		role         string
+		renderer     bool
+		wantRole     string
+		wantLogin    string
+		wantSnapshot *bool
+		wantWarnings int
+	}{
+		{
+			name:         "Test0 - Editor user with renderer",
+			auth:         common.Auth{Basic: common.Basic{Username: "editor", Password: "pass"}},
+			role:         "Editor",
+			renderer:     true,
+			wantRole:     "Editor",
+			wantLogin:    "editor",
+			wantSnapshot: &canSnapshot,
+			wantWarnings: 0,
+		},
+		{
+			name:         "Test1 - API key without renderer",
+			auth:         common.Auth{BearerToken: common.BearerToken{Token: "key"}},
+			renderer:     false,
+			wantSnapshot: &cantSnapshot,
+			wantWarnings: 2,
+		},
+		{
+			name:         "Test2 - Viewer user with renderer",
+			auth:         common.Auth{Basic: common.Basic{Username: "editor", Password: "pass"}},
+			role:         "Viewer",
+			renderer:     true,
+			wantRole:     "Viewer",
+			wantLogin:    "editor",
+			wantSnapshot: &cantSnapshot,
+			wantWarnings: 1,
+		},
+		{
+			name:         "Test3 - API key with renderer has an unknown role",
+			auth:         common.Auth{BearerToken: common.BearerToken{Token: "key"}},
+			renderer:     true,
+			wantSnapshot: nil,
+			wantWarnings: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				_, _, isUser := r.BasicAuth()
+				switch r.URL.Path {
+				case healthURL:
+					w.Write([]byte(`{"commit":"abc","database":"ok","version":"9.3.2"}`))
+				case orgURL:
+					w.Write([]byte(`{"id":2,"name":"Ops"}`))
+				case userURL:
+					if !isUser {
+						w.WriteHeader(http.StatusNotFound)
+						return
+					}
+					w.Write([]byte(`{"id":5,"login":"editor"}`))
+				case userOrgsURL:
+					w.Write([]byte(`[{"orgId":1,"name":"Main","role":"Admin"},{"orgId":2,"name":"Ops","role":"` + tt.role + `"}]`))
+				case frontendSettingsURL:
+					if tt.renderer {
+						w.Write([]byte(`{"rendererAvailable":true}`))
+					} else {
+						w.Write([]byte(`{"rendererAvailable":false}`))
+					}
+				default:
+					w.WriteHeader(http.StatusNotFound)
+				}
+			}))
+			defer server.Close()
+			host, port := test.SplitServerURL(t, server.URL)
+
+			info := Introspect(context.Background(), logrus.NewEntry(logrus.New()), upstream.New(config.NewConfWithDefaults().Upstream),
+				common.GrafanaUserV1{Auth: tt.auth, Host: host, Port: port})
+
+			if info.Version != "9.3.2" || info.OrgID != 2 || info.OrgName != "Ops" {
+				t.Errorf("Introspect() version and org = <%v> <%v> <%v>, want <9.3.2> <2> <Ops>", info.Version, info.OrgID, info.OrgName)
+			}
+			if info.Role != tt.wantRole || info.Login != tt.wantLogin {
+				t.Errorf("Introspect() role and login = <%v> <%v>, want <%v> <%v>", info.Role, info.Login, tt.wantRole, tt.wantLogin)
+			}
+			if (info.CanSnapshot == nil) != (tt.wantSnapshot == nil) || (info.CanSnapshot != nil && *info.CanSnapshot != *tt.wantSnapshot) {
+				t.Errorf("Introspect() CanSnapshot = %v, want %v", info.CanSnapshot, tt.wantSnapshot)
+			}
+			if len(info.Warnings) != tt.wantWarnings {
+				t.Errorf("Introspect() warnings = %v, want %v warnings", info.Warnings, tt.wantWarnings)
+			}
+		})
+	}
+}
+
+//TestOrgSelection - Validates that the org header is sent on every request and org membership is checked
+func TestOrgSelection(t *testing.T) {
+
+	tests := []struct {
+		name       string
+		auth       common.Auth
+		orgID      int
+		wantHeader string
+		wantErr    bool
+	}{
+		{
+			name:       "Test0 - User is a member of the configured org",
+			auth:       common.Auth{Basic: common.Basic{Username: "editor", Password: "pass"}},
+			orgID:      3,
+			wantHeader: "3",
+			wantErr:    false,
+		},
+		{
+			name:       "Test1 - API key belongs to a different org",
+			auth:       common.Auth{BearerToken: common.BearerToken{Token: "key"}},
+			orgID:      3,
+			wantHeader: "3",
+			wantErr:    true,
+		},
+		{
+			name:       "Test2 - No org configured uses the current org",
+			auth:       common.Auth{Basic: common.Basic{Username: "editor", Password: "pass"}},
+			wantHeader: "",
+			wantErr:    false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+
+			var headers []string
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				headers = append(headers, r.Header.Get(common.GrafanaOrgIDHeader))
+				_, _, isUser := r.BasicAuth()
+				switch r.URL.Path {
+				case loginPingURL:
+					w.WriteHeader(http.StatusOK)
+				case orgURL:
+					//API keys are bound to org 2 and ignore the org header
+					if isUser && r.Header.Get(common.GrafanaOrgIDHeader) == "3" {
+						w.Write([]byte(`{"id":3,"name":"Team"}`))
+						return
+					}
+					w.Write([]byte(`{"id":2,"name":"Ops"}`))
+				default:
+					w.Write([]byte(`{}`))
+				}
+			}))
+			defer server.Close()
+			host, port := test.SplitServerURL(t, server.URL)
+
+			user := common.GrafanaUserV1{Auth: tt.auth, Host: host, Port: port, OrgID: tt.orgID}
+			client := upstream.New(config.NewConfWithDefaults().Upstream)
+			if ok, err := IsValidLogin(context.Background(), logrus.NewEntry(logrus.New()), client, user); !ok || err != nil {
+				t.Fatalf("IsValidLogin() = %v, %v, want true and no error", ok, err)
+			}
+			info := Introspect(context.Background(), logrus.NewEntry(logrus.New()), client, user)
+
+			if err := CheckOrgMembership(user, info); (err != nil) != tt.wantErr {
+				t.Errorf("CheckOrgMembership() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			for i, h := range headers {
+				if h != tt.wantHeader {
+					t.Errorf("request <%v> %v = <%v>, want <%v>", i, common.GrafanaOrgIDHeader, h, tt.wantHeader)
+				}
+			}
+		})
+	}
+}
@@ -1,38 +1,52 @@
 package grafana
 
 import (
-	"fmt"
+	"context"
 	"github.com/sajeevany/graph-snapper/internal/common"
+	"github.com/sajeevany/graph-snapper/internal/upstream"
 	"github.com/sirupsen/logrus"
 	"net/http"
 )
 
-const loginPingURL = "/api/login/ping"
+const (
+	loginPingURL = "/api/login/ping"
 
-func IsValidLogin(logger *logrus.Logger, auth common.Auth, host string, port int) (bool, error) {
+	//ServiceName - name used for grafana in upstream errors and spans
+	ServiceName = "grafana"
+)
+
+func IsValidLogin(ctx context.Context, logger *logrus.Entry, client *upstream.Client, user common.GrafanaUserV1) (bool, error) {
 
 	logger.Debug("Starting a grafana valid login API key check")
 
-	client := http.Client{}
-	reqURL := buildLoginRequestURL(host, port)
-	req, err := http.NewRequest(http.MethodGet, reqURL, nil)
+	reqURL := buildLoginRequestURL(user)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, reqURL, nil)
 	if err != nil {
 		logger.Debugf("An error was found when creating http request to validate grafana user. <%v>", err)
 		return false, err
 	}
 
 	//add headers
-	common.SetAuthHeader(logger, auth, req)
+	if aErr := setRequestHeaders(logger, client, user, req); aErr != nil {
+		logger.Debugf("Unable to set auth header to validate grafana user. <%v>", aErr)
+		//The identity provider rejected the oauth2 client credentials
+		if upstream.IsAuthError(aErr) {
+			return false, nil
+		}
+		return false, aErr
+	}
 	logger.Debug("common headers set")
-	logger.Infof("req %#v", req)
 
 	//execute
-	resp, rErr := client.Do(req)
+	resp, rErr := client.Do(ctx, logger, Endpoint(user), "grafana.IsValidLogin", req)
 	if rErr != nil {
-		logger.Debugf("Error when calling request to <%v>. err <%v>", reqURL, err)
+		if upstream.IsAuthError(rErr) {
+			logger.Debugf("grafana rejected credentials. <%v>", rErr)
+			return false, nil
+		}
+		logger.Debugf("Error when calling request to <%v>. err <%v>", reqURL, rErr)
 		return false, rErr
 	}
-	logger.Infof("response <%#v>", resp)
 	logger.Debugf("login API request executed. Received status code. <%v>", resp.StatusCode)
 	defer resp.Body.Close()
 
@@ -41,15 +55,32 @@ func IsValidLogin(logger *logrus.Logger, auth common.Auth, host string, port int
 	case http.StatusOK:
 		logger.Debug("grafana user check returned 200")
 		return true, nil
-	case http.StatusUnauthorized:
-		logger.Debugf("Unauthorized (401) response body <%v>", resp.Body)
-		return false, nil
 	default:
 		logger.Debugf("Unexpected response status code <%v>", resp.StatusCode)
 		return false, nil
 	}
 }
 
-func buildLoginRequestURL(host string, port int) string {
-	return fmt.Sprintf("http://%v:%v%v", host, port, loginPingURL)
+//Endpoint - returns the upstream connection settings of the grafana user
+func Endpoint(user common.GrafanaUserV1) upstream.Endpoint {
+	return upstream.Endpoint{
+		Service: ServiceName,
+		Host:    user.Host,
+		Port:    user.Port,
+		TLS:     user.TLS,
+		Proxy:   user.Proxy,
+	}
+}
+
+//setRequestHeaders - sets the auth and org headers sent with every request made with the grafana user
+func setRequestHeaders(logger *logrus.Entry, client *upstream.Client, user common.GrafanaUserV1, req *http.Request) error {
+	if err := client.SetAuthHeader(logger, Endpoint(user), user.Auth, req); err != nil {
+		return err
+	}
+	user.SetOrgHeader(req)
+	return nil
+}
+
+func buildLoginRequestURL(user common.GrafanaUserV1) string {
+	return user.BaseURL() + loginPingURL
 }
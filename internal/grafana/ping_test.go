@@ -2,9 +2,20 @@ package grafana
 
 import (
 	"context"
+	"encoding/pem"
+	"fmt"
 	"github.com/sajeevany/graph-snapper/internal/common"
+	"github.com/sajeevany/graph-snapper/internal/config"
 	"github.com/sajeevany/graph-snapper/internal/test"
+	"github.com/sajeevany/graph-snapper/internal/tracing"
+	"github.com/sajeevany/graph-snapper/internal/upstream"
 	"github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"net/http"
+	"net/http/httptest"
 	"testing"
 )
 
@@ -23,7 +34,7 @@ func TestIsValidLogin(t *testing.T) {
 
 	//Scenarios
 	type args struct {
-		logger *logrus.Logger
+		logger *logrus.Entry
 		auth   common.Auth
 		host   string
 		port   int
@@ -37,7 +48,7 @@ func TestIsValidLogin(t *testing.T) {
 		{
 			name: "Test0 - Validate enabled admin API key",
 			args: args{
-				logger: logrus.New(),
+				logger: logrus.NewEntry(logrus.New()),
 				auth: common.Auth{
 					BearerToken: common.BearerToken{
 						Token: test.GrafanaAdminUserAPIKey,
@@ -52,7 +63,7 @@ func TestIsValidLogin(t *testing.T) {
 		{
 			name: "Test1 - Validate enabled editor API key",
 			args: args{
-				logger: logrus.New(),
+				logger: logrus.NewEntry(logrus.New()),
 				auth: common.Auth{
 					BearerToken: common.BearerToken{
 						Token: test.GrafanaEditorUserAPIKey,
@@ -67,7 +78,7 @@ func TestIsValidLogin(t *testing.T) {
 		{
 			name: "Test2 - Validate enabled viewer API key",
 			args: args{
-				logger: logrus.New(),
+				logger: logrus.NewEntry(logrus.New()),
 				auth: common.Auth{
 					BearerToken: common.BearerToken{
 						Token: test.GrafanaViewerUserAPIKey,
@@ -82,7 +93,7 @@ func TestIsValidLogin(t *testing.T) {
 		{
 			name: "Test3 - Validate enabled basic admin credentials",
 			args: args{
-				logger: logrus.New(),
+				logger: logrus.NewEntry(logrus.New()),
 				auth: common.Auth{
 					Basic: common.Basic{
 						Username: test.GrafanaBasicAuthUsername,
@@ -98,7 +109,7 @@ func TestIsValidLogin(t *testing.T) {
 		{
 			name: "Test4 - Validate invalid API key",
 			args: args{
-				logger: logrus.New(),
+				logger: logrus.NewEntry(logrus.New()),
 				auth: common.Auth{
 					BearerToken: common.BearerToken{
 						Token: "abcde",
@@ -113,7 +124,7 @@ func TestIsValidLogin(t *testing.T) {
 		{
 			name: "Test5 - Validate invalid basic auth",
 			args: args{
-				logger: logrus.New(),
+				logger: logrus.NewEntry(logrus.New()),
 				auth: common.Auth{
 					Basic: common.Basic{
 						Username: "fakeUser",
@@ -130,7 +141,118 @@ func TestIsValidLogin(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			got, err := IsValidLogin(tt.args.logger, tt.args.auth, tt.args.host, tt.args.port)
+			got, err := IsValidLogin(ctx, tt.args.logger, upstream.New(config.NewConfWithDefaults().Upstream), common.GrafanaUserV1{Auth: tt.args.auth, Host: tt.args.host, Port: tt.args.port})
+			if (err != nil) != tt.wantErr {
+				t.Errorf("IsValidLogin() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if got != tt.want {
+				t.Errorf("IsValidLogin() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+//TestIsValidLoginPropagatesTraceContext - Validates that a child span is created and its context is sent to grafana
+func TestIsValidLoginPropagatesTraceContext(t *testing.T) {
+
+	//Record spans in memory
+	recorder := tracetest.NewSpanRecorder()
+	otel.SetTracerProvider(tracing.NewProvider("test", 1, sdktrace.WithSpanProcessor(recorder)))
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+
+	//Capture the trace header received by grafana
+	var receivedTraceparent string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		receivedTraceparent = r.Header.Get("traceparent")
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+	host, port := test.SplitServerURL(t, server.URL)
+
+	//Run check as part of a parent span
+	ctx, parent := tracing.StartSpan(context.Background(), "parent")
+	got, err := IsValidLogin(ctx, logrus.NewEntry(logrus.New()), upstream.New(config.NewConfWithDefaults().Upstream), common.GrafanaUserV1{Auth: common.Auth{BearerToken: common.BearerToken{Token: "abc"}}, Host: host, Port: port})
+	parent.End()
+	if err != nil || !got {
+		t.Fatalf("IsValidLogin() got = %v, err = %v, want true and no error", got, err)
+	}
+
+	//Validate
+	var child sdktrace.ReadOnlySpan
+	for _, s := range recorder.Ended() {
+		if s.Name() == "grafana.IsValidLogin" {
+			child = s
+		}
+	}
+	if child == nil {
+		t.Fatalf("Expected grafana.IsValidLogin span in <%v>", recorder.Ended())
+	}
+	if child.Parent().SpanID() != parent.SpanContext().SpanID() {
+		t.Errorf("grafana.IsValidLogin parent = <%v>, want <%v>", child.Parent().SpanID(), parent.SpanContext().SpanID())
+	}
+	want := fmt.Sprintf("00-%s-%s-01", child.SpanContext().TraceID(), child.SpanContext().SpanID())
+	if receivedTraceparent != want {
+		t.Errorf("traceparent = <%v>, want <%v>", receivedTraceparent, want)
+	}
+}
+
+//TestIsValidLoginOverHTTPS - Validates that grafana instances served over https from a context path can be checked
+func TestIsValidLoginOverHTTPS(t *testing.T) {
+
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/grafana"+loginPingURL {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+	host, port := test.SplitServerURL(t, server.URL)
+	caCert := string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw}))
+	auth := common.Auth{BearerToken: common.BearerToken{Token: "abc"}}
+
+	tests := []struct {
+		name    string
+		user    common.GrafanaUserV1
+		want    bool
+		wantErr bool
+	}{
+		{
+			name:    "Test0 - Trusted custom CA",
+			user:    common.GrafanaUserV1{Auth: auth, Scheme: common.HTTPSScheme, Host: host, Port: port, ContextPath: "/grafana/", TLS: common.TLSConfigV1{CACert: caCert}},
+			want:    true,
+			wantErr: false,
+		},
+		{
+			name:    "Test1 - Unknown CA is rejected",
+			user:    common.GrafanaUserV1{Auth: auth, Scheme: common.HTTPSScheme, Host: host, Port: port, ContextPath: "grafana"},
+			want:    false,
+			wantErr: true,
+		},
+		{
+			name:    "Test2 - Skip verify",
+			user:    common.GrafanaUserV1{Auth: auth, Scheme: common.HTTPSScheme, Host: host, Port: port, ContextPath: "grafana", TLS: common.TLSConfigV1{InsecureSkipVerify: true}},
+			want:    true,
+			wantErr: false,
+		},
+		{
+			name:    "Test3 - Missing context path",
+			user:    common.GrafanaUserV1{Auth: auth, Scheme: common.HTTPSScheme, Host: host, Port: port, TLS: common.TLSConfigV1{CACert: caCert}},
+			want:    false,
+			wantErr: false,
+		},
+		{
+			name:    "Test4 - Invalid CA bundle",
+			user:    common.GrafanaUserV1{Auth: auth, Scheme: common.HTTPSScheme, Host: host, Port: port, TLS: common.TLSConfigV1{CACert: "not a cert"}},
+			want:    false,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := IsValidLogin(context.Background(), logrus.NewEntry(logrus.New()), upstream.New(config.NewConfWithDefaults().Upstream), tt.user)
 			if (err != nil) != tt.wantErr {
 				t.Errorf("IsValidLogin() error = %v, wantErr %v", err, tt.wantErr)
 				return
@@ -0,0 +1,659 @@
+package grafana
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/sajeevany/graph-snapper/internal/artifact"
+	"github.com/sajeevany/graph-snapper/internal/chart"
+	"github.com/sajeevany/graph-snapper/internal/common"
+	"github.com/sajeevany/graph-snapper/internal/upstream"
+	"github.com/sirupsen/logrus"
+	"io/ioutil"
+	"math"
+	"net/http"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	dsQueryURL     = "/api/ds/query"
+	datasourcesURL = "/api/datasources"
+
+	//Datasources which aren't queryable by reference. Mixed panels reference a datasource per query
+	mixedDatasourceUID     = "-- Mixed --"
+	dashboardDatasourceUID = "-- Dashboard --"
+
+	timeFieldType   = "time"
+	numberFieldType = "number"
+	nameLabel       = "__name__"
+
+	legendHidden   = "hidden"
+	thresholdsOff  = "off"
+	percentageMode = "percentage"
+)
+
+//variableRegex - template variable reference. ie $env, ${env}, ${env:regex} or [[env]]
+var variableRegex = regexp.MustCompile(`\$\{(\w+)(?::[^}]*)?\}|\[\[(\w+)(?::[^\]]*)?\]\]|\$(\w+)`)
+
+//regexDatasourceTypes - datasources which receive multi value variables as a regex. Others receive a {a,b} glob
+var regexDatasourceTypes = map[string]bool{"prometheus": true, "loki": true}
+
+//QueryError - a panel query was rejected by its datasource
+type QueryError struct {
+	RefID   string
+	Message string
+}
+
+func (e *QueryError) Error() string {
+	return fmt.Sprintf("query <%v> failed. <%v>", e.RefID, e.Message)
+}
+
+//IsQueryError - returns true if a panel query was rejected by its datasource
+func IsQueryError(err error) bool {
+	var qErr *QueryError
+	return errors.As(err, &qErr)
+}
+
+//dashboardModelResp - dashboard with the panel models and variable values used to query panels
+type dashboardModelResp struct {
+	Dashboard struct {
+		Panels []panelModel
+		Rows   []struct {
+			Panels []panelModel
+		}
+		Templating struct {
+			List []struct {
+				Name    string
+				Current struct {
+					Value json.RawMessage //A value or a list of values
+				}
+			}
+		}
+	}
+}
+
+//panelModel - panel fields used to query the panel and draw it like grafana would
+type panelModel struct {
+	ID          int
+	Title       string
+	Type        string
+	Datasource  json.RawMessage //A reference, a datasource name or null for the default datasource
+	Targets     []map[string]interface{}
+	Panels      []panelModel //Panels of a collapsed row
+	FieldConfig struct {
+		Defaults struct {
+			Unit       string
+			Thresholds struct {
+				Mode  string
+				Steps []struct {
+					Color string
+					Value *float64 //The base step has no value
+				}
+			}
+			Custom struct {
+				ThresholdsStyle struct {
+					Mode string
+				}
+			}
+		}
+	}
+	Options struct {
+		Legend struct {
+			ShowLegend  *bool
+			DisplayMode string
+		}
+	}
+
+	//Graph panel settings which were replaced by fieldConfig and options in grafana 7
+	Legend struct {
+		Show *bool
+	}
+	Yaxes []struct {
+		Format string
+	}
+	Thresholds json.RawMessage //A list for graph panels. Decoded leniently since singlestat panels used a string
+}
+
+type legacyThreshold struct {
+	Value     float64
+	ColorMode string
+	LineColor string
+}
+
+//datasourceRef - datasource of a query
+type datasourceRef struct {
+	UID  string `json:"uid"`
+	Type string `json:"type,omitempty"`
+}
+
+type datasourceResp struct {
+	UID       string
+	Name      string
+	Type      string
+	IsDefault bool
+}
+
+type dsQueryReq struct {
+	Queries []map[string]interface{} `json:"queries"`
+	From    string                   `json:"from"`
+	To      string                   `json:"to"`
+}
+
+type dsQueryResp struct {
+	Results map[string]struct {
+		Error  string
+		Frames []frameResp
+	}
+}
+
+type frameResp struct {
+	Schema struct {
+		Name   string
+		Fields []struct {
+			Name   string
+			Type   string
+			Labels map[string]string
+			Config struct {
+				DisplayName       string
+				DisplayNameFromDS string
+			}
+		}
+	}
+	Data struct {
+		Values []json.RawMessage
+	}
+}
+
+//RenderPanelData - Queries the target panel's data with QueryPanel and draws it in the target's format
+func RenderPanelData(ctx context.Context, logger *logrus.Entry, client *upstream.Client, user common.GrafanaUserV1, target SnapshotTargetV1, now time.Time) ([]byte, string, error) {
+	c, err := QueryPanel(ctx, logger, client, user, target, now)
+	if err != nil {
+		return nil, "", err
+	}
+	return drawChart(c, target.Format)
+}
+
+//QueryPanel - Queries the target panel's datasources through grafana and returns the time series as a chart with the
+//panel title, unit, legend and thresholds where grafana's settings allow. Unset target variables take the dashboard's
+//current values. *UnknownVariablesError is returned if the target sets variables the dashboard doesn't define and
+//*QueryError if a datasource rejects a query
+func QueryPanel(ctx context.Context, logger *logrus.Entry, client *upstream.Client, user common.GrafanaUserV1, target SnapshotTargetV1, now time.Time) (chart.Chart, error) {
+
+	var dash dashboardModelResp
+	if err := getJSON(ctx, logger, client, user, fmt.Sprintf(dashboardUIDURL, url.PathEscape(target.DashboardUID)), "grafana.GetDashboard", &dash); err != nil {
+		logger.Debugf("grafana dashboard <%v> lookup failed. <%v>", target.DashboardUID, err)
+		return chart.Chart{}, err
+	}
+
+	vars, unknown := dash.variables(target)
+	if len(unknown) != 0 {
+		return chart.Chart{}, &UnknownVariablesError{DashboardUID: target.DashboardUID, Names: unknown}
+	}
+	panel, found := dash.findPanel(target.PanelID)
+	if !found {
+		return chart.Chart{}, &StatusError{Path: fmt.Sprintf("%v panel %v", target.DashboardUID, target.PanelID), StatusCode: http.StatusNotFound}
+	}
+
+	from, to, err := target.TimeRange.Bounds(now)
+	if err != nil {
+		return chart.Chart{}, err
+	}
+	width, height := target.size()
+
+	queries, err := panel.queries(ctx, logger, client, user, vars, to.Sub(from), width)
+	if err != nil {
+		return chart.Chart{}, err
+	}
+	series, err := QueryData(ctx, logger, client, user, queries, from, to)
+	if err != nil {
+		return chart.Chart{}, err
+	}
+
+	return chart.Chart{
+		Title:      interpolate(panel.Title, vars, false),
+		Width:      width,
+		Height:     height,
+		Series:     series,
+		From:       from,
+		To:         to,
+		Unit:       panel.unit(),
+		Thresholds: panel.thresholds(),
+		HideLegend: panel.legendHidden(),
+		Location:   target.location(),
+	}, nil
+}
+
+//drawChart - returns the chart as an image in the format and its content type
+func drawChart(c chart.Chart, format string) ([]byte, string, error) {
+	if format == SVGFormat {
+		img, err := c.SVG()
+		return img, chart.SVGContentType, err
+	}
+	img, err := c.PNG()
+	return img, chart.PNGContentType, err
+}
+
+//exportData - encodes the chart's series in each export format
+func exportData(c chart.Chart, formats []string) ([]artifact.DataExport, error) {
+	exports := make([]artifact.DataExport, 0, len(formats))
+	for _, format := range formats {
+		var data []byte
+		var err error
+		switch format {
+		case artifact.CSVExportFormat:
+			data, err = chart.CSV(c.Series)
+		case artifact.JSONExportFormat:
+			data, err = chart.JSON(c.Series)
+		default:
+			err = fmt.Errorf("unsupported export format <%v>", format)
+		}
+		if err != nil {
+			return nil, err
+		}
+		exports = append(exports, artifact.DataExport{Format: format, Data: data})
+	}
+	return exports, nil
+}
+
+//QueryData - Runs datasource queries through grafana and returns the time series of each frame. Queries must
+//reference their datasource by uid. Queries grafana rejects are returned as *QueryError
+func QueryData(ctx context.Context, logger *logrus.Entry, client *upstream.Client, user common.GrafanaUserV1, queries []map[string]interface{}, from, to time.Time) ([]chart.Series, error) {
+
+	body, err := json.Marshal(dsQueryReq{
+		Queries: queries,
+		From:    strconv.FormatInt(from.UnixNano()/int64(time.Millisecond), 10),
+		To:      strconv.FormatInt(to.UnixNano()/int64(time.Millisecond), 10),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, user.BaseURL()+dsQueryURL, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	if aErr := setRequestHeaders(logger, client, user, req); aErr != nil {
+		return nil, aErr
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json")
+
+	//Failed queries are reported per query. Grafana responds with 400 or 500 depending on the version. The body of a
+	//500 is kept on the upstream error
+	resp, err := client.Do(ctx, logger, Endpoint(user), "grafana.QueryData", req)
+	if err != nil {
+		var uErr *upstream.Error
+		if errors.As(err, &uErr) && uErr.Kind == upstream.ServerErrorKind {
+			var result dsQueryResp
+			if json.Unmarshal(uErr.Body, &result) == nil && result.queryError() != nil {
+				return nil, result.queryError()
+			}
+		}
+		logger.Debugf("grafana datasource query failed. <%v>", err)
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	respBody, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read datasource query response. err <%v>", err)
+	}
+
+	var result dsQueryResp
+	jErr := json.Unmarshal(respBody, &result)
+	if jErr == nil && result.queryError() != nil {
+		return nil, result.queryError()
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, &StatusError{Path: dsQueryURL, StatusCode: resp.StatusCode}
+	}
+	if jErr != nil {
+		return nil, fmt.Errorf("unable to decode datasource query response. err <%v>", jErr)
+	}
+
+	series := []chart.Series{}
+	for _, refID := range sortedRefIDs(result) {
+		for _, frame := range result.Results[refID].Frames {
+			s, fErr := frame.toSeries()
+			if fErr != nil {
+				return nil, fmt.Errorf("unable to read frame of query <%v>. err <%v>", refID, fErr)
+			}
+			series = append(series, s...)
+		}
+	}
+	return series, nil
+}
+
+//variables - returns the variable values used to interpolate queries. Target values replace the dashboard's current
+//values. Target variables the dashboard doesn't define are returned as unknown
+func (d dashboardModelResp) variables(target SnapshotTargetV1) (map[string][]string, []string) {
+
+	vars := make(map[string][]string, len(d.Dashboard.Templating.List))
+	for _, v := range d.Dashboard.Templating.List {
+		var values []string
+		if err := json.Unmarshal(v.Current.Value, &values); err != nil {
+			var value string
+			json.Unmarshal(v.Current.Value, &value)
+			values = []string{value}
+		}
+		vars[v.Name] = values
+	}
+
+	unknown := []string{}
+	for _, name := range target.variableNames() {
+		if _, exists := vars[name]; !exists {
+			unknown = append(unknown, name)
+			continue
+		}
+		vars[name] = target.Variables[name]
+	}
+	return vars, unknown
+}
+
+//findPanel - returns the panel with the ID. Panels of collapsed rows and pre grafana 5 rows are included
+func (d dashboardModelResp) findPanel(id int) (panelModel, bool) {
+	panels := append([]panelModel{}, d.Dashboard.Panels...)
+	for _, p := range d.Dashboard.Panels {
+		panels = append(panels, p.Panels...)
+	}
+	for _, r := range d.Dashboard.Rows {
+		panels = append(panels, r.Panels...)
+	}
+	for _, p := range panels {
+		if p.ID == id && p.Type != rowPanelType {
+			return p, true
+		}
+	}
+	return panelModel{}, false
+}
+
+//queries - returns the panel's visible targets with their variables interpolated and their datasource resolved. The
+//interval aims for a point per pixel of width
+func (p panelModel) queries(ctx context.Context, logger *logrus.Entry, client *upstream.Client, user common.GrafanaUserV1, vars map[string][]string, timeRange time.Duration, width int) ([]map[string]interface{}, error) {
+
+	interval := timeRange / time.Duration(width)
+	if interval < time.Second {
+		interval = time.Second
+	}
+
+	var datasources []datasourceResp
+	queries := make([]map[string]interface{}, 0, len(p.Targets))
+	for i, t := range p.Targets {
+		if hide, _ := t["hide"].(bool); hide {
+			continue
+		}
+
+		rawRef := p.Datasource
+		if ds, exists := t["datasource"]; exists && ds != nil {
+			rawRef, _ = json.Marshal(ds)
+		}
+		ref, resolved := parseDatasourceRef(rawRef, vars)
+		if !resolved {
+			//Names, variables and the default datasource are resolved with the datasource list
+			if datasources == nil {
+				if err := getJSON(ctx, logger, client, user, datasourcesURL, "grafana.ListDatasources", &datasources); err != nil {
+					return nil, err
+				}
+			}
+			var err error
+			if ref, err = findDatasource(datasources, ref); err != nil {
+				return nil, fmt.Errorf("panel <%v> query <%v>. %v", p.ID, i, err)
+			}
+		}
+		if ref.UID == mixedDatasourceUID || ref.UID == dashboardDatasourceUID {
+			return nil, fmt.Errorf("panel <%v> query <%v> uses datasource <%v> which can't be queried directly", p.ID, i, ref.UID)
+		}
+
+		query := interpolateValue(t, vars, regexDatasourceTypes[ref.Type]).(map[string]interface{})
+		query["datasource"] = ref
+		if refID, _ := query["refId"].(string); refID == "" {
+			query["refId"] = string(rune('A' + i%26))
+		}
+		query["intervalMs"] = interval.Milliseconds()
+		query["maxDataPoints"] = width
+		queries = append(queries, query)
+	}
+
+	if len(queries) == 0 {
+		return nil, fmt.Errorf("panel <%v> doesn't have visible queries", p.ID)
+	}
+	return queries, nil
+}
+
+//unit - returns the unit of the panel's values
+func (p panelModel) unit() string {
+	if p.FieldConfig.Defaults.Unit != "" {
+		return p.FieldConfig.Defaults.Unit
+	}
+	if len(p.Yaxes) != 0 {
+		return p.Yaxes[0].Format
+	}
+	return ""
+}
+
+//legendHidden - returns true if the panel hides its legend
+func (p panelModel) legendHidden() bool {
+	legend := p.Options.Legend
+	return (legend.ShowLegend != nil && !*legend.ShowLegend) || legend.DisplayMode == legendHidden || (p.Legend.Show != nil && !*p.Legend.Show)
+}
+
+//thresholds - returns the threshold lines grafana would draw. Time series panels only draw them when thresholdsStyle
+//is enabled and percentage thresholds can't be drawn without the field min and max
+func (p panelModel) thresholds() []chart.Threshold {
+
+	thresholds := []chart.Threshold{}
+
+	defaults := p.FieldConfig.Defaults
+	if mode := defaults.Custom.ThresholdsStyle.Mode; mode != "" && mode != thresholdsOff && defaults.Thresholds.Mode != percentageMode {
+		for _, step := range defaults.Thresholds.Steps {
+			if step.Value != nil {
+				thresholds = append(thresholds, chart.Threshold{Value: *step.Value, Color: parseColor(step.Color)})
+			}
+		}
+	}
+
+	var legacy []legacyThreshold
+	if err := json.Unmarshal(p.Thresholds, &legacy); err == nil {
+		for _, t := range legacy {
+			color := t.ColorMode
+			if color == "custom" {
+				color = t.LineColor
+			}
+			thresholds = append(thresholds, chart.Threshold{Value: t.Value, Color: parseColor(color)})
+		}
+	}
+
+	return thresholds
+}
+
+//toSeries - returns a series per number field of a frame with a time field. Null values are returned as NaN
+func (f frameResp) toSeries() ([]chart.Series, error) {
+
+	fields := f.Schema.Fields
+	if len(f.Data.Values) != len(fields) {
+		return nil, fmt.Errorf("frame has <%v> fields but <%v> value lists", len(fields), len(f.Data.Values))
+	}
+
+	var times []*float64
+	valueFields := 0
+	for i, field := range fields {
+		switch field.Type {
+		case timeFieldType:
+			if times == nil {
+				if err := json.Unmarshal(f.Data.Values[i], &times); err != nil {
+					return nil, err
+				}
+			}
+		case numberFieldType:
+			valueFields++
+		}
+	}
+	if times == nil {
+		//Frames without a time field such as table results can't be drawn over time
+		return nil, nil
+	}
+
+	series := []chart.Series{}
+	for i, field := range fields {
+		if field.Type != numberFieldType {
+			continue
+		}
+		var values []*float64
+		if err := json.Unmarshal(f.Data.Values[i], &values); err != nil {
+			return nil, err
+		}
+
+		name := field.Config.DisplayName
+		switch {
+		case name != "":
+		case field.Config.DisplayNameFromDS != "":
+			name = field.Config.DisplayNameFromDS
+		case len(field.Labels) != 0:
+			name = formatLabels(field.Labels)
+		case f.Schema.Name != "" && valueFields == 1:
+			name = f.Schema.Name
+		default:
+			name = field.Name
+		}
+
+		s := chart.Series{Name: name, Points: make([]chart.Point, 0, len(values))}
+		for j, v := range values {
+			if j >= len(times) || times[j] == nil {
+				continue
+			}
+			p := chart.Point{Time: time.Unix(0, int64(*times[j])*int64(time.Millisecond)).UTC(), Value: math.NaN()}
+			if v != nil {
+				p.Value = *v
+			}
+			s.Points = append(s.Points, p)
+		}
+		series = append(series, s)
+	}
+	return series, nil
+}
+
+//parseDatasourceRef - returns the datasource reference and true if it has a uid which doesn't need to be resolved.
+//Otherwise the name or uid to resolve is returned as the uid. An empty uid selects the default datasource
+func parseDatasourceRef(raw json.RawMessage, vars map[string][]string) (datasourceRef, bool) {
+
+	var ref datasourceRef
+	if err := json.Unmarshal(raw, &ref); err != nil {
+		var name string
+		json.Unmarshal(raw, &name)
+		ref = datasourceRef{UID: name}
+	} else if ref.UID != "" && !variableRegex.MatchString(ref.UID) {
+		return ref, true
+	}
+
+	ref.UID = interpolate(ref.UID, vars, false)
+	return ref, false
+}
+
+//findDatasource - returns the datasource with the ref's uid or name. An empty uid selects the default datasource
+func findDatasource(datasources []datasourceResp, ref datasourceRef) (datasourceRef, error) {
+	for _, ds := range datasources {
+		if (ref.UID == "" && ds.IsDefault) || (ref.UID != "" && (ds.UID == ref.UID || ds.Name == ref.UID)) {
+			return datasourceRef{UID: ds.UID, Type: ds.Type}, nil
+		}
+	}
+	if ref.UID == "" {
+		return datasourceRef{}, errors.New("a default datasource isn't configured")
+	}
+	return datasourceRef{}, fmt.Errorf("datasource <%v> doesn't exist", ref.UID)
+}
+
+//interpolateValue - interpolates variables in every string of a decoded json value
+func interpolateValue(val interface{}, vars map[string][]string, regex bool) interface{} {
+	switch v := val.(type) {
+	case string:
+		return interpolate(v, vars, regex)
+	case map[string]interface{}:
+		m := make(map[string]interface{}, len(v))
+		for k, nested := range v {
+			m[k] = interpolateValue(nested, vars, regex)
+		}
+		return m
+	case []interface{}:
+		l := make([]interface{}, len(v))
+		for i, nested := range v {
+			l[i] = interpolateValue(nested, vars, regex)
+		}
+		return l
+	default:
+		return val
+	}
+}
+
+//interpolate - replaces dashboard variable references with their values. Multiple values and $__all are formatted as
+//a regex or a glob. Grafana's built in variables such as $__interval are left for the datasource to replace
+func interpolate(text string, vars map[string][]string, regex bool) string {
+	return variableRegex.ReplaceAllStringFunc(text, func(ref string) string {
+		m := variableRegex.FindStringSubmatch(ref)
+		name := m[1] + m[2] + m[3]
+		values, exists := vars[name]
+		if !exists {
+			return ref
+		}
+
+		all := len(values) == 0
+		for _, v := range values {
+			if v == AllValue || v == allAlias {
+				all = true
+			}
+		}
+		switch {
+		case all && regex:
+			return ".*"
+		case all:
+			return "*"
+		case len(values) == 1:
+			return values[0]
+		case regex:
+			quoted := make([]string, len(values))
+			for i, v := range values {
+				quoted[i] = regexp.QuoteMeta(v)
+			}
+			return "(" + strings.Join(quoted, "|") + ")"
+		default:
+			return "{" + strings.Join(values, ",") + "}"
+		}
+	})
+}
+
+//formatLabels - formats labels the way grafana names series. ie up{instance="a:9100", job="node"}
+func formatLabels(labels map[string]string) string {
+	pairs := make([]string, 0, len(labels))
+	for name, val := range labels {
+		if name != nameLabel {
+			pairs = append(pairs, fmt.Sprintf("%v=%q", name, val))
+		}
+	}
+	sort.Strings(pairs)
+	return fmt.Sprintf("%v{%v}", labels[nameLabel], strings.Join(pairs, ", "))
+}
+
+func sortedRefIDs(resp dsQueryResp) []string {
+	ids := make([]string, 0, len(resp.Results))
+	for id := range resp.Results {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+//queryError - returns the error of the first failed query in ref ID order or nil if none failed
+func (r dsQueryResp) queryError() error {
+	for _, refID := range sortedRefIDs(r) {
+		if msg := r.Results[refID].Error; msg != "" {
+			return &QueryError{RefID: refID, Message: msg}
+		}
+	}
+	return nil
+}
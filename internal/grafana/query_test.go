@@ -0,0 +1,303 @@
+package grafana
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"github.com/sajeevany/graph-snapper/internal/artifact"
+	"github.com/sajeevany/graph-snapper/internal/chart"
+	"github.com/sajeevany/graph-snapper/internal/common"
+	"github.com/sajeevany/graph-snapper/internal/config"
+	"github.com/sajeevany/graph-snapper/internal/test"
+	"github.com/sajeevany/graph-snapper/internal/upstream"
+	"github.com/sirupsen/logrus"
+	"image/color"
+	"image/png"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+)
+
+//newQueryServer - fake grafana serving a time series dashboard, a legacy graph dashboard and datasource queries.
+//Queries are checked against the expected expression of their datasource
+func newQueryServer(t *testing.T) (*httptest.Server, common.GrafanaUserV1) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/dashboards/uid/cpu01":
+			w.Write([]byte(`{"dashboard":{"uid":"cpu01","title":"CPU","templating":{"list":[
+				{"name":"env","current":{"value":"dev"}},
+				{"name":"cluster","current":{"value":["a","b"]}}
+			]},"panels":[{"id":2,"title":"CPU $env","type":"timeseries","datasource":{"uid":"prom1","type":"prometheus"},
+				"targets":[
+					{"refId":"A","expr":"rate(cpu{env=\"$env\",cluster=~\"${cluster}\"}[$__rate_interval])"},
+					{"refId":"B","expr":"up","hide":true}
+				],
+				"fieldConfig":{"defaults":{"unit":"percent","custom":{"thresholdsStyle":{"mode":"line"}},
+					"thresholds":{"mode":"absolute","steps":[{"color":"green","value":null},{"color":"dark-red","value":80}]}}},
+				"options":{"legend":{"showLegend":true,"displayMode":"list"}}}]}}`))
+		case "/api/dashboards/uid/legacy":
+			w.Write([]byte(`{"dashboard":{"uid":"legacy","title":"Legacy","rows":[{"title":"Disk","panels":[{"id":1,"title":"IOPS","type":"graph",
+				"datasource":"Graphite","targets":[{"target":"disk.*.iops"}],"legend":{"show":false},"yaxes":[{"format":"ops"}],
+				"thresholds":[{"value":500,"colorMode":"critical"}]}]}]}}`))
+		case datasourcesURL:
+			w.Write([]byte(`[{"uid":"prom1","name":"Prometheus","type":"prometheus","isDefault":true},{"uid":"graphite1","name":"Graphite","type":"graphite"}]`))
+		case dsQueryURL:
+			var req dsQueryReq
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil || len(req.Queries) != 1 {
+				t.Errorf("unexpected query request <%+v>. err <%v>", req, err)
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			q := req.Queries[0]
+			ds, _ := q["datasource"].(map[string]interface{})
+			switch {
+			case ds["uid"] == "prom1" && q["expr"] == `rate(cpu{env="prod",cluster=~"(a|b)"}[$__rate_interval])`:
+				w.Write([]byte(`{"results":{"A":{"frames":[
+					{"schema":{"fields":[{"name":"Time","type":"time"},{"name":"Value","type":"number","labels":{"cluster":"a"}}]},
+					 "data":{"values":[[1615383000000,1615386600000,1615390200000],[25,null,90]]}},
+					{"schema":{"fields":[{"name":"Time","type":"time"},{"name":"Value","type":"number","labels":{"cluster":"b"}}]},
+					 "data":{"values":[[1615383000000,1615390200000],[50,60]]}}
+				]}}}`))
+			case ds["uid"] == "graphite1" && q["target"] == "disk.*.iops":
+				w.Write([]byte(`{"results":{"A":{"frames":[
+					{"schema":{"name":"disk.sda.iops","fields":[{"name":"Time","type":"time"},{"name":"Value","type":"number"}]},
+					 "data":{"values":[[1615383000000,1615390200000],[300,700]]}}
+				]}}}`))
+			case ds["uid"] == "prom1" && q["expr"] == `rate(cpu{env="stage",cluster=~"(a|b)"}[$__rate_interval])`:
+				//Grafana versions which report failed queries with a 500
+				w.WriteHeader(http.StatusInternalServerError)
+				w.Write([]byte(`{"results":{"A":{"error":"query timed out","frames":[]}}}`))
+			case ds["uid"] == "prom1" && q["expr"] == `rate(cpu{env="qa",cluster=~"(a|b)"}[$__rate_interval])`:
+				w.Write([]byte(`<html>proxy error page</html>`))
+			default:
+				w.WriteHeader(http.StatusBadRequest)
+				w.Write([]byte(`{"results":{"A":{"error":"parse error","frames":[]}}}`))
+			}
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	host, port := test.SplitServerURL(t, server.URL)
+	return server, common.GrafanaUserV1{Auth: common.Auth{BearerToken: common.BearerToken{Token: "key"}}, Host: host, Port: port}
+}
+
+func TestRenderPanelData(t *testing.T) {
+
+	server, user := newQueryServer(t)
+	defer server.Close()
+	now := time.Date(2021, 3, 10, 15, 30, 0, 0, time.UTC)
+
+	tests := []struct {
+		name            string
+		target          SnapshotTargetV1
+		wantContentType string
+		wantErr         func(error) bool
+	}{
+		{
+			name: "Test0 - Time series panel with target and dashboard variables",
+			target: SnapshotTargetV1{DashboardUID: "cpu01", PanelID: 2, Width: 400, Height: 200, Mode: DataMode,
+				Variables: map[string][]string{"env": {"prod"}}, TimeRange: TimeRangeV1{From: "now-2h", To: "now"}},
+			wantContentType: chart.PNGContentType,
+		},
+		{
+			name: "Test1 - Legacy graph panel with a datasource name as svg",
+			target: SnapshotTargetV1{DashboardUID: "legacy", PanelID: 1, Mode: DataMode, Format: SVGFormat,
+				TimeRange: TimeRangeV1{From: "now-2h", To: "now"}},
+			wantContentType: chart.SVGContentType,
+		},
+		{
+			name:    "Test2 - Rejected query",
+			target:  SnapshotTargetV1{DashboardUID: "cpu01", PanelID: 2, Mode: DataMode, Variables: map[string][]string{"env": {"dev"}}},
+			wantErr: IsQueryError,
+		},
+		{
+			name:    "Test3 - Query rejected with a 500",
+			target:  SnapshotTargetV1{DashboardUID: "cpu01", PanelID: 2, Mode: DataMode, Variables: map[string][]string{"env": {"stage"}}},
+			wantErr: IsQueryError,
+		},
+		{
+			name:   "Test4 - Undecodable query response",
+			target: SnapshotTargetV1{DashboardUID: "cpu01", PanelID: 2, Mode: DataMode, Variables: map[string][]string{"env": {"qa"}}},
+			wantErr: func(err error) bool {
+				return err != nil && !IsQueryError(err)
+			},
+		},
+		{
+			name:    "Test5 - Unknown variables",
+			target:  SnapshotTargetV1{DashboardUID: "cpu01", PanelID: 2, Mode: DataMode, Variables: map[string][]string{"region": {"us"}}},
+			wantErr: IsUnknownVariables,
+		},
+		{
+			name:    "Test6 - Missing panel",
+			target:  SnapshotTargetV1{DashboardUID: "cpu01", PanelID: 9, Mode: DataMode},
+			wantErr: IsNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			img, contentType, err := RenderPanelData(context.Background(), logrus.NewEntry(logrus.New()), upstream.New(config.NewConfWithDefaults().Upstream), user, tt.target, now)
+			if tt.wantErr != nil {
+				if !tt.wantErr(err) {
+					t.Errorf("RenderPanelData() error = %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("RenderPanelData() unexpected error = %v", err)
+			}
+			if contentType != tt.wantContentType {
+				t.Errorf("RenderPanelData() content type = %v, want %v", contentType, tt.wantContentType)
+			}
+			if contentType == chart.SVGContentType {
+				if xErr := xml.Unmarshal(img, new(interface{})); xErr != nil {
+					t.Errorf("RenderPanelData() returned invalid svg. <%v>", xErr)
+				}
+				return
+			}
+			decoded, dErr := png.Decode(bytes.NewReader(img))
+			if dErr != nil {
+				t.Fatalf("RenderPanelData() returned an invalid png. <%v>", dErr)
+			}
+			if size := decoded.Bounds().Size(); size.X != tt.target.Width || size.Y != tt.target.Height {
+				t.Errorf("RenderPanelData() image size = %v", size)
+			}
+		})
+	}
+}
+
+func Test_panelModel_settings(t *testing.T) {
+
+	var dash dashboardModelResp
+	if err := json.Unmarshal([]byte(`{"dashboard":{"panels":[
+		{"id":1,"type":"timeseries","fieldConfig":{"defaults":{"unit":"bytes","thresholds":{"steps":[{"color":"green","value":null},{"color":"#EAB839","value":80}]}}},
+		 "options":{"legend":{"displayMode":"hidden"}}},
+		{"id":2,"type":"graph","legend":{"show":true},"yaxes":[{"format":"ms"},{"format":"short"}],
+		 "thresholds":[{"value":10,"colorMode":"warning"},{"value":20,"colorMode":"custom","lineColor":"rgba(31, 120, 193, 0.6)"}]},
+		{"id":3,"type":"singlestat","thresholds":"80,90"}
+	]}}`), &dash); err != nil {
+		t.Fatalf("unable to decode dashboard. err <%v>", err)
+	}
+
+	tests := []struct {
+		name           string
+		panelID        int
+		wantUnit       string
+		wantHidden     bool
+		wantThresholds []chart.Threshold
+	}{
+		{
+			name:           "Test0 - Thresholds aren't drawn by default",
+			panelID:        1,
+			wantUnit:       "bytes",
+			wantHidden:     true,
+			wantThresholds: []chart.Threshold{},
+		},
+		{
+			name:     "Test1 - Graph panel settings",
+			panelID:  2,
+			wantUnit: "ms",
+			wantThresholds: []chart.Threshold{
+				{Value: 10, Color: thresholdColors["warning"]},
+				{Value: 20, Color: color.RGBA{R: 31, G: 120, B: 193, A: 0xff}},
+			},
+		},
+		{
+			name:           "Test2 - Singlestat thresholds are ignored",
+			panelID:        3,
+			wantThresholds: []chart.Threshold{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, found := dash.findPanel(tt.panelID)
+			if !found {
+				t.Fatalf("findPanel() didn't find panel <%v>", tt.panelID)
+			}
+			if got := p.unit(); got != tt.wantUnit {
+				t.Errorf("unit() = %v, want %v", got, tt.wantUnit)
+			}
+			if got := p.legendHidden(); got != tt.wantHidden {
+				t.Errorf("legendHidden() = %v, want %v", got, tt.wantHidden)
+			}
+			if got := p.thresholds(); !reflect.DeepEqual(got, tt.wantThresholds) {
+				t.Errorf("thresholds() = %v, want %v", got, tt.wantThresholds)
+			}
+		})
+	}
+}
+
+func Test_interpolate(t *testing.T) {
+
+	vars := map[string][]string{"env": {"prod"}, "host": {"a.1", "b"}, "all": {"All"}}
+
+	tests := []struct {
+		name  string
+		text  string
+		regex bool
+		want  string
+	}{
+		{
+			name: "Test0 - Single value in every syntax",
+			text: "$env ${env} ${env:raw} [[env]]",
+			want: "prod prod prod prod",
+		},
+		{
+			name:  "Test1 - Multiple values as a regex",
+			text:  `up{host=~"$host"}`,
+			regex: true,
+			want:  `up{host=~"(a\.1|b)"}`,
+		},
+		{
+			name: "Test2 - Multiple values as a glob",
+			text: "servers.$host.cpu",
+			want: "servers.{a.1,b}.cpu",
+		},
+		{
+			name:  "Test3 - All values",
+			text:  `up{host=~"$all"}`,
+			regex: true,
+			want:  `up{host=~".*"}`,
+		},
+		{
+			name: "Test4 - Built in and unknown variables are kept",
+			text: "rate(x[$__rate_interval]) $environment",
+			want: "rate(x[$__rate_interval]) $environment",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := interpolate(tt.text, vars, tt.regex); got != tt.want {
+				t.Errorf("interpolate() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSource_RenderExports(t *testing.T) {
+
+	server, user := newQueryServer(t)
+	defer server.Close()
+
+	s := NewSource(upstream.New(config.NewConfWithDefaults().Upstream))
+	s.now = func() time.Time { return time.Date(2021, 3, 10, 15, 30, 0, 0, time.UTC) }
+	target := SnapshotTargetV1{DashboardUID: "legacy", PanelID: 1, Mode: DataMode, TimeRange: TimeRangeV1{From: "now-2h", To: "now"},
+		Exports: []string{artifact.CSVExportFormat, artifact.JSONExportFormat}}
+
+	got, err := s.Render(context.Background(), logrus.NewEntry(logrus.New()), user, target)
+	if err != nil {
+		t.Fatalf("Render() unexpected error = %v", err)
+	}
+	if len(got.Exports) != 2 || got.Exports[0].Format != artifact.CSVExportFormat || got.Exports[1].Format != artifact.JSONExportFormat {
+		t.Fatalf("Render() exports = %+v", got.Exports)
+	}
+	if want := "Time,disk.sda.iops\n2021-03-10T13:30:00Z,300\n2021-03-10T15:30:00Z,700\n"; string(got.Exports[0].Data) != want {
+		t.Errorf("Render() csv export = %s, want %s", got.Exports[0].Data, want)
+	}
+	if got.ContentType != chart.PNGContentType || got.DashboardTitle != "Legacy" || got.PanelTitle != "IOPS" {
+		t.Errorf("Render() = %+v", got)
+	}
+}
@@ -0,0 +1,110 @@
+package grafana
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/sajeevany/graph-snapper/internal/common"
+	"github.com/sajeevany/graph-snapper/internal/upstream"
+	"github.com/sirupsen/logrus"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+const (
+	//renderSoloURL - renders a single dashboard panel to a png with the image renderer
+	renderSoloURL = "/render/d-solo/%s"
+
+	pngContentType = "image/png"
+)
+
+//UnknownVariablesError - the target sets template variables the dashboard doesn't define
+type UnknownVariablesError struct {
+	DashboardUID string
+	Names        []string
+}
+
+func (e *UnknownVariablesError) Error() string {
+	return fmt.Sprintf("dashboard <%v> doesn't define template variables <%v>", e.DashboardUID, strings.Join(e.Names, ", "))
+}
+
+//IsUnknownVariables - returns true if the target sets template variables the dashboard doesn't define
+func IsUnknownVariables(err error) bool {
+	var vErr *UnknownVariablesError
+	return errors.As(err, &vErr)
+}
+
+//FindUnknownVariables - Returns the sorted names of target variables missing from the dashboard's templating list
+func FindUnknownVariables(ctx context.Context, logger *logrus.Entry, client *upstream.Client, user common.GrafanaUserV1, target SnapshotTargetV1) ([]string, error) {
+
+	unknown := []string{}
+	if len(target.Variables) == 0 {
+		return unknown, nil
+	}
+
+	dash, err := getDashboard(ctx, logger, client, user, target.DashboardUID)
+	if err != nil {
+		return nil, err
+	}
+
+	defined := make(map[string]bool, len(dash.Dashboard.Templating.List))
+	for _, v := range dash.Dashboard.Templating.List {
+		defined[v.Name] = true
+	}
+	for _, name := range target.variableNames() {
+		if !defined[name] {
+			unknown = append(unknown, name)
+		}
+	}
+
+	return unknown, nil
+}
+
+//RenderPanel - Captures the target panel as a png using the grafana image renderer. Template variables are checked
+//against the dashboard first and *UnknownVariablesError is returned if any aren't defined
+func RenderPanel(ctx context.Context, logger *logrus.Entry, client *upstream.Client, user common.GrafanaUserV1, target SnapshotTargetV1) ([]byte, error) {
+
+	unknown, err := FindUnknownVariables(ctx, logger, client, user, target)
+	if err != nil {
+		return nil, err
+	}
+	if len(unknown) != 0 {
+		return nil, &UnknownVariablesError{DashboardUID: target.DashboardUID, Names: unknown}
+	}
+
+	path := fmt.Sprintf(renderSoloURL, url.PathEscape(target.DashboardUID))
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, user.BaseURL()+path+"?"+target.RenderParams().Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+	if aErr := setRequestHeaders(logger, client, user, req); aErr != nil {
+		return nil, aErr
+	}
+	req.Header.Set("Accept", pngContentType)
+
+	resp, err := client.Do(ctx, logger, Endpoint(user), "grafana.RenderPanel", req)
+	if err != nil {
+		logger.Debugf("grafana panel render failed. <%v>", err)
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, &StatusError{Path: path, StatusCode: resp.StatusCode}
+	}
+
+	//The renderer responds with an error page when it isn't installed
+	if ct := resp.Header.Get("Content-Type"); !strings.HasPrefix(ct, pngContentType) {
+		return nil, fmt.Errorf("%v returned content type <%v> instead of an image. Check that the image renderer is available", path, ct)
+	}
+
+	img, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read rendered panel. err <%v>", err)
+	}
+	logger.Debugf("rendered panel <%v> of dashboard <%v>. <%v> bytes", target.PanelID, target.DashboardUID, len(img))
+
+	return img, nil
+}
@@ -0,0 +1,96 @@
+package grafana
+
+import (
+	"context"
+	"github.com/sajeevany/graph-snapper/internal/common"
+	"github.com/sajeevany/graph-snapper/internal/config"
+	"github.com/sajeevany/graph-snapper/internal/test"
+	"github.com/sajeevany/graph-snapper/internal/upstream"
+	"github.com/sirupsen/logrus"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func TestRenderPanel(t *testing.T) {
+
+	png := []byte("\x89PNG\r\n\x1a\n")
+
+	tests := []struct {
+		name        string
+		target      SnapshotTargetV1
+		renderer    bool
+		want        []byte
+		wantUnknown []string
+		wantErr     bool
+	}{
+		{
+			name: "Test0 - Variables and time range are passed to the renderer",
+			target: SnapshotTargetV1{DashboardUID: "cpu01", PanelID: 2, Variables: map[string][]string{"env": {"prod"}, "cluster": {"All"}},
+				TimeRange: TimeRangeV1{From: "now-7d", To: "now"}, Timezone: "utc", Theme: "light"},
+			renderer: true,
+			want:     png,
+		},
+		{
+			name:        "Test1 - Unknown variables are reported before capture",
+			target:      SnapshotTargetV1{DashboardUID: "cpu01", PanelID: 2, Variables: map[string][]string{"env": {"prod"}, "region": {"us"}, "dc": {"1"}}},
+			renderer:    true,
+			wantUnknown: []string{"dc", "region"},
+			wantErr:     true,
+		},
+		{
+			name:     "Test2 - Renderer isn't installed",
+			target:   SnapshotTargetV1{DashboardUID: "cpu01", PanelID: 2},
+			renderer: false,
+			wantErr:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+
+			rendered := false
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				switch r.URL.Path {
+				case "/api/dashboards/uid/cpu01":
+					w.Write([]byte(`{"dashboard":{"uid":"cpu01","templating":{"list":[{"name":"env"},{"name":"cluster"}]}}}`))
+				case "/render/d-solo/cpu01":
+					rendered = true
+					if !tt.renderer {
+						w.Header().Set("Content-Type", "text/html")
+						w.Write([]byte("<html>No image renderer available/installed</html>"))
+						return
+					}
+					if !reflect.DeepEqual(r.URL.Query(), tt.target.RenderParams()) {
+						t.Errorf("render params = %v, want %v", r.URL.Query(), tt.target.RenderParams())
+					}
+					w.Header().Set("Content-Type", "image/png")
+					w.Write(png)
+				default:
+					w.WriteHeader(http.StatusNotFound)
+				}
+			}))
+			defer server.Close()
+			host, port := test.SplitServerURL(t, server.URL)
+
+			got, err := RenderPanel(context.Background(), logrus.NewEntry(logrus.New()), upstream.New(config.NewConfWithDefaults().Upstream),
+				common.GrafanaUserV1{Auth: common.Auth{BearerToken: common.BearerToken{Token: "key"}}, Host: host, Port: port}, tt.target)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("RenderPanel() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("RenderPanel() = %v, want %v", got, tt.want)
+			}
+			if tt.wantUnknown != nil {
+				vErr, ok := err.(*UnknownVariablesError)
+				if !ok || !reflect.DeepEqual(vErr.Names, tt.wantUnknown) {
+					t.Errorf("RenderPanel() error = %v, want unknown variables %v", err, tt.wantUnknown)
+				}
+				if rendered {
+					t.Error("RenderPanel() rendered a target with unknown variables")
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,198 @@
+package grafana
+
+import (
+	"context"
+	"fmt"
+	"github.com/sajeevany/graph-snapper/internal/artifact"
+	"github.com/sajeevany/graph-snapper/internal/chart"
+	"github.com/sajeevany/graph-snapper/internal/common"
+	"github.com/sajeevany/graph-snapper/internal/plugin"
+	"github.com/sajeevany/graph-snapper/internal/source"
+	"github.com/sajeevany/graph-snapper/internal/upstream"
+	"github.com/sirupsen/logrus"
+	"net/url"
+	"time"
+)
+
+//SourceType - source type of grafana users
+const SourceType = "grafana"
+
+//dashboardSearchType - grafana search type of dashboards
+const dashboardSearchType = "dash-db"
+
+//Source - Renders dashboard panels with the grafana image renderer or draws their query data
+type Source struct {
+	client *upstream.Client
+	now    func() time.Time
+}
+
+//NewSource - returns the grafana source using the client for all calls
+func NewSource(client *upstream.Client) *Source {
+	return &Source{client: client, now: time.Now}
+}
+
+func (s *Source) Type() string {
+	return SourceType
+}
+
+func (s *Source) NewCredential() source.Credential {
+	return &common.GrafanaUserV1{}
+}
+
+func (s *Source) Validate(cred source.Credential) error {
+	user, err := grafanaUser(cred)
+	if err != nil {
+		return err
+	}
+	if !user.IsValid() {
+		return fmt.Errorf("grafana user <%#v> is invalid", user.GetRedactedView())
+	}
+	return nil
+}
+
+//CheckAccess - Checks that the user can log in and is a member of its org. The instance and credential details of
+//users which can log in are returned as the result details
+func (s *Source) CheckAccess(ctx context.Context, logger *logrus.Entry, cred source.Credential) plugin.CheckResultV1 {
+
+	user, err := grafanaUser(cred)
+	if err != nil {
+		return plugin.Failed(err.Error())
+	}
+
+	isValid, rErr := IsValidLogin(ctx, logger, s.client, user)
+	logger.WithFields(user.GetFields()).Debugf("Received grafana login check result <%v> err <%v>", isValid, rErr)
+	if rErr != nil {
+		logger.WithFields(user.GetFields()).Errorf("Error checking if grafana user has login access. <%v>", rErr)
+		return plugin.Failed(rErr.Error())
+	}
+
+	if !isValid {
+		cause := "Unauthorized. Received 401"
+		if user.OrgID != 0 {
+			//Grafana rejects users which aren't members of the requested org
+			cause = fmt.Sprintf("%v. Check that the user is a member of org %v", cause, user.OrgID)
+		}
+		return plugin.Failed(cause)
+	}
+
+	//Report what the credential can do in addition to whether it can log in
+	info := Introspect(ctx, logger, s.client, user)
+	if oErr := CheckOrgMembership(user, info); oErr != nil {
+		logger.WithFields(user.GetFields()).Debugf("Grafana user failed org check. <%v>", oErr)
+		result := plugin.Failed(oErr.Error())
+		result.Details = &info
+		return result
+	}
+	return plugin.Passed(&info)
+}
+
+//ListDashboards - Searches dashboards by title
+func (s *Source) ListDashboards(ctx context.Context, logger *logrus.Entry, cred source.Credential, query string) ([]source.DashboardV1, error) {
+
+	user, err := grafanaUser(cred)
+	if err != nil {
+		return nil, err
+	}
+
+	params := url.Values{}
+	params.Set("type", dashboardSearchType)
+	if query != "" {
+		params.Set("query", query)
+	}
+	hits, err := Search(ctx, logger, s.client, user, params)
+	if err != nil {
+		return nil, err
+	}
+
+	dashboards := make([]source.DashboardV1, len(hits))
+	for i, hit := range hits {
+		dashboards[i] = source.DashboardV1{UID: hit.UID, Title: hit.Title, URL: hit.URL, Tags: hit.Tags}
+	}
+	return dashboards, nil
+}
+
+func (s *Source) NewTarget() source.Target {
+	return &SnapshotTargetV1{}
+}
+
+//Render - Renders the target panel in the target's mode and looks up the dashboard and panel titles. Titles are
+//informational so lookup failures are logged and empty titles are returned
+func (s *Source) Render(ctx context.Context, logger *logrus.Entry, cred source.Credential, t source.Target) (source.RenderV1, error) {
+
+	user, err := grafanaUser(cred)
+	if err != nil {
+		return source.RenderV1{}, err
+	}
+	target, ok := t.(SnapshotTargetV1)
+	if !ok {
+		return source.RenderV1{}, fmt.Errorf("target <%T> isn't a grafana snapshot target", t)
+	}
+
+	img, contentType, exports, err := s.render(ctx, logger, user, target)
+	switch {
+	case IsUnknownVariables(err), IsQueryError(err), chart.IsSizeError(err):
+		return source.RenderV1{}, &source.TargetError{Err: err}
+	case IsNotFound(err):
+		return source.RenderV1{}, &source.NotFoundError{Err: err}
+	case err != nil:
+		return source.RenderV1{}, err
+	}
+
+	from, to := target.TimeRange.Params()
+	render := source.RenderV1{
+		Image:        img,
+		ContentType:  contentType,
+		Exports:      exports,
+		DashboardUID: target.DashboardUID,
+		PanelID:      target.PanelID,
+		From:         from,
+		To:           to,
+	}
+
+	dash, err := GetDashboardPanels(ctx, logger, s.client, user, target.DashboardUID)
+	if err != nil {
+		logger.Warnf("Unable to read titles of dashboard <%v>. <%v>", target.DashboardUID, err)
+		return render, nil
+	}
+	render.DashboardTitle = dash.Title
+	for _, p := range dash.Panels {
+		if p.ID == target.PanelID {
+			render.PanelTitle = p.Title
+		}
+	}
+	return render, nil
+}
+
+//render - returns the target's image in its mode and the exports of its query result. Panel data is only queried
+//once when it's both drawn and exported
+func (s *Source) render(ctx context.Context, logger *logrus.Entry, user common.GrafanaUserV1, target SnapshotTargetV1) ([]byte, string, []artifact.DataExport, error) {
+
+	if target.Mode != DataMode && len(target.Exports) == 0 {
+		img, err := RenderPanel(ctx, logger, s.client, user, target)
+		return img, pngContentType, nil, err
+	}
+
+	data, err := QueryPanel(ctx, logger, s.client, user, target, s.now())
+	if err != nil {
+		return nil, "", nil, err
+	}
+	exports, err := exportData(data, target.Exports)
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	if target.Mode == DataMode {
+		img, contentType, err := drawChart(data, target.Format)
+		return img, contentType, exports, err
+	}
+	img, err := RenderPanel(ctx, logger, s.client, user, target)
+	return img, pngContentType, exports, err
+}
+
+func grafanaUser(cred source.Credential) (common.GrafanaUserV1, error) {
+	user, ok := cred.(common.GrafanaUserV1)
+	if !ok {
+		return common.GrafanaUserV1{}, fmt.Errorf("credential <%T> isn't a grafana user", cred)
+	}
+	return user, nil
+}
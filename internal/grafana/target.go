@@ -0,0 +1,374 @@
+package grafana
+
+import (
+	"fmt"
+	"github.com/sajeevany/graph-snapper/internal/artifact"
+	"github.com/sajeevany/graph-snapper/internal/config"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	//AllValue - selects every value of a template variable. "All" is accepted as an alias
+	AllValue = "$__all"
+	allAlias = "All"
+
+	defaultFrom   = "now-6h"
+	defaultTo     = "now"
+	defaultWidth  = 1000
+	defaultHeight = 500
+	maxDimension  = 10000
+
+	browserTimezone = "browser"
+	utcTimezone     = "utc"
+	lightTheme      = "light"
+	darkTheme       = "dark"
+
+	//ImageMode - panels are rendered by grafana's image renderer
+	ImageMode = "image"
+	//DataMode - panel queries are run through grafana and the results are drawn without the image renderer
+	DataMode = "data"
+
+	//PNGFormat - png images. Supported by every mode
+	PNGFormat = "png"
+	//SVGFormat - svg images. Only supported by DataMode
+	SVGFormat = "svg"
+
+	//varParamPrefix - grafana reads template variable values from var-<name> url parameters
+	varParamPrefix = "var-"
+)
+
+//relativeTimeRegex - grafana relative time. ie now, now-7d, now-1d/d, now/w
+var relativeTimeRegex = regexp.MustCompile(`^now([+-][0-9]+[smhdwMy])*(/[smhdwMy])?$`)
+
+//relativeOpRegex - offset of a relative time. ie -7d
+var relativeOpRegex = regexp.MustCompile(`([+-])([0-9]+)([smhdwMy])`)
+
+//SnapshotTargetV1 - Dashboard panel to capture and how to render it
+type SnapshotTargetV1 struct {
+	DashboardUID string
+	PanelID      int
+	Width        int                 //Optional. Image width in pixels. Defaults to 1000
+	Height       int                 //Optional. Image height in pixels. Defaults to 500
+	Variables    map[string][]string //Optional. Template variable values by name. Use $__all or All to select every value
+	TimeRange    TimeRangeV1
+	Timezone     string   //Optional. browser, utc or an IANA zone such as Europe/London. Defaults to the dashboard's timezone
+	Theme        string   //Optional. light or dark. Defaults to the grafana default theme
+	Mode         string   //Optional. image or data. Defaults to image
+	Format       string   //Optional. png or svg. Defaults to png. svg is only supported by data mode
+	Exports      []string //Optional. Formats the panel's query result is stored in with the image. csv or json
+}
+
+//TimeRangeV1 - Relative (now-7d) or absolute time range. Absolute times are epoch milliseconds or RFC3339 timestamps
+type TimeRangeV1 struct {
+	From string //Optional. Defaults to now-6h
+	To   string //Optional. Defaults to now
+}
+
+//IsValid - Adds an entry per invalid field to invalidArgs
+func (t SnapshotTargetV1) IsValid(currentPath string, invalidArgs map[string]string) bool {
+
+	valid := true
+
+	if t.DashboardUID == "" {
+		config.AddInvalidArgWithCause(currentPath, "DashboardUID", t.DashboardUID, "value is empty", invalidArgs)
+		valid = false
+	}
+	if t.PanelID <= 0 {
+		config.AddInvalidArgWithCause(currentPath, "PanelID", strconv.Itoa(t.PanelID), "value is 0 or negative", invalidArgs)
+		valid = false
+	}
+	if t.Width < 0 || t.Width > maxDimension {
+		config.AddInvalidArgWithCause(currentPath, "Width", strconv.Itoa(t.Width), fmt.Sprintf("value isn't between 0 and %v", maxDimension), invalidArgs)
+		valid = false
+	}
+	if t.Height < 0 || t.Height > maxDimension {
+		config.AddInvalidArgWithCause(currentPath, "Height", strconv.Itoa(t.Height), fmt.Sprintf("value isn't between 0 and %v", maxDimension), invalidArgs)
+		valid = false
+	}
+
+	for _, name := range t.variableNames() {
+		if name == "" || strings.HasPrefix(name, varParamPrefix) {
+			config.AddInvalidArgWithCause(currentPath, "Variables", name, "name is empty or starts with var-", invalidArgs)
+			valid = false
+		} else if len(t.Variables[name]) == 0 {
+			config.AddInvalidArgWithCause(currentPath, "Variables."+name, "", "variable has no values", invalidArgs)
+			valid = false
+		}
+	}
+
+	if !t.TimeRange.IsValid(fmt.Sprintf("%s.%s", currentPath, "TimeRange"), invalidArgs) {
+		valid = false
+	}
+
+	switch t.Timezone {
+	case "", browserTimezone, utcTimezone:
+	default:
+		if _, err := time.LoadLocation(t.Timezone); err != nil || t.Timezone == "Local" {
+			config.AddInvalidArgWithCause(currentPath, "Timezone", t.Timezone, "value isn't browser, utc or an IANA timezone", invalidArgs)
+			valid = false
+		}
+	}
+
+	switch t.Theme {
+	case "", lightTheme, darkTheme:
+	default:
+		config.AddInvalidArgWithCause(currentPath, "Theme", t.Theme, "value isn't light or dark", invalidArgs)
+		valid = false
+	}
+
+	switch t.Mode {
+	case "", ImageMode, DataMode:
+	default:
+		config.AddInvalidArgWithCause(currentPath, "Mode", t.Mode, "value isn't image or data", invalidArgs)
+		valid = false
+	}
+
+	switch t.Format {
+	case "", PNGFormat:
+	case SVGFormat:
+		if t.Mode != DataMode {
+			config.AddInvalidArgWithCause(currentPath, "Format", t.Format, "svg is only supported by data mode", invalidArgs)
+			valid = false
+		}
+	default:
+		config.AddInvalidArgWithCause(currentPath, "Format", t.Format, "value isn't png or svg", invalidArgs)
+		valid = false
+	}
+
+	exported := make(map[string]bool, len(t.Exports))
+	for _, format := range t.Exports {
+		if !artifact.IsExportFormat(format) || exported[format] {
+			config.AddInvalidArgWithCause(currentPath, "Exports", format, "value isn't csv or json or is repeated", invalidArgs)
+			valid = false
+		}
+		exported[format] = true
+	}
+
+	return valid
+}
+
+//IsValid - Adds an entry per invalid field to invalidArgs. From must be before To when both are absolute
+func (r TimeRangeV1) IsValid(currentPath string, invalidArgs map[string]string) bool {
+
+	from, fErr := parseTime(r.From)
+	if fErr != nil {
+		config.AddInvalidArgWithCause(currentPath, "From", r.From, fErr.Error(), invalidArgs)
+	}
+	to, tErr := parseTime(r.To)
+	if tErr != nil {
+		config.AddInvalidArgWithCause(currentPath, "To", r.To, tErr.Error(), invalidArgs)
+	}
+	if fErr != nil || tErr != nil {
+		return false
+	}
+
+	fromMS, fErr := strconv.ParseInt(from, 10, 64)
+	toMS, tErr := strconv.ParseInt(to, 10, 64)
+	if fErr == nil && tErr == nil && fromMS >= toMS {
+		config.AddInvalidArgWithCause(currentPath, "From", r.From, "value isn't before To", invalidArgs)
+		return false
+	}
+
+	return true
+}
+
+//Params - Returns the from and to url parameters. Absolute times are converted to epoch milliseconds and empty
+//values are replaced by the defaults
+func (r TimeRangeV1) Params() (string, string) {
+	from, _ := parseTime(r.From)
+	to, _ := parseTime(r.To)
+	if from == "" {
+		from = defaultFrom
+	}
+	if to == "" {
+		to = defaultTo
+	}
+	return from, to
+}
+
+//Bounds - Returns the absolute start and end of the range. Relative times are resolved against now in UTC. Rounded
+//times such as now/d are rounded down for From and up to the end of the unit for To
+func (r TimeRangeV1) Bounds(now time.Time) (time.Time, time.Time, error) {
+	fromParam, toParam := r.Params()
+	from, err := resolveTime(fromParam, now, false)
+	if err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+	to, err := resolveTime(toParam, now, true)
+	if err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+	return from, to, nil
+}
+
+//RenderParams - Returns the url parameters grafana uses to render the target panel
+func (t SnapshotTargetV1) RenderParams() url.Values {
+
+	params := url.Values{}
+	params.Set("panelId", strconv.Itoa(t.PanelID))
+
+	width, height := t.size()
+	params.Set("width", strconv.Itoa(width))
+	params.Set("height", strconv.Itoa(height))
+
+	from, to := t.TimeRange.Params()
+	params.Set("from", from)
+	params.Set("to", to)
+
+	if t.Timezone != "" {
+		params.Set("tz", t.Timezone)
+	}
+	if t.Theme != "" {
+		params.Set("theme", t.Theme)
+	}
+
+	for _, name := range t.variableNames() {
+		for _, val := range t.Variables[name] {
+			if val == allAlias {
+				val = AllValue
+			}
+			params.Add(varParamPrefix+name, val)
+		}
+	}
+
+	return params
+}
+
+//size - returns the image width and height with defaults applied
+func (t SnapshotTargetV1) size() (int, int) {
+	width, height := t.Width, t.Height
+	if width == 0 {
+		width = defaultWidth
+	}
+	if height == 0 {
+		height = defaultHeight
+	}
+	return width, height
+}
+
+//location - returns the timezone of chart labels. The browser timezone isn't known so utc is used instead
+func (t SnapshotTargetV1) location() *time.Location {
+	switch t.Timezone {
+	case "", browserTimezone, utcTimezone:
+		return time.UTC
+	}
+	loc, err := time.LoadLocation(t.Timezone)
+	if err != nil {
+		return time.UTC
+	}
+	return loc
+}
+
+//variableNames - returns the variable names in sorted order
+func (t SnapshotTargetV1) variableNames() []string {
+	names := make([]string, 0, len(t.Variables))
+	for name := range t.Variables {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+//parseTime - returns the grafana url value of a relative or absolute time. Empty values are returned as is
+func parseTime(val string) (string, error) {
+	switch {
+	case val == "":
+		return "", nil
+	case relativeTimeRegex.MatchString(val):
+		return val, nil
+	case isAbsolute(val):
+		return val, nil
+	}
+
+	ts, err := time.Parse(time.RFC3339, val)
+	if err != nil {
+		return "", fmt.Errorf("value isn't a relative time such as now-7d, epoch milliseconds or an RFC3339 timestamp")
+	}
+	return strconv.FormatInt(ts.UnixNano()/int64(time.Millisecond), 10), nil
+}
+
+//resolveTime - returns the time of a url value returned by parseTime. Rounded relative times are rounded to the end of
+//the unit when roundUp is set
+func resolveTime(val string, now time.Time, roundUp bool) (time.Time, error) {
+
+	if isAbsolute(val) {
+		ms, _ := strconv.ParseInt(val, 10, 64)
+		return time.Unix(0, ms*int64(time.Millisecond)).UTC(), nil
+	}
+	if !relativeTimeRegex.MatchString(val) {
+		return time.Time{}, fmt.Errorf("time <%v> isn't relative or epoch milliseconds", val)
+	}
+
+	t := now.UTC()
+	for _, op := range relativeOpRegex.FindAllStringSubmatch(strings.TrimPrefix(val, "now"), -1) {
+		n, err := strconv.Atoi(op[2])
+		if err != nil {
+			return time.Time{}, fmt.Errorf("time <%v> offset <%v> isn't a number", val, op[2])
+		}
+		if op[1] == "-" {
+			n = -n
+		}
+		t = addUnit(t, op[3], n)
+	}
+
+	if i := strings.Index(val, "/"); i != -1 {
+		unit := val[i+1:]
+		t = truncateUnit(t, unit)
+		if roundUp {
+			t = addUnit(t, unit, 1).Add(-time.Millisecond)
+		}
+	}
+	return t, nil
+}
+
+//addUnit - adds n of a grafana time unit. ie 7 d
+func addUnit(t time.Time, unit string, n int) time.Time {
+	switch unit {
+	case "s":
+		return t.Add(time.Duration(n) * time.Second)
+	case "m":
+		return t.Add(time.Duration(n) * time.Minute)
+	case "h":
+		return t.Add(time.Duration(n) * time.Hour)
+	case "d":
+		return t.AddDate(0, 0, n)
+	case "w":
+		return t.AddDate(0, 0, 7*n)
+	case "M":
+		return t.AddDate(0, n, 0)
+	default:
+		return t.AddDate(n, 0, 0)
+	}
+}
+
+//truncateUnit - returns the start of the unit t is in. Weeks start on monday
+func truncateUnit(t time.Time, unit string) time.Time {
+	switch unit {
+	case "s":
+		return t.Truncate(time.Second)
+	case "m":
+		return t.Truncate(time.Minute)
+	case "h":
+		return t.Truncate(time.Hour)
+	case "d":
+		return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+	case "w":
+		day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+		return day.AddDate(0, 0, -((int(day.Weekday()) + 6) % 7))
+	case "M":
+		return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, t.Location())
+	default:
+		return time.Date(t.Year(), time.January, 1, 0, 0, 0, 0, t.Location())
+	}
+}
+
+//isAbsolute - returns true if val is epoch milliseconds
+func isAbsolute(val string) bool {
+	ms, err := strconv.ParseInt(val, 10, 64)
+	return err == nil && ms >= 0
+}
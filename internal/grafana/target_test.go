@@ -0,0 +1,189 @@
+package grafana
+
+import (
+	"net/url"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+)
+
+func TestSnapshotTargetV1_IsValid(t *testing.T) {
+
+	tests := []struct {
+		name        string
+		target      SnapshotTargetV1
+		wantInvalid []string
+	}{
+		{
+			name:   "Test0 - Minimal target",
+			target: SnapshotTargetV1{DashboardUID: "cpu01", PanelID: 2},
+		},
+		{
+			name: "Test1 - Relative range, variables, timezone and theme",
+			target: SnapshotTargetV1{
+				DashboardUID: "cpu01",
+				PanelID:      2,
+				Variables:    map[string][]string{"env": {"prod"}, "cluster": {"a", "b"}, "host": {"All"}},
+				TimeRange:    TimeRangeV1{From: "now-7d", To: "now"},
+				Timezone:     "Europe/London",
+				Theme:        "light",
+			},
+		},
+		{
+			name:   "Test2 - Absolute range",
+			target: SnapshotTargetV1{DashboardUID: "cpu01", PanelID: 2, TimeRange: TimeRangeV1{From: "2021-03-01T00:00:00Z", To: "1614643200000"}},
+		},
+		{
+			name:        "Test3 - Missing dashboard and panel",
+			target:      SnapshotTargetV1{},
+			wantInvalid: []string{"target.DashboardUID", "target.PanelID"},
+		},
+		{
+			name:        "Test4 - Unparseable and reversed time ranges",
+			target:      SnapshotTargetV1{DashboardUID: "cpu01", PanelID: 2, TimeRange: TimeRangeV1{From: "yesterday", To: "now-"}},
+			wantInvalid: []string{"target.TimeRange.From", "target.TimeRange.To"},
+		},
+		{
+			name:        "Test5 - From after To",
+			target:      SnapshotTargetV1{DashboardUID: "cpu01", PanelID: 2, TimeRange: TimeRangeV1{From: "1614643200000", To: "999999999999"}},
+			wantInvalid: []string{"target.TimeRange.From"},
+		},
+		{
+			name: "Test6 - Bad variables, timezone, theme and size",
+			target: SnapshotTargetV1{
+				DashboardUID: "cpu01",
+				PanelID:      2,
+				Width:        -1,
+				Height:       20000,
+				Variables:    map[string][]string{"var-env": {"prod"}, "cluster": {}},
+				Timezone:     "Mars/Olympus",
+				Theme:        "blue",
+			},
+			wantInvalid: []string{"target.Height", "target.Theme", "target.Timezone", "target.Variables", "target.Variables.cluster", "target.Width"},
+		},
+		{
+			name:   "Test7 - Data mode svg",
+			target: SnapshotTargetV1{DashboardUID: "cpu01", PanelID: 2, Mode: DataMode, Format: SVGFormat},
+		},
+		{
+			name:        "Test8 - Unknown mode and svg from the image renderer",
+			target:      SnapshotTargetV1{DashboardUID: "cpu01", PanelID: 2, Mode: "browser", Format: SVGFormat},
+			wantInvalid: []string{"target.Format", "target.Mode"},
+		},
+		{
+			name:        "Test9 - Unknown and repeated exports",
+			target:      SnapshotTargetV1{DashboardUID: "cpu01", PanelID: 2, Exports: []string{"csv", "xlsx", "csv"}},
+			wantInvalid: []string{"target.Exports"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			invalidArgs := make(map[string]string)
+			valid := tt.target.IsValid("target", invalidArgs)
+
+			var got []string
+			for k := range invalidArgs {
+				got = append(got, k)
+			}
+			sort.Strings(got)
+			if valid != (len(tt.wantInvalid) == 0) || !reflect.DeepEqual(got, tt.wantInvalid) {
+				t.Errorf("IsValid() = %v with invalid args %v, want %v", valid, invalidArgs, tt.wantInvalid)
+			}
+		})
+	}
+}
+
+func TestSnapshotTargetV1_RenderParams(t *testing.T) {
+
+	tests := []struct {
+		name   string
+		target SnapshotTargetV1
+		want   url.Values
+	}{
+		{
+			name:   "Test0 - Defaults",
+			target: SnapshotTargetV1{DashboardUID: "cpu01", PanelID: 2},
+			want: url.Values{
+				"panelId": {"2"}, "width": {"1000"}, "height": {"500"}, "from": {"now-6h"}, "to": {"now"},
+			},
+		},
+		{
+			name: "Test1 - Multi value and all variables with absolute range",
+			target: SnapshotTargetV1{
+				DashboardUID: "cpu01",
+				PanelID:      2,
+				Width:        800,
+				Height:       400,
+				Variables:    map[string][]string{"cluster": {"a", "b"}, "host": {"All"}, "env": {AllValue}},
+				TimeRange:    TimeRangeV1{From: "2021-03-01T00:00:00Z", To: "now"},
+				Timezone:     "utc",
+				Theme:        "dark",
+			},
+			want: url.Values{
+				"panelId": {"2"}, "width": {"800"}, "height": {"400"}, "from": {"1614556800000"}, "to": {"now"},
+				"tz": {"utc"}, "theme": {"dark"},
+				"var-cluster": {"a", "b"}, "var-host": {"$__all"}, "var-env": {"$__all"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.target.RenderParams(); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("RenderParams() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTimeRangeV1_Bounds(t *testing.T) {
+
+	//Wednesday
+	now := time.Date(2021, 3, 10, 15, 30, 0, 0, time.UTC)
+
+	tests := []struct {
+		name      string
+		timeRange TimeRangeV1
+		wantFrom  time.Time
+		wantTo    time.Time
+	}{
+		{
+			name:      "Test0 - Default range",
+			timeRange: TimeRangeV1{},
+			wantFrom:  time.Date(2021, 3, 10, 9, 30, 0, 0, time.UTC),
+			wantTo:    now,
+		},
+		{
+			name:      "Test1 - Absolute times",
+			timeRange: TimeRangeV1{From: "2021-03-01T00:00:00Z", To: "1614643200000"},
+			wantFrom:  time.Date(2021, 3, 1, 0, 0, 0, 0, time.UTC),
+			wantTo:    time.Date(2021, 3, 2, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name:      "Test2 - Yesterday",
+			timeRange: TimeRangeV1{From: "now-1d/d", To: "now-1d/d"},
+			wantFrom:  time.Date(2021, 3, 9, 0, 0, 0, 0, time.UTC),
+			wantTo:    time.Date(2021, 3, 9, 23, 59, 59, int(999*time.Millisecond), time.UTC),
+		},
+		{
+			name:      "Test3 - Week so far and multiple offsets",
+			timeRange: TimeRangeV1{From: "now/w", To: "now-1h+30m"},
+			wantFrom:  time.Date(2021, 3, 8, 0, 0, 0, 0, time.UTC),
+			wantTo:    time.Date(2021, 3, 10, 15, 0, 0, 0, time.UTC),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			from, to, err := tt.timeRange.Bounds(now)
+			if err != nil {
+				t.Fatalf("Bounds() unexpected error = %v", err)
+			}
+			if !from.Equal(tt.wantFrom) || !to.Equal(tt.wantTo) {
+				t.Errorf("Bounds() = %v, %v, want %v, %v", from, to, tt.wantFrom, tt.wantTo)
+			}
+		})
+	}
+}
@@ -2,6 +2,7 @@ package health
 
 import (
 	"github.com/gin-gonic/gin"
+	"github.com/sajeevany/graph-snapper/internal/logging"
 	"github.com/sirupsen/logrus"
 	"net/http"
 )
@@ -21,7 +22,7 @@ type Ping struct {
 //@Tags health
 func Hello(logger *logrus.Logger) gin.HandlerFunc {
 	return func(ctx *gin.Context) {
-		logger.Println("Hello from within hello")
+		logging.FromContext(ctx.Request.Context(), logger).Println("Hello from within hello")
 
 		//Set response
 		ctx.JSON(http.StatusOK, Ping{Response: "hello"})
@@ -0,0 +1,142 @@
+package imagediff
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+)
+
+const (
+	//PNGContentType - content type of the images compared pixel by pixel
+	PNGContentType = "image/png"
+
+	//DefaultTolerance - perceived color difference below which a pixel is unchanged. Absorbs anti-aliasing and
+	//compression noise
+	DefaultTolerance = 0.1
+
+	//maxDelta - largest YIQ difference of two colors
+	maxDelta = 35215.0
+	//fade - how much of the current image is kept in unchanged areas of the highlighted image
+	fade = 0.1
+)
+
+var highlight = color.RGBA{R: 255, A: 255}
+
+//Options - How images are compared
+type Options struct {
+	Tolerance *float64 //Perceived color difference from 0 to 1 a pixel must exceed to be changed. Nil uses DefaultTolerance
+	Highlight bool     //Draws the changed pixels over a faded copy of the current image
+}
+
+//Result - Difference of two captures
+type Result struct {
+	Score         float64 //Fraction of pixels that changed. 0 when identical and 1 when the captures can't be compared
+	ChangedPixels int
+	Image         []byte //PNG highlighting the changed pixels. Only set if requested and differing PNGs have the same size
+}
+
+//Compare - Compares two captures of the content type. PNGs are compared pixel by pixel using the perceived difference
+//of their colors. Other content types can only be compared byte for byte and have a score of 0 or 1
+func Compare(previous, current []byte, contentType string, opts Options) (Result, error) {
+
+	if bytes.Equal(previous, current) {
+		return Result{}, nil
+	}
+	if contentType != PNGContentType {
+		return Result{Score: 1}, nil
+	}
+
+	prevImg, err := png.Decode(bytes.NewReader(previous))
+	if err != nil {
+		return Result{}, fmt.Errorf("unable to decode previous image. err <%v>", err)
+	}
+	curImg, err := png.Decode(bytes.NewReader(current))
+	if err != nil {
+		return Result{}, fmt.Errorf("unable to decode current image. err <%v>", err)
+	}
+	return compareImages(prevImg, curImg, opts)
+}
+
+//compareImages - Images of different sizes are entirely changed
+func compareImages(previous, current image.Image, opts Options) (Result, error) {
+
+	pb, cb := previous.Bounds(), current.Bounds()
+	if pb.Dx() != cb.Dx() || pb.Dy() != cb.Dy() {
+		return Result{Score: 1, ChangedPixels: cb.Dx() * cb.Dy()}, nil
+	}
+	total := cb.Dx() * cb.Dy()
+	if total == 0 {
+		return Result{}, nil
+	}
+
+	tolerance := DefaultTolerance
+	if opts.Tolerance != nil {
+		tolerance = *opts.Tolerance
+	}
+	limit := maxDelta * tolerance * tolerance
+
+	var out *image.RGBA
+	if opts.Highlight {
+		out = image.NewRGBA(image.Rect(0, 0, cb.Dx(), cb.Dy()))
+	}
+
+	changed := 0
+	for y := 0; y < cb.Dy(); y++ {
+		for x := 0; x < cb.Dx(); x++ {
+			c := current.At(cb.Min.X+x, cb.Min.Y+y)
+			isChanged := delta(previous.At(pb.Min.X+x, pb.Min.Y+y), c) > limit
+			if isChanged {
+				changed++
+			}
+			if out == nil {
+				continue
+			}
+			if isChanged {
+				out.SetRGBA(x, y, highlight)
+			} else {
+				out.SetRGBA(x, y, faded(c))
+			}
+		}
+	}
+
+	result := Result{Score: float64(changed) / float64(total), ChangedPixels: changed}
+	if out != nil {
+		var buf bytes.Buffer
+		if err := png.Encode(&buf, out); err != nil {
+			return Result{}, fmt.Errorf("unable to encode highlighted image. err <%v>", err)
+		}
+		result.Image = buf.Bytes()
+	}
+	return result, nil
+}
+
+//delta - squared YIQ distance of the colors blended over white. Matches how different the colors are perceived
+func delta(a, b color.Color) float64 {
+	ar, ag, ab := blend(a)
+	br, bg, bb := blend(b)
+
+	y := luma(ar, ag, ab) - luma(br, bg, bb)
+	i := 0.59597799*(ar-br) - 0.27417610*(ag-bg) - 0.32180189*(ab-bb)
+	q := 0.21147017*(ar-br) - 0.52261711*(ag-bg) + 0.31114694*(ab-bb)
+	return 0.5053*y*y + 0.299*i*i + 0.1957*q*q
+}
+
+//blend - returns the 8 bit channels of the color blended over white
+func blend(c color.Color) (float64, float64, float64) {
+	r, g, b, a := c.RGBA()
+	white := float64(0xffff - a)
+	return (float64(r) + white) / 257, (float64(g) + white) / 257, (float64(b) + white) / 257
+}
+
+func luma(r, g, b float64) float64 {
+	return 0.29889531*r + 0.58662247*g + 0.11448223*b
+}
+
+//faded - grayscale of the color mostly washed out to white
+func faded(c color.Color) color.RGBA {
+	r, g, b := blend(c)
+	v := uint8(255 + (luma(r, g, b)-255)*fade)
+	return color.RGBA{R: v, G: v, B: v, A: 255}
+}
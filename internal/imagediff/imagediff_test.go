@@ -0,0 +1,155 @@
+package imagediff
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/png"
+	"testing"
+)
+
+//encode - returns a PNG of the size filled with white and the pixels set to their colors
+func encode(t *testing.T, width, height int, pixels map[image.Point]color.RGBA) []byte {
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for x := 0; x < width; x++ {
+		for y := 0; y < height; y++ {
+			img.SetRGBA(x, y, color.RGBA{R: 255, G: 255, B: 255, A: 255})
+		}
+	}
+	for p, c := range pixels {
+		img.SetRGBA(p.X, p.Y, c)
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func tolerance(v float64) *float64 {
+	return &v
+}
+
+func TestCompare(t *testing.T) {
+
+	blank := encode(t, 10, 10, nil)
+	red := color.RGBA{R: 255, A: 255}
+	nearWhite := color.RGBA{R: 250, G: 250, B: 250, A: 255}
+
+	tests := []struct {
+		name        string
+		previous    []byte
+		current     []byte
+		contentType string
+		opts        Options
+		wantScore   float64
+		wantChanged int
+		wantErr     bool
+	}{
+		{
+			name:        "test0 identical",
+			previous:    blank,
+			current:     encode(t, 10, 10, nil),
+			contentType: PNGContentType,
+		},
+		{
+			name:        "test1 changed pixels",
+			previous:    blank,
+			current:     encode(t, 10, 10, map[image.Point]color.RGBA{{X: 1, Y: 1}: red, {X: 2, Y: 1}: red}),
+			contentType: PNGContentType,
+			wantScore:   0.02,
+			wantChanged: 2,
+		},
+		{
+			name:        "test2 difference within tolerance",
+			previous:    blank,
+			current:     encode(t, 10, 10, map[image.Point]color.RGBA{{X: 1, Y: 1}: nearWhite}),
+			contentType: PNGContentType,
+		},
+		{
+			name:        "test3 difference above a lower tolerance",
+			previous:    blank,
+			current:     encode(t, 10, 10, map[image.Point]color.RGBA{{X: 1, Y: 1}: nearWhite}),
+			contentType: PNGContentType,
+			opts:        Options{Tolerance: tolerance(0.01)},
+			wantScore:   0.01,
+			wantChanged: 1,
+		},
+		{
+			name:        "test3a zero tolerance counts any difference",
+			previous:    blank,
+			current:     encode(t, 10, 10, map[image.Point]color.RGBA{{X: 1, Y: 1}: {R: 254, G: 255, B: 255, A: 255}}),
+			contentType: PNGContentType,
+			opts:        Options{Tolerance: tolerance(0)},
+			wantScore:   0.01,
+			wantChanged: 1,
+		},
+		{
+			name:        "test4 different sizes",
+			previous:    blank,
+			current:     encode(t, 5, 10, nil),
+			contentType: PNGContentType,
+			wantScore:   1,
+			wantChanged: 50,
+		},
+		{
+			name:        "test5 identical svg",
+			previous:    []byte("<svg></svg>"),
+			current:     []byte("<svg></svg>"),
+			contentType: "image/svg+xml",
+		},
+		{
+			name:        "test6 different svg",
+			previous:    []byte("<svg></svg>"),
+			current:     []byte("<svg><line/></svg>"),
+			contentType: "image/svg+xml",
+			wantScore:   1,
+		},
+		{
+			name:        "test7 invalid png",
+			previous:    []byte("image"),
+			current:     blank,
+			contentType: PNGContentType,
+			wantErr:     true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Compare(tt.previous, tt.current, tt.contentType, tt.opts)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Compare() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got.Score != tt.wantScore || got.ChangedPixels != tt.wantChanged {
+				t.Errorf("Compare() = %v with %v changed pixels, want %v with %v", got.Score, got.ChangedPixels, tt.wantScore, tt.wantChanged)
+			}
+		})
+	}
+}
+
+func TestCompare_Highlight(t *testing.T) {
+
+	black := color.RGBA{A: 255}
+	previous := encode(t, 4, 4, map[image.Point]color.RGBA{{X: 0, Y: 0}: black})
+	current := encode(t, 4, 4, map[image.Point]color.RGBA{{X: 0, Y: 0}: black, {X: 3, Y: 3}: black})
+
+	got, err := Compare(previous, current, PNGContentType, Options{Highlight: true})
+	if err != nil {
+		t.Fatalf("Compare() unexpected error = %v", err)
+	}
+	img, err := png.Decode(bytes.NewReader(got.Image))
+	if err != nil {
+		t.Fatalf("Compare() returned an invalid png. <%v>", err)
+	}
+
+	if c := color.RGBAModel.Convert(img.At(3, 3)); c != highlight {
+		t.Errorf("changed pixel = %v, want %v", c, highlight)
+	}
+	//Unchanged pixels are faded so that the highlight stands out
+	if c := color.RGBAModel.Convert(img.At(0, 0)).(color.RGBA); c.R != c.G || c.R < 200 {
+		t.Errorf("unchanged black pixel = %v, want light gray", c)
+	}
+
+	if got, _ := Compare(previous, current, PNGContentType, Options{}); got.Image != nil {
+		t.Errorf("Compare() returned an image without highlight")
+	}
+}
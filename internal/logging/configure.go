@@ -0,0 +1,67 @@
+package logging
+
+import (
+	"fmt"
+	"github.com/sajeevany/graph-snapper/internal/config"
+	"github.com/sirupsen/logrus"
+	"gopkg.in/natefinch/lumberjack.v2"
+	"io"
+	"os"
+	"strings"
+)
+
+//CloseFunc - closes the log file
+type CloseFunc func() error
+
+//Configure - Applies the configured level, format and outputs to the logger. Logs are always written to stdout and
+//additionally to a size rotated file when file output is enabled. The returned function closes the file on shutdown
+func Configure(logger *logrus.Logger, conf config.Logging) (CloseFunc, error) {
+
+	level, err := logrus.ParseLevel(conf.Level)
+	if err != nil {
+		return nil, fmt.Errorf("invalid logging level <%v>. err <%v>", conf.Level, err)
+	}
+
+	formatter, err := newFormatter(conf.Format)
+	if err != nil {
+		return nil, err
+	}
+
+	output, closeFile := newOutput(conf.File)
+	logger.SetLevel(level)
+	logger.SetFormatter(formatter)
+	logger.SetOutput(output)
+
+	return func() error {
+		//Later log messages go to stdout only so that they don't reopen the file
+		logger.SetOutput(os.Stdout)
+		return closeFile()
+	}, nil
+}
+
+func newFormatter(format string) (logrus.Formatter, error) {
+	switch strings.ToLower(format) {
+	case "", config.TextLogFormat:
+		return &logrus.TextFormatter{FullTimestamp: true}, nil
+	case config.JSONLogFormat:
+		return &logrus.JSONFormatter{}, nil
+	default:
+		return nil, fmt.Errorf("unsupported logging format <%v>", format)
+	}
+}
+
+func newOutput(file config.LogFile) (io.Writer, CloseFunc) {
+	if !file.Enabled {
+		return os.Stdout, func() error { return nil }
+	}
+
+	//lumberjack creates the file and parent directories on first write and rotates once MaxSizeMB is reached
+	rotator := &lumberjack.Logger{
+		Filename:   file.Path,
+		MaxSize:    file.MaxSizeMB,
+		MaxBackups: file.MaxBackups,
+		MaxAge:     file.MaxAgeDays,
+		Compress:   file.Compress,
+	}
+	return io.MultiWriter(os.Stdout, rotator), rotator.Close
+}
@@ -0,0 +1,103 @@
+package logging
+
+import (
+	"github.com/sajeevany/graph-snapper/internal/config"
+	"github.com/sirupsen/logrus"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestConfigure(t *testing.T) {
+
+	tmpDir, err := ioutil.TempDir("", "graph-snapper-logs")
+	if err != nil {
+		t.Fatalf("SETUP FAILURE: unable to create temp dir. err <%v>", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	tests := []struct {
+		name          string
+		conf          config.Logging
+		wantErr       bool
+		wantLevel     logrus.Level
+		wantFormatter logrus.Formatter
+		wantFileLine  string
+	}{
+		{
+			name:          "test0 text format at warn level",
+			conf:          config.Logging{Level: "warn", Format: config.TextLogFormat},
+			wantLevel:     logrus.WarnLevel,
+			wantFormatter: &logrus.TextFormatter{},
+		},
+		{
+			name: "test1 json format at info level with file output",
+			conf: config.Logging{
+				Level:  "info",
+				Format: config.JSONLogFormat,
+				File: config.LogFile{
+					Enabled:   true,
+					Path:      filepath.Join(tmpDir, "nested", "graph-snapper.log"),
+					MaxSizeMB: 1,
+				},
+			},
+			wantLevel:     logrus.InfoLevel,
+			wantFormatter: &logrus.JSONFormatter{},
+			wantFileLine:  `"msg":"written to file"`,
+		},
+		{
+			name:    "test2 invalid level",
+			conf:    config.Logging{Level: "loud"},
+			wantErr: true,
+		},
+		{
+			name:    "test3 invalid format",
+			conf:    config.Logging{Level: "info", Format: "xml"},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			logger := logrus.New()
+			closeLogs, err := Configure(logger, tt.conf)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Configure() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			defer func() {
+				if cErr := closeLogs(); cErr != nil {
+					t.Errorf("close error = %v", cErr)
+				}
+			}()
+
+			if logger.GetLevel() != tt.wantLevel {
+				t.Errorf("Level = <%v>, want <%v>", logger.GetLevel(), tt.wantLevel)
+			}
+			switch tt.wantFormatter.(type) {
+			case *logrus.JSONFormatter:
+				if _, ok := logger.Formatter.(*logrus.JSONFormatter); !ok {
+					t.Errorf("Formatter = <%T>, want <%T>", logger.Formatter, tt.wantFormatter)
+				}
+			case *logrus.TextFormatter:
+				if _, ok := logger.Formatter.(*logrus.TextFormatter); !ok {
+					t.Errorf("Formatter = <%T>, want <%T>", logger.Formatter, tt.wantFormatter)
+				}
+			}
+
+			if tt.wantFileLine != "" {
+				logger.Info("written to file")
+				data, rErr := ioutil.ReadFile(tt.conf.File.Path)
+				if rErr != nil {
+					t.Fatalf("Unable to read log file <%v>. err <%v>", tt.conf.File.Path, rErr)
+				}
+				if !strings.Contains(string(data), tt.wantFileLine) {
+					t.Errorf("Log file contents <%s> do not contain <%v>", data, tt.wantFileLine)
+				}
+			}
+		})
+	}
+}
@@ -1,21 +1,50 @@
 package logging
 
 import (
+	"context"
+	"github.com/sajeevany/graph-snapper/internal/redact"
 	"github.com/sirupsen/logrus"
 )
 
 const LoggerKey = "logger"
 
+const (
+	//RequestIDKey - gin context key and log field holding the request ID
+	RequestIDKey = "requestID"
+	//RequestIDHeader - header used to receive and return the request ID
+	RequestIDHeader = "X-Request-ID"
+	//AccountIDKey - log field holding the account ID of the request
+	AccountIDKey = "accountID"
+	//RouteKey - log field holding the matched route template
+	RouteKey = "route"
+)
+
+type loggerCtxKey struct{}
+
+//Init - Returns a logger that masks secrets in every entry
 func Init() *logrus.Logger {
-	return logrus.New()
+	logger := logrus.New()
+	logger.AddHook(redact.NewHook())
+	return logger
 }
 
-//Returns values as a redacted string if non empty
-func RedactNonEmpty(val string) string {
+//NewContext - Returns a copy of ctx carrying the request scoped logger
+func NewContext(ctx context.Context, logger *logrus.Entry) context.Context {
+	return context.WithValue(ctx, loggerCtxKey{}, logger)
+}
 
-	if val != "" {
-		return "*****"
+//FromContext - Returns the request scoped logger stored in ctx. Falls back to an entry of the provided logger when
+//ctx does not have one, ie. when called outside of a request
+func FromContext(ctx context.Context, fallback *logrus.Logger) *logrus.Entry {
+	if ctx != nil {
+		if entry, ok := ctx.Value(loggerCtxKey{}).(*logrus.Entry); ok && entry != nil {
+			return entry
+		}
 	}
+	return logrus.NewEntry(fallback)
+}
 
-	return val
+//Returns values as a redacted string if non empty
+func RedactNonEmpty(val string) string {
+	return redact.NonEmpty(val)
 }
@@ -3,6 +3,7 @@ package middleware
 import (
 	"fmt"
 	"github.com/gin-gonic/gin"
+	"github.com/sajeevany/graph-snapper/internal/logging"
 	"github.com/sirupsen/logrus"
 	"time"
 )
@@ -27,7 +28,7 @@ func LogRequest(logger *logrus.Logger) gin.HandlerFunc {
 		timeFormatted := end.Format("2006-01-02 15:04:05")
 
 		msg := fmt.Sprintf("[%v] %v (%v) %v %v %v", timeFormatted, method, path, statusCode, duration, raw)
-		logger.WithFields(logrus.Fields{
+		logging.FromContext(c.Request.Context(), logger).WithFields(logrus.Fields{
 			"endTime":  timeFormatted,
 			"method":   method,
 			"path":     path,
@@ -0,0 +1,39 @@
+package middleware
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/sajeevany/graph-snapper/internal/logging"
+	"unicode"
+)
+
+//maxRequestIDLen - incoming request IDs longer than this are replaced to keep log lines bounded
+const maxRequestIDLen = 128
+
+//SetRequestID - Sets the request ID defined by RequestIDKey. Honors the caller's X-Request-ID header when it's usable,
+//otherwise generates a new ID. The ID is returned to the caller in the X-Request-ID response header.
+func SetRequestID() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		requestID := c.GetHeader(logging.RequestIDHeader)
+		if !isValidRequestID(requestID) {
+			requestID = uuid.New().String()
+		}
+
+		c.Set(logging.RequestIDKey, requestID)
+		c.Header(logging.RequestIDHeader, requestID)
+		c.Next()
+	}
+}
+
+//isValidRequestID - returns true if the ID is non-empty, bounded and only contains printable ascii characters
+func isValidRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLen {
+		return false
+	}
+	for _, r := range id {
+		if r > unicode.MaxASCII || !unicode.IsPrint(r) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,105 @@
+package middleware
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/sajeevany/graph-snapper/internal/logging"
+	"github.com/sirupsen/logrus"
+	"github.com/sirupsen/logrus/hooks/test"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestSetRequestIDAndCtxLogger(t *testing.T) {
+
+	tests := []struct {
+		name           string
+		incomingID     string
+		wantIncomingID bool
+		path           string
+		wantAccountID  string
+		wantRoute      string
+	}{
+		{
+			name:           "test0 incoming request id is honored",
+			incomingID:     "abc-123",
+			wantIncomingID: true,
+			path:           "/api/v1/account/act0",
+			wantAccountID:  "act0",
+			wantRoute:      "/api/v1/account/:id",
+		},
+		{
+			name:           "test1 missing request id is generated",
+			incomingID:     "",
+			wantIncomingID: false,
+			path:           "/api/v1/account/act1",
+			wantAccountID:  "act1",
+			wantRoute:      "/api/v1/account/:id",
+		},
+		{
+			name:           "test2 oversized request id is replaced",
+			incomingID:     strings.Repeat("a", maxRequestIDLen+1),
+			wantIncomingID: false,
+			path:           "/api/v1/account/act2",
+			wantAccountID:  "act2",
+			wantRoute:      "/api/v1/account/:id",
+		},
+		{
+			name:           "test3 request id with control characters is replaced",
+			incomingID:     "abc\n123",
+			wantIncomingID: false,
+			path:           "/api/v1/account/act3",
+			wantAccountID:  "act3",
+			wantRoute:      "/api/v1/account/:id",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			logger, hook := test.NewNullLogger()
+
+			gin.SetMode(gin.TestMode)
+			w := httptest.NewRecorder()
+			_, r := gin.CreateTestContext(w)
+			r.Use(SetRequestID())
+			r.Use(SetCtxLogger(logger))
+			r.GET("/api/v1/account/:id", func(ctx *gin.Context) {
+				logging.FromContext(ctx.Request.Context(), logrus.New()).Info("handled")
+				ctx.Status(http.StatusOK)
+			})
+
+			req, _ := http.NewRequest(http.MethodGet, tt.path, nil)
+			if tt.incomingID != "" {
+				req.Header.Set(logging.RequestIDHeader, tt.incomingID)
+			}
+			r.ServeHTTP(w, req)
+
+			//Validate returned request id
+			returnedID := w.Header().Get(logging.RequestIDHeader)
+			if returnedID == "" {
+				t.Fatalf("Expected %v response header to be set", logging.RequestIDHeader)
+			}
+			if tt.wantIncomingID && returnedID != tt.incomingID {
+				t.Errorf("Request ID = <%v>, want <%v>", returnedID, tt.incomingID)
+			}
+			if !tt.wantIncomingID && returnedID == tt.incomingID {
+				t.Errorf("Request ID <%v> should have been replaced", returnedID)
+			}
+
+			//Validate that the handler logged with the request scoped fields
+			entry := hook.LastEntry()
+			if entry == nil {
+				t.Fatalf("Expected handler to log an entry")
+			}
+			if entry.Data[logging.RequestIDKey] != returnedID {
+				t.Errorf("Logged request ID = <%v>, want <%v>", entry.Data[logging.RequestIDKey], returnedID)
+			}
+			if entry.Data[logging.AccountIDKey] != tt.wantAccountID {
+				t.Errorf("Logged account ID = <%v>, want <%v>", entry.Data[logging.AccountIDKey], tt.wantAccountID)
+			}
+			if entry.Data[logging.RouteKey] != tt.wantRoute {
+				t.Errorf("Logged route = <%v>, want <%v>", entry.Data[logging.RouteKey], tt.wantRoute)
+			}
+		})
+	}
+}
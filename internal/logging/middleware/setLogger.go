@@ -4,12 +4,28 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/sajeevany/graph-snapper/internal/logging"
 	"github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel/trace"
 )
 
-//SetCtxLogger - Sets a logger access defined by LoggerKey
+//SetCtxLogger - Sets a request scoped logger access defined by LoggerKey. The logger carries the request ID, account
+//ID and route so that log lines from concurrent requests can be correlated. The logger is also stored in the request
+//context so that it can be retrieved with logging.FromContext.
 func SetCtxLogger(logger *logrus.Logger) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		c.Set(logging.LoggerKey, logger)
+		fields := logrus.Fields{
+			logging.RequestIDKey: c.GetString(logging.RequestIDKey),
+			logging.RouteKey:     c.FullPath(),
+		}
+		if accountID := c.Param("id"); accountID != "" {
+			fields[logging.AccountIDKey] = accountID
+		}
+		if sc := trace.SpanContextFromContext(c.Request.Context()); sc.IsValid() {
+			fields["traceID"] = sc.TraceID().String()
+		}
+
+		entry := logger.WithFields(fields)
+		c.Set(logging.LoggerKey, entry)
+		c.Request = c.Request.WithContext(logging.NewContext(c.Request.Context(), entry))
 		c.Next()
 	}
 }
@@ -0,0 +1,42 @@
+package plugin
+
+import (
+	"github.com/sirupsen/logrus"
+)
+
+//Plugin - Source or destination implementation registered by type name
+type Plugin interface {
+	//Type - unique name the plugin is registered and its credentials are stored under. ie grafana or s3
+	Type() string
+	//NewCredential - returns a pointer to an empty credential which request bodies and stored bin maps are decoded into
+	NewCredential() Credential
+}
+
+//Credential - Settings and secrets of a source or destination. Values are stored in aerospike as the bin map of their
+//fields
+type Credential interface {
+	//GetFields - returns loggable fields without secrets
+	GetFields() logrus.Fields
+	//ToBinMap - returns the unredacted bin map stored in aerospike. Keys are the field names
+	ToBinMap() map[string]interface{}
+	//RedactedView - returns a copy without secrets which is returned by the api
+	RedactedView() interface{}
+}
+
+//CheckResultV1 - Result of a credential check
+type CheckResultV1 struct {
+	Result    bool
+	Cause     string      `json:"Cause,omitempty"`
+	LatencyMS int64       //Time taken by the check in milliseconds
+	Details   interface{} `json:"Details,omitempty"` //Source or destination specific details. ie confluence space permissions
+}
+
+//Failed - returns a failed check result with the cause
+func Failed(cause string) CheckResultV1 {
+	return CheckResultV1{Result: false, Cause: cause}
+}
+
+//Passed - returns a passing check result with optional details
+func Passed(details interface{}) CheckResultV1 {
+	return CheckResultV1{Result: true, Details: details}
+}
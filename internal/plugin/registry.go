@@ -0,0 +1,118 @@
+package plugin
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/mitchellh/mapstructure"
+	"github.com/sajeevany/graph-snapper/internal/redact"
+	"reflect"
+	"sort"
+	"sync"
+)
+
+//Registry - Plugins of one kind keyed by type name. Safe for concurrent use
+type Registry struct {
+	kind string //ie source or destination. Used in errors and panics
+
+	mu      sync.RWMutex
+	plugins map[string]Plugin
+}
+
+//NewRegistry - returns an empty registry of the kind of plugin
+func NewRegistry(kind string) *Registry {
+	return &Registry{kind: kind, plugins: make(map[string]Plugin)}
+}
+
+//Register - makes the plugin available by its type name. Panics if the type is empty or already registered
+func (r *Registry) Register(p Plugin) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if p == nil {
+		panic(fmt.Sprintf("%v: Register %v is nil", r.kind, r.kind))
+	}
+	typeName := p.Type()
+	if typeName == "" {
+		panic(fmt.Sprintf("%v: Register %v type is empty", r.kind, r.kind))
+	}
+	if _, exists := r.plugins[typeName]; exists {
+		panic(fmt.Sprintf("%v: Register called twice for type <%v>", r.kind, typeName))
+	}
+	r.plugins[typeName] = p
+}
+
+//Get - returns the plugin registered with the type name
+func (r *Registry) Get(typeName string) (Plugin, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	p, exists := r.plugins[typeName]
+	return p, exists
+}
+
+//Types - returns the registered type names in sorted order
+func (r *Registry) Types() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	types := make([]string, 0, len(r.plugins))
+	for typeName := range r.plugins {
+		types = append(types, typeName)
+	}
+	sort.Strings(types)
+	return types
+}
+
+//Lookup - returns the plugin registered with the type name or an error listing the registered types
+func (r *Registry) Lookup(typeName string) (Plugin, error) {
+	p, exists := r.Get(typeName)
+	if !exists {
+		return nil, fmt.Errorf("unknown %v type <%v>. Expected one of %v", r.kind, typeName, r.Types())
+	}
+	return p, nil
+}
+
+//DecodeJSON - decodes a request body credential of the type
+func (r *Registry) DecodeJSON(typeName string, data []byte) (Credential, error) {
+	return r.decodeCredential(typeName, func(target interface{}) error {
+		return json.Unmarshal(data, target)
+	})
+}
+
+//DecodeBinMap - decodes a stored credential of the type. bm is the bin map returned by aerospike. Errors only name the
+//invalid fields so that stored secrets aren't logged
+func (r *Registry) DecodeBinMap(typeName string, bm interface{}) (Credential, error) {
+	return r.decodeCredential(typeName, func(target interface{}) error {
+		return redact.DecodeError(mapstructure.Decode(bm, target))
+	})
+}
+
+func (r *Registry) decodeCredential(typeName string, decodeInto func(target interface{}) error) (Credential, error) {
+	p, err := r.Lookup(typeName)
+	if err != nil {
+		return nil, err
+	}
+	val, err := r.Decode(typeName, p.NewCredential(), decodeInto)
+	if err != nil {
+		return nil, err
+	}
+	cred, ok := val.(Credential)
+	if !ok {
+		return nil, fmt.Errorf("%v type <%v> credential <%T> must implement Credential with value receivers", r.kind, typeName, val)
+	}
+	return cred, nil
+}
+
+//Decode - decodes into ptr and returns the value it points to. Used for values other than credentials which plugins
+//return pointers to. ie source targets
+func (r *Registry) Decode(typeName string, ptr interface{}, decodeInto func(target interface{}) error) (interface{}, error) {
+
+	val := reflect.ValueOf(ptr)
+	if val.Kind() != reflect.Ptr || val.IsNil() {
+		return nil, fmt.Errorf("%v type <%v> returned <%T> which isn't a pointer", r.kind, typeName, ptr)
+	}
+	if err := decodeInto(ptr); err != nil {
+		return nil, fmt.Errorf("unable to decode <%v> value. err <%v>", typeName, err)
+	}
+	return val.Elem().Interface(), nil
+}
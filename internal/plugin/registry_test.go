@@ -0,0 +1,146 @@
+package plugin
+
+import (
+	"github.com/sirupsen/logrus"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type fakeCredential struct {
+	URL   string
+	Port  int
+	Token string
+}
+
+func (c fakeCredential) GetFields() logrus.Fields {
+	return logrus.Fields{"URL": c.URL, "Port": c.Port}
+}
+
+func (c fakeCredential) ToBinMap() map[string]interface{} {
+	return map[string]interface{}{"URL": c.URL, "Port": c.Port, "Token": c.Token}
+}
+
+func (c fakeCredential) RedactedView() interface{} {
+	c.Token = ""
+	return c
+}
+
+type fakePlugin struct {
+	typeName string
+}
+
+func (p fakePlugin) Type() string {
+	return p.typeName
+}
+
+func (p fakePlugin) NewCredential() Credential {
+	return &fakeCredential{}
+}
+
+func TestRegistry_Register(t *testing.T) {
+
+	registry := NewRegistry("fake")
+	tests := []struct {
+		name      string
+		plugin    Plugin
+		wantPanic bool
+	}{
+		{
+			name:   "test0 new type",
+			plugin: fakePlugin{typeName: "b"},
+		},
+		{
+			name:   "test1 second type",
+			plugin: fakePlugin{typeName: "a"},
+		},
+		{
+			name:      "test2 duplicate type",
+			plugin:    fakePlugin{typeName: "b"},
+			wantPanic: true,
+		},
+		{
+			name:      "test3 empty type",
+			plugin:    fakePlugin{},
+			wantPanic: true,
+		},
+		{
+			name:      "test4 nil plugin",
+			wantPanic: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			defer func() {
+				if r := recover(); (r != nil) != tt.wantPanic {
+					t.Errorf("Register() panic = %v, wantPanic %v", r, tt.wantPanic)
+				}
+			}()
+			registry.Register(tt.plugin)
+		})
+	}
+
+	if got := registry.Types(); !reflect.DeepEqual(got, []string{"a", "b"}) {
+		t.Errorf("Types() = %v, want [a b]", got)
+	}
+	if _, err := registry.Lookup("c"); err == nil || !strings.Contains(err.Error(), "unknown fake type <c>") {
+		t.Errorf("Lookup() of an unregistered type error = %v", err)
+	}
+}
+
+func TestRegistry_Decode(t *testing.T) {
+
+	registry := NewRegistry("fake")
+	registry.Register(fakePlugin{typeName: "fake"})
+	cred := fakeCredential{URL: "http://prometheus:9090", Port: 9090, Token: "secret"}
+
+	tests := []struct {
+		name    string
+		decode  func() (Credential, error)
+		want    Credential
+		wantErr bool
+	}{
+		{
+			name: "test0 request body credential",
+			decode: func() (Credential, error) {
+				return registry.DecodeJSON("fake", []byte(`{"URL":"http://prometheus:9090","Port":9090,"Token":"secret"}`))
+			},
+			want: cred,
+		},
+		{
+			name: "test1 stored bin map",
+			decode: func() (Credential, error) {
+				return registry.DecodeBinMap("fake", cred.ToBinMap())
+			},
+			want: cred,
+		},
+		{
+			name: "test2 invalid stored bin map",
+			decode: func() (Credential, error) {
+				return registry.DecodeBinMap("fake", map[string]interface{}{"Port": "secret"})
+			},
+			wantErr: true,
+		},
+		{
+			name: "test3 unknown type",
+			decode: func() (Credential, error) {
+				return registry.DecodeJSON("unknown", []byte(`{}`))
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.decode()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("decode() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil && strings.Contains(err.Error(), "secret") {
+				t.Errorf("decode() error <%v> contains the stored value", err)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("decode() got = %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
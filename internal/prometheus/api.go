@@ -0,0 +1,205 @@
+package prometheus
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/sajeevany/graph-snapper/internal/common"
+	"github.com/sajeevany/graph-snapper/internal/upstream"
+	"github.com/sirupsen/logrus"
+	"math"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+const (
+	buildInfoURL   = "/api/v1/status/buildinfo"
+	metricNamesURL = "/api/v1/label/__name__/values"
+	queryRangeURL  = "/api/v1/query_range"
+
+	//ServiceName - name used for prometheus in upstream errors and spans
+	ServiceName = "prometheus"
+
+	successStatus   = "success"
+	badDataType     = "bad_data"
+	matrixValueType = "matrix"
+)
+
+//APIError - prometheus rejected the request or couldn't execute the query
+type APIError struct {
+	Path       string
+	StatusCode int
+	ErrorType  string //ie bad_data, execution or timeout
+	Message    string
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("%v returned status code <%v>. %v: %v", e.Path, e.StatusCode, e.ErrorType, e.Message)
+}
+
+//IsBadData - returns true if prometheus rejected the request parameters. ie the query couldn't be parsed
+func (e *APIError) IsBadData() bool {
+	return e.ErrorType == badDataType
+}
+
+//BuildInfoV1 - Version of the prometheus server
+type BuildInfoV1 struct {
+	Version   string
+	Revision  string
+	Branch    string
+	GoVersion string
+}
+
+//SeriesV1 - Labels and values of a range query series
+type SeriesV1 struct {
+	Metric map[string]string
+	Values []SampleV1
+}
+
+//SampleV1 - Value of a series at a time
+type SampleV1 struct {
+	Time  time.Time
+	Value float64
+}
+
+//apiResp - envelope of every prometheus api response
+type apiResp struct {
+	Status    string
+	Data      json.RawMessage
+	ErrorType string
+	Error     string
+}
+
+type matrixResp struct {
+	ResultType string
+	Result     []struct {
+		Metric map[string]string
+		Values [][2]interface{} //Unix time in seconds and the value as a string
+	}
+}
+
+//GetBuildInfo - Returns the version of the prometheus server. Used to check that the user can query the api
+func GetBuildInfo(ctx context.Context, logger *logrus.Entry, client *upstream.Client, user common.PrometheusUserV1) (BuildInfoV1, error) {
+
+	var info BuildInfoV1
+	if err := getJSON(ctx, logger, client, user, buildInfoURL, nil, "prometheus.GetBuildInfo", &info); err != nil {
+		logger.Debugf("prometheus build info lookup failed. <%v>", err)
+		return BuildInfoV1{}, err
+	}
+	return info, nil
+}
+
+//ListMetricNames - Returns the sorted names of every metric stored by prometheus
+func ListMetricNames(ctx context.Context, logger *logrus.Entry, client *upstream.Client, user common.PrometheusUserV1) ([]string, error) {
+
+	names := []string{}
+	if err := getJSON(ctx, logger, client, user, metricNamesURL, nil, "prometheus.ListMetricNames", &names); err != nil {
+		logger.Debugf("prometheus metric name lookup failed. <%v>", err)
+		return nil, err
+	}
+	return names, nil
+}
+
+//QueryRange - Evaluates the query at each step between start and end. Only range vector results are supported
+func QueryRange(ctx context.Context, logger *logrus.Entry, client *upstream.Client, user common.PrometheusUserV1, query string, start, end time.Time, step time.Duration) ([]SeriesV1, error) {
+
+	params := url.Values{}
+	params.Set("query", query)
+	params.Set("start", formatTime(start))
+	params.Set("end", formatTime(end))
+	params.Set("step", strconv.FormatFloat(step.Seconds(), 'f', -1, 64))
+
+	var matrix matrixResp
+	if err := getJSON(ctx, logger, client, user, queryRangeURL, params, "prometheus.QueryRange", &matrix); err != nil {
+		logger.Debugf("prometheus range query failed. <%v>", err)
+		return nil, err
+	}
+	if matrix.ResultType != matrixValueType {
+		return nil, fmt.Errorf("range query returned result type <%v>. Expected %v", matrix.ResultType, matrixValueType)
+	}
+
+	series := make([]SeriesV1, len(matrix.Result))
+	for i, r := range matrix.Result {
+		series[i] = SeriesV1{Metric: r.Metric, Values: make([]SampleV1, len(r.Values))}
+		for j, v := range r.Values {
+			sample, err := toSample(v)
+			if err != nil {
+				return nil, err
+			}
+			series[i].Values[j] = sample
+		}
+	}
+	return series, nil
+}
+
+//Endpoint - returns the upstream connection settings of the prometheus user
+func Endpoint(user common.PrometheusUserV1) upstream.Endpoint {
+	return upstream.Endpoint{
+		Service: ServiceName,
+		Host:    user.Host,
+		Port:    user.Port,
+		TLS:     user.TLS,
+		Proxy:   user.Proxy,
+	}
+}
+
+//getJSON - decodes the data of a successful api response into out. Error responses are returned as *APIError
+func getJSON(ctx context.Context, logger *logrus.Entry, client *upstream.Client, user common.PrometheusUserV1, path string, params url.Values, spanName string, out interface{}) error {
+
+	reqURL := user.BaseURL() + path
+	if len(params) != 0 {
+		reqURL += "?" + params.Encode()
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, reqURL, nil)
+	if err != nil {
+		return err
+	}
+	if aErr := client.SetAuthHeader(logger, Endpoint(user), user.Auth, req); aErr != nil {
+		return aErr
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := client.Do(ctx, logger, Endpoint(user), spanName, req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	var body apiResp
+	if dErr := json.NewDecoder(resp.Body).Decode(&body); dErr != nil {
+		if resp.StatusCode != http.StatusOK {
+			return &APIError{Path: path, StatusCode: resp.StatusCode}
+		}
+		return dErr
+	}
+	if resp.StatusCode != http.StatusOK || body.Status != successStatus {
+		return &APIError{Path: path, StatusCode: resp.StatusCode, ErrorType: body.ErrorType, Message: body.Error}
+	}
+
+	return json.Unmarshal(body.Data, out)
+}
+
+func toSample(v [2]interface{}) (SampleV1, error) {
+	ts, ok := v[0].(float64)
+	if !ok {
+		return SampleV1{}, fmt.Errorf("sample time <%v> isn't a number", v[0])
+	}
+	raw, ok := v[1].(string)
+	if !ok {
+		return SampleV1{}, fmt.Errorf("sample value <%v> isn't a string", v[1])
+	}
+	//Prometheus encodes NaN and infinities as strings which ParseFloat accepts
+	val, err := strconv.ParseFloat(raw, 64)
+	if err != nil {
+		return SampleV1{}, fmt.Errorf("sample value <%v> isn't a number. <%v>", raw, err)
+	}
+	ms := int64(math.Round(ts * 1000))
+	return SampleV1{Time: time.Unix(0, ms*int64(time.Millisecond)).UTC(), Value: val}, nil
+}
+
+//formatTime - returns the unix time in seconds with millisecond precision
+func formatTime(t time.Time) string {
+	return strconv.FormatFloat(float64(t.UnixNano()/int64(time.Millisecond))/1000, 'f', -1, 64)
+}
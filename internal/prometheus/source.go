@@ -0,0 +1,193 @@
+package prometheus
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/sajeevany/graph-snapper/internal/chart"
+	"github.com/sajeevany/graph-snapper/internal/common"
+	"github.com/sajeevany/graph-snapper/internal/plugin"
+	"github.com/sajeevany/graph-snapper/internal/source"
+	"github.com/sajeevany/graph-snapper/internal/upstream"
+	"github.com/sirupsen/logrus"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+//SourceType - source type of prometheus servers
+const SourceType = "prometheus"
+
+//nameLabel - label holding the metric name
+const nameLabel = "__name__"
+
+//Source - Renders range queries to line charts without a browser
+type Source struct {
+	client *upstream.Client
+	now    func() time.Time
+}
+
+//NewSource - returns the prometheus source using the client for all calls
+func NewSource(client *upstream.Client) *Source {
+	return &Source{client: client, now: time.Now}
+}
+
+func (s *Source) Type() string {
+	return SourceType
+}
+
+func (s *Source) NewCredential() source.Credential {
+	return &common.PrometheusUserV1{}
+}
+
+func (s *Source) Validate(cred source.Credential) error {
+	user, err := prometheusUser(cred)
+	if err != nil {
+		return err
+	}
+	if !user.IsValid() {
+		return fmt.Errorf("prometheus user <%#v> is invalid", user.GetRedactedView())
+	}
+	return nil
+}
+
+//CheckAccess - Checks that the api can be queried. The server version is returned as the result details
+func (s *Source) CheckAccess(ctx context.Context, logger *logrus.Entry, cred source.Credential) plugin.CheckResultV1 {
+
+	user, err := prometheusUser(cred)
+	if err != nil {
+		return plugin.Failed(err.Error())
+	}
+
+	info, err := GetBuildInfo(ctx, logger, s.client, user)
+	if err != nil {
+		logger.WithFields(user.GetFields()).Debugf("prometheus access check failed. <%v>", err)
+		if upstream.IsAuthError(err) {
+			return plugin.Failed("Unauthorized. Check the auth of the prometheus user or its proxy")
+		}
+		return plugin.Failed(err.Error())
+	}
+	return plugin.Passed(&info)
+}
+
+//ListDashboards - Prometheus doesn't have dashboards so the metric names containing query are listed instead. Each
+//name can be used as the query of a target
+func (s *Source) ListDashboards(ctx context.Context, logger *logrus.Entry, cred source.Credential, query string) ([]source.DashboardV1, error) {
+
+	user, err := prometheusUser(cred)
+	if err != nil {
+		return nil, err
+	}
+
+	names, err := ListMetricNames(ctx, logger, s.client, user)
+	if err != nil {
+		return nil, err
+	}
+
+	query = strings.ToLower(query)
+	dashboards := []source.DashboardV1{}
+	for _, name := range names {
+		if strings.Contains(strings.ToLower(name), query) {
+			dashboards = append(dashboards, source.DashboardV1{UID: name, Title: name})
+		}
+	}
+	return dashboards, nil
+}
+
+func (s *Source) NewTarget() source.Target {
+	return &TargetV1{}
+}
+
+//Render - Runs the range query and draws each returned series as a line. Queries prometheus rejects, and results which
+//can't be drawn at the target size, are returned as target errors
+func (s *Source) Render(ctx context.Context, logger *logrus.Entry, cred source.Credential, t source.Target) (source.RenderV1, error) {
+
+	user, err := prometheusUser(cred)
+	if err != nil {
+		return source.RenderV1{}, err
+	}
+	target, ok := t.(TargetV1)
+	if !ok {
+		return source.RenderV1{}, fmt.Errorf("target <%T> isn't a prometheus target", t)
+	}
+
+	from, to, err := target.TimeRange.Bounds(s.now())
+	if err != nil {
+		return source.RenderV1{}, &source.TargetError{Err: err}
+	}
+
+	result, err := QueryRange(ctx, logger, s.client, user, target.Query, from, to, target.step(from, to))
+	if err != nil {
+		var aErr *APIError
+		if errors.As(err, &aErr) && (aErr.IsBadData() || aErr.StatusCode == http.StatusUnprocessableEntity) {
+			return source.RenderV1{}, &source.TargetError{Err: err}
+		}
+		return source.RenderV1{}, err
+	}
+
+	width, height := target.size()
+	c := chart.Chart{
+		Title:  target.title(),
+		Width:  width,
+		Height: height,
+		Series: toChartSeries(result),
+		From:   from,
+		To:     to,
+	}
+	img, err := c.PNG()
+	if err != nil {
+		return source.RenderV1{}, &source.TargetError{Err: err}
+	}
+
+	return source.RenderV1{
+		Image:          img,
+		ContentType:    chart.PNGContentType,
+		DashboardUID:   target.Query,
+		DashboardTitle: target.title(),
+		From:           strconv.FormatInt(from.UnixNano()/int64(time.Millisecond), 10),
+		To:             strconv.FormatInt(to.UnixNano()/int64(time.Millisecond), 10),
+	}, nil
+}
+
+//toChartSeries - converts query results into chart series named by their labels
+func toChartSeries(result []SeriesV1) []chart.Series {
+	series := make([]chart.Series, len(result))
+	for i, r := range result {
+		points := make([]chart.Point, len(r.Values))
+		for j, v := range r.Values {
+			points[j] = chart.Point{Time: v.Time, Value: v.Value}
+		}
+		series[i] = chart.Series{Name: seriesName(r.Metric), Points: points}
+	}
+	return series
+}
+
+//seriesName - formats labels the way prometheus does. ie up{instance="a:9100",job="node"}
+func seriesName(metric map[string]string) string {
+
+	labels := make([]string, 0, len(metric))
+	for name, val := range metric {
+		if name != nameLabel {
+			labels = append(labels, fmt.Sprintf("%v=%q", name, val))
+		}
+	}
+	sort.Strings(labels)
+
+	if len(labels) == 0 {
+		if metric[nameLabel] == "" {
+			return "{}"
+		}
+		return metric[nameLabel]
+	}
+	return fmt.Sprintf("%v{%v}", metric[nameLabel], strings.Join(labels, ","))
+}
+
+func prometheusUser(cred source.Credential) (common.PrometheusUserV1, error) {
+	user, ok := cred.(common.PrometheusUserV1)
+	if !ok {
+		return common.PrometheusUserV1{}, fmt.Errorf("credential <%T> isn't a prometheus user", cred)
+	}
+	return user, nil
+}
@@ -0,0 +1,203 @@
+package prometheus
+
+import (
+	"bytes"
+	"context"
+	"github.com/sajeevany/graph-snapper/internal/common"
+	"github.com/sajeevany/graph-snapper/internal/config"
+	"github.com/sajeevany/graph-snapper/internal/grafana"
+	"github.com/sajeevany/graph-snapper/internal/source"
+	"github.com/sajeevany/graph-snapper/internal/test"
+	"github.com/sajeevany/graph-snapper/internal/upstream"
+	"github.com/sirupsen/logrus"
+	"image/png"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+)
+
+//newPrometheusServer - fake prometheus requiring basic auth and serving a single cpu query
+func newPrometheusServer(t *testing.T) (*httptest.Server, common.PrometheusUserV1) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if user, pass, ok := r.BasicAuth(); !ok || user != "user" || pass != "pass" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		switch r.URL.Path {
+		case buildInfoURL:
+			w.Write([]byte(`{"status":"success","data":{"version":"2.45.0","revision":"abc","branch":"HEAD","goVersion":"go1.20"}}`))
+		case metricNamesURL:
+			w.Write([]byte(`{"status":"success","data":["node_cpu_seconds_total","node_load1","up"]}`))
+		case queryRangeURL:
+			q := r.URL.Query()
+			if q.Get("query") != "rate(node_cpu_seconds_total[5m])" {
+				w.WriteHeader(http.StatusBadRequest)
+				w.Write([]byte(`{"status":"error","errorType":"bad_data","error":"1:6: parse error: unexpected end of input"}`))
+				return
+			}
+			if q.Get("start") != "1615383000" || q.Get("end") != "1615390200" || q.Get("step") != "15" {
+				t.Errorf("unexpected range query params <%v>", q.Encode())
+			}
+			w.Write([]byte(`{"status":"success","data":{"resultType":"matrix","result":[
+				{"metric":{"cpu":"0","mode":"user"},"values":[[1615383000,"0.5"],[1615386600,"NaN"],[1615390200,"0.75"]]},
+				{"metric":{"cpu":"1","mode":"user"},"values":[[1615383000,"0.25"],[1615390200.5,"1"]]}
+			]}}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	host, port := test.SplitServerURL(t, server.URL)
+	return server, common.PrometheusUserV1{Auth: common.Auth{Basic: common.Basic{Username: "user", Password: "pass"}}, Host: host, Port: port}
+}
+
+func TestSource_CheckAccess(t *testing.T) {
+	server, user := newPrometheusServer(t)
+	defer server.Close()
+
+	unauthorized := user
+	unauthorized.Auth = common.Auth{}
+
+	tests := []struct {
+		name       string
+		user       common.PrometheusUserV1
+		wantResult bool
+	}{
+		{
+			name:       "test0 valid user",
+			user:       user,
+			wantResult: true,
+		},
+		{
+			name:       "test1 missing auth",
+			user:       unauthorized,
+			wantResult: false,
+		},
+	}
+	s := NewSource(upstream.New(config.NewConfWithDefaults().Upstream))
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := s.CheckAccess(context.Background(), logrus.NewEntry(logrus.New()), tt.user)
+			if got.Result != tt.wantResult {
+				t.Errorf("CheckAccess() = %+v, want result %v", got, tt.wantResult)
+			}
+			if tt.wantResult && !reflect.DeepEqual(got.Details, &BuildInfoV1{Version: "2.45.0", Revision: "abc", Branch: "HEAD", GoVersion: "go1.20"}) {
+				t.Errorf("CheckAccess() details = %+v", got.Details)
+			}
+		})
+	}
+}
+
+func TestSource_ListDashboards(t *testing.T) {
+	server, user := newPrometheusServer(t)
+	defer server.Close()
+
+	s := NewSource(upstream.New(config.NewConfWithDefaults().Upstream))
+	got, err := s.ListDashboards(context.Background(), logrus.NewEntry(logrus.New()), user, "NODE")
+	if err != nil {
+		t.Fatalf("ListDashboards() unexpected error = %v", err)
+	}
+	want := []source.DashboardV1{
+		{UID: "node_cpu_seconds_total", Title: "node_cpu_seconds_total"},
+		{UID: "node_load1", Title: "node_load1"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ListDashboards() = %+v, want %+v", got, want)
+	}
+}
+
+func TestSource_Render(t *testing.T) {
+	server, user := newPrometheusServer(t)
+	defer server.Close()
+
+	tests := []struct {
+		name          string
+		target        TargetV1
+		wantTargetErr bool
+	}{
+		{
+			name: "test0 renders matrix",
+			target: TargetV1{
+				Query:     "rate(node_cpu_seconds_total[5m])",
+				TimeRange: grafana.TimeRangeV1{From: "now-2h", To: "now"},
+				Step:      "15s",
+				Width:     400,
+				Height:    200,
+			},
+		},
+		{
+			name:          "test1 bad query",
+			target:        TargetV1{Query: "rate(", TimeRange: grafana.TimeRangeV1{From: "now-2h"}},
+			wantTargetErr: true,
+		},
+		{
+			name: "test2 legend doesn't fit",
+			target: TargetV1{
+				Query:     "rate(node_cpu_seconds_total[5m])",
+				TimeRange: grafana.TimeRangeV1{From: "now-2h", To: "now"},
+				Step:      "15s",
+				Width:     400,
+				Height:    100,
+			},
+			wantTargetErr: true,
+		},
+	}
+	s := NewSource(upstream.New(config.NewConfWithDefaults().Upstream))
+	s.now = func() time.Time { return time.Date(2021, 3, 10, 15, 30, 0, 0, time.UTC) }
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := s.Render(context.Background(), logrus.NewEntry(logrus.New()), user, tt.target)
+			if tt.wantTargetErr {
+				if !source.IsTargetError(err) {
+					t.Errorf("Render() error = %v, want target error", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Render() unexpected error = %v", err)
+			}
+			img, dErr := png.Decode(bytes.NewReader(got.Image))
+			if dErr != nil {
+				t.Fatalf("Render() returned an invalid png. <%v>", dErr)
+			}
+			if img.Bounds().Dx() != 400 || img.Bounds().Dy() != 200 {
+				t.Errorf("Render() image size = %v, want 400x200", img.Bounds().Size())
+			}
+			if got.From != "1615383000000" || got.To != "1615390200000" || got.DashboardTitle != tt.target.Query {
+				t.Errorf("Render() = %+v", got)
+			}
+		})
+	}
+}
+
+func Test_seriesName(t *testing.T) {
+	tests := []struct {
+		name   string
+		metric map[string]string
+		want   string
+	}{
+		{
+			name:   "test0 name and labels",
+			metric: map[string]string{"__name__": "up", "job": "node", "instance": "a:9100"},
+			want:   `up{instance="a:9100",job="node"}`,
+		},
+		{
+			name:   "test1 labels without name",
+			metric: map[string]string{"mode": "user"},
+			want:   `{mode="user"}`,
+		},
+		{
+			name:   "test2 no labels",
+			metric: map[string]string{},
+			want:   "{}",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := seriesName(tt.metric); got != tt.want {
+				t.Errorf("seriesName() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,106 @@
+package prometheus
+
+import (
+	"fmt"
+	"github.com/sajeevany/graph-snapper/internal/config"
+	"github.com/sajeevany/graph-snapper/internal/grafana"
+	"math"
+	"strconv"
+	"time"
+)
+
+const (
+	defaultWidth  = 1000
+	defaultHeight = 500
+	minDimension  = 100
+	maxDimension  = 10000
+
+	//maxPoints - prometheus rejects range queries returning more than 11000 points per series
+	maxPoints = 11000
+)
+
+//TargetV1 - PromQL range query rendered to a line chart
+type TargetV1 struct {
+	Query     string
+	Title     string              //Optional. Chart title. Defaults to the query
+	TimeRange grafana.TimeRangeV1 //Grafana style relative or absolute range. ie now-7d to now
+	Step      string              //Optional. Query resolution as a duration. ie 30s. Defaults to one point per 2 pixels
+	Width     int                 //Optional. Image width in pixels. Defaults to 1000
+	Height    int                 //Optional. Image height in pixels. Defaults to 500
+}
+
+//IsValid - Adds an entry per invalid field to invalidArgs
+func (t TargetV1) IsValid(currentPath string, invalidArgs map[string]string) bool {
+
+	valid := true
+
+	if t.Query == "" {
+		config.AddInvalidArgWithCause(currentPath, "Query", t.Query, "value is empty", invalidArgs)
+		valid = false
+	}
+	if t.Step != "" {
+		if step, err := time.ParseDuration(t.Step); err != nil || step <= 0 {
+			config.AddInvalidArgWithCause(currentPath, "Step", t.Step, "value isn't a positive duration. ie 30s", invalidArgs)
+			valid = false
+		}
+	}
+	if t.Width != 0 && (t.Width < minDimension || t.Width > maxDimension) {
+		config.AddInvalidArgWithCause(currentPath, "Width", strconv.Itoa(t.Width), fmt.Sprintf("value isn't 0 or between %v and %v", minDimension, maxDimension), invalidArgs)
+		valid = false
+	}
+	if t.Height != 0 && (t.Height < minDimension || t.Height > maxDimension) {
+		config.AddInvalidArgWithCause(currentPath, "Height", strconv.Itoa(t.Height), fmt.Sprintf("value isn't 0 or between %v and %v", minDimension, maxDimension), invalidArgs)
+		valid = false
+	}
+	if !t.TimeRange.IsValid(fmt.Sprintf("%s.%s", currentPath, "TimeRange"), invalidArgs) {
+		valid = false
+	}
+
+	return valid
+}
+
+//title - returns the chart title
+func (t TargetV1) title() string {
+	if t.Title != "" {
+		return t.Title
+	}
+	return t.Query
+}
+
+//size - returns the chart width and height with defaults applied
+func (t TargetV1) size() (int, int) {
+	width, height := t.Width, t.Height
+	if width == 0 {
+		width = defaultWidth
+	}
+	if height == 0 {
+		height = defaultHeight
+	}
+	return width, height
+}
+
+//step - returns the query resolution. Defaults to a point per 2 pixels of chart width rounded up to whole seconds and
+//is raised if the range would return more points than prometheus allows
+func (t TargetV1) step(from, to time.Time) time.Duration {
+
+	rangeSec := to.Sub(from).Seconds()
+	minStep := time.Duration(math.Ceil(rangeSec/maxPoints)) * time.Second
+
+	if t.Step != "" {
+		step, _ := time.ParseDuration(t.Step)
+		if step < minStep {
+			return minStep
+		}
+		return step
+	}
+
+	width, _ := t.size()
+	step := time.Duration(math.Ceil(rangeSec/float64(width/2))) * time.Second
+	if step < time.Second {
+		step = time.Second
+	}
+	if step < minStep {
+		return minStep
+	}
+	return step
+}
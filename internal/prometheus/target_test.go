@@ -0,0 +1,84 @@
+package prometheus
+
+import (
+	"github.com/sajeevany/graph-snapper/internal/grafana"
+	"testing"
+	"time"
+)
+
+func TestTargetV1_IsValid(t *testing.T) {
+	tests := []struct {
+		name        string
+		target      TargetV1
+		want        bool
+		wantInvalid int
+	}{
+		{
+			name:   "test0 defaults",
+			target: TargetV1{Query: "up"},
+			want:   true,
+		},
+		{
+			name:        "test1 empty query and bad step",
+			target:      TargetV1{Step: "-1m"},
+			want:        false,
+			wantInvalid: 2,
+		},
+		{
+			name:        "test2 too small and reversed range",
+			target:      TargetV1{Query: "up", Width: 50, TimeRange: grafana.TimeRangeV1{From: "1615390200000", To: "1615383000000"}},
+			want:        false,
+			wantInvalid: 2,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			invalid := map[string]string{}
+			if got := tt.target.IsValid("Target", invalid); got != tt.want || len(invalid) != tt.wantInvalid {
+				t.Errorf("IsValid() = %v with invalid args %v, want %v with %v invalid args", got, invalid, tt.want, tt.wantInvalid)
+			}
+		})
+	}
+}
+
+func TestTargetV1_step(t *testing.T) {
+	from := time.Date(2021, 3, 10, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name   string
+		target TargetV1
+		to     time.Time
+		want   time.Duration
+	}{
+		{
+			name:   "test0 point per 2 pixels",
+			target: TargetV1{},
+			to:     from.Add(6 * time.Hour),
+			want:   44 * time.Second,
+		},
+		{
+			name:   "test1 explicit step",
+			target: TargetV1{Step: "1m"},
+			to:     from.Add(6 * time.Hour),
+			want:   time.Minute,
+		},
+		{
+			name:   "test2 step raised to prometheus point limit",
+			target: TargetV1{Step: "1s"},
+			to:     from.Add(24 * time.Hour),
+			want:   8 * time.Second,
+		},
+		{
+			name:   "test3 short range",
+			target: TargetV1{},
+			to:     from.Add(time.Minute),
+			want:   time.Second,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.target.step(from, tt.to); got != tt.want {
+				t.Errorf("step() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,111 @@
+package redact
+
+import (
+	"github.com/sirupsen/logrus"
+	"net/http"
+	"regexp"
+)
+
+//authHeaderPattern - matches credentials of an Authorization header, ie. `Authorization: Bearer abc` or a printed
+//header map `"Authorization":[]string{"Basic YWJj"}`
+var authHeaderPattern = regexp.MustCompile(`(?i)(authorization[^a-z0-9]*(?:\[\]string\{)?[^a-z0-9]*)(bearer|basic)(\s+)[^\s"'\]\},]+`)
+
+//Hook - logrus hook that masks secrets in every entry before it's formatted. Values implementing Redactor are replaced
+//with their redacted copy, string values of sensitive field names are masked, http requests and headers have their
+//credentials removed and Authorization header values are masked in the message.
+type Hook struct{}
+
+func NewHook() *Hook {
+	return &Hook{}
+}
+
+func (h *Hook) Levels() []logrus.Level {
+	return logrus.AllLevels
+}
+
+func (h *Hook) Fire(entry *logrus.Entry) error {
+	//Entry data is shared with the parent entry. Replace it rather than modifying it in place
+	if len(entry.Data) != 0 {
+		entry.Data = redactFields(entry.Data)
+	}
+	entry.Message = Message(entry.Message)
+	return nil
+}
+
+//Message - masks Authorization header credentials found in free text
+func Message(msg string) string {
+	return authHeaderPattern.ReplaceAllString(msg, "${1}${2}${3}"+Mask)
+}
+
+func redactFields(fields logrus.Fields) logrus.Fields {
+	redacted := make(logrus.Fields, len(fields))
+	for k, v := range fields {
+		redacted[k] = redactValue(k, v)
+	}
+	return redacted
+}
+
+func redactValue(key string, val interface{}) interface{} {
+	switch v := val.(type) {
+	case Redactor:
+		return v.Redacted()
+	case logrus.Fields:
+		return redactFields(v)
+	case map[string]interface{}:
+		return map[string]interface{}(redactFields(v))
+	case map[string]string:
+		m := make(map[string]string, len(v))
+		for mk, mv := range v {
+			if IsSensitiveKey(mk) {
+				m[mk] = NonEmpty(mv)
+			} else {
+				m[mk] = Message(mv)
+			}
+		}
+		return m
+	case http.Header:
+		return Header(v)
+	case *http.Request:
+		return Request(v)
+	case string:
+		if IsSensitiveKey(key) {
+			return NonEmpty(v)
+		}
+		return Message(v)
+	case error:
+		return Message(v.Error())
+	default:
+		return val
+	}
+}
+
+//Header - returns a copy of the headers with sensitive header values masked
+func Header(h http.Header) http.Header {
+	redacted := make(http.Header, len(h))
+	for k, vals := range h {
+		cp := make([]string, len(vals))
+		for i, v := range vals {
+			if IsSensitiveKey(k) {
+				cp[i] = NonEmpty(v)
+			} else {
+				cp[i] = v
+			}
+		}
+		redacted[k] = cp
+	}
+	return redacted
+}
+
+//Request - returns loggable details of a request without credentials
+func Request(req *http.Request) logrus.Fields {
+	if req == nil {
+		return nil
+	}
+	u := *req.URL
+	u.User = nil
+	return logrus.Fields{
+		"method":  req.Method,
+		"url":     u.String(),
+		"headers": Header(req.Header),
+	}
+}
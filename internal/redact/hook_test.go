@@ -0,0 +1,96 @@
+package redact_test
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/sajeevany/graph-snapper/internal/common"
+	"github.com/sajeevany/graph-snapper/internal/config"
+	"github.com/sajeevany/graph-snapper/internal/db/aerospike/record"
+	"github.com/sajeevany/graph-snapper/internal/logging"
+	"github.com/sirupsen/logrus"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+//Secret values that must never appear in log output
+const (
+	secretPassword = "hunter2-password"
+	secretToken    = "eyJrIjoiU0VDUkVUVE9LRU4ifQ"
+	secretUsername = "secret-user"
+)
+
+//TestHookRedactsSecrets - logs every known secret carrying type with each formatter and scans the output for secrets
+func TestHookRedactsSecrets(t *testing.T) {
+
+	basicAuth := common.Auth{Basic: common.Basic{Username: secretUsername, Password: secretPassword}}
+	tokenAuth := common.Auth{BearerToken: common.BearerToken{Token: secretToken}}
+	grafanaUser := common.GrafanaUserV1{Auth: tokenAuth, Host: "grafana", Port: 3000}
+	confluenceUser := common.ConfluenceServerUserV1{Auth: basicAuth, Host: "confluence", Port: 8090}
+
+	req, _ := http.NewRequest(http.MethodGet, "http://grafana:3000/api/login/ping", nil)
+	common.SetAuthHeader(logrus.NewEntry(logrus.New()), tokenAuth, req)
+	basicReq, _ := http.NewRequest(http.MethodGet, "http://confluence:8090/rest/api/accessmode", nil)
+	common.SetAuthHeader(logrus.NewEntry(logrus.New()), basicAuth, basicReq)
+
+	scenarios := []struct {
+		name string
+		log  func(logger *logrus.Logger)
+	}{
+		{name: "aerospike config fields", log: func(l *logrus.Logger) {
+			l.WithFields(config.AerospikeCfg{Host: "as", Password: secretPassword}.GetFields()).Info("config")
+		}},
+		{name: "record basic and bearer token fields", log: func(l *logrus.Logger) {
+			l.WithFields(record.DBAuth{
+				Basic:       record.Basic{Username: secretUsername, Password: secretPassword},
+				BearerToken: record.BearerToken{Token: secretToken},
+			}.GetFields()).Info("db auth")
+		}},
+		{name: "auth as field value", log: func(l *logrus.Logger) {
+			l.WithField("auth", basicAuth).WithField("token", tokenAuth).Info("auth")
+		}},
+		{name: "users as field values", log: func(l *logrus.Logger) {
+			l.WithField("grafana", grafanaUser).WithField("confluence", confluenceUser).Info("users")
+		}},
+		{name: "users printed in message", log: func(l *logrus.Logger) {
+			l.Infof("%v %+v %#v %s", grafanaUser, confluenceUser, basicAuth, tokenAuth)
+		}},
+		{name: "error wrapping user", log: func(l *logrus.Logger) {
+			l.WithError(fmt.Errorf("grafana user <%#v> is invalid", grafanaUser)).Error("invalid")
+		}},
+		{name: "raw sensitive keys", log: func(l *logrus.Logger) {
+			l.WithFields(logrus.Fields{"Password": secretPassword, "apiToken": secretToken}).Info("raw")
+		}},
+		{name: "request as field value", log: func(l *logrus.Logger) {
+			l.WithField("request", req).WithField("headers", basicReq.Header).Info("request")
+		}},
+		{name: "request printed in message", log: func(l *logrus.Logger) {
+			l.Infof("req %#v", req)
+			l.Infof("req %+v", basicReq)
+		}},
+	}
+
+	formatters := []logrus.Formatter{&logrus.TextFormatter{}, &logrus.JSONFormatter{}}
+	for _, formatter := range formatters {
+		for _, s := range scenarios {
+			t.Run(fmt.Sprintf("%T %s", formatter, s.name), func(t *testing.T) {
+				var buf bytes.Buffer
+				logger := logging.Init()
+				logger.SetOutput(&buf)
+				logger.SetFormatter(formatter)
+
+				s.log(logger)
+
+				out := buf.String()
+				if out == "" {
+					t.Fatalf("Expected log output")
+				}
+				for _, secret := range []string{secretPassword, secretToken, secretUsername, basicReq.Header.Get("Authorization")[len("Basic "):]} {
+					if strings.Contains(out, secret) {
+						t.Errorf("Log output leaked secret <%v>: %v", secret, out)
+					}
+				}
+			})
+		}
+	}
+}
@@ -0,0 +1,120 @@
+package redact
+
+import (
+	"errors"
+	"fmt"
+	"github.com/mitchellh/mapstructure"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+//Mask - value logged in place of a secret
+const Mask = "*****"
+
+//Redactor - implemented by types holding secrets. Redacted returns a value that is safe to log.
+type Redactor interface {
+	Redacted() interface{}
+}
+
+//NonEmpty - Returns values as a redacted string if non empty
+func NonEmpty(val string) string {
+
+	if val != "" {
+		return Mask
+	}
+
+	return val
+}
+
+//sensitiveKeys - lower case substrings of field names whose string values are always masked. Substrings name the
+//secret itself rather than what holds it so that credential names and check results stay readable
+var sensitiveKeys = []string{
+	"password",
+	"token",
+	"secret",
+	"apikey",
+	"api_key",
+	"authorization",
+	"cookie",
+	"clientkey",
+	"privatekey",
+}
+
+//IsSensitiveKey - returns true if a field or header with this name is expected to hold a secret
+func IsSensitiveKey(key string) bool {
+	k := strings.ToLower(key)
+	for _, s := range sensitiveKeys {
+		if strings.Contains(k, s) {
+			return true
+		}
+	}
+	return false
+}
+
+//Format - prints the redacted copy of a value with the caller's fmt directive. Types holding secrets implement
+//fmt.Formatter with it so that their secrets aren't leaked through fmt verbs or log messages. The copy must be of a type
+//without a Format method, ie a local type definition of the value's type, or printing recurses
+func Format(f fmt.State, verb rune, redacted interface{}) {
+	fmt.Fprintf(f, formatDirective(f, verb), redacted)
+}
+
+//formatDirective - rebuilds the fmt directive, ie %+v, used to print a value
+func formatDirective(f fmt.State, verb rune) string {
+	var b strings.Builder
+	b.WriteByte('%')
+	for _, flag := range "+-# 0" {
+		if f.Flag(int(flag)) {
+			b.WriteRune(flag)
+		}
+	}
+	if w, ok := f.Width(); ok {
+		b.WriteString(fmt.Sprintf("%d", w))
+	}
+	if p, ok := f.Precision(); ok {
+		b.WriteString(fmt.Sprintf(".%d", p))
+	}
+	b.WriteRune(verb)
+	return b.String()
+}
+
+//URL - Returns the url with any userinfo password masked. Values which can't be parsed are masked entirely
+func URL(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return NonEmpty(rawURL)
+	}
+	if _, hasPassword := u.User.Password(); hasPassword {
+		u.User = url.UserPassword(u.User.Username(), Mask)
+	}
+	return u.String()
+}
+
+//decodeFieldPattern - field name quoted at the start of each mapstructure decode error
+var decodeFieldPattern = regexp.MustCompile(`'([^']*)'`)
+
+//DecodeError - returns an error naming only the fields that couldn't be decoded. mapstructure errors print the invalid
+//values, which can be passwords or tokens of stored credentials
+func DecodeError(err error) error {
+
+	if err == nil {
+		return nil
+	}
+
+	msgs := []string{err.Error()}
+	var mErr *mapstructure.Error
+	if errors.As(err, &mErr) {
+		msgs = mErr.Errors
+	}
+
+	var fields []string
+	for _, msg := range msgs {
+		if m := decodeFieldPattern.FindStringSubmatch(msg); m != nil {
+			fields = append(fields, m[1])
+		}
+	}
+	if len(fields) == 0 {
+		return fmt.Errorf("unable to decode value")
+	}
+	return fmt.Errorf("unable to decode fields %v", fields)
+}
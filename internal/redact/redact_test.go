@@ -0,0 +1,51 @@
+package redact
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestIsSensitiveKey(t *testing.T) {
+	tests := []struct {
+		key  string
+		want bool
+	}{
+		{key: "Password", want: true},
+		{key: "ClientSecret", want: true},
+		{key: "SessionToken", want: true},
+		{key: "Proxy-Authorization", want: true},
+		{key: "ClientKey", want: true},
+		{key: "Credential", want: false},
+		{key: "CredChecks", want: false},
+		{key: "ConfluenceCredentials", want: false},
+		{key: "Username", want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.key, func(t *testing.T) {
+			if got := IsSensitiveKey(tt.key); got != tt.want {
+				t.Errorf("IsSensitiveKey(%v) = %v, want %v", tt.key, got, tt.want)
+			}
+		})
+	}
+}
+
+//formatted - value holding a secret that prints a redacted copy
+type formatted struct {
+	Name     string
+	Password string
+}
+
+func (v formatted) Format(f fmt.State, verb rune) {
+	type plain formatted
+	v.Password = NonEmpty(v.Password)
+	Format(f, verb, plain(v))
+}
+
+func TestFormat(t *testing.T) {
+	v := formatted{Name: "u", Password: "hunter2"}
+	for directive, want := range map[string]string{"%v": "{u *****}", "%+v": "{Name:u Password:*****}", "%s": "{u *****}"} {
+		if got := fmt.Sprintf(directive, v); got != want {
+			t.Errorf("Sprintf(%v) = %v, want %v", directive, got, want)
+		}
+	}
+}
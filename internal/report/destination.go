@@ -0,0 +1,101 @@
+package report
+
+import (
+	"context"
+	"fmt"
+	"github.com/sajeevany/graph-snapper/internal/artifact"
+	"github.com/sajeevany/graph-snapper/internal/common"
+	"github.com/sajeevany/graph-snapper/internal/destination"
+	"github.com/sajeevany/graph-snapper/internal/plugin"
+	"github.com/sirupsen/logrus"
+)
+
+//DestinationType - destination type of static HTML reports
+const DestinationType = "html-report"
+
+//Destination - Regenerates a static HTML report over the account's stored snapshots after each capture
+type Destination struct {
+	writer *Writer
+	store  *artifact.Store
+}
+
+//NewDestination - returns the HTML report destination. Report images are read from the store
+func NewDestination(writer *Writer, store *artifact.Store) *Destination {
+	return &Destination{writer: writer, store: store}
+}
+
+func (d *Destination) Type() string {
+	return DestinationType
+}
+
+func (d *Destination) NewCredential() destination.Credential {
+	return &common.HTMLReportV1{}
+}
+
+func (d *Destination) Validate(cred destination.Credential) error {
+	report, err := htmlReport(cred)
+	if err != nil {
+		return err
+	}
+	if !report.IsValid() {
+		return fmt.Errorf("HTML report <%#v> is invalid. Path must be a relative directory without . or .. segments", report)
+	}
+	return nil
+}
+
+//CheckAccess - Reports are written locally so only the reports directory is checked
+func (d *Destination) CheckAccess(ctx context.Context, logger *logrus.Entry, cred destination.Credential) plugin.CheckResultV1 {
+	if _, err := htmlReport(cred); err != nil {
+		return plugin.Failed(err.Error())
+	}
+	if err := d.writer.CheckWritable(); err != nil {
+		logger.Errorf("Reports directory isn't writable. <%v>", err)
+		return plugin.Failed(err.Error())
+	}
+	return plugin.Passed(nil)
+}
+
+//Publish - Regenerates the report's index. The bundle's snapshots are already in the store so they're listed with the
+//account's other snapshots
+func (d *Destination) Publish(ctx context.Context, logger *logrus.Entry, name string, cred destination.Credential, bundle destination.Bundle) (destination.ReceiptV1, error) {
+
+	report, err := htmlReport(cred)
+	if err != nil {
+		return destination.ReceiptV1{}, err
+	}
+
+	result, err := d.writer.Write(ctx, logger, d.store, bundle.AccountID, name, report)
+	if err != nil {
+		return destination.ReceiptV1{}, err
+	}
+
+	return destination.ReceiptV1{Location: result.Index}, nil
+}
+
+//Rollback - Marks the bundle's snapshots as rolled back in the store and regenerates the report's index without them.
+//Later writes don't list them either
+func (d *Destination) Rollback(ctx context.Context, logger *logrus.Entry, name string, cred destination.Credential, bundle destination.Bundle, receipt destination.ReceiptV1) error {
+
+	report, err := htmlReport(cred)
+	if err != nil {
+		return err
+	}
+
+	ids := make([]string, 0, len(bundle.Items))
+	for _, item := range bundle.Items {
+		ids = append(ids, item.Snapshot.ID)
+	}
+	if mErr := d.store.MarkRolledBack(ctx, bundle.AccountID, ids); mErr != nil {
+		return fmt.Errorf("unable to mark snapshots of job <%v> as rolled back. err <%v>", bundle.JobID, mErr)
+	}
+	_, err = d.writer.Write(ctx, logger, d.store, bundle.AccountID, name, report)
+	return err
+}
+
+func htmlReport(cred destination.Credential) (common.HTMLReportV1, error) {
+	report, ok := cred.(common.HTMLReportV1)
+	if !ok {
+		return common.HTMLReportV1{}, fmt.Errorf("credential <%T> isn't an HTML report", cred)
+	}
+	return report, nil
+}
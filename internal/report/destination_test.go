@@ -0,0 +1,62 @@
+package report
+
+import (
+	"context"
+	"github.com/sajeevany/graph-snapper/internal/artifact"
+	"github.com/sajeevany/graph-snapper/internal/common"
+	"github.com/sajeevany/graph-snapper/internal/config"
+	"github.com/sajeevany/graph-snapper/internal/destination"
+	"github.com/sirupsen/logrus"
+	"io/ioutil"
+	"os"
+	"testing"
+)
+
+func TestDestination_Rollback(t *testing.T) {
+
+	dir, err := ioutil.TempDir("", "reports")
+	if err != nil {
+		t.Fatalf("SETUP FAILURE: Unable to create temp dir. err <%v>", err)
+	}
+	defer os.RemoveAll(dir)
+
+	writer, err := NewWriter(config.Reports{Dir: dir})
+	if err != nil {
+		t.Fatalf("NewWriter() unexpected error = %v", err)
+	}
+	store := artifact.NewStoreWithBackend(artifact.NewMemoryBackend(), config.Artifacts{})
+	dest := NewDestination(writer, store)
+	logger := logrus.NewEntry(logrus.New())
+	ctx := context.Background()
+	report := common.HTMLReportV1{Path: "ops"}
+
+	kept, err := store.Save(ctx, logger, artifact.SnapshotV1{AccountID: "acct1", DashboardUID: "cpu01", PanelID: 1}, []byte("first"))
+	if err != nil {
+		t.Fatalf("SETUP FAILURE: Unable to save snapshot. err <%v>", err)
+	}
+	rolledBack, err := store.Save(ctx, logger, artifact.SnapshotV1{AccountID: "acct1", DashboardUID: "cpu01", PanelID: 2}, []byte("second"))
+	if err != nil {
+		t.Fatalf("SETUP FAILURE: Unable to save snapshot. err <%v>", err)
+	}
+
+	bundle := destination.Bundle{AccountID: "acct1", Items: []destination.Item{{Snapshot: rolledBack, Image: []byte("second")}}}
+	receipt, err := dest.Publish(ctx, logger, "ops", report, bundle)
+	if err != nil {
+		t.Fatalf("Publish() unexpected error = %v", err)
+	}
+	if err := dest.Rollback(ctx, logger, "ops", report, bundle, receipt); err != nil {
+		t.Fatalf("Rollback() unexpected error = %v", err)
+	}
+
+	//Writes after the rollback don't list the rolled back snapshot
+	result, err := writer.Write(ctx, logger, store, "acct1", "ops", report)
+	if err != nil {
+		t.Fatalf("Write() unexpected error = %v", err)
+	}
+	if result.Snapshots != 1 {
+		t.Errorf("Write() listed <%v> snapshots, want only <%v>", result.Snapshots, kept.ID)
+	}
+	if snapshot, err := store.Get(ctx, "acct1", rolledBack.ID); err != nil || !snapshot.RolledBack {
+		t.Errorf("Get() = %+v, %v. want the snapshot marked as rolled back", snapshot, err)
+	}
+}
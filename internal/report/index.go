@@ -0,0 +1,73 @@
+package report
+
+import (
+	"fmt"
+	"github.com/sajeevany/graph-snapper/internal/artifact"
+	"html/template"
+)
+
+//indexPage - data rendered by indexTemplate
+type indexPage struct {
+	Title       string
+	Description string
+	JobID       string
+	GeneratedAt string
+	Entries     []indexEntry
+}
+
+//indexEntry - a single snapshot of the index
+type indexEntry struct {
+	PanelTitle     string
+	DashboardTitle string
+	From           string
+	To             string
+	CapturedAt     string
+	Image          string //Path relative to the index
+	SnapshotID     string
+}
+
+func newIndexEntry(s artifact.SnapshotV1, image string) indexEntry {
+	e := indexEntry{
+		PanelTitle:     s.PanelTitle,
+		DashboardTitle: s.DashboardTitle,
+		From:           formatRangeTime(s.From),
+		To:             formatRangeTime(s.To),
+		CapturedAt:     formatTime(s.CapturedAt),
+		Image:          image,
+		SnapshotID:     s.ID,
+	}
+	if e.PanelTitle == "" {
+		e.PanelTitle = fmt.Sprintf("Panel %v", s.PanelID)
+	}
+	if e.DashboardTitle == "" {
+		e.DashboardTitle = s.DashboardUID
+	}
+	return e
+}
+
+//indexTemplate - self contained page so that reports can be opened from the filesystem without network access
+var indexTemplate = template.Must(template.New(indexFile).Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>{{.Title}}</title>
+<style>
+body { font-family: sans-serif; margin: 2em; color: #222; }
+.snapshot { margin-bottom: 2em; padding-bottom: 1em; border-bottom: 1px solid #ddd; }
+.snapshot img { max-width: 100%; border: 1px solid #ccc; }
+.meta { color: #555; font-size: 0.9em; }
+</style>
+</head>
+<body>
+<h1>{{.Title}}</h1>
+{{if .Description}}<p>{{.Description}}</p>
+{{end}}<p class="meta">Generated {{.GeneratedAt}}{{if .JobID}} for job {{.JobID}}{{end}}. {{len .Entries}} snapshots.</p>
+{{range .Entries}}<div class="snapshot" id="{{.SnapshotID}}">
+<h2>{{.PanelTitle}}</h2>
+<p class="meta">{{.DashboardTitle}} | {{.From}} to {{.To}} | Captured {{.CapturedAt}}</p>
+<img src="{{.Image}}" alt="{{.PanelTitle}}">
+</div>
+{{else}}<p>No snapshots have been captured.</p>
+{{end}}</body>
+</html>
+`))
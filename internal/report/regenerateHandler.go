@@ -0,0 +1,100 @@
+package report
+
+import (
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"github.com/sajeevany/graph-snapper/internal/artifact"
+	"github.com/sajeevany/graph-snapper/internal/common"
+	as "github.com/sajeevany/graph-snapper/internal/db/aerospike"
+	"github.com/sajeevany/graph-snapper/internal/logging"
+	"github.com/sirupsen/logrus"
+	"net/http"
+)
+
+const RegenerateReportEndpoint = "/:id/reports/:report/index"
+
+//@Summary Regenerate an HTML report
+//@Description Non-authenticated endpoint that rewrites the index of a stored HTML report over the account's historical snapshots. Images missing from the report are copied from the artifact store
+//@Produce json
+//@Param id path string true "Account ID"
+//@Param report path string true "HTML report name"
+//@Success 200 {object} ResultV1
+//@Fail 404 {object} gin.H
+//@Fail 500 {object} gin.H
+//@Router /account/:id/reports/:report/index [post]
+//@Tags snapshot
+func RegenerateReportV1(baseLogger *logrus.Logger, aeroClient *as.ASClient, store *artifact.Store, writer *Writer) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+
+		//Use request scoped logger
+		logger := logging.FromContext(ctx.Request.Context(), baseLogger)
+
+		report, found := getReport(ctx, logger, aeroClient)
+		if !found {
+			return
+		}
+
+		result, err := writer.Write(ctx.Request.Context(), logger, store, ctx.Param("id"), ctx.Param("report"), report)
+		if err != nil {
+			hrErrMsg := fmt.Sprintf("Unable to write HTML report %v", ctx.Param("report"))
+			logger.Errorf("%v. err <%v>", hrErrMsg, err)
+			ctx.JSON(http.StatusInternalServerError, gin.H{
+				"error":              err.Error(),
+				"humanReadableError": hrErrMsg,
+			})
+			return
+		}
+
+		ctx.JSON(http.StatusOK, result)
+	}
+}
+
+//getReport - returns the stored HTML report named in the path. Writes the error response and returns false if the
+//account or report doesn't exist
+func getReport(ctx *gin.Context, logger *logrus.Entry, aeroClient *as.ASClient) (common.HTMLReportV1, bool) {
+
+	accountID, name := ctx.Param("id"), ctx.Param("report")
+
+	reader := aeroClient.GetReader()
+	exists, key, kErr := reader.KeyExists(ctx.Request.Context(), accountID)
+	if kErr != nil {
+		hrErrMsg := fmt.Sprintf("unable to check db for key <%v>", accountID)
+		logger.Errorf("%v. err <%v>", hrErrMsg, kErr)
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"error":              kErr.Error(),
+			"humanReadableError": hrErrMsg,
+		})
+		return common.HTMLReportV1{}, false
+	}
+	if !exists {
+		logger.Debugf("account <%v> does not exist. Returning 404", accountID)
+		ctx.JSON(http.StatusNotFound, gin.H{
+			"error":              fmt.Sprintf("key <%v> doesn't exist", accountID),
+			"humanReadableError": fmt.Sprintf("No account exists with ID %v", accountID),
+		})
+		return common.HTMLReportV1{}, false
+	}
+
+	rec, rErr := reader.ReadRecord(ctx.Request.Context(), key)
+	if rErr != nil {
+		hrErrMsg := "Internal error when reading account from Aerospike data store"
+		logger.Errorf("%v. err <%v>", hrErrMsg, rErr)
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"error":              rErr.Error(),
+			"humanReadableError": hrErrMsg,
+		})
+		return common.HTMLReportV1{}, false
+	}
+
+	report, exists := rec.GetCredentials().Destinations[DestinationType][name].(common.HTMLReportV1)
+	if !exists {
+		logger.Debugf("HTML report <%v> does not exist on account <%v>. Returning 404", name, accountID)
+		ctx.JSON(http.StatusNotFound, gin.H{
+			"error":              fmt.Sprintf("HTML report <%v> doesn't exist", name),
+			"humanReadableError": fmt.Sprintf("No HTML report named %v exists on account %v", name, accountID),
+		})
+		return common.HTMLReportV1{}, false
+	}
+
+	return report, true
+}
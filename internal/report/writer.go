@@ -0,0 +1,217 @@
+package report
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"github.com/sajeevany/graph-snapper/internal/artifact"
+	"github.com/sajeevany/graph-snapper/internal/common"
+	"github.com/sajeevany/graph-snapper/internal/config"
+	"github.com/sirupsen/logrus"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	indexFile = "index.html"
+	imagesDir = "images"
+
+	dirPerm  = 0750
+	filePerm = 0640
+)
+
+//imageSuffixes - suffixes of the image files of a report. Other files such as in progress writes are left alone
+var imageSuffixes = map[string]bool{".png": true, ".svg": true}
+
+//ResultV1 - Report index written for a destination. Error is set if the report couldn't be written
+type ResultV1 struct {
+	Report    string
+	Index     string //Path of the index page relative to the reports directory. ie acct1/ops/weekly/index.html
+	Snapshots int    //Number of snapshots listed by the index
+	Error     string `json:"Error,omitempty"`
+}
+
+//Writer - Writes static HTML reports below <dir>/<account ID>/<report path>. Each report holds an index.html and an
+//images directory with one file per distinct image
+type Writer struct {
+	dir string
+	now func() time.Time
+
+	//mu - serializes writes so that concurrent captures don't remove each other's images
+	mu sync.Mutex
+}
+
+//NewWriter - Returns a writer rooted at the configured reports directory. The directory is created if it doesn't exist
+func NewWriter(conf config.Reports) (*Writer, error) {
+	if err := os.MkdirAll(conf.Dir, dirPerm); err != nil {
+		return nil, fmt.Errorf("unable to create reports dir <%v>. err <%v>", conf.Dir, err)
+	}
+	return &Writer{dir: conf.Dir, now: time.Now}, nil
+}
+
+//Write - Regenerates the report's index over the account's stored snapshots, newest first. Only snapshots of the
+//report's job are listed when it has a JobID. Rolled back snapshots aren't listed. Images are copied from the artifact
+//store and images no longer listed are removed
+func (w *Writer) Write(ctx context.Context, logger *logrus.Entry, store *artifact.Store, accountID, name string, report common.HTMLReportV1) (ResultV1, error) {
+
+	if !report.IsValid() {
+		return ResultV1{}, fmt.Errorf("invalid report path <%v>", report.Path)
+	}
+	account := url.PathEscape(accountID)
+	if account == "" || account == "." || account == ".." {
+		return ResultV1{}, fmt.Errorf("invalid account ID <%v>", accountID)
+	}
+	relDir := path.Join(account, report.Path)
+	dir := filepath.Join(w.dir, filepath.FromSlash(relDir))
+
+	snapshots, err := store.List(ctx, accountID)
+	if err != nil {
+		return ResultV1{}, fmt.Errorf("unable to list snapshots of account <%v>. err <%v>", accountID, err)
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if mErr := os.MkdirAll(filepath.Join(dir, imagesDir), dirPerm); mErr != nil {
+		return ResultV1{}, fmt.Errorf("unable to create report dir <%v>. err <%v>", relDir, mErr)
+	}
+
+	page := indexPage{
+		Title:       report.Title,
+		Description: report.Description,
+		JobID:       report.JobID,
+		GeneratedAt: formatTime(w.now()),
+	}
+	if page.Title == "" {
+		page.Title = name
+	}
+
+	images := make(map[string]bool)
+	for _, s := range snapshots {
+		if (report.JobID != "" && s.JobID != report.JobID) || s.RolledBack {
+			continue
+		}
+
+		image := s.SHA256 + "." + s.ImageExtension()
+		if !images[image] {
+			written, wErr := writeImage(ctx, store, s, filepath.Join(dir, imagesDir, image))
+			if wErr != nil {
+				return ResultV1{}, wErr
+			}
+			if !written {
+				//Removed by retention after it was listed
+				logger.Debugf("Snapshot <%v> no longer exists. Skipping it", s.ID)
+				continue
+			}
+			images[image] = true
+		}
+		page.Entries = append(page.Entries, newIndexEntry(s, path.Join(imagesDir, image)))
+	}
+
+	var buf bytes.Buffer
+	if tErr := indexTemplate.Execute(&buf, page); tErr != nil {
+		return ResultV1{}, fmt.Errorf("unable to render report index. err <%v>", tErr)
+	}
+	if wErr := writeFile(filepath.Join(dir, indexFile), buf.Bytes()); wErr != nil {
+		return ResultV1{}, fmt.Errorf("unable to write report index <%v>. err <%v>", relDir, wErr)
+	}
+
+	//Stale images don't affect the index. Failing to remove them is logged and retried on the next write
+	if rErr := removeStaleImages(filepath.Join(dir, imagesDir), images); rErr != nil {
+		logger.Warnf("Unable to remove stale images of report <%v>. <%v>", relDir, rErr)
+	}
+
+	logger.Debugf("Wrote report <%v> with <%v> snapshots", relDir, len(page.Entries))
+	return ResultV1{
+		Report:    name,
+		Index:     path.Join(relDir, indexFile),
+		Snapshots: len(page.Entries),
+	}, nil
+}
+
+//CheckWritable - Checks that files can be created in the reports directory
+func (w *Writer) CheckWritable() error {
+	tmp, err := ioutil.TempFile(w.dir, ".probe-")
+	if err != nil {
+		return fmt.Errorf("unable to create files in reports dir. err <%v>", err)
+	}
+	tmp.Close()
+	return os.Remove(tmp.Name())
+}
+
+//writeImage - copies the snapshot image from the store unless the report already has it. Returns false if the snapshot
+//no longer exists
+func writeImage(ctx context.Context, store *artifact.Store, s artifact.SnapshotV1, dest string) (bool, error) {
+	if _, err := os.Stat(dest); err == nil {
+		return true, nil
+	}
+	_, img, err := store.GetImage(ctx, s.AccountID, s.ID)
+	if err == artifact.ErrNotFound {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("unable to read image of snapshot <%v>. err <%v>", s.ID, err)
+	}
+	if wErr := writeFile(dest, img); wErr != nil {
+		return false, fmt.Errorf("unable to write image of snapshot <%v>. err <%v>", s.ID, wErr)
+	}
+	return true, nil
+}
+
+//removeStaleImages - removes images which aren't in keep
+func removeStaleImages(dir string, keep map[string]bool) error {
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+	for _, f := range files {
+		if f.IsDir() || !imageSuffixes[filepath.Ext(f.Name())] || keep[f.Name()] {
+			continue
+		}
+		if rErr := os.Remove(filepath.Join(dir, f.Name())); rErr != nil && !os.IsNotExist(rErr) {
+			return rErr
+		}
+	}
+	return nil
+}
+
+//writeFile - writes to a temporary file in the same directory and renames it into place so that readers never see a
+//partial index
+func writeFile(path string, data []byte) error {
+	tmp, err := ioutil.TempFile(filepath.Dir(path), ".tmp-")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, wErr := tmp.Write(data); wErr != nil {
+		tmp.Close()
+		return wErr
+	}
+	if cErr := tmp.Close(); cErr != nil {
+		return cErr
+	}
+	if pErr := os.Chmod(tmp.Name(), filePerm); pErr != nil {
+		return pErr
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+//formatRangeTime - formats epoch milliseconds as UTC times. Relative times such as now-7d are returned as is
+func formatRangeTime(val string) string {
+	ms, err := strconv.ParseInt(val, 10, 64)
+	if err != nil {
+		return val
+	}
+	return formatTime(time.Unix(0, ms*int64(time.Millisecond)))
+}
+
+func formatTime(t time.Time) string {
+	return t.UTC().Format("2006-01-02 15:04:05 UTC")
+}
@@ -0,0 +1,142 @@
+package report
+
+import (
+	"context"
+	"github.com/sajeevany/graph-snapper/internal/artifact"
+	"github.com/sajeevany/graph-snapper/internal/common"
+	"github.com/sajeevany/graph-snapper/internal/config"
+	"github.com/sirupsen/logrus"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWriter_Write(t *testing.T) {
+
+	dir, err := ioutil.TempDir("", "reports")
+	if err != nil {
+		t.Fatalf("SETUP FAILURE: Unable to create temp dir. err <%v>", err)
+	}
+	defer os.RemoveAll(dir)
+
+	writer, err := NewWriter(config.Reports{Dir: dir})
+	if err != nil {
+		t.Fatalf("NewWriter() unexpected error = %v", err)
+	}
+	writer.now = func() time.Time { return time.Date(2021, 3, 1, 12, 0, 0, 0, time.UTC) }
+
+	store := artifact.NewStoreWithBackend(artifact.NewMemoryBackend(), config.Artifacts{})
+	logger := logrus.NewEntry(logrus.New())
+	ctx := context.Background()
+
+	saves := []struct {
+		snapshot artifact.SnapshotV1
+		img      string
+	}{
+		{
+			snapshot: artifact.SnapshotV1{AccountID: "acct/1", JobID: "weekly", DashboardUID: "cpu01", DashboardTitle: "CPU", PanelID: 2,
+				PanelTitle: "<b>Load</b>", From: "1614556800000", To: "now", CapturedAt: time.Date(2021, 3, 1, 9, 0, 0, 0, time.UTC)},
+			img: "first",
+		},
+		{
+			snapshot: artifact.SnapshotV1{AccountID: "acct/1", JobID: "weekly", DashboardUID: "cpu01", PanelID: 3, From: "now-7d", To: "now",
+				CapturedAt: time.Date(2021, 3, 1, 10, 0, 0, 0, time.UTC)},
+			img: "first",
+		},
+		{
+			snapshot: artifact.SnapshotV1{AccountID: "acct/1", JobID: "daily", DashboardUID: "mem01", PanelID: 1, From: "now-1d", To: "now",
+				CapturedAt: time.Date(2021, 3, 1, 11, 0, 0, 0, time.UTC)},
+			img: "second",
+		},
+	}
+	for _, s := range saves {
+		if _, sErr := store.Save(ctx, logger, s.snapshot, []byte(s.img)); sErr != nil {
+			t.Fatalf("SETUP FAILURE: Unable to save snapshot. err <%v>", sErr)
+		}
+	}
+
+	//Every snapshot of the account is listed without a JobID
+	all, err := writer.Write(ctx, logger, store, "acct/1", "all", common.HTMLReportV1{Path: "ops/all"})
+	if err != nil {
+		t.Fatalf("Write() unexpected error = %v", err)
+	}
+	if all.Index != "acct%2F1/ops/all/index.html" || all.Snapshots != 3 {
+		t.Errorf("Write() = %+v", all)
+	}
+	if images := listImages(t, filepath.Join(dir, "acct%2F1", "ops", "all")); len(images) != 2 {
+		t.Errorf("Write() wrote images <%v>, want one per distinct image", images)
+	}
+
+	//Only the job's snapshots are listed with a JobID. Images of other jobs are removed
+	weeklyDir := filepath.Join(dir, "acct%2F1", "ops", "weekly")
+	if err := os.MkdirAll(filepath.Join(weeklyDir, imagesDir), dirPerm); err != nil {
+		t.Fatalf("SETUP FAILURE: Unable to create images dir. err <%v>", err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(weeklyDir, imagesDir, "stale.png"), []byte("stale"), filePerm); err != nil {
+		t.Fatalf("SETUP FAILURE: Unable to write stale image. err <%v>", err)
+	}
+	weekly, err := writer.Write(ctx, logger, store, "acct/1", "weekly", common.HTMLReportV1{Path: "ops/weekly", Title: "Weekly ops", JobID: "weekly"})
+	if err != nil {
+		t.Fatalf("Write() unexpected error = %v", err)
+	}
+	if weekly.Snapshots != 2 {
+		t.Errorf("Write() listed <%v> snapshots, want 2", weekly.Snapshots)
+	}
+	if images := listImages(t, weeklyDir); len(images) != 1 || images[0] == "stale.png" {
+		t.Errorf("Write() left images <%v>, want the weekly image only", images)
+	}
+
+	index, err := ioutil.ReadFile(filepath.Join(weeklyDir, indexFile))
+	if err != nil {
+		t.Fatalf("Unable to read index. err <%v>", err)
+	}
+	for _, want := range []string{
+		"<title>Weekly ops</title>",
+		"Generated 2021-03-01 12:00:00 UTC for job weekly",
+		"&lt;b&gt;Load&lt;/b&gt;",
+		"CPU | 2021-03-01 00:00:00 UTC to now | Captured 2021-03-01 09:00:00 UTC",
+		"<h2>Panel 3</h2>",
+		"cpu01 | now-7d to now",
+	} {
+		if !strings.Contains(string(index), want) {
+			t.Errorf("index doesn't contain <%v>\n%s", want, index)
+		}
+	}
+	if strings.Contains(string(index), "mem01") {
+		t.Errorf("index lists a snapshot of another job\n%s", index)
+	}
+}
+
+func TestWriter_WriteInvalidPath(t *testing.T) {
+
+	dir, err := ioutil.TempDir("", "reports")
+	if err != nil {
+		t.Fatalf("SETUP FAILURE: Unable to create temp dir. err <%v>", err)
+	}
+	defer os.RemoveAll(dir)
+
+	writer, err := NewWriter(config.Reports{Dir: dir})
+	if err != nil {
+		t.Fatalf("NewWriter() unexpected error = %v", err)
+	}
+	store := artifact.NewStoreWithBackend(artifact.NewMemoryBackend(), config.Artifacts{})
+
+	if _, err := writer.Write(context.Background(), logrus.NewEntry(logrus.New()), store, "acct1", "escape", common.HTMLReportV1{Path: "../escape"}); err == nil {
+		t.Errorf("Write() of a path outside the account dir didn't return an error")
+	}
+}
+
+func listImages(t *testing.T, reportDir string) []string {
+	files, err := ioutil.ReadDir(filepath.Join(reportDir, imagesDir))
+	if err != nil {
+		t.Fatalf("Unable to read images dir. err <%v>", err)
+	}
+	names := make([]string, len(files))
+	for i, f := range files {
+		names[i] = f.Name()
+	}
+	return names
+}
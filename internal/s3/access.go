@@ -0,0 +1,38 @@
+package s3
+
+import (
+	"context"
+	"github.com/google/uuid"
+	"github.com/sajeevany/graph-snapper/internal/common"
+	"github.com/sajeevany/graph-snapper/internal/upstream"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	//probeKeyPrefix - probe objects are written below the key template prefix so that prefix scoped policies are checked
+	probeKeyPrefix   = ".graph-snapper-probe-"
+	probeContentType = "text/plain"
+)
+
+//HasWriteAccess - Checks that the user can write to its bucket by writing and deleting a probe object. Returns false
+//without an error when S3 denies the write
+func HasWriteAccess(ctx context.Context, logger *logrus.Entry, client *upstream.Client, user common.S3UserV1) (bool, error) {
+
+	logger.Debug("Starting an S3 write access check")
+
+	key := user.KeyPrefix() + probeKeyPrefix + uuid.New().String()
+	if err := PutObject(ctx, logger, client, user, key, probeContentType, []byte("graph-snapper write check")); err != nil {
+		if upstream.IsAuthError(err) {
+			logger.Debugf("S3 denied the probe object write. <%v>", err)
+			return false, nil
+		}
+		return false, err
+	}
+
+	//The write succeeded. Failing to clean up the probe doesn't change the result
+	if err := DeleteObject(ctx, logger, client, user, key); err != nil {
+		logger.Warnf("Unable to delete S3 probe object <%v>. <%v>", key, err)
+	}
+
+	return true, nil
+}
@@ -0,0 +1,92 @@
+package s3
+
+import (
+	"context"
+	"github.com/sajeevany/graph-snapper/internal/common"
+	"github.com/sajeevany/graph-snapper/internal/config"
+	"github.com/sajeevany/graph-snapper/internal/test"
+	"github.com/sajeevany/graph-snapper/internal/upstream"
+	"github.com/sirupsen/logrus"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestHasWriteAccess(t *testing.T) {
+	tests := []struct {
+		name       string
+		putStatus  int
+		want       bool
+		wantErr    bool
+		wantDelete bool
+	}{
+		{
+			name:       "test0 bucket is writable",
+			putStatus:  http.StatusOK,
+			want:       true,
+			wantDelete: true,
+		},
+		{
+			name:      "test1 write is denied",
+			putStatus: http.StatusForbidden,
+			want:      false,
+		},
+		{
+			name:      "test2 bucket doesn't exist",
+			putStatus: http.StatusNotFound,
+			want:      false,
+			wantErr:   true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var putKey, deleteKey string
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if !strings.HasPrefix(r.Header.Get("Authorization"), "AWS4-HMAC-SHA256 Credential=minio/") ||
+					!strings.HasPrefix(r.URL.Path, "/snapshots/reports/") {
+					w.WriteHeader(http.StatusBadRequest)
+					return
+				}
+				switch r.Method {
+				case http.MethodPut:
+					putKey = r.URL.Path
+					w.WriteHeader(tt.putStatus)
+				case http.MethodDelete:
+					deleteKey = r.URL.Path
+					w.WriteHeader(http.StatusNoContent)
+				}
+			}))
+			defer server.Close()
+
+			got, err := HasWriteAccess(context.Background(), logrus.NewEntry(logrus.New()), upstream.New(config.NewConfWithDefaults().Upstream), testUser(t, server.URL))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("HasWriteAccess() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("HasWriteAccess() got = %v, want %v", got, tt.want)
+			}
+			if !strings.HasPrefix(putKey, "/snapshots/reports/"+probeKeyPrefix) {
+				t.Errorf("HasWriteAccess() wrote probe <%v>, want it below the key template prefix", putKey)
+			}
+			if tt.wantDelete && deleteKey != putKey {
+				t.Errorf("HasWriteAccess() deleted <%v>, want <%v>", deleteKey, putKey)
+			}
+		})
+	}
+}
+
+//testUser - path style MinIO user of the test server
+func testUser(t *testing.T, rawURL string) common.S3UserV1 {
+	host, port := test.SplitServerURL(t, rawURL)
+	return common.S3UserV1{
+		Host:            host,
+		Port:            port,
+		Region:          "us-east-1",
+		Bucket:          "snapshots",
+		PathStyle:       true,
+		AccessKeyID:     "minio",
+		SecretAccessKey: "minio123",
+		KeyTemplate:     "reports/{account}/{panel}.png",
+	}
+}
@@ -0,0 +1,112 @@
+package s3
+
+import (
+	"context"
+	"fmt"
+	"github.com/sajeevany/graph-snapper/internal/common"
+	"github.com/sajeevany/graph-snapper/internal/destination"
+	"github.com/sajeevany/graph-snapper/internal/plugin"
+	"github.com/sajeevany/graph-snapper/internal/upstream"
+	"github.com/sirupsen/logrus"
+)
+
+//DestinationType - destination type of S3 compatible object store users
+const DestinationType = "s3"
+
+//Destination - Uploads bundle images to a bucket under the keys built from the user's key template
+type Destination struct {
+	client *upstream.Client
+}
+
+//NewDestination - returns the S3 destination using the client for all calls
+func NewDestination(client *upstream.Client) *Destination {
+	return &Destination{client: client}
+}
+
+func (d *Destination) Type() string {
+	return DestinationType
+}
+
+func (d *Destination) NewCredential() destination.Credential {
+	return &common.S3UserV1{}
+}
+
+func (d *Destination) Validate(cred destination.Credential) error {
+	user, err := s3User(cred)
+	if err != nil {
+		return err
+	}
+	if !user.IsValid() {
+		return fmt.Errorf("S3 user <%#v> is invalid", user)
+	}
+	return nil
+}
+
+//CheckAccess - Checks that the user can write to its bucket with a probe object
+func (d *Destination) CheckAccess(ctx context.Context, logger *logrus.Entry, cred destination.Credential) plugin.CheckResultV1 {
+
+	user, err := s3User(cred)
+	if err != nil {
+		return plugin.Failed(err.Error())
+	}
+
+	hasWriteAccess, wErr := HasWriteAccess(ctx, logger, d.client, user)
+	if wErr != nil {
+		logger.WithFields(user.GetFields()).Errorf("Error checking if S3 user can write to its bucket. <%v>", wErr)
+		return plugin.Failed(wErr.Error())
+	}
+	if !hasWriteAccess {
+		logger.Debug("S3 denied the probe object write")
+		return plugin.Failed(fmt.Sprintf("Access denied. Unable to write objects to bucket %v", user.Bucket))
+	}
+
+	return plugin.Passed(nil)
+}
+
+//Publish - Writes each image of the bundle. Objects written before a failed upload are deleted
+func (d *Destination) Publish(ctx context.Context, logger *logrus.Entry, name string, cred destination.Credential, bundle destination.Bundle) (destination.ReceiptV1, error) {
+
+	user, err := s3User(cred)
+	if err != nil {
+		return destination.ReceiptV1{}, err
+	}
+
+	receipt := destination.ReceiptV1{Location: user.Bucket}
+	for _, item := range bundle.Items {
+		key := user.ObjectKey(keyValues(item.Snapshot))
+		if pErr := PutObject(ctx, logger, d.client, user, key, item.Snapshot.ContentType, item.Image); pErr != nil {
+			if rErr := d.deleteObjects(ctx, logger, user, receipt.IDs); rErr != nil {
+				logger.Errorf("Unable to delete objects of credential <%v> after a failed upload. <%v>", name, rErr)
+			}
+			return destination.ReceiptV1{}, pErr
+		}
+		receipt.IDs = append(receipt.IDs, key)
+	}
+	return receipt, nil
+}
+
+//Rollback - Deletes the objects written by the publish
+func (d *Destination) Rollback(ctx context.Context, logger *logrus.Entry, name string, cred destination.Credential, bundle destination.Bundle, receipt destination.ReceiptV1) error {
+	user, err := s3User(cred)
+	if err != nil {
+		return err
+	}
+	return d.deleteObjects(ctx, logger, user, receipt.IDs)
+}
+
+func (d *Destination) deleteObjects(ctx context.Context, logger *logrus.Entry, user common.S3UserV1, keys []string) error {
+	for _, key := range keys {
+		if err := DeleteObject(ctx, logger, d.client, user, key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func s3User(cred destination.Credential) (common.S3UserV1, error) {
+	user, ok := cred.(common.S3UserV1)
+	if !ok {
+		return common.S3UserV1{}, fmt.Errorf("credential <%T> isn't an S3 user", cred)
+	}
+	return user, nil
+}
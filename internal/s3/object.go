@@ -0,0 +1,121 @@
+package s3
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"github.com/sajeevany/graph-snapper/internal/artifact"
+	"github.com/sajeevany/graph-snapper/internal/common"
+	"github.com/sajeevany/graph-snapper/internal/upstream"
+	"github.com/sirupsen/logrus"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"time"
+)
+
+//ServiceName - name used for S3 in upstream errors and spans
+const ServiceName = "s3"
+
+//now - signing time. Replaced in tests
+var now = time.Now
+
+//Endpoint - returns the upstream connection settings of the S3 user
+func Endpoint(user common.S3UserV1) upstream.Endpoint {
+	host := user.Host
+	if !user.PathStyle {
+		host = user.Bucket + "." + user.Host
+	}
+	return upstream.Endpoint{
+		Service: ServiceName,
+		Host:    host,
+		Port:    user.Port,
+		TLS:     user.TLS,
+		Proxy:   user.Proxy,
+	}
+}
+
+//PutObject - Writes the object to the user's bucket
+func PutObject(ctx context.Context, logger *logrus.Entry, client *upstream.Client, user common.S3UserV1, key, contentType string, body []byte) error {
+
+	req, err := newRequest(ctx, http.MethodPut, user, key, body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", contentType)
+	signRequest(req, user.AccessKeyID, user.SecretAccessKey, user.Region, hashHex(body), now())
+
+	resp, err := client.Do(ctx, logger, Endpoint(user), "s3.PutObject", req)
+	if err != nil {
+		logger.Debugf("S3 put of object <%v> failed. <%v>", key, err)
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return readError(resp, key)
+	}
+	logger.Debugf("Wrote object <%v> to bucket <%v>", key, user.Bucket)
+	return nil
+}
+
+//DeleteObject - Deletes the object from the user's bucket. Deleting a missing object succeeds
+func DeleteObject(ctx context.Context, logger *logrus.Entry, client *upstream.Client, user common.S3UserV1, key string) error {
+
+	req, err := newRequest(ctx, http.MethodDelete, user, key, nil)
+	if err != nil {
+		return err
+	}
+	signRequest(req, user.AccessKeyID, user.SecretAccessKey, user.Region, hashHex(nil), now())
+
+	resp, err := client.Do(ctx, logger, Endpoint(user), "s3.DeleteObject", req)
+	if err != nil {
+		logger.Debugf("S3 delete of object <%v> failed. <%v>", key, err)
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusNoContent && resp.StatusCode != http.StatusOK {
+		return readError(resp, key)
+	}
+	return nil
+}
+
+func newRequest(ctx context.Context, method string, user common.S3UserV1, key string, body []byte) (*http.Request, error) {
+	var reader io.Reader
+	if body != nil {
+		reader = bytes.NewReader(body)
+	}
+	return http.NewRequestWithContext(ctx, method, user.BaseURL()+"/"+escapeKey(key), reader)
+}
+
+//readError - returns the S3 error code and message of a failed request. ie NoSuchBucket
+func readError(resp *http.Response, key string) error {
+	body, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 4096))
+	return &Error{Key: key, StatusCode: resp.StatusCode, Body: string(body)}
+}
+
+//Error - S3 responded with an unexpected status code
+type Error struct {
+	Key        string
+	StatusCode int
+	Body       string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("s3 request for object <%v> returned status code <%v>. <%v>", e.Key, e.StatusCode, e.Body)
+}
+
+//keyValues - returns the key template values of the snapshot
+func keyValues(snapshot artifact.SnapshotV1) common.S3KeyValuesV1 {
+	return common.S3KeyValuesV1{
+		Account:    snapshot.AccountID,
+		Job:        snapshot.JobID,
+		Dashboard:  snapshot.DashboardUID,
+		Panel:      snapshot.PanelID,
+		Snapshot:   snapshot.ID,
+		SHA256:     snapshot.SHA256,
+		Ext:        snapshot.ImageExtension(),
+		CapturedAt: snapshot.CapturedAt,
+	}
+}
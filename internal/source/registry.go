@@ -0,0 +1,61 @@
+package source
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/sajeevany/graph-snapper/internal/plugin"
+)
+
+var registry = plugin.NewRegistry("source")
+
+//Register - makes the source available by its type name. Panics if the type is empty or already registered
+func Register(s Source) {
+	if s == nil {
+		panic("source: Register source is nil")
+	}
+	registry.Register(s)
+}
+
+//Get - returns the source registered with the type name
+func Get(typeName string) (Source, bool) {
+	p, exists := registry.Get(typeName)
+	if !exists {
+		return nil, false
+	}
+	return p.(Source), true
+}
+
+//Types - returns the registered type names in sorted order
+func Types() []string {
+	return registry.Types()
+}
+
+//DecodeJSON - decodes a request body credential of the type
+func DecodeJSON(typeName string, data []byte) (Credential, error) {
+	return registry.DecodeJSON(typeName, data)
+}
+
+//DecodeBinMap - decodes a stored credential of the type. bm is the bin map returned by aerospike. Errors only name the
+//invalid fields so that stored secrets aren't logged
+func DecodeBinMap(typeName string, bm interface{}) (Credential, error) {
+	return registry.DecodeBinMap(typeName, bm)
+}
+
+//DecodeTarget - decodes a request body target of the type
+func DecodeTarget(typeName string, data []byte) (Target, error) {
+	p, err := registry.Lookup(typeName)
+	if err != nil {
+		return nil, err
+	}
+	val, err := registry.Decode(typeName, p.(Source).NewTarget(), func(target interface{}) error {
+		return json.Unmarshal(data, target)
+	})
+	if err != nil {
+		return nil, err
+	}
+	target, ok := val.(Target)
+	if !ok {
+		return nil, fmt.Errorf("source type <%v> target <%T> must implement Target with value receivers", typeName, val)
+	}
+	return target, nil
+}
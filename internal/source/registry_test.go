@@ -0,0 +1,168 @@
+package source
+
+import (
+	"context"
+	"github.com/sajeevany/graph-snapper/internal/plugin"
+	"github.com/sirupsen/logrus"
+	"reflect"
+	"testing"
+)
+
+type fakeCredential struct {
+	URL   string
+	Token string
+}
+
+func (c fakeCredential) GetFields() logrus.Fields {
+	return logrus.Fields{"URL": c.URL}
+}
+
+func (c fakeCredential) ToBinMap() map[string]interface{} {
+	return map[string]interface{}{"URL": c.URL, "Token": c.Token}
+}
+
+func (c fakeCredential) RedactedView() interface{} {
+	c.Token = ""
+	return c
+}
+
+type fakeTarget struct {
+	Query string
+}
+
+func (t fakeTarget) IsValid(currentPath string, invalidArgs map[string]string) bool {
+	return t.Query != ""
+}
+
+type fakeSource struct {
+	typeName string
+}
+
+func (s fakeSource) Type() string {
+	return s.typeName
+}
+
+func (s fakeSource) NewCredential() Credential {
+	return &fakeCredential{}
+}
+
+func (s fakeSource) Validate(cred Credential) error {
+	return nil
+}
+
+func (s fakeSource) CheckAccess(ctx context.Context, logger *logrus.Entry, cred Credential) plugin.CheckResultV1 {
+	return plugin.Passed(nil)
+}
+
+func (s fakeSource) ListDashboards(ctx context.Context, logger *logrus.Entry, cred Credential, query string) ([]DashboardV1, error) {
+	return nil, nil
+}
+
+func (s fakeSource) NewTarget() Target {
+	return &fakeTarget{}
+}
+
+func (s fakeSource) Render(ctx context.Context, logger *logrus.Entry, cred Credential, target Target) (RenderV1, error) {
+	return RenderV1{}, nil
+}
+
+func TestRegister(t *testing.T) {
+	tests := []struct {
+		name      string
+		source    Source
+		wantPanic bool
+	}{
+		{
+			name:   "test0 new type",
+			source: fakeSource{typeName: "fake-register"},
+		},
+		{
+			name:      "test1 duplicate type",
+			source:    fakeSource{typeName: "fake-register"},
+			wantPanic: true,
+		},
+		{
+			name:      "test2 empty type",
+			source:    fakeSource{},
+			wantPanic: true,
+		},
+		{
+			name:      "test3 nil source",
+			wantPanic: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			defer func() {
+				if r := recover(); (r != nil) != tt.wantPanic {
+					t.Errorf("Register() panic = %v, wantPanic %v", r, tt.wantPanic)
+				}
+			}()
+			Register(tt.source)
+		})
+	}
+
+	if _, exists := Get("fake-register"); !exists {
+		t.Errorf("Get() didn't return the registered source")
+	}
+}
+
+func TestDecode(t *testing.T) {
+
+	Register(fakeSource{typeName: "fake-decode"})
+	cred := fakeCredential{URL: "http://prometheus:9090", Token: "secret"}
+
+	tests := []struct {
+		name    string
+		decode  func() (interface{}, error)
+		want    interface{}
+		wantErr bool
+	}{
+		{
+			name: "test0 request body credential",
+			decode: func() (interface{}, error) {
+				return DecodeJSON("fake-decode", []byte(`{"URL":"http://prometheus:9090","Token":"secret"}`))
+			},
+			want: cred,
+		},
+		{
+			name: "test1 stored bin map",
+			decode: func() (interface{}, error) {
+				return DecodeBinMap("fake-decode", cred.ToBinMap())
+			},
+			want: cred,
+		},
+		{
+			name: "test2 target",
+			decode: func() (interface{}, error) {
+				return DecodeTarget("fake-decode", []byte(`{"Query":"up"}`))
+			},
+			want: fakeTarget{Query: "up"},
+		},
+		{
+			name: "test3 invalid target",
+			decode: func() (interface{}, error) {
+				return DecodeTarget("fake-decode", []byte(`{"Query":1}`))
+			},
+			wantErr: true,
+		},
+		{
+			name: "test4 unknown type",
+			decode: func() (interface{}, error) {
+				return DecodeTarget("fake-unknown", []byte(`{}`))
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.decode()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("decode() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("decode() got = %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,96 @@
+package source
+
+import (
+	"context"
+	"errors"
+	"github.com/sajeevany/graph-snapper/internal/artifact"
+	"github.com/sajeevany/graph-snapper/internal/plugin"
+	"github.com/sirupsen/logrus"
+)
+
+//Source - Graph service snapshots are captured from. Implementations are registered by type name and own the clients
+//they need so that handlers only deal with stored credentials
+type Source interface {
+	//Type - unique name the source is registered and its credentials are stored under. ie grafana
+	Type() string
+	//NewCredential - returns a pointer to an empty credential which request bodies and stored bin maps are decoded into
+	NewCredential() Credential
+	//Validate - returns an error describing why the credential can't be stored. Connectivity isn't checked
+	Validate(cred Credential) error
+	//CheckAccess - checks that the credential can read from the source. Failures are reported in the result
+	CheckAccess(ctx context.Context, logger *logrus.Entry, cred Credential) plugin.CheckResultV1
+	//ListDashboards - returns the dashboards visible to the credential which match query. An empty query matches all
+	ListDashboards(ctx context.Context, logger *logrus.Entry, cred Credential, query string) ([]DashboardV1, error)
+	//NewTarget - returns a pointer to an empty target which capture requests are decoded into
+	NewTarget() Target
+	//Render - renders the target to an image
+	Render(ctx context.Context, logger *logrus.Entry, cred Credential, target Target) (RenderV1, error)
+}
+
+//Credential - Settings and secrets of a source
+type Credential = plugin.Credential
+
+//Target - What a source renders. ie a grafana dashboard panel or a prometheus query
+type Target interface {
+	//IsValid - adds an entry per invalid field to invalidArgs
+	IsValid(currentPath string, invalidArgs map[string]string) bool
+}
+
+//DashboardV1 - Dashboard or other renderable item listed by a source
+type DashboardV1 struct {
+	UID   string
+	Title string
+	URL   string   `json:"URL,omitempty"`
+	Tags  []string `json:"Tags,omitempty"`
+}
+
+//RenderV1 - Rendered image and the details recorded with its snapshot
+type RenderV1 struct {
+	Image          []byte
+	ContentType    string
+	DashboardUID   string
+	DashboardTitle string
+	PanelID        int
+	PanelTitle     string
+	From           string //Time range the image was rendered with. ie now-7d or epoch milliseconds
+	To             string
+	Exports        []artifact.DataExport //Optional. Query results stored with the image
+}
+
+//TargetError - The target can't be rendered as requested. ie it references variables or queries the source rejects
+type TargetError struct {
+	Err error
+}
+
+func (e *TargetError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *TargetError) Unwrap() error {
+	return e.Err
+}
+
+//IsTargetError - returns true if err, or any error it wraps, is a TargetError
+func IsTargetError(err error) bool {
+	var tErr *TargetError
+	return errors.As(err, &tErr)
+}
+
+//NotFoundError - The source doesn't have what the request referenced or it isn't visible to the credential
+type NotFoundError struct {
+	Err error
+}
+
+func (e *NotFoundError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *NotFoundError) Unwrap() error {
+	return e.Err
+}
+
+//IsNotFound - returns true if err, or any error it wraps, is a NotFoundError
+func IsNotFound(err error) bool {
+	var nErr *NotFoundError
+	return errors.As(err, &nErr)
+}
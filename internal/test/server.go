@@ -0,0 +1,20 @@
+package test
+
+import (
+	"net/url"
+	"strconv"
+	"testing"
+)
+
+//SplitServerURL - returns the host and port of a test server url, ie httptest.Server.URL
+func SplitServerURL(t *testing.T, rawURL string) (string, int) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		t.Fatalf("Unable to parse test server url <%v>. err <%v>", rawURL, err)
+	}
+	port, err := strconv.Atoi(u.Port())
+	if err != nil {
+		t.Fatalf("Unable to parse test server port <%v>. err <%v>", u.Port(), err)
+	}
+	return u.Hostname(), port
+}
@@ -0,0 +1,53 @@
+package middleware
+
+import (
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"github.com/sajeevany/graph-snapper/internal/tracing"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.17.0"
+	"go.opentelemetry.io/otel/trace"
+	"net/http"
+)
+
+//TraceRequest - Starts a server span per request. Joins any W3C trace context sent by the caller and stores the span
+//in the request context so that handlers can create child spans from ctx.Request.Context()
+func TraceRequest() gin.HandlerFunc {
+	return func(c *gin.Context) {
+
+		//Join caller trace if one was provided
+		parentCtx := otel.GetTextMapPropagator().Extract(c.Request.Context(), propagation.HeaderCarrier(c.Request.Header))
+
+		//Use the route template rather than the raw path to keep span names low cardinality
+		route := c.FullPath()
+		if route == "" {
+			route = "unmatched route"
+		}
+
+		ctx, span := otel.Tracer(tracing.TracerName).Start(parentCtx, fmt.Sprintf("%s %s", c.Request.Method, route),
+			trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(
+				semconv.HTTPMethodKey.String(c.Request.Method),
+				semconv.HTTPRouteKey.String(route),
+				semconv.HTTPTargetKey.String(c.Request.URL.Path),
+				semconv.HTTPUserAgentKey.String(c.Request.UserAgent()),
+			))
+		defer span.End()
+
+		// Process request
+		c.Request = c.Request.WithContext(ctx)
+		c.Next()
+
+		//Record result
+		status := c.Writer.Status()
+		span.SetAttributes(semconv.HTTPStatusCodeKey.Int(status))
+		if status >= http.StatusInternalServerError {
+			span.SetStatus(codes.Error, http.StatusText(status))
+		}
+		if len(c.Errors) > 0 {
+			span.RecordError(c.Errors.Last())
+		}
+	}
+}
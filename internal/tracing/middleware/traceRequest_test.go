@@ -0,0 +1,91 @@
+package middleware
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/sajeevany/graph-snapper/internal/tracing"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	semconv "go.opentelemetry.io/otel/semconv/v1.17.0"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestTraceRequest(t *testing.T) {
+
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+
+	const callerTraceID = "4bf92f3577b34da6a3ce929d0e0e4736"
+
+	tests := []struct {
+		name          string
+		traceparent   string
+		status        int
+		wantName      string
+		wantTraceID   string
+		wantHasParent bool
+	}{
+		{
+			name:          "test0 joins caller trace",
+			traceparent:   "00-" + callerTraceID + "-00f067aa0ba902b7-01",
+			status:        http.StatusOK,
+			wantName:      "GET /api/v1/account/:id",
+			wantTraceID:   callerTraceID,
+			wantHasParent: true,
+		},
+		{
+			name:          "test1 starts new trace without caller context",
+			status:        http.StatusInternalServerError,
+			wantName:      "GET /api/v1/account/:id",
+			wantHasParent: false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			//Record spans in memory
+			recorder := tracetest.NewSpanRecorder()
+			otel.SetTracerProvider(tracing.NewProvider("test", 1, trace.WithSpanProcessor(recorder)))
+
+			gin.SetMode(gin.TestMode)
+			w := httptest.NewRecorder()
+			_, r := gin.CreateTestContext(w)
+			r.Use(TraceRequest())
+			r.GET("/api/v1/account/:id", func(ctx *gin.Context) {
+				ctx.Status(tt.status)
+			})
+
+			req, _ := http.NewRequest(http.MethodGet, "/api/v1/account/abc", nil)
+			if tt.traceparent != "" {
+				req.Header.Set("traceparent", tt.traceparent)
+			}
+			r.ServeHTTP(w, req)
+
+			spans := recorder.Ended()
+			if len(spans) != 1 {
+				t.Fatalf("Expected 1 ended span but got <%v>", len(spans))
+			}
+			span := spans[0]
+			if span.Name() != tt.wantName {
+				t.Errorf("Span name = <%v>, want <%v>", span.Name(), tt.wantName)
+			}
+			if tt.wantTraceID != "" && span.SpanContext().TraceID().String() != tt.wantTraceID {
+				t.Errorf("Trace ID = <%v>, want <%v>", span.SpanContext().TraceID(), tt.wantTraceID)
+			}
+			if span.Parent().IsValid() != tt.wantHasParent {
+				t.Errorf("Span has parent = <%v>, want <%v>", span.Parent().IsValid(), tt.wantHasParent)
+			}
+
+			foundStatus := false
+			for _, attr := range span.Attributes() {
+				if attr.Key == semconv.HTTPStatusCodeKey && attr.Value.AsInt64() == int64(tt.status) {
+					foundStatus = true
+				}
+			}
+			if !foundStatus {
+				t.Errorf("Expected status code attribute <%v> in <%v>", tt.status, span.Attributes())
+			}
+		})
+	}
+}
@@ -0,0 +1,120 @@
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"github.com/sajeevany/graph-snapper/internal/config"
+	"github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.17.0"
+	"go.opentelemetry.io/otel/trace"
+	"net/http"
+	"os"
+	"strings"
+)
+
+//TracerName - instrumentation name used for all spans created by graph-snapper
+const TracerName = "github.com/sajeevany/graph-snapper"
+
+//ShutdownFunc - flushes and stops the configured exporter
+type ShutdownFunc func(ctx context.Context) error
+
+//Init - Sets the global tracer provider and W3C trace-context propagator using the tracing config. When tracing is
+//disabled the global no-op tracer provider is kept so that instrumented code paths remain cheap.
+func Init(logger *logrus.Logger, conf config.Tracing) (ShutdownFunc, error) {
+
+	//Always propagate trace context so that upstream services can join traces started by callers
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+
+	if !conf.Enabled || strings.ToLower(conf.Exporter) == config.NoneTraceExporter {
+		logger.WithFields(conf.GetFields()).Info("Tracing is disabled. Using no-op tracer provider")
+		return func(ctx context.Context) error { return nil }, nil
+	}
+
+	exporter, err := newExporter(conf)
+	if err != nil {
+		logger.WithFields(conf.GetFields()).Errorf("Unable to create trace exporter. err <%v>", err)
+		return nil, err
+	}
+
+	tp := NewProvider(conf.ServiceName, conf.SampleRatio, sdktrace.WithBatcher(exporter))
+	otel.SetTracerProvider(tp)
+	logger.WithFields(conf.GetFields()).Info("Tracing is enabled")
+
+	return tp.Shutdown, nil
+}
+
+//NewProvider - Returns an sdk tracer provider for the service. Additional options such as span processors are appended
+func NewProvider(serviceName string, sampleRatio float64, opts ...sdktrace.TracerProviderOption) *sdktrace.TracerProvider {
+	res := resource.NewWithAttributes(semconv.SchemaURL, semconv.ServiceName(serviceName))
+	opts = append([]sdktrace.TracerProviderOption{
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(sampleRatio))),
+	}, opts...)
+	return sdktrace.NewTracerProvider(opts...)
+}
+
+func newExporter(conf config.Tracing) (sdktrace.SpanExporter, error) {
+	switch strings.ToLower(conf.Exporter) {
+	case config.StdoutTraceExporter:
+		return stdouttrace.New(stdouttrace.WithWriter(os.Stdout))
+	case config.OTLPTraceExporter:
+		opts := []otlptracehttp.Option{otlptracehttp.WithEndpoint(conf.Endpoint)}
+		if conf.URLPath != "" {
+			opts = append(opts, otlptracehttp.WithURLPath(conf.URLPath))
+		}
+		if conf.Insecure {
+			opts = append(opts, otlptracehttp.WithInsecure())
+		}
+		return otlptracehttp.New(context.Background(), opts...)
+	default:
+		return nil, fmt.Errorf("unsupported trace exporter <%v>", conf.Exporter)
+	}
+}
+
+//StartSpan - Starts a child span of any span found in ctx using the global tracer provider
+func StartSpan(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return otel.Tracer(TracerName).Start(ctx, name, trace.WithAttributes(attrs...))
+}
+
+//StartClientSpan - Starts a client span for an outgoing request and injects its trace context into the request headers
+func StartClientSpan(ctx context.Context, name string, req *http.Request, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	attrs = append(attrs,
+		semconv.HTTPMethodKey.String(req.Method),
+		semconv.HTTPURLKey.String(req.URL.String()))
+	ctx, span := otel.Tracer(TracerName).Start(ctx, name, trace.WithSpanKind(trace.SpanKindClient), trace.WithAttributes(attrs...))
+	InjectHeaders(ctx, req.Header)
+	return ctx, span
+}
+
+//InjectHeaders - Adds the W3C trace context of ctx to the http headers
+func InjectHeaders(ctx context.Context, header http.Header) {
+	otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(header))
+}
+
+//EndSpan - Records err, if present, and ends the span
+func EndSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+//SetResponseStatus - Records the http status code of an upstream response on the span
+func SetResponseStatus(span trace.Span, resp *http.Response) {
+	if resp == nil {
+		return
+	}
+	span.SetAttributes(semconv.HTTPStatusCodeKey.Int(resp.StatusCode))
+	if resp.StatusCode >= http.StatusInternalServerError {
+		span.SetStatus(codes.Error, resp.Status)
+	}
+}
@@ -0,0 +1,231 @@
+package upstream
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"github.com/sajeevany/graph-snapper/internal/common"
+	"github.com/sajeevany/graph-snapper/internal/config"
+	"github.com/sajeevany/graph-snapper/internal/redact"
+	"github.com/sajeevany/graph-snapper/internal/tracing"
+	"github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel/attribute"
+	semconv "go.opentelemetry.io/otel/semconv/v1.17.0"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+)
+
+//Endpoint - connection settings of the upstream service a request is sent to
+type Endpoint struct {
+	Service string //Name used in errors and spans. ie grafana
+	Host    string
+	Port    int
+	TLS     common.TLSConfigV1
+	Proxy   string //Optional proxy url. HTTPS_PROXY, HTTP_PROXY and NO_PROXY are used when empty
+}
+
+//Client - Shared http client for upstream services. Connections are pooled per TLS and proxy configuration
+type Client struct {
+	conf config.Upstream
+
+	mu      sync.Mutex
+	clients map[string]*http.Client
+
+	//tokens - oauth2 access tokens shared by all requests
+	tokens *oauth2TokenCache
+}
+
+//New - Returns an upstream client using the configured timeouts, retries and pool sizes
+func New(conf config.Upstream) *Client {
+	return &Client{
+		conf:    conf,
+		clients: make(map[string]*http.Client),
+		tokens:  newOAuth2TokenCache(),
+	}
+}
+
+//Do - Sends the request to the endpoint within a client span. The configured timeout is applied unless the caller's
+//deadline is sooner. Idempotent requests are retried after network and server errors. Network, 401/403 and 5xx
+//failures are returned as *Error. The start of a 5xx response body is kept on the error. Any other response is returned and must be closed by the caller. A cached oauth2
+//access token rejected with a 401 is removed from the cache
+func (c *Client) Do(ctx context.Context, logger *logrus.Entry, ep Endpoint, spanName string, req *http.Request) (*http.Response, error) {
+	return c.do(ctx, logger, ep, spanName, req, isIdempotent(req.Method))
+}
+
+//do - sends the request like Do. The request is only retried if retry is set
+func (c *Client) do(ctx context.Context, logger *logrus.Entry, ep Endpoint, spanName string, req *http.Request, retry bool) (resp *http.Response, err error) {
+
+	client, err := c.httpClient(ep)
+	if err != nil {
+		logger.Debugf("Unable to create http client for <%v>. <%v>", ep.Service, err)
+		return nil, err
+	}
+
+	//Apply deadline. The context is cancelled once the response body is closed
+	ctx, cancel := context.WithTimeout(ctx, time.Duration(c.conf.TimeoutMS)*time.Millisecond)
+	defer func() {
+		if err != nil {
+			cancel()
+		}
+	}()
+
+	//Start span and propagate trace context to the upstream service
+	ctx, span := tracing.StartClientSpan(ctx, spanName, req,
+		semconv.NetPeerNameKey.String(ep.Host),
+		semconv.NetPeerPortKey.Int(ep.Port))
+	defer func() { tracing.EndSpan(span, err) }()
+
+	attempts := 1
+	if retry {
+		attempts += c.conf.Retries
+	}
+
+	backoff := time.Duration(c.conf.RetryBackoffMS) * time.Millisecond
+	for attempt := 1; ; attempt++ {
+
+		resp, err = send(ctx, client, ep, req)
+		retryable := (err != nil && !isCertificateError(err)) || (err == nil && resp.StatusCode >= http.StatusInternalServerError)
+		if !retryable || attempt >= attempts || ctx.Err() != nil {
+			span.SetAttributes(attribute.Int("http.attempts", attempt))
+			break
+		}
+
+		//Discard failed response and wait before retrying
+		if resp != nil {
+			io.Copy(ioutil.Discard, resp.Body)
+			resp.Body.Close()
+		}
+		logger.Debugf("%v request attempt <%v/%v> failed. Retrying in <%v>. <%v>", ep.Service, attempt, attempts, backoff, describe(resp, err))
+		select {
+		case <-time.After(backoff):
+		case <-ctx.Done():
+		}
+		backoff *= 2
+	}
+
+	if err != nil {
+		return nil, &Error{Kind: NetworkErrorKind, Service: ep.Service, Err: err}
+	}
+	tracing.SetResponseStatus(span, resp)
+
+	switch {
+	case resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden:
+		resp.Body.Close()
+		if resp.StatusCode == http.StatusUnauthorized {
+			c.tokens.invalidate(bearerToken(req))
+		}
+		return nil, &Error{Kind: AuthErrorKind, Service: ep.Service, StatusCode: resp.StatusCode}
+	case resp.StatusCode >= http.StatusInternalServerError:
+		body, _ := ioutil.ReadAll(io.LimitReader(resp.Body, maxErrorBodySize))
+		resp.Body.Close()
+		return nil, &Error{Kind: ServerErrorKind, Service: ep.Service, StatusCode: resp.StatusCode, Body: body}
+	}
+
+	resp.Body = &cancelOnClose{ReadCloser: resp.Body, cancel: cancel}
+	return resp, nil
+}
+
+func send(ctx context.Context, client *http.Client, ep Endpoint, req *http.Request) (*http.Response, error) {
+	attemptReq := req.Clone(ctx)
+	if req.GetBody != nil {
+		body, err := req.GetBody()
+		if err != nil {
+			return nil, err
+		}
+		attemptReq.Body = body
+	}
+	return client.Do(attemptReq)
+}
+
+//httpClient - returns the pooled client for the endpoint's TLS and proxy settings
+func (c *Client) httpClient(ep Endpoint) (*http.Client, error) {
+
+	key := poolKey(ep)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if client, exists := c.clients[key]; exists {
+		return client, nil
+	}
+
+	client, err := newHTTPClient(c.conf, ep)
+	if err != nil {
+		return nil, err
+	}
+	c.clients[key] = client
+
+	return client, nil
+}
+
+func newHTTPClient(conf config.Upstream, ep Endpoint) (*http.Client, error) {
+
+	tc, err := ep.TLS.ToTLSConfig()
+	if err != nil {
+		return nil, fmt.Errorf("invalid TLS configuration. err <%v>", err)
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = tc
+	transport.MaxIdleConnsPerHost = conf.MaxIdleConnsPerHost
+	transport.IdleConnTimeout = time.Duration(conf.IdleConnTimeoutMS) * time.Millisecond
+
+	if ep.Proxy != "" {
+		proxyURL, pErr := url.Parse(ep.Proxy)
+		if pErr != nil {
+			return nil, fmt.Errorf("invalid proxy url <%v>", redact.URL(ep.Proxy))
+		}
+		transport.Proxy = http.ProxyURL(proxyURL)
+	}
+
+	return &http.Client{Transport: transport}, nil
+}
+
+//poolKey - hash of the settings that require a separate transport. Hashing avoids keeping keys readable in the map
+func poolKey(ep Endpoint) string {
+	sum := sha256.Sum256([]byte(fmt.Sprintf("%q|%v|%q|%q|%q", ep.TLS.CACert, ep.TLS.InsecureSkipVerify, ep.TLS.ClientCert, ep.TLS.ClientKey, ep.Proxy)))
+	return hex.EncodeToString(sum[:])
+}
+
+func isIdempotent(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodPut, http.MethodDelete:
+		return true
+	default:
+		return false
+	}
+}
+
+//isCertificateError - returns true if the server certificate was rejected. Retrying won't change the result
+func isCertificateError(err error) bool {
+	var unknownAuthority x509.UnknownAuthorityError
+	var invalidCert x509.CertificateInvalidError
+	var hostname x509.HostnameError
+	return errors.As(err, &unknownAuthority) || errors.As(err, &invalidCert) || errors.As(err, &hostname)
+}
+
+func describe(resp *http.Response, err error) string {
+	if err != nil {
+		return err.Error()
+	}
+	return resp.Status
+}
+
+//cancelOnClose - releases the request deadline once the caller is done with the response body
+type cancelOnClose struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (c *cancelOnClose) Close() error {
+	err := c.ReadCloser.Close()
+	c.cancel()
+	return err
+}
@@ -0,0 +1,241 @@
+package upstream
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"github.com/sajeevany/graph-snapper/internal/common"
+	"github.com/sajeevany/graph-snapper/internal/config"
+	"github.com/sirupsen/logrus"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// TestNewHTTPClient_MutualTLS - Validates that the configured client certificate is presented to the upstream server
+func TestClient_Do_MutualTLS(t *testing.T) {
+
+	clientCert, clientKey, clientPool := newTestClientCert(t)
+
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	server.TLS = &tls.Config{ClientAuth: tls.RequireAndVerifyClientCert, ClientCAs: clientPool}
+	server.StartTLS()
+	defer server.Close()
+	caCert := string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw}))
+
+	tests := []struct {
+		name    string
+		conf    common.TLSConfigV1
+		wantErr bool
+	}{
+		{
+			name:    "Test0 - Client certificate is presented",
+			conf:    common.TLSConfigV1{CACert: caCert, ClientCert: clientCert, ClientKey: clientKey},
+			wantErr: false,
+		},
+		{
+			name:    "Test1 - Missing client certificate is rejected by server",
+			conf:    common.TLSConfigV1{CACert: caCert},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := New(testConf)
+			resp, err := client.Do(context.Background(), testLogger(), Endpoint{Service: "test", TLS: tt.conf}, "test", newGetRequest(t, server.URL))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Do() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil {
+				resp.Body.Close()
+			} else if !IsNetworkError(err) {
+				t.Errorf("Do() error = %v, want network error", err)
+			}
+		})
+	}
+}
+
+func TestClient_Do_InvalidKeyPair(t *testing.T) {
+	clientCert, _, _ := newTestClientCert(t)
+	ep := Endpoint{Service: "test", TLS: common.TLSConfigV1{ClientCert: clientCert, ClientKey: "not a key"}}
+	if _, err := New(testConf).Do(context.Background(), testLogger(), ep, "test", newGetRequest(t, "https://127.0.0.1:1")); err == nil {
+		t.Errorf("Do() expected error for invalid client key")
+	}
+}
+
+// TestClient_Do_Errors - Validates retries of idempotent requests and classification of failures
+func TestClient_Do_Errors(t *testing.T) {
+
+	tests := []struct {
+		name         string
+		method       string
+		statuses     []int //Returned in order. The last status is repeated
+		wantStatus   int
+		wantKind     ErrorKind
+		wantAttempts int
+	}{
+		{name: "Test0 - Success", method: http.MethodGet, statuses: []int{http.StatusOK}, wantStatus: http.StatusOK, wantAttempts: 1},
+		{name: "Test1 - Retried server error recovers", method: http.MethodGet, statuses: []int{http.StatusServiceUnavailable, http.StatusOK}, wantStatus: http.StatusOK, wantAttempts: 2},
+		{name: "Test2 - Server error after all retries", method: http.MethodGet, statuses: []int{http.StatusBadGateway}, wantKind: ServerErrorKind, wantAttempts: 3},
+		{name: "Test3 - Non idempotent request isn't retried", method: http.MethodPost, statuses: []int{http.StatusServiceUnavailable}, wantKind: ServerErrorKind, wantAttempts: 1},
+		{name: "Test4 - Unauthorized", method: http.MethodGet, statuses: []int{http.StatusUnauthorized}, wantKind: AuthErrorKind, wantAttempts: 1},
+		{name: "Test5 - Forbidden", method: http.MethodGet, statuses: []int{http.StatusForbidden}, wantKind: AuthErrorKind, wantAttempts: 1},
+		{name: "Test6 - Not found is returned to caller", method: http.MethodGet, statuses: []int{http.StatusNotFound}, wantStatus: http.StatusNotFound, wantAttempts: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var attempts int32
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				i := int(atomic.AddInt32(&attempts, 1)) - 1
+				if i >= len(tt.statuses) {
+					i = len(tt.statuses) - 1
+				}
+				w.WriteHeader(tt.statuses[i])
+				w.Write([]byte("status " + strconv.Itoa(tt.statuses[i])))
+			}))
+			defer server.Close()
+
+			req, _ := http.NewRequest(tt.method, server.URL, strings.NewReader("body"))
+			resp, err := New(testConf).Do(context.Background(), testLogger(), Endpoint{Service: "test"}, "test", req)
+
+			if int(atomic.LoadInt32(&attempts)) != tt.wantAttempts {
+				t.Errorf("attempts = %v, want %v", attempts, tt.wantAttempts)
+			}
+			if tt.wantKind != "" {
+				if !isKind(err, tt.wantKind) {
+					t.Errorf("Do() error = %v, want kind %v", err, tt.wantKind)
+				}
+				//Server errors keep the response body for callers which report upstream error details
+				var uErr *Error
+				if tt.wantKind == ServerErrorKind && (!errors.As(err, &uErr) || string(uErr.Body) != "status "+strconv.Itoa(tt.statuses[len(tt.statuses)-1])) {
+					t.Errorf("Do() error body = %q", uErr.Body)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Do() unexpected error = %v", err)
+			}
+			defer resp.Body.Close()
+			if resp.StatusCode != tt.wantStatus {
+				t.Errorf("Do() status = %v, want %v", resp.StatusCode, tt.wantStatus)
+			}
+		})
+	}
+}
+
+// TestClient_Do_Deadline - Validates that the caller's deadline is applied to a hung upstream service
+func TestClient_Do_Deadline(t *testing.T) {
+
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer server.Close()
+	defer close(release)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	_, err := New(testConf).Do(ctx, testLogger(), Endpoint{Service: "test"}, "test", newGetRequest(t, server.URL))
+	if !IsNetworkError(err) {
+		t.Errorf("Do() error = %v, want network error", err)
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("Do() returned after <%v>. Expected the caller's deadline to be applied", elapsed)
+	}
+}
+
+// TestClient_Do_Proxy - Validates that the endpoint proxy is used and that clients are pooled per proxy
+func TestClient_Do_Proxy(t *testing.T) {
+
+	var proxied string
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		proxied = r.URL.String()
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer proxy.Close()
+
+	client := New(testConf)
+	ep := Endpoint{Service: "test", Proxy: proxy.URL}
+	for i := 0; i < 2; i++ {
+		resp, err := client.Do(context.Background(), testLogger(), ep, "test", newGetRequest(t, "http://grafana.invalid:3000/api/login/ping"))
+		if err != nil {
+			t.Fatalf("Do() unexpected error = %v", err)
+		}
+		resp.Body.Close()
+	}
+
+	if proxied != "http://grafana.invalid:3000/api/login/ping" {
+		t.Errorf("proxy received <%v>, want request for grafana.invalid", proxied)
+	}
+	if len(client.clients) != 1 {
+		t.Errorf("pooled clients = %v, want 1", len(client.clients))
+	}
+}
+
+var testConf = config.Upstream{
+	TimeoutMS:           5000,
+	Retries:             2,
+	RetryBackoffMS:      1,
+	MaxIdleConnsPerHost: 2,
+	IdleConnTimeoutMS:   1000,
+}
+
+func testLogger() *logrus.Entry {
+	return logrus.NewEntry(logrus.New())
+}
+
+func newGetRequest(t *testing.T, url string) *http.Request {
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		t.Fatalf("Unable to create request. err <%v>", err)
+	}
+	return req
+}
+
+// newTestClientCert - returns a PEM encoded self signed client certificate, its key and a pool trusting it
+func newTestClientCert(t *testing.T) (string, string, *x509.CertPool) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("Unable to generate key. err <%v>", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "graph-snapper-test"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("Unable to create certificate. err <%v>", err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatalf("Unable to marshal key. err <%v>", err)
+	}
+	cert, _ := x509.ParseCertificate(der)
+	pool := x509.NewCertPool()
+	pool.AddCert(cert)
+
+	return string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})),
+		string(pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})),
+		pool
+}
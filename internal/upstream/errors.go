@@ -0,0 +1,58 @@
+package upstream
+
+import (
+	"errors"
+	"fmt"
+)
+
+//maxErrorBodySize - bytes of a server error's response body kept on the error
+const maxErrorBodySize = 64 * 1024
+
+//ErrorKind - category of an upstream call failure
+type ErrorKind string
+
+const (
+	NetworkErrorKind ErrorKind = "network" //No response was received. ie connection refused, TLS failure or deadline exceeded
+	AuthErrorKind    ErrorKind = "auth"    //Upstream rejected the credentials with a 401 or 403
+	ServerErrorKind  ErrorKind = "server"  //Upstream responded with a 5xx after all retries
+)
+
+//Error - failed call to an upstream service
+type Error struct {
+	Kind       ErrorKind
+	Service    string
+	StatusCode int    //0 for network errors
+	Body       []byte //Start of the response body of server errors. Not included in Error() since it may echo request details
+	Err        error
+}
+
+func (e *Error) Error() string {
+	if e.StatusCode != 0 {
+		return fmt.Sprintf("%v %v error. status code <%v>", e.Service, e.Kind, e.StatusCode)
+	}
+	return fmt.Sprintf("%v %v error. <%v>", e.Service, e.Kind, e.Err)
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+//IsNetworkError - returns true if err, or any error it wraps, is an upstream network error
+func IsNetworkError(err error) bool {
+	return isKind(err, NetworkErrorKind)
+}
+
+//IsAuthError - returns true if err, or any error it wraps, is an upstream auth error
+func IsAuthError(err error) bool {
+	return isKind(err, AuthErrorKind)
+}
+
+//IsServerError - returns true if err, or any error it wraps, is an upstream server error
+func IsServerError(err error) bool {
+	return isKind(err, ServerErrorKind)
+}
+
+func isKind(err error, kind ErrorKind) bool {
+	var uErr *Error
+	return errors.As(err, &uErr) && uErr.Kind == kind
+}
@@ -0,0 +1,213 @@
+package upstream
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"github.com/sajeevany/graph-snapper/internal/common"
+	"github.com/sirupsen/logrus"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	//oauth2ExpiryDelta - access tokens are refreshed this long before they expire so that in flight requests don't fail
+	oauth2ExpiryDelta = 30 * time.Second
+)
+
+//SetAuthHeader - sets the authentication header of the auth type. OAuth2 client credentials are exchanged for an access
+//token at their token url. Tokens are cached until they expire or the endpoint rejects them. A token request rejected
+//by the identity provider is returned as an auth error
+func (c *Client) SetAuthHeader(logger *logrus.Entry, ep Endpoint, auth common.Auth, req *http.Request) error {
+
+	authType, err := auth.GetAuthType()
+	if err != nil {
+		logger.Debugf("Unable to determine auth type. <%v>", err)
+		return err
+	}
+	if authType != common.OAuth2ClientCredentialsAuthType {
+		return common.SetAuthHeader(logger, auth, req)
+	}
+
+	logger.Debug("Request has oauth2 client credentials. Setting bearer auth header with access token.")
+	token, tErr := c.tokens.get(req.Context(), logger, c, ep, auth.OAuth2ClientCredentials)
+	if tErr != nil {
+		logger.Debugf("Unable to fetch oauth2 access token. <%v>", tErr)
+		return tErr
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	return nil
+}
+
+//cacheKey - hash of the credentials so that secrets aren't held as map keys
+func cacheKey(creds common.OAuth2ClientCredentials) string {
+	sum := sha256.Sum256([]byte(strings.Join([]string{creds.TokenURL, creds.ClientID, creds.ClientSecret, creds.Scope}, "\x00")))
+	return hex.EncodeToString(sum[:])
+}
+
+type oauth2Token struct {
+	accessToken string
+	expiry      time.Time //Zero if the token doesn't expire
+}
+
+func (t oauth2Token) isValid(now time.Time) bool {
+	return t.accessToken != "" && (t.expiry.IsZero() || now.Add(oauth2ExpiryDelta).Before(t.expiry))
+}
+
+type oauth2TokenResp struct {
+	AccessToken string `json:"access_token"`
+	TokenType   string `json:"token_type"`
+	ExpiresIn   int64  `json:"expires_in"`
+}
+
+//oauth2TokenCache - access tokens keyed by the client credentials they were issued to
+type oauth2TokenCache struct {
+	mux    sync.Mutex
+	tokens map[string]oauth2Token
+	now    func() time.Time
+}
+
+func newOAuth2TokenCache() *oauth2TokenCache {
+	return &oauth2TokenCache{
+		tokens: make(map[string]oauth2Token),
+		now:    time.Now,
+	}
+}
+
+func (tc *oauth2TokenCache) get(ctx context.Context, logger *logrus.Entry, client *Client, ep Endpoint, creds common.OAuth2ClientCredentials) (string, error) {
+
+	key := cacheKey(creds)
+	tc.mux.Lock()
+	token, exists := tc.tokens[key]
+	tc.mux.Unlock()
+	if exists && token.isValid(tc.now()) {
+		return token.accessToken, nil
+	}
+
+	token, err := tc.fetch(ctx, logger, client, ep, creds)
+	if err != nil {
+		return "", err
+	}
+
+	tc.mux.Lock()
+	defer tc.mux.Unlock()
+	//Expired tokens of other credentials are evicted so that removed credentials don't stay cached
+	now := tc.now()
+	for k, t := range tc.tokens {
+		if !t.isValid(now) {
+			delete(tc.tokens, k)
+		}
+	}
+	tc.tokens[key] = token
+
+	return token.accessToken, nil
+}
+
+//invalidate - removes the access token so that the next request fetches a new one
+func (tc *oauth2TokenCache) invalidate(accessToken string) {
+	if accessToken == "" {
+		return
+	}
+	tc.mux.Lock()
+	defer tc.mux.Unlock()
+	for k, t := range tc.tokens {
+		if t.accessToken == accessToken {
+			delete(tc.tokens, k)
+		}
+	}
+}
+
+//fetch - requests an access token with the client credentials grant. Client credentials are sent using basic auth.
+//Token requests don't change state and are retried like idempotent requests
+func (tc *oauth2TokenCache) fetch(ctx context.Context, logger *logrus.Entry, client *Client, ep Endpoint, creds common.OAuth2ClientCredentials) (oauth2Token, error) {
+
+	tokenEP, err := tokenEndpoint(ep, creds.TokenURL)
+	if err != nil {
+		return oauth2Token{}, err
+	}
+
+	form := url.Values{"grant_type": {"client_credentials"}}
+	if creds.Scope != "" {
+		form.Set("scope", creds.Scope)
+	}
+	body := form.Encode()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, creds.TokenURL, strings.NewReader(body))
+	if err != nil {
+		return oauth2Token{}, err
+	}
+	req.SetBasicAuth(url.QueryEscape(creds.ClientID), url.QueryEscape(creds.ClientSecret))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+
+	issuedAt := tc.now()
+	resp, err := client.do(ctx, logger, tokenEP, "oauth2.Token", req, true)
+	if err != nil {
+		return oauth2Token{}, err
+	}
+	defer resp.Body.Close()
+
+	respBody, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return oauth2Token{}, err
+	}
+	switch {
+	//Identity providers reject invalid client credentials with a 400 invalid_client or invalid_grant error
+	case resp.StatusCode == http.StatusBadRequest:
+		return oauth2Token{}, &Error{Kind: AuthErrorKind, Service: tokenEP.Service, StatusCode: resp.StatusCode}
+	case resp.StatusCode != http.StatusOK:
+		return oauth2Token{}, fmt.Errorf("oauth2 token request to <%v> returned status code <%v>", creds.TokenURL, resp.StatusCode)
+	}
+
+	var tr oauth2TokenResp
+	if uErr := json.Unmarshal(respBody, &tr); uErr != nil || tr.AccessToken == "" {
+		return oauth2Token{}, fmt.Errorf("oauth2 token response from <%v> doesn't contain an access token", creds.TokenURL)
+	}
+	if tr.TokenType != "" && !strings.EqualFold(tr.TokenType, "bearer") {
+		return oauth2Token{}, fmt.Errorf("unsupported oauth2 token type <%v>", tr.TokenType)
+	}
+
+	token := oauth2Token{accessToken: tr.AccessToken}
+	if tr.ExpiresIn > 0 {
+		token.expiry = issuedAt.Add(time.Duration(tr.ExpiresIn) * time.Second)
+	}
+	return token, nil
+}
+
+//tokenEndpoint - endpoint of the token url. The identity provider is a different host than the credential's endpoint so
+//it's requested with default TLS and proxy settings. The endpoint's client certificate isn't presented to it and the
+//client secret isn't sent when certificate verification is disabled for the endpoint
+func tokenEndpoint(ep Endpoint, tokenURL string) (Endpoint, error) {
+
+	u, err := url.Parse(tokenURL)
+	if err != nil || u.Hostname() == "" {
+		return Endpoint{}, fmt.Errorf("invalid oauth2 token url <%v>", tokenURL)
+	}
+	port, _ := strconv.Atoi(u.Port())
+	if port == 0 && strings.EqualFold(u.Scheme, common.HTTPSScheme) {
+		port = 443
+	} else if port == 0 {
+		port = 80
+	}
+
+	return Endpoint{
+		Service: ep.Service + " oauth2",
+		Host:    u.Hostname(),
+		Port:    port,
+	}, nil
+}
+
+//bearerToken - returns the bearer token of the request's Authorization header
+func bearerToken(req *http.Request) string {
+	const prefix = "Bearer "
+	if h := req.Header.Get("Authorization"); strings.HasPrefix(h, prefix) {
+		return strings.TrimPrefix(h, prefix)
+	}
+	return ""
+}
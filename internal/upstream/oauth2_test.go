@@ -0,0 +1,178 @@
+package upstream
+
+import (
+	"context"
+	"encoding/pem"
+	"github.com/sajeevany/graph-snapper/internal/common"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+//newTokenServer - fake identity provider issuing numbered tokens to the graph-snapper client. Invalid client
+//credentials are rejected with a 400 invalid_client error
+func newTokenServer(tls bool) (*httptest.Server, *int32) {
+	var requests int32
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id, secret, _ := r.BasicAuth()
+		if r.Method != http.MethodPost || r.FormValue("grant_type") != "client_credentials" || r.FormValue("scope") != "read write" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		if id != "graph-snapper" || secret != "s3cret" {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"error":"invalid_client"}`))
+			return
+		}
+		n := atomic.AddInt32(&requests, 1)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"access_token":"token` + string('0'+n) + `","token_type":"Bearer","expires_in":300}`))
+	})
+	if tls {
+		return httptest.NewTLSServer(handler), &requests
+	}
+	return httptest.NewServer(handler), &requests
+}
+
+func oauth2Auth(tokenURL string) common.Auth {
+	return common.Auth{OAuth2ClientCredentials: common.OAuth2ClientCredentials{TokenURL: tokenURL, ClientID: "graph-snapper", ClientSecret: "s3cret", Scope: "read write"}}
+}
+
+//TestClient_SetAuthHeader_OAuth2 - Validates that access tokens are fetched with the client credentials and cached until
+//they're about to expire
+func TestClient_SetAuthHeader_OAuth2(t *testing.T) {
+
+	server, requests := newTokenServer(false)
+	defer server.Close()
+
+	client := New(testConf)
+	now := time.Now()
+	client.tokens.now = func() time.Time { return now }
+
+	auth := oauth2Auth(server.URL)
+	header := func() string {
+		req, _ := http.NewRequestWithContext(context.Background(), http.MethodGet, "http://grafana.local", nil)
+		if err := client.SetAuthHeader(testLogger(), Endpoint{Service: "grafana"}, auth, req); err != nil {
+			t.Fatalf("SetAuthHeader() unexpected error = %v", err)
+		}
+		return req.Header.Get("Authorization")
+	}
+
+	if got := header(); got != "Bearer token1" {
+		t.Errorf("Authorization = %v, want Bearer token1", got)
+	}
+	now = now.Add(4 * time.Minute)
+	if got := header(); got != "Bearer token1" {
+		t.Errorf("Authorization of cached token = %v, want Bearer token1", got)
+	}
+	now = now.Add(40 * time.Second)
+	if got := header(); got != "Bearer token2" {
+		t.Errorf("Authorization after expiry = %v, want Bearer token2", got)
+	}
+	if got := atomic.LoadInt32(requests); got != 2 {
+		t.Errorf("token requests = %v, want 2", got)
+	}
+
+	//Rejected client credentials are auth errors and fail the request instead of sending it without auth
+	auth.OAuth2ClientCredentials.ClientSecret = "wrong"
+	req, _ := http.NewRequest(http.MethodGet, "http://grafana.local", nil)
+	if err := client.SetAuthHeader(testLogger(), Endpoint{Service: "grafana"}, auth, req); !IsAuthError(err) || req.Header.Get("Authorization") != "" {
+		t.Errorf("SetAuthHeader() error = %v, Authorization = %v, want an auth error and no header", err, req.Header.Get("Authorization"))
+	}
+
+	//Expired tokens are evicted when a new token is cached
+	now = now.Add(time.Hour)
+	auth.OAuth2ClientCredentials.ClientSecret = "s3cret"
+	header()
+	if got := len(client.tokens.tokens); got != 1 {
+		t.Errorf("cached tokens = %v, want 1", got)
+	}
+}
+
+//TestClient_SetAuthHeader_OAuth2TLS - Validates that the token url isn't requested with the TLS and proxy settings of
+//the credential's endpoint
+func TestClient_SetAuthHeader_OAuth2TLS(t *testing.T) {
+
+	tlsServer, _ := newTokenServer(true)
+	defer tlsServer.Close()
+	caCert := string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: tlsServer.Certificate().Raw}))
+	server, _ := newTokenServer(false)
+	defer server.Close()
+
+	tests := []struct {
+		name     string
+		tokenURL string
+		ep       Endpoint
+		wantErr  bool
+	}{
+		{
+			name:     "Test0 - Token url isn't trusted with the endpoint's CA",
+			tokenURL: tlsServer.URL,
+			ep:       Endpoint{Service: "grafana", TLS: common.TLSConfigV1{CACert: caCert}},
+			wantErr:  true,
+		},
+		{
+			name:     "Test1 - Token url certificate is verified when the endpoint skips verification",
+			tokenURL: tlsServer.URL,
+			ep:       Endpoint{Service: "grafana", TLS: common.TLSConfigV1{InsecureSkipVerify: true}},
+			wantErr:  true,
+		},
+		{
+			name:     "Test2 - Token url isn't requested through the endpoint's proxy",
+			tokenURL: server.URL,
+			ep:       Endpoint{Service: "grafana", Proxy: "http://127.0.0.1:1"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, _ := http.NewRequest(http.MethodGet, "https://grafana.local", nil)
+			err := New(testConf).SetAuthHeader(testLogger(), tt.ep, oauth2Auth(tt.tokenURL), req)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("SetAuthHeader() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+//TestClient_Do_InvalidatesRejectedToken - Validates that a cached access token rejected with a 401 is fetched again
+func TestClient_Do_InvalidatesRejectedToken(t *testing.T) {
+
+	tokenServer, requests := newTokenServer(false)
+	defer tokenServer.Close()
+
+	//The first token is revoked
+	grafana := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer token2" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer grafana.Close()
+
+	client := New(testConf)
+	ep := Endpoint{Service: "grafana"}
+	do := func() error {
+		req := newGetRequest(t, grafana.URL)
+		if err := client.SetAuthHeader(testLogger(), ep, oauth2Auth(tokenServer.URL), req); err != nil {
+			t.Fatalf("SetAuthHeader() unexpected error = %v", err)
+		}
+		resp, err := client.Do(context.Background(), testLogger(), ep, "test", req)
+		if err == nil {
+			resp.Body.Close()
+		}
+		return err
+	}
+
+	if err := do(); !IsAuthError(err) {
+		t.Errorf("Do() with revoked token error = %v, want auth error", err)
+	}
+	if err := do(); err != nil {
+		t.Errorf("Do() with new token unexpected error = %v", err)
+	}
+	if got := atomic.LoadInt32(requests); got != 2 {
+		t.Errorf("token requests = %v, want 2", got)
+	}
+}